@@ -0,0 +1,63 @@
+package whisper
+
+import (
+	"sync"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// CGO
+
+/*
+#include <ggml.h>
+#include <stdbool.h>
+
+extern void callGgmlAbort(char* message);
+
+// Abort callback, called by ggml with the message before it aborts the process
+static void whisper_ggml_abort_cb(const char* message) {
+    callGgmlAbort((char*)message);
+}
+
+static void whisper_ggml_set_abort_cb(bool enable) {
+    ggml_set_abort_callback(enable ? whisper_ggml_abort_cb : NULL);
+}
+*/
+import "C"
+
+///////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+var (
+	abortMu sync.Mutex
+	abortFn func(string)
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Set a function which is called with the message of a fatal error in ggml,
+// such as a failed assertion of a backend, instead of printing it to stderr.
+// This is a diagnostics hook: ggml aborts the process once the function
+// returns, and the error cannot be recovered from, so it can only be used to
+// log the error or flush state. Failures which a backend reports instead,
+// such as a failed GPU command buffer, are returned by Whisper_full as a
+// *FullError. Pass nil to restore the default behaviour.
+func Ggml_set_abort_callback(fn func(message string)) {
+	abortMu.Lock()
+	defer abortMu.Unlock()
+	abortFn = fn
+	C.whisper_ggml_set_abort_cb(C.bool(fn != nil))
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// CALLBACKS
+
+//export callGgmlAbort
+func callGgmlAbort(message *C.char) {
+	abortMu.Lock()
+	fn := abortFn
+	abortMu.Unlock()
+	if fn != nil {
+		fn(C.GoString(message))
+	}
+}
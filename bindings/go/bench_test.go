@@ -0,0 +1,94 @@
+package whisper_test
+
+import (
+	"os"
+	"testing"
+
+	// Packages
+	whisper "github.com/ggerganov/whisper.cpp/bindings/go"
+	wav "github.com/go-audio/wav"
+)
+
+// Return a context which has processed the sample, for benchmarking the
+// accessors of the results
+func processedContext(b *testing.B) *whisper.Context {
+	b.Helper()
+	if _, err := os.Stat(ModelPath); os.IsNotExist(err) {
+		b.Skip("Skipping benchmark, model not found:", ModelPath)
+	}
+	fh, err := os.Open(SamplePath)
+	if err != nil {
+		b.Skip("Skipping benchmark, sample not found:", SamplePath)
+	}
+	defer fh.Close()
+	buf, err := wav.NewDecoder(fh).FullPCMBuffer()
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	ctx := whisper.Whisper_init(ModelPath)
+	if ctx == nil {
+		b.Fatal("Whisper_init failed")
+	}
+	b.Cleanup(ctx.Whisper_free)
+	params := ctx.Whisper_full_default_params(whisper.SAMPLING_GREEDY)
+	if err := ctx.Whisper_full(params, buf.AsFloat32Buffer().Data, nil, nil, nil); err != nil {
+		b.Fatal(err)
+	}
+	if ctx.Whisper_full_n_segments() == 0 {
+		b.Fatal("no segments")
+	}
+	return ctx
+}
+
+func Benchmark_Whisper_lang_str(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		whisper.Whisper_lang_str(i % whisper.Whisper_lang_max_id())
+	}
+}
+
+func Benchmark_Whisper_token_eot(b *testing.B) {
+	ctx := processedContext(b)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ctx.Whisper_token_eot()
+	}
+}
+
+func Benchmark_Whisper_full_get_segment_text(b *testing.B) {
+	ctx := processedContext(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ctx.Whisper_full_get_segment_text(0)
+	}
+}
+
+func Benchmark_Whisper_full_append_segment_text(b *testing.B) {
+	ctx := processedContext(b)
+	buf := make([]byte, 0, 1024)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		buf = ctx.Whisper_full_append_segment_text(buf[:0], 0)
+	}
+}
+
+func Benchmark_Whisper_full_get_token_text(b *testing.B) {
+	ctx := processedContext(b)
+	n := ctx.Whisper_full_n_tokens(0)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ctx.Whisper_full_get_token_text(0, i%n)
+	}
+}
+
+func Benchmark_Whisper_full_get_token_data(b *testing.B) {
+	ctx := processedContext(b)
+	n := ctx.Whisper_full_n_tokens(0)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ctx.Whisper_full_get_token_data(0, i%n)
+	}
+}
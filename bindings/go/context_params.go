@@ -0,0 +1,280 @@
+package whisper
+
+import (
+	"fmt"
+	"unsafe"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// CGO
+
+/*
+#include <whisper.h>
+#include <stdlib.h>
+
+extern bool callLoadProgress(void* user_data, size_t n_bytes, size_t n_bytes_total, int n_tensors, int n_tensors_total);
+
+// Load progress callback
+// Called after each tensor of the model is loaded
+// If it returns false, loading is cancelled
+bool whisper_load_progress_cb(size_t n_bytes, size_t n_bytes_total, int n_tensors, int n_tensors_total, void * user_data) {
+    return callLoadProgress(user_data, n_bytes, n_bytes_total, n_tensors, n_tensors_total);
+}
+*/
+import "C"
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+type (
+	ContextParams C.struct_whisper_context_params
+	AheadsPreset  C.enum_whisper_alignment_heads_preset
+)
+
+// Ahead is an alignment head used for token-level timestamps with DTW,
+// identified by a text layer and a head within that layer
+type Ahead struct {
+	TextLayer int
+	Head      int
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+const (
+	AHEADS_NONE           AheadsPreset = C.WHISPER_AHEADS_NONE
+	AHEADS_N_TOP_MOST     AheadsPreset = C.WHISPER_AHEADS_N_TOP_MOST // All heads from the N-top-most text layers
+	AHEADS_CUSTOM         AheadsPreset = C.WHISPER_AHEADS_CUSTOM
+	AHEADS_TINY_EN        AheadsPreset = C.WHISPER_AHEADS_TINY_EN
+	AHEADS_TINY           AheadsPreset = C.WHISPER_AHEADS_TINY
+	AHEADS_BASE_EN        AheadsPreset = C.WHISPER_AHEADS_BASE_EN
+	AHEADS_BASE           AheadsPreset = C.WHISPER_AHEADS_BASE
+	AHEADS_SMALL_EN       AheadsPreset = C.WHISPER_AHEADS_SMALL_EN
+	AHEADS_SMALL          AheadsPreset = C.WHISPER_AHEADS_SMALL
+	AHEADS_MEDIUM_EN      AheadsPreset = C.WHISPER_AHEADS_MEDIUM_EN
+	AHEADS_MEDIUM         AheadsPreset = C.WHISPER_AHEADS_MEDIUM
+	AHEADS_LARGE_V1       AheadsPreset = C.WHISPER_AHEADS_LARGE_V1
+	AHEADS_LARGE_V2       AheadsPreset = C.WHISPER_AHEADS_LARGE_V2
+	AHEADS_LARGE_V3       AheadsPreset = C.WHISPER_AHEADS_LARGE_V3
+	AHEADS_LARGE_V3_TURBO AheadsPreset = C.WHISPER_AHEADS_LARGE_V3_TURBO
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Return default parameters for loading a model
+func Whisper_context_default_params() ContextParams {
+	return ContextParams(C.whisper_context_default_params())
+}
+
+// Allocates all memory needed for the model and loads the model from the
+// given file, using the given parameters. Returns NULL on failure.
+func Whisper_init_with_params(path string, params ContextParams) *Context {
+	cPath := C.CString(path)
+	defer C.free(unsafe.Pointer(cPath))
+	if ctx := C.whisper_init_from_file_with_params(cPath, (C.struct_whisper_context_params)(params)); ctx != nil {
+		return (*Context)(ctx)
+	} else {
+		return nil
+	}
+}
+
+// Allocates all memory needed for the model and loads the model from the
+// given buffer, using the given parameters. The weights are copied, so the
+// buffer can be released once the call returns. Returns NULL on failure.
+func Whisper_init_from_buffer_with_params(buf []byte, params ContextParams) *Context {
+	if len(buf) == 0 {
+		return nil
+	}
+	if ctx := C.whisper_init_from_buffer_with_params(unsafe.Pointer(&buf[0]), C.size_t(len(buf)), (C.struct_whisper_context_params)(params)); ctx != nil {
+		return (*Context)(ctx)
+	} else {
+		return nil
+	}
+}
+
+// Load the model from the given file without allocating the default state,
+// which is allocated by Whisper_reset_state. Returns NULL on failure.
+func Whisper_init_with_params_no_state(path string, params ContextParams) *Context {
+	cPath := C.CString(path)
+	defer C.free(unsafe.Pointer(cPath))
+	if ctx := C.whisper_init_from_file_with_params_no_state(cPath, (C.struct_whisper_context_params)(params)); ctx != nil {
+		return (*Context)(ctx)
+	} else {
+		return nil
+	}
+}
+
+// Load the model from the given buffer without allocating the default
+// state, which is allocated by Whisper_reset_state. Returns NULL on failure.
+func Whisper_init_from_buffer_with_params_no_state(buf []byte, params ContextParams) *Context {
+	if len(buf) == 0 {
+		return nil
+	}
+	if ctx := C.whisper_init_from_buffer_with_params_no_state(unsafe.Pointer(&buf[0]), C.size_t(len(buf)), (C.struct_whisper_context_params)(params)); ctx != nil {
+		return (*Context)(ctx)
+	} else {
+		return nil
+	}
+}
+
+// Use the GPU for inference, when the library is built with GPU support
+func (p *ContextParams) SetUseGPU(v bool) {
+	p.use_gpu = toBool(v)
+}
+
+// Use flash attention, when supported by the backend
+func (p *ContextParams) SetFlashAttn(v bool) {
+	p.flash_attn = toBool(v)
+}
+
+func (p *ContextParams) UseGPU() bool {
+	return bool(p.use_gpu)
+}
+
+func (p *ContextParams) FlashAttn() bool {
+	return bool(p.flash_attn)
+}
+
+// Select the GPU used for inference, counting only GPU devices of the
+// registered backends from zero
+func (p *ContextParams) SetGPUDevice(n int) {
+	p.gpu_device = C.int(n)
+}
+
+func (p *ContextParams) GPUDevice() int {
+	return int(p.gpu_device)
+}
+
+// Set custom alignment heads for token-level timestamps with DTW,
+// for models whose alignment heads differ from the stock checkpoints.
+// This also enables DTW token-level timestamps. The heads are copied
+// into memory which is never freed, as the native context refers to
+// them for as long as it exists.
+func (p *ContextParams) SetDTWAheads(heads []Ahead) {
+	if len(heads) == 0 {
+		p.dtw_aheads_preset = C.WHISPER_AHEADS_NONE
+		p.dtw_aheads.n_heads = 0
+		p.dtw_aheads.heads = nil
+		return
+	}
+	ptr := (*C.whisper_ahead)(C.malloc(C.size_t(len(heads)) * C.size_t(unsafe.Sizeof(C.whisper_ahead{}))))
+	dst := unsafe.Slice(ptr, len(heads))
+	for i, head := range heads {
+		dst[i].n_text_layer = C.int(head.TextLayer)
+		dst[i].n_head = C.int(head.Head)
+	}
+	p.dtw_token_timestamps = toBool(true)
+	p.dtw_aheads_preset = C.WHISPER_AHEADS_CUSTOM
+	p.dtw_aheads.n_heads = C.size_t(len(heads))
+	p.dtw_aheads.heads = ptr
+}
+
+// Compute token-level timestamps with DTW, using the alignment heads of
+// the preset. This is disabled by the native library when flash attention
+// is enabled.
+func (p *ContextParams) SetDTWTokenTimestamps(v bool) {
+	p.dtw_token_timestamps = toBool(v)
+}
+
+func (p *ContextParams) DTWTokenTimestamps() bool {
+	return bool(p.dtw_token_timestamps)
+}
+
+// Set the alignment heads used for DTW to those of a stock checkpoint, or to
+// all heads of the top text layers with AHEADS_N_TOP_MOST. Custom heads are
+// set with SetDTWAheads.
+func (p *ContextParams) SetDTWAheadsPreset(v AheadsPreset) {
+	p.dtw_aheads_preset = C.enum_whisper_alignment_heads_preset(v)
+}
+
+func (p *ContextParams) DTWAheadsPreset() AheadsPreset {
+	return AheadsPreset(p.dtw_aheads_preset)
+}
+
+// Set the number of top text layers whose heads are used with
+// AHEADS_N_TOP_MOST
+func (p *ContextParams) SetDTWNTop(n int) {
+	p.dtw_n_top = C.int(n)
+}
+
+func (p *ContextParams) DTWNTop() int {
+	return int(p.dtw_n_top)
+}
+
+// Set the size in bytes of the memory used to compute DTW
+func (p *ContextParams) SetDTWMemSize(n uint64) {
+	p.dtw_mem_size = C.size_t(n)
+}
+
+func (p *ContextParams) DTWMemSize() uint64 {
+	return uint64(p.dtw_mem_size)
+}
+
+// Set the callback for the progress of loading the model. The callback is
+// registered by memory which is allocated once and never freed, as copies
+// of the parameters refer to it, so clearing it with nil also clears it
+// for the copies.
+func (p *ContextParams) SetLoadProgressCallback(fn LoadProgressCallback) {
+	key := p.load_progress_callback_user_data
+	if fn == nil {
+		if key != nil {
+			registerLoadProgressCallback(key, nil)
+		}
+		p.load_progress_callback = nil
+		p.load_progress_callback_user_data = nil
+		return
+	}
+	if key == nil {
+		key = C.malloc(1)
+	}
+	registerLoadProgressCallback(key, fn)
+	p.load_progress_callback = C.whisper_load_progress_callback(C.whisper_load_progress_cb)
+	p.load_progress_callback_user_data = key
+}
+
+// Return the load progress callback
+func (p *ContextParams) LoadProgressCallback() LoadProgressCallback {
+	if p.load_progress_callback_user_data == nil {
+		return nil
+	}
+	return lookupLoadProgressCallback(p.load_progress_callback_user_data)
+}
+
+// Return the custom alignment heads for token-level timestamps with DTW
+func (p *ContextParams) DTWAheads() []Ahead {
+	if p.dtw_aheads_preset != C.WHISPER_AHEADS_CUSTOM || p.dtw_aheads.heads == nil {
+		return nil
+	}
+	src := unsafe.Slice(p.dtw_aheads.heads, p.dtw_aheads.n_heads)
+	result := make([]Ahead, len(src))
+	for i, head := range src {
+		result[i] = Ahead{TextLayer: int(head.n_text_layer), Head: int(head.n_head)}
+	}
+	return result
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (p *ContextParams) String() string {
+	str := "<whisper.context_params"
+	str += fmt.Sprintf(" gpu_device=%d", p.gpu_device)
+	str += fmt.Sprintf(" dtw_aheads_preset=%d", p.dtw_aheads_preset)
+	if heads := p.DTWAheads(); len(heads) > 0 {
+		str += fmt.Sprintf(" dtw_aheads=%v", heads)
+	}
+	if p.use_gpu {
+		str += " use_gpu"
+	}
+	if p.flash_attn {
+		str += " flash_attn"
+	}
+	if p.dtw_token_timestamps {
+		str += " dtw_token_timestamps"
+		if p.dtw_aheads_preset == C.WHISPER_AHEADS_N_TOP_MOST {
+			str += fmt.Sprintf(" dtw_n_top=%d", p.dtw_n_top)
+		}
+	}
+	return str + ">"
+}
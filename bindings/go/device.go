@@ -0,0 +1,88 @@
+package whisper
+
+import (
+	"unsafe"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// CGO
+
+/*
+#include <ggml-backend.h>
+*/
+import "C"
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+type (
+	BackendDevice     C.struct_ggml_backend_device
+	BackendDeviceType C.enum_ggml_backend_dev_type
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+const (
+	DEVICE_TYPE_CPU   BackendDeviceType = C.GGML_BACKEND_DEVICE_TYPE_CPU
+	DEVICE_TYPE_GPU   BackendDeviceType = C.GGML_BACKEND_DEVICE_TYPE_GPU
+	DEVICE_TYPE_IGPU  BackendDeviceType = C.GGML_BACKEND_DEVICE_TYPE_IGPU  // Integrated GPU using host memory
+	DEVICE_TYPE_ACCEL BackendDeviceType = C.GGML_BACKEND_DEVICE_TYPE_ACCEL // Accelerator used together with the CPU, such as BLAS
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Return the number of devices of all registered backends
+func Ggml_backend_dev_count() int {
+	return int(C.ggml_backend_dev_count())
+}
+
+// Return a device by index, or nil if the index is out of range
+func Ggml_backend_dev_get(i int) *BackendDevice {
+	if i < 0 || i >= Ggml_backend_dev_count() {
+		return nil
+	}
+	return (*BackendDevice)(unsafe.Pointer(C.ggml_backend_dev_get(C.size_t(i))))
+}
+
+func (dev *BackendDevice) Name() string {
+	return C.GoString(C.ggml_backend_dev_name(dev.native()))
+}
+
+func (dev *BackendDevice) Description() string {
+	return C.GoString(C.ggml_backend_dev_description(dev.native()))
+}
+
+// Return the name of the backend of the device, such as CPU, CUDA or Metal
+func (dev *BackendDevice) Backend() string {
+	reg := C.ggml_backend_dev_backend_reg(dev.native())
+	if reg == nil {
+		return ""
+	}
+	return C.GoString(C.ggml_backend_reg_name(reg))
+}
+
+func (dev *BackendDevice) Type() BackendDeviceType {
+	return BackendDeviceType(C.ggml_backend_dev_type(dev.native()))
+}
+
+// Return the free and total memory of the device in bytes
+func (dev *BackendDevice) Memory() (uint64, uint64) {
+	var free, total C.size_t
+	C.ggml_backend_dev_memory(dev.native(), &free, &total)
+	return uint64(free), uint64(total)
+}
+
+// Return true if the device is a GPU, which can be selected when loading a
+// model with SetGPUDevice
+func (t BackendDeviceType) IsGPU() bool {
+	return t == DEVICE_TYPE_GPU || t == DEVICE_TYPE_IGPU
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+func (dev *BackendDevice) native() C.ggml_backend_dev_t {
+	return C.ggml_backend_dev_t(unsafe.Pointer(dev))
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	// Package imports
+	whisper "github.com/ggerganov/whisper.cpp/bindings/go/pkg/whisper"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// Dictation records the microphone while it is toggled on, and types each
+// utterance into the focused window as soon as the speaker pauses
+type Dictation struct {
+	transcriber whisper.Transcriber
+	recorder    Recorder
+	injector    Injector
+
+	typed bool // Whether text was typed, so that the next needs a space
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// LIFECYCLE
+
+// Return a dictation which transcribes with a context of the model
+func NewDictation(transcriber whisper.Transcriber, recorder Recorder, injector Injector) *Dictation {
+	return &Dictation{transcriber: transcriber, recorder: recorder, injector: injector}
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Toggle dictation with the hotkey until the context is cancelled or the
+// hotkey is no longer available
+func (d *Dictation) Run(ctx context.Context, hotkey Hotkey) error {
+	toggles := hotkey.Toggles()
+	for {
+		fmt.Fprintln(os.Stderr, "Press the hotkey to start dictation")
+		select {
+		case <-ctx.Done():
+			return nil
+		case _, ok := <-toggles:
+			if !ok {
+				return nil
+			}
+		}
+
+		// Dictate until the hotkey is pressed again
+		fmt.Fprintln(os.Stderr, "Listening, press the hotkey to stop")
+		session, stop := context.WithCancel(ctx)
+		done := make(chan error, 1)
+		go func() {
+			done <- d.dictate(ctx, session)
+		}()
+		var err error
+		select {
+		case <-toggles:
+			stop()
+			err = <-done
+		case err = <-done:
+			// The recording ended by itself
+		}
+		stop()
+		if err != nil {
+			return err
+		}
+	}
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// Record and type utterances until the session is cancelled, then type the
+// rest of the speech. Typing uses the parent context, so that the last
+// utterance is still typed when the session stops.
+func (d *Dictation) dictate(ctx, session context.Context) error {
+	recording, err := d.recorder.Record(session)
+	if err != nil {
+		return err
+	}
+	defer recording.Close()
+
+	endpointer := whisper.NewEndpointer(d.transcriber, whisper.EndpointOptions{})
+	buf := make([]byte, chunkBytes())
+	for {
+		data, err := readChunk(recording, buf)
+		if err != nil {
+			// The recording is killed when the session stops
+			if !errors.Is(err, io.EOF) && session.Err() == nil {
+				return err
+			}
+			break
+		}
+		utterances, err := endpointer.Write(data)
+		if err != nil {
+			return err
+		}
+		if err := d.inject(ctx, utterances); err != nil {
+			return err
+		}
+	}
+	utterances, err := endpointer.Flush()
+	if err != nil {
+		return err
+	}
+	return d.inject(ctx, utterances)
+}
+
+// Type the text of the utterances, separated by spaces
+func (d *Dictation) inject(ctx context.Context, utterances []whisper.Utterance) error {
+	for _, utterance := range utterances {
+		text := strings.TrimSpace(utterance.Text)
+		if text == "" {
+			continue
+		}
+		if d.typed {
+			text = " " + text
+		}
+		if err := d.injector.Type(ctx, text); err != nil {
+			return err
+		}
+		d.typed = true
+	}
+	return nil
+}
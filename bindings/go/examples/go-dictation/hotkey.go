@@ -0,0 +1,49 @@
+package main
+
+import (
+	"bufio"
+	"io"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// Hotkey toggles dictation. Implement it with a global hotkey library, or
+// with a tray icon, to toggle dictation while another window has the focus.
+type Hotkey interface {
+	// Return a channel which receives a value each time the hotkey is
+	// pressed, and which is closed when the hotkey is no longer available
+	Toggles() <-chan struct{}
+}
+
+// TerminalHotkey is pressed by entering a line in the terminal
+type TerminalHotkey struct {
+	toggles chan struct{}
+}
+
+// Make sure TerminalHotkey adheres to the interface
+var _ Hotkey = (*TerminalHotkey)(nil)
+
+///////////////////////////////////////////////////////////////////////////////
+// LIFECYCLE
+
+// Return a hotkey which is pressed by each line read from r, until the end
+// of r
+func NewTerminalHotkey(r io.Reader) *TerminalHotkey {
+	hotkey := &TerminalHotkey{toggles: make(chan struct{})}
+	go func() {
+		defer close(hotkey.toggles)
+		scanner := bufio.NewScanner(r)
+		for scanner.Scan() {
+			hotkey.toggles <- struct{}{}
+		}
+	}()
+	return hotkey
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+func (h *TerminalHotkey) Toggles() <-chan struct{} {
+	return h.toggles
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"slices"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// Injector types text into the focused window. Implement it with an input
+// injection library to type without an external command.
+type Injector interface {
+	Type(ctx context.Context, text string) error
+}
+
+// CommandInjector types with a command which takes the text as its last
+// argument, such as xdotool on X11 or wtype on Wayland
+type CommandInjector struct {
+	Name string
+	Args []string
+}
+
+// StdoutInjector prints the text instead of typing it
+type StdoutInjector struct{}
+
+// Make sure the injectors adhere to the interface
+var _ Injector = (*CommandInjector)(nil)
+var _ Injector = StdoutInjector{}
+
+///////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+// Types the first argument with the System Events of macOS, which needs the
+// accessibility permission
+var osascript = []string{
+	"-e", "on run argv",
+	"-e", `tell application "System Events" to keystroke (item 1 of argv)`,
+	"-e", "end run",
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// LIFECYCLE
+
+// Return an injector for the platform whose command is found, or one which
+// prints the text if there is none
+func DefaultInjector() Injector {
+	var candidates []CommandInjector
+	switch {
+	case runtime.GOOS == "darwin":
+		candidates = append(candidates, CommandInjector{Name: "osascript", Args: osascript})
+	case os.Getenv("WAYLAND_DISPLAY") != "":
+		candidates = append(candidates, CommandInjector{Name: "wtype", Args: []string{"--"}})
+		fallthrough
+	default:
+		candidates = append(candidates, CommandInjector{Name: "xdotool", Args: []string{"type", "--clearmodifiers", "--"}})
+	}
+	for _, candidate := range candidates {
+		if _, err := exec.LookPath(candidate.Name); err == nil {
+			return &candidate
+		}
+	}
+	return StdoutInjector{}
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+func (i *CommandInjector) Type(ctx context.Context, text string) error {
+	cmd := exec.CommandContext(ctx, i.Name, slices.Concat(i.Args, []string{text})...)
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s: %w", i.Name, err)
+	}
+	return nil
+}
+
+func (StdoutInjector) Type(_ context.Context, text string) error {
+	_, err := fmt.Print(text)
+	return err
+}
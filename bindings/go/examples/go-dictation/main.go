@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+
+	// Package imports
+	whisper "github.com/ggerganov/whisper.cpp/bindings/go/pkg/whisper"
+)
+
+var (
+	// The model used to transcribe the dictation
+	flagModel = flag.String("model", "", "Path to the model file")
+
+	// The spoken language, or auto to detect it
+	flagLanguage = flag.String("language", "en", "Spoken language")
+
+	// The command which records the microphone, writing raw 16kHz mono
+	// signed 16-bit little endian audio to stdout. When not set, a command
+	// is chosen for the platform.
+	flagRecord = flag.String("record", "", "Command which records the microphone")
+
+	// How the text is typed into the focused window: auto to use xdotool,
+	// wtype or osascript, whichever is found, or stdout to print it
+	flagInject = flag.String("inject", "auto", "Text injection (auto or stdout)")
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// MAIN
+
+func main() {
+	flag.Usage = func() {
+		name := filepath.Base(flag.CommandLine.Name())
+		fmt.Fprintf(flag.CommandLine.Output(), `
+			Usage: %s [options]
+
+			Dictates into the focused window. Press Enter to start listening, and
+			Enter again to stop. Each utterance is typed as soon as you pause.
+
+			Options:
+		`, name)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if *flagModel == "" {
+		fmt.Fprintln(os.Stderr, "Use -model flag to specify which model file to use")
+		os.Exit(1)
+	}
+
+	// Stop on interrupt
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+
+	// Choose the recorder and the injector
+	recorder := DefaultRecorder()
+	if *flagRecord != "" {
+		recorder = NewCommandRecorder(strings.Fields(*flagRecord)...)
+	}
+	var injector Injector = StdoutInjector{}
+	if *flagInject == "auto" {
+		injector = DefaultInjector()
+	} else if *flagInject != "stdout" {
+		fmt.Fprintln(os.Stderr, "Unsupported injection:", *flagInject)
+		os.Exit(1)
+	}
+
+	// Load model
+	model, err := whisper.New(*flagModel)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	defer model.Close()
+
+	// Create the context which transcribes the utterances
+	context, err := model.NewContext()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if model.IsMultilingual() {
+		if err := context.SetLanguage(*flagLanguage); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+
+	// Dictate until interrupted, or until stdin is closed
+	dictation := NewDictation(context, recorder, injector)
+	if err := dictation.Run(ctx, NewTerminalHotkey(os.Stdin)); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"time"
+
+	// Package imports
+	whisper "github.com/ggerganov/whisper.cpp/bindings/go/pkg/whisper"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// Recorder records the microphone while dictation is on. Implement it with
+// an audio library to record without an external command.
+type Recorder interface {
+	// Start recording, returning 16kHz mono signed 16-bit little endian
+	// audio until the context is cancelled
+	Record(ctx context.Context) (io.ReadCloser, error)
+}
+
+// CommandRecorder records with a command which writes the audio to stdout,
+// such as arecord or sox
+type CommandRecorder struct {
+	Name string
+	Args []string
+}
+
+// The stdout of a recording command, which waits for the command on close
+type recording struct {
+	io.ReadCloser
+	cmd *exec.Cmd
+}
+
+// Make sure CommandRecorder adheres to the interface
+var _ Recorder = (*CommandRecorder)(nil)
+
+///////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+// The audio is read and written to the endpointer in chunks
+const chunkSize = 100 * time.Millisecond
+
+///////////////////////////////////////////////////////////////////////////////
+// LIFECYCLE
+
+// Return a recorder which runs a command with arguments
+func NewCommandRecorder(args ...string) *CommandRecorder {
+	if len(args) == 0 {
+		return &CommandRecorder{}
+	}
+	return &CommandRecorder{Name: args[0], Args: args[1:]}
+}
+
+// Return a recorder for the default microphone of the platform, with ALSA
+// on Linux and sox elsewhere
+func DefaultRecorder() *CommandRecorder {
+	if runtime.GOOS == "linux" {
+		return NewCommandRecorder("arecord", "-q", "-f", "S16_LE", "-r", "16000", "-c", "1", "-t", "raw")
+	}
+	return NewCommandRecorder("sox", "-q", "-d", "-t", "raw", "-r", "16000", "-c", "1", "-b", "16", "-e", "signed-integer", "-")
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+func (r *CommandRecorder) Record(ctx context.Context) (io.ReadCloser, error) {
+	if r.Name == "" {
+		return nil, errors.New("no recording command")
+	}
+	cmd := exec.CommandContext(ctx, r.Name, r.Args...)
+	cmd.Stderr = os.Stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	return &recording{ReadCloser: stdout, cmd: cmd}, nil
+}
+
+func (r *recording) Close() error {
+	r.ReadCloser.Close()
+	return r.cmd.Wait()
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// Read the next chunk of audio, returning io.EOF at the end of the recording
+func readChunk(r io.Reader, buf []byte) ([]float32, error) {
+	n, err := io.ReadFull(r, buf)
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		err = nil
+	} else if err != nil {
+		return nil, err
+	}
+	data := make([]float32, n/2)
+	for i := range data {
+		data[i] = float32(int16(binary.LittleEndian.Uint16(buf[2*i:]))) / 32768
+	}
+	return data, err
+}
+
+// Return the size in bytes of a chunk of audio
+func chunkBytes() int {
+	return 2 * int(chunkSize*whisper.SampleRate/time.Second)
+}
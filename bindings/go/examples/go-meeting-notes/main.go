@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"time"
+
+	// Package imports
+	whisper "github.com/ggerganov/whisper.cpp/bindings/go/pkg/whisper"
+)
+
+var (
+	// The model used to transcribe the meeting
+	flagModel = flag.String("model", "", "Path to the model file")
+
+	// The spoken language, or auto to detect it
+	flagLanguage = flag.String("language", "en", "Spoken language")
+
+	// The number of contexts which transcribe the speakers concurrently
+	flagContexts = flag.Int("contexts", 2, "Number of concurrent contexts")
+
+	// The meeting title and the template the notes are rendered with
+	flagTitle    = flag.String("title", "Meeting", "Meeting title")
+	flagTemplate = flag.String("template", "", "Template file for the notes (default is markdown)")
+
+	// An OpenAI compatible chat completions endpoint, which summarizes the
+	// transcript. The API key is read from the LLM_API_KEY environment
+	// variable. When not set, the notes have no summary.
+	flagLLM      = flag.String("llm", "", "URL of a chat completions endpoint")
+	flagLLMModel = flag.String("llm-model", "", "Name of the LLM model")
+
+	// The output file. When not set, write to stdout.
+	flagOut = flag.String("out", "", "Output file")
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// MAIN
+
+func main() {
+	flag.Usage = func() {
+		name := filepath.Base(flag.CommandLine.Name())
+		fmt.Fprintf(flag.CommandLine.Output(), `
+			Usage: %s [options] [<speaker>=]<file.wav>...
+
+			Transcribes a meeting recorded with one 16kHz mono WAV file per speaker,
+			and writes the meeting notes. The speaker is named after the file unless
+			a name is given.
+
+			Options:
+		`, name)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if *flagModel == "" {
+		fmt.Fprintln(os.Stderr, "Use -model flag to specify which model file to use")
+		os.Exit(1)
+	} else if flag.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "No input files specified")
+		os.Exit(1)
+	}
+
+	// Cancel the pipeline on interrupt
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+
+	// Load model
+	model, err := whisper.New(*flagModel)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	defer model.Close()
+
+	// Transcribe the speakers into one transcript
+	speakers := Speakers(flag.Args())
+	start := time.Now()
+	transcript, err := Transcribe(ctx, model, speakers)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	// Summarize the transcript
+	notes := NewNotes(*flagTitle, start, speakers, transcript)
+	if *flagLLM != "" {
+		var summarizer Summarizer = &ChatSummarizer{URL: *flagLLM, Model: *flagLLMModel, Key: os.Getenv("LLM_API_KEY")}
+		if notes.Summary, err = summarizer.Summarize(ctx, notes.Text()); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+
+	// Render the notes
+	w := os.Stdout
+	if *flagOut != "" {
+		if w, err = os.Create(*flagOut); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		defer w.Close()
+	}
+	if err := notes.Render(w, *flagTemplate); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// Speakers returns the speakers of the arguments, which are either a file
+// or a name and a file separated by an equals sign
+func Speakers(args []string) []Speaker {
+	var result []Speaker
+	for _, arg := range args {
+		name, path, found := strings.Cut(arg, "=")
+		if !found {
+			path = arg
+			name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+		}
+		result = append(result, Speaker{Name: name, Path: path})
+	}
+	return result
+}
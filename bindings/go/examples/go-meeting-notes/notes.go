@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// Notes are the data the template is rendered with
+type Notes struct {
+	Title      string
+	Date       time.Time
+	Duration   time.Duration
+	Speakers   []string
+	Transcript []Line
+	Summary    string // Summary from the LLM, or empty
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+// The template used when none is given with -template
+const defaultTemplate = `# {{ .Title }}
+
+{{ .Date.Format "2006-01-02 15:04" }}, {{ duration .Duration }}
+
+Attendees: {{ join .Speakers ", " }}
+{{ if .Summary }}
+## Summary
+
+{{ .Summary }}
+{{ end }}
+## Transcript
+{{ range .Transcript }}
+**{{ .Speaker }}** [{{ timestamp .Start }}] {{ .Text }}
+{{ end -}}
+`
+
+var funcs = template.FuncMap{
+	"join":      strings.Join,
+	"duration":  func(d time.Duration) string { return d.Round(time.Second).String() },
+	"timestamp": timestamp,
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// LIFECYCLE
+
+func NewNotes(title string, date time.Time, speakers []Speaker, transcript []Line) *Notes {
+	notes := &Notes{Title: title, Date: date, Transcript: transcript}
+	for _, speaker := range speakers {
+		notes.Speakers = append(notes.Speakers, speaker.Name)
+	}
+	for _, line := range transcript {
+		notes.Duration = max(notes.Duration, line.End)
+	}
+	return notes
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Text returns the transcript as plain text, with one line for each segment
+// labelled with its speaker, which is the input to the LLM
+func (notes *Notes) Text() string {
+	var str strings.Builder
+	for _, line := range notes.Transcript {
+		fmt.Fprintf(&str, "[%s] %s: %s\n", timestamp(line.Start), line.Speaker, strings.TrimSpace(line.Text))
+	}
+	return str.String()
+}
+
+// Render the notes with the template in the file at path, or with the
+// default template if path is empty
+func (notes *Notes) Render(w io.Writer, path string) error {
+	text := defaultTemplate
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		text = string(data)
+	}
+	tmpl, err := template.New("notes").Funcs(funcs).Parse(text)
+	if err != nil {
+		return err
+	}
+	return tmpl.Execute(w, notes)
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+func timestamp(d time.Duration) string {
+	d = d.Truncate(time.Second)
+	return fmt.Sprintf("%02d:%02d:%02d", int(d.Hours()), int(d.Minutes())%60, int(d.Seconds())%60)
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// Summarizer turns a transcript into a summary. Implement it to call any
+// LLM from the pipeline.
+type Summarizer interface {
+	Summarize(ctx context.Context, transcript string) (string, error)
+}
+
+// ChatSummarizer calls an OpenAI compatible chat completions endpoint, such
+// as the server of llama.cpp
+type ChatSummarizer struct {
+	URL   string
+	Model string
+	Key   string // API key, which is optional for local servers
+}
+
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type chatRequest struct {
+	Model    string        `json:"model,omitempty"`
+	Messages []chatMessage `json:"messages"`
+}
+
+type chatResponse struct {
+	Choices []struct {
+		Message chatMessage `json:"message"`
+	} `json:"choices"`
+}
+
+// Make sure ChatSummarizer adheres to the interface
+var _ Summarizer = (*ChatSummarizer)(nil)
+
+///////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+const summaryPrompt = `You write meeting notes. Each line of the transcript is a timestamp, the
+name of the speaker and what they said. Summarize the meeting in a few
+sentences, then list the decisions made and the action items with their
+owners as markdown bullet points.`
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+func (s *ChatSummarizer) Summarize(ctx context.Context, transcript string) (string, error) {
+	body, err := json.Marshal(chatRequest{
+		Model: s.Model,
+		Messages: []chatMessage{
+			{Role: "system", Content: summaryPrompt},
+			{Role: "user", Content: transcript},
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.URL, bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.Key != "" {
+		req.Header.Set("Authorization", "Bearer "+s.Key)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("summarize: %s", resp.Status)
+	}
+	var result chatResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", err
+	} else if len(result.Choices) == 0 {
+		return "", fmt.Errorf("summarize: no choices in response")
+	}
+	return strings.TrimSpace(result.Choices[0].Message.Content), nil
+}
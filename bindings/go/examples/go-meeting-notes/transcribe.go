@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	// Package imports
+	whisper "github.com/ggerganov/whisper.cpp/bindings/go/pkg/whisper"
+	wav "github.com/go-audio/wav"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// Speaker is a participant of the meeting, recorded in their own file
+type Speaker struct {
+	Name string
+	Path string
+}
+
+// Line is a segment of the transcript, labelled with its speaker
+type Line struct {
+	Speaker    string
+	Start, End time.Duration
+	Text       string
+}
+
+// The audio is written to the streams in chunks, as it would arrive live
+const chunkSize = 100 * time.Millisecond
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Transcribe the speakers at the same time, with one stream for each, and
+// return the lines of all speakers in order of time
+func Transcribe(ctx context.Context, model whisper.Model, speakers []Speaker) ([]Line, error) {
+	pool := whisper.NewContextPool(model, *flagContexts)
+	defer pool.Close()
+	if err := configure(model, pool); err != nil {
+		return nil, err
+	}
+
+	// Open a stream for each speaker, and transcribe them concurrently
+	manager := whisper.NewStreamManager(pool, whisper.EndpointOptions{})
+	names := make(map[whisper.StreamID]string)
+	type result struct {
+		segments []whisper.StreamSegment
+		err      error
+	}
+	results := make(chan result, len(speakers))
+	for _, speaker := range speakers {
+		id := manager.Open(0)
+		names[id] = speaker.Name
+		go func() {
+			segments, err := transcribe(ctx, manager, id, speaker.Path)
+			if err != nil {
+				err = fmt.Errorf("%s: %w", speaker.Name, err)
+			}
+			results <- result{segments, err}
+		}()
+	}
+
+	// Mix the segments of the speakers
+	var segments [][]whisper.StreamSegment
+	for range speakers {
+		result := <-results
+		if result.err != nil {
+			return nil, result.err
+		}
+		segments = append(segments, result.segments)
+	}
+	var lines []Line
+	for _, segment := range whisper.MixSegments(segments...) {
+		lines = append(lines, Line{Speaker: names[segment.Stream], Start: segment.Start, End: segment.End, Text: segment.Text})
+	}
+	return lines, nil
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// Set the language of the contexts of the pool, which keep it while they
+// are idle. English-only models have no language to set.
+func configure(model whisper.Model, pool *whisper.ContextPool) error {
+	if !model.IsMultilingual() {
+		return nil
+	}
+	var contexts []whisper.Context
+	defer func() {
+		for _, context := range contexts {
+			pool.Put(context)
+		}
+	}()
+	for range pool.Capacity() {
+		context, err := pool.Get()
+		if err != nil {
+			return err
+		}
+		contexts = append(contexts, context)
+		if err := context.SetLanguage(*flagLanguage); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Write the file of a speaker to its stream, and return its segments
+func transcribe(ctx context.Context, manager *whisper.StreamManager, id whisper.StreamID, path string) ([]whisper.StreamSegment, error) {
+	data, err := load(path)
+	if err != nil {
+		return nil, err
+	}
+	var result []whisper.StreamSegment
+	n := int(chunkSize * whisper.SampleRate / time.Second)
+	for i := 0; i < len(data); i += n {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		segments, err := manager.Write(id, data[i:min(i+n, len(data))])
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, segments...)
+	}
+	segments, err := manager.Close(id)
+	return append(result, segments...), err
+}
+
+// Decode a 16kHz mono WAV file
+func load(path string) ([]float32, error) {
+	fh, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer fh.Close()
+
+	dec := wav.NewDecoder(fh)
+	if buf, err := dec.FullPCMBuffer(); err != nil {
+		return nil, err
+	} else if dec.SampleRate != whisper.SampleRate {
+		return nil, fmt.Errorf("unsupported sample rate: %d", dec.SampleRate)
+	} else if dec.NumChans != 1 {
+		return nil, fmt.Errorf("unsupported number of channels: %d", dec.NumChans)
+	} else {
+		return buf.AsFloat32Buffer().Data, nil
+	}
+}
@@ -2,6 +2,9 @@ package main
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"io"
@@ -20,6 +23,7 @@ import (
 const (
 	srcUrl  = "https://huggingface.co/ggerganov/whisper.cpp/resolve/main/" // The location of the models
 	srcExt  = ".bin"                                                       // Filename extension
+	infoExt = ".json"                                                      // Filename extension of the sidecar
 	bufSize = 1024 * 64                                                    // Size of the buffer used for downloading the model
 )
 
@@ -52,6 +56,20 @@ var (
 	flagQuiet = flag.Bool("quiet", false, "Quiet mode")
 )
 
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// Sidecar is written next to each downloaded model, with the same filename
+// and a .json extension, and read by whisper.ModelManager.Describe
+type Sidecar struct {
+	Name       string    `json:"name"`
+	URL        string    `json:"url"`
+	Revision   string    `json:"revision,omitempty"`
+	SHA256     string    `json:"sha256"`
+	Size       int64     `json:"size"`
+	Downloaded time.Time `json:"downloaded"`
+}
+
 ///////////////////////////////////////////////////////////////////////////////
 // MAIN
 
@@ -258,7 +276,8 @@ func Download(ctx context.Context, p io.Writer, model, out string) (string, erro
 		return "", nil
 	}
 
-	// Create file
+	// Create file, removing the sidecar of any previous download
+	os.Remove(path + infoExt)
 	w, err := os.Create(path)
 	if err != nil {
 		return "", err
@@ -269,6 +288,7 @@ func Download(ctx context.Context, p io.Writer, model, out string) (string, erro
 	fmt.Fprintln(p, "Downloading", model, "to", out)
 
 	// Progressively download the model
+	hash := sha256.New()
 	data := make([]byte, bufSize)
 	count, pct := int64(0), int64(0)
 	ticker := time.NewTicker(5 * time.Second)
@@ -286,6 +306,7 @@ func Download(ctx context.Context, p io.Writer, model, out string) (string, erro
 				if m, err := w.Write(data[:n]); err != nil {
 					return path, err
 				} else {
+					hash.Write(data[:m])
 					count += int64(m)
 				}
 			}
@@ -293,7 +314,14 @@ func Download(ctx context.Context, p io.Writer, model, out string) (string, erro
 			if err != nil {
 				if err == io.EOF {
 					DownloadReport(p, pct, count, resp.ContentLength)
-					return path, nil
+					return path, WriteSidecar(path, Sidecar{
+						Name:       filepath.Base(path),
+						URL:        model,
+						Revision:   Revision(resp),
+						SHA256:     hex.EncodeToString(hash.Sum(nil)),
+						Size:       count,
+						Downloaded: time.Now().UTC(),
+					})
 				}
 				return path, err
 			}
@@ -301,6 +329,24 @@ func Download(ctx context.Context, p io.Writer, model, out string) (string, erro
 	}
 }
 
+// Revision returns the repository commit the model was served from, or its
+// entity tag when the server does not report a commit
+func Revision(resp *http.Response) string {
+	if commit := resp.Header.Get("X-Repo-Commit"); commit != "" {
+		return commit
+	}
+	return strings.Trim(resp.Header.Get("ETag"), `"`)
+}
+
+// WriteSidecar writes the metadata of a downloaded model next to it
+func WriteSidecar(path string, info Sidecar) error {
+	data, err := json.MarshalIndent(info, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path+infoExt, append(data, '\n'), 0644)
+}
+
 // Report periodically reports the download progress when percentage changes
 func DownloadReport(w io.Writer, pct, count, total int64) int64 {
 	pct_ := count * 100 / total
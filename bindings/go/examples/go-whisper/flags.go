@@ -84,6 +84,10 @@ func (flags *Flags) GetMaxTokens() uint {
 	return flags.Lookup("max-tokens").Value.(flag.Getter).Get().(uint)
 }
 
+func (flags *Flags) GetMaxDuration() time.Duration {
+	return flags.Lookup("max-duration").Value.(flag.Getter).Get().(time.Duration)
+}
+
 func (flags *Flags) GetWordThreshold() float32 {
 	return float32(flags.Lookup("word-thold").Value.(flag.Getter).Get().(float64))
 }
@@ -119,6 +123,14 @@ func (flags *Flags) SetParams(context whisper.Context) error {
 		fmt.Fprintf(flags.Output(), "Setting max_tokens to %d\n", max_tokens)
 		context.SetMaxTokensPerSegment(max_tokens)
 	}
+	if max_duration := flags.GetMaxDuration(); max_duration != 0 {
+		decoding, ok := context.(whisper.DecodingConfigurable)
+		if !ok {
+			return fmt.Errorf("max_duration is not supported by the context")
+		}
+		fmt.Fprintf(flags.Output(), "Setting max_duration to %v\n", max_duration)
+		decoding.SetMaxSegmentDuration(max_duration)
+	}
 	if word_threshold := flags.GetWordThreshold(); word_threshold != 0 {
 		fmt.Fprintf(flags.Output(), "Setting word_threshold to %f\n", word_threshold)
 		context.SetTokenThreshold(word_threshold)
@@ -140,8 +152,9 @@ func registerFlags(flag *Flags) {
 	flag.Uint("threads", 0, "Number of threads to use")
 	flag.Uint("max-len", 0, "Maximum segment length in characters")
 	flag.Uint("max-tokens", 0, "Maximum tokens per segment")
+	flag.Duration("max-duration", 0, "Maximum segment duration, split on word boundaries")
 	flag.Float64("word-thold", 0, "Maximum segment score")
 	flag.Bool("tokens", false, "Display tokens")
 	flag.Bool("colorize", false, "Colorize tokens")
-	flag.String("out", "", "Output format (srt, none or leave as empty string)")
+	flag.String("out", "", "Output format (srt, scc, none or leave as empty string)")
 }
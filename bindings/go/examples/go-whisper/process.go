@@ -71,12 +71,20 @@ func Process(model whisper.Model, path string, flags *Flags) error {
 		return err
 	}
 
-	context.PrintTimings()
+	if reporter, ok := context.(whisper.TimingsReporter); ok {
+		if err := reporter.WriteTimings(os.Stderr); err != nil {
+			return err
+		}
+	} else {
+		context.PrintTimings()
+	}
 
 	// Print out the results
 	switch {
 	case flags.GetOut() == "srt":
 		return OutputSRT(os.Stdout, context)
+	case flags.GetOut() == "scc":
+		return OutputSCC(os.Stdout, context)
 	case flags.GetOut() == "none":
 		return nil
 	default:
@@ -102,6 +110,21 @@ func OutputSRT(w io.Writer, context whisper.Context) error {
 	}
 }
 
+// Output text as SCC file of CEA-608 captions
+func OutputSCC(w io.Writer, context whisper.Context) error {
+	var segments []whisper.Segment
+	for {
+		segment, err := context.NextSegment()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+		segments = append(segments, segment)
+	}
+	return whisper.WriteSCC(w, segments, whisper.CaptionOptions{})
+}
+
 // Output text to terminal
 func Output(w io.Writer, context whisper.Context, colorize bool) error {
 	for {
@@ -1,7 +1,10 @@
 package whisper
 
 import (
+	"errors"
 	"fmt"
+	"slices"
+	"unsafe"
 )
 
 ///////////////////////////////////////////////////////////////////////////////
@@ -9,6 +12,33 @@ import (
 
 /*
 #include <whisper.h>
+#include <stdlib.h>
+#include <string.h>
+
+extern void callLogitsFilter(void* user_data, const whisper_token_data* tokens, int n_tokens, float* logits, int n_vocab);
+
+// Bias added to the logits of tokens during decoding, and whether a Go
+// callback is registered for the filter
+typedef struct {
+    int             n;
+    whisper_token * ids;
+    float         * bias;
+    bool            callback;
+} whisper_logits_filter;
+
+// Logits filter callback, which applies the bias and then calls the Go callback
+void whisper_logits_filter_cb(struct whisper_context * ctx, struct whisper_state * state, const whisper_token_data * tokens, int n_tokens, float * logits, void * user_data) {
+    const whisper_logits_filter * f = (const whisper_logits_filter *) user_data;
+    const int n_vocab = whisper_n_vocab(ctx);
+    for (int i = 0; i < f->n; i++) {
+        if (f->ids[i] >= 0 && f->ids[i] < n_vocab) {
+            logits[f->ids[i]] += f->bias[i];
+        }
+    }
+    if (f->callback) {
+        callLogitsFilter(user_data, tokens, n_tokens, logits, n_vocab);
+    }
+}
 */
 import "C"
 
@@ -19,6 +49,11 @@ func (p *Params) SetTranslate(v bool) {
 	p.translate = toBool(v)
 }
 
+// Return true if the speech is translated into English
+func (p *Params) Translate() bool {
+	return bool(p.translate)
+}
+
 func (p *Params) SetSplitOnWord(v bool) {
 	p.split_on_word = toBool(v)
 }
@@ -27,6 +62,11 @@ func (p *Params) SetNoContext(v bool) {
 	p.no_context = toBool(v)
 }
 
+// Set no timestamps flag, to decode text only without timestamp tokens
+func (p *Params) SetNoTimestamps(v bool) {
+	p.no_timestamps = toBool(v)
+}
+
 func (p *Params) SetSingleSegment(v bool) {
 	p.single_segment = toBool(v)
 }
@@ -52,6 +92,11 @@ func (p *Params) SetVAD(v bool) {
 	p.vad = toBool(v)
 }
 
+// Get Voice Activity Detection (VAD) flag
+func (p *Params) VAD() bool {
+	return bool(p.vad)
+}
+
 func (p *Params) SetVADModelPath(path string) {
 	p.vad_model_path = C.CString(path)
 }
@@ -95,6 +140,11 @@ func (p *Params) SetLanguage(lang int) error {
 	return nil
 }
 
+// Only detect the language, without transcribing
+func (p *Params) SetDetectLanguage(v bool) {
+	p.detect_language = toBool(v)
+}
+
 // Get language id
 func (p *Params) Language() int {
 	if p.language == nil {
@@ -118,11 +168,26 @@ func (p *Params) SetOffset(offset_ms int) {
 	p.offset_ms = C.int(offset_ms)
 }
 
+// Get start offset in ms
+func (p *Params) Offset() int {
+	return int(p.offset_ms)
+}
+
 // Set audio duration to process in ms
 func (p *Params) SetDuration(duration_ms int) {
 	p.duration_ms = C.int(duration_ms)
 }
 
+// Get audio duration to process in ms (0 = until the end)
+func (p *Params) Duration() int {
+	return int(p.duration_ms)
+}
+
+// Set the latest timestamp in seconds the first segment of a window may start at
+func (p *Params) SetMaxInitialTs(t float32) {
+	p.max_initial_ts = C.float(t)
+}
+
 // Set timestamp token probability threshold (~0.01)
 func (p *Params) SetTokenThreshold(t float32) {
 	p.thold_pt = C.float(t)
@@ -147,23 +212,87 @@ func (p *Params) SetMaxTokensPerSegment(n int) {
 	p.max_tokens = C.int(n)
 }
 
+// Set max segment duration in milliseconds (0 = no limit). Longer segments
+// are split before the last word which starts within the limit, when split
+// on word is set, or otherwise before the last token. Requires token
+// timestamps.
+func (p *Params) SetMaxSegmentDuration(ms int) {
+	p.max_duration_ms = C.int(ms)
+}
+
 // Set audio encoder context
 func (p *Params) SetAudioCtx(n int) {
 	p.audio_ctx = C.int(n)
 }
 
+// Set the maximum number of tokens of previous text used as the prompt of
+// each window. The native library uses at most half the text context of
+// the model, and no previous text when zero.
 func (p *Params) SetMaxContext(n int) {
 	p.n_max_text_ctx = C.int(n)
 }
 
+// Get the maximum number of tokens of previous text used as the prompt
+func (p *Params) MaxContext() int {
+	return int(p.n_max_text_ctx)
+}
+
+// Set the sampling strategy
+func (p *Params) SetStrategy(strategy SamplingStrategy) {
+	p.strategy = C.enum_whisper_sampling_strategy(strategy)
+}
+
+// Return the sampling strategy
+func (p *Params) Strategy() SamplingStrategy {
+	return SamplingStrategy(p.strategy)
+}
+
 func (p *Params) SetBeamSize(n int) {
 	p.beam_search.beam_size = C.int(n)
 }
 
+// Set beam search patience factor (not implemented by whisper.cpp yet)
+func (p *Params) SetPatience(t float32) {
+	p.beam_search.patience = C.float(t)
+}
+
+// Set the length penalty used to rank beam search candidates
+// Pass -1.0 to rank by the simple length normalized log probability
+func (p *Params) SetLengthPenalty(t float32) {
+	p.length_penalty = C.float(t)
+}
+
+// Set number of candidates to sample when decoding with non-zero temperature
+func (p *Params) SetBestOf(n int) {
+	p.greedy.best_of = C.int(n)
+}
+
 func (p *Params) SetEntropyThold(t float32) {
 	p.entropy_thold = C.float(t)
 }
 
+// Set the average log probability threshold, below which decoding is
+// retried at a higher temperature
+func (p *Params) SetLogprobThold(t float32) {
+	p.logprob_thold = C.float(t)
+}
+
+// Get the average log probability threshold
+func (p *Params) LogprobThold() float32 {
+	return float32(p.logprob_thold)
+}
+
+// Set the no speech probability threshold, above which a window is treated
+// as silence when the average log probability is also below LogprobThold
+func (p *Params) SetNoSpeechThold(t float32) {
+	p.no_speech_thold = C.float(t)
+}
+
+// Get the no speech probability threshold
+func (p *Params) NoSpeechThold() float32 {
+	return float32(p.no_speech_thold)
+}
+
 func (p *Params) SetTemperature(t float32) {
 	p.temperature = C.float(t)
 }
@@ -174,18 +303,283 @@ func (p *Params) SetTemperatureFallback(t float32) {
 	p.temperature_inc = C.float(t)
 }
 
+// Set an explicit temperature fallback schedule, which is used instead of
+// the temperature and fallback incrementation. Decoding starts at the first
+// temperature and moves to the next on entropy or log probability failures.
+// The temperatures are copied into C memory, which frees the previous
+// schedule. Pass nil to clear.
+func (p *Params) SetTemperatures(temperatures []float32) {
+	C.free(unsafe.Pointer(p.temperatures))
+	if len(temperatures) == 0 {
+		p.temperatures = nil
+		p.n_temperatures = 0
+		return
+	}
+	ptr := (*C.float)(C.malloc(C.size_t(len(temperatures)) * C.size_t(unsafe.Sizeof(C.float(0)))))
+	copy(unsafe.Slice((*float32)(unsafe.Pointer(ptr)), len(temperatures)), temperatures)
+	p.temperatures = ptr
+	p.n_temperatures = C.int(len(temperatures))
+}
+
+// Return the explicit temperature fallback schedule
+func (p *Params) Temperatures() []float32 {
+	if p.temperatures == nil || p.n_temperatures <= 0 {
+		return nil
+	}
+	return slices.Clone(unsafe.Slice((*float32)(unsafe.Pointer(p.temperatures)), int(p.n_temperatures)))
+}
+
+// Set a bias which is added to the logits of tokens during decoding, to
+// boost (positive) or suppress (negative) them. A bias of negative infinity
+// prevents a token from being sampled. The bias is copied into C memory and
+// applied by a logits filter callback, before any LogitsFilterCallback.
+// Pass nil to clear.
+func (p *Params) SetLogitBias(bias map[Token]float32) {
+	p.setLogitsFilter(bias, p.LogitsFilterCallback())
+}
+
+// Return the logit bias
+func (p *Params) LogitBias() map[Token]float32 {
+	f := p.logitsFilter()
+	if f == nil || f.n <= 0 {
+		return nil
+	}
+	ids := unsafe.Slice((*Token)(unsafe.Pointer(f.ids)), int(f.n))
+	bias := unsafe.Slice((*float32)(unsafe.Pointer(f.bias)), int(f.n))
+	result := make(map[Token]float32, len(ids))
+	for i, id := range ids {
+		result[id] = bias[i]
+	}
+	return result
+}
+
+// Set a callback which can modify the logits of the vocabulary before each
+// token is sampled, for decoding constraints which the other parameters do
+// not allow. The tokens of the current sequence and the logits are only
+// valid during the call. The callback is referenced until it is replaced, or
+// the parameters and their copies are freed. Pass nil to clear.
+func (p *Params) SetLogitsFilterCallback(fn LogitsFilterCallback) {
+	p.setLogitsFilter(p.LogitBias(), fn)
+}
+
+// Return the logits filter callback
+func (p *Params) LogitsFilterCallback() LogitsFilterCallback {
+	f := p.logitsFilter()
+	if f == nil || !f.callback {
+		return nil
+	}
+	return lookupLogitsFilterCallback(unsafe.Pointer(f))
+}
+
 // Set initial prompt
 func (p *Params) SetInitialPrompt(prompt string) {
 	p.initial_prompt = C.CString(prompt)
 }
 
+// Set the initial prompt as tokens, which takes precedence over the prompt
+// set with SetInitialPrompt. The tokens are copied into C memory, so that
+// the parameters can be passed to whisper_full, which frees the previous
+// tokens. Pass nil to clear.
+func (p *Params) SetInitialPromptTokens(tokens []Token) {
+	C.free(unsafe.Pointer(p.prompt_tokens))
+	if len(tokens) == 0 {
+		p.prompt_tokens = nil
+		p.prompt_n_tokens = 0
+		return
+	}
+	ptr := (*C.whisper_token)(C.malloc(C.size_t(len(tokens)) * C.size_t(unsafe.Sizeof(C.whisper_token(0)))))
+	copy(unsafe.Slice((*Token)(unsafe.Pointer(ptr)), len(tokens)), tokens)
+	p.prompt_tokens = ptr
+	p.prompt_n_tokens = C.int(len(tokens))
+}
+
+// Return the initial prompt tokens
+func (p *Params) InitialPromptTokens() []Token {
+	if p.prompt_tokens == nil || p.prompt_n_tokens <= 0 {
+		return nil
+	}
+	return slices.Clone(unsafe.Slice((*Token)(unsafe.Pointer(p.prompt_tokens)), int(p.prompt_n_tokens)))
+}
+
 func (p *Params) SetCarryInitialPrompt(v bool) {
 	p.carry_initial_prompt = toBool(v)
 }
 
+// Suppress blank outputs at the beginning of the sampling
+func (p *Params) SetSuppressBlank(v bool) {
+	p.suppress_blank = toBool(v)
+}
+
+// Suppress non-speech tokens (punctuation, symbols and bracketed annotations)
+func (p *Params) SetSuppressNonSpeechTokens(v bool) {
+	p.suppress_nst = toBool(v)
+}
+
+// Set a regular expression matching tokens to suppress, which frees the
+// previous expression
+func (p *Params) SetSuppressRegex(regex string) {
+	C.free(unsafe.Pointer(p.suppress_regex))
+	if regex == "" {
+		p.suppress_regex = nil
+	} else {
+		p.suppress_regex = C.CString(regex)
+	}
+}
+
+// Voice Activity Detection (VAD) parameters for Whisper_vad_segments_from_samples
+func (p *VadParams) SetThreshold(t float32) {
+	p.threshold = C.float(t)
+}
+
+func (p *VadParams) SetMinSpeechMs(ms int) {
+	p.min_speech_duration_ms = C.int(ms)
+}
+
+func (p *VadParams) SetMinSilenceMs(ms int) {
+	p.min_silence_duration_ms = C.int(ms)
+}
+
+func (p *VadParams) SetMaxSpeechSec(s float32) {
+	p.max_speech_duration_s = C.float(s)
+}
+
+func (p *VadParams) SetSpeechPadMs(ms int) {
+	p.speech_pad_ms = C.int(ms)
+}
+
+func (p *VadParams) SetSamplesOverlap(sec float32) {
+	p.samples_overlap = C.float(sec)
+}
+
+// Validate the parameters for use with a model, returning an error which
+// wraps ErrInvalidParams for each invalid setting. This catches mistakes
+// which whisper_full would otherwise ignore or fail on without explanation.
+func (p *Params) Validate(ctx *Context) error {
+	var result []error
+	if ctx.Whisper_is_multilingual() == 0 {
+		if p.language != nil && C.GoString(p.language) != "en" {
+			result = append(result, fmt.Errorf("%w: language %q requires a multilingual model", ErrInvalidParams, C.GoString(p.language)))
+		}
+		if p.translate {
+			result = append(result, ErrTranslateUnsupported)
+		}
+		if p.detect_language {
+			result = append(result, fmt.Errorf("%w: detect_language requires a multilingual model", ErrInvalidParams))
+		}
+	}
+	if p.vad && (p.vad_model_path == nil || C.GoString(p.vad_model_path) == "") {
+		result = append(result, fmt.Errorf("%w: vad is enabled without a vad model path", ErrInvalidParams))
+	}
+	if p.strategy == C.WHISPER_SAMPLING_GREEDY && p.beam_search.beam_size > 1 {
+		result = append(result, fmt.Errorf("%w: beam_size=%d is ignored by the greedy strategy", ErrInvalidParams, p.beam_search.beam_size))
+	}
+	if p.strategy == C.WHISPER_SAMPLING_BEAM_SEARCH && p.beam_search.beam_size < 1 {
+		result = append(result, fmt.Errorf("%w: beam_size=%d must be positive for beam search", ErrInvalidParams, p.beam_search.beam_size))
+	}
+	if n := ctx.Whisper_n_audio_ctx(); int(p.audio_ctx) > n {
+		result = append(result, fmt.Errorf("%w: audio_ctx=%d exceeds the model audio context of %d", ErrInvalidParams, p.audio_ctx, n))
+	}
+	if p.audio_ctx < 0 {
+		result = append(result, fmt.Errorf("%w: audio_ctx=%d must not be negative", ErrInvalidParams, p.audio_ctx))
+	}
+	if p.max_duration_ms < 0 {
+		result = append(result, fmt.Errorf("%w: max_duration_ms=%d must not be negative", ErrInvalidParams, p.max_duration_ms))
+	}
+	if p.max_duration_ms > 0 && !p.token_timestamps {
+		result = append(result, fmt.Errorf("%w: max_duration_ms requires token_timestamps", ErrInvalidParams))
+	}
+	if p.n_max_text_ctx < 0 {
+		result = append(result, fmt.Errorf("%w: n_max_text_ctx=%d must not be negative", ErrInvalidParams, p.n_max_text_ctx))
+	}
+	return errors.Join(result...)
+}
+
+// Return an independent copy of the parameters. Strings and prompt tokens
+// are copied into new C memory, so that setters on either copy do not
+// affect the other. The language refers to static storage and is shared,
+// and so is the logits filter, which is never modified and is freed with
+// the last copy which refers to it. Free the copy when it is no longer used.
+func (p *Params) Clone() Params {
+	result := *p
+	result.initial_prompt = cloneCString(p.initial_prompt)
+	result.suppress_regex = cloneCString(p.suppress_regex)
+	result.vad_model_path = cloneCString(p.vad_model_path)
+	result.prompt_tokens, result.prompt_n_tokens = nil, 0
+	result.SetInitialPromptTokens(p.InitialPromptTokens())
+	result.temperatures, result.n_temperatures = nil, 0
+	result.SetTemperatures(p.Temperatures())
+	if f := p.logitsFilter(); f != nil {
+		retainLogitsFilter(unsafe.Pointer(f))
+	}
+	return result
+}
+
+// Free the C memory of the parameters, which must not be used afterwards,
+// and release the logits filter. Only free parameters which do not share
+// their memory with others, such as copies made with Clone, and not plain
+// copies of them.
+func (p *Params) Free() {
+	for _, ptr := range []unsafe.Pointer{unsafe.Pointer(p.initial_prompt), unsafe.Pointer(p.suppress_regex), unsafe.Pointer(p.vad_model_path)} {
+		C.free(ptr)
+	}
+	p.initial_prompt, p.suppress_regex, p.vad_model_path = nil, nil, nil
+	p.SetInitialPromptTokens(nil)
+	p.SetTemperatures(nil)
+	p.setLogitsFilter(nil, nil)
+}
+
 ///////////////////////////////////////////////////////////////////////////////
 // PRIVATE METHODS
 
+// Replace the logits filter with a new one, so that copies of the parameters
+// are not affected, and release the previous one. The filter is cleared when
+// there is no bias or callback.
+func (p *Params) setLogitsFilter(bias map[Token]float32, fn LogitsFilterCallback) {
+	if f := p.logitsFilter(); f != nil && releaseLogitsFilter(unsafe.Pointer(f)) {
+		C.free(unsafe.Pointer(f.ids))
+		C.free(unsafe.Pointer(f.bias))
+		C.free(unsafe.Pointer(f))
+	}
+	if len(bias) == 0 && fn == nil {
+		p.logits_filter_callback = nil
+		p.logits_filter_callback_user_data = nil
+		return
+	}
+	ids := make([]Token, 0, len(bias))
+	for id := range bias {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	f := (*C.whisper_logits_filter)(C.malloc(C.size_t(unsafe.Sizeof(C.whisper_logits_filter{}))))
+	f.n = C.int(len(ids))
+	f.ids = (*C.whisper_token)(C.malloc(C.size_t(len(ids)+1) * C.size_t(unsafe.Sizeof(C.whisper_token(0)))))
+	f.bias = (*C.float)(C.malloc(C.size_t(len(ids)+1) * C.size_t(unsafe.Sizeof(C.float(0)))))
+	f.callback = toBool(fn != nil)
+	dstIds := unsafe.Slice((*Token)(unsafe.Pointer(f.ids)), len(ids))
+	dstBias := unsafe.Slice((*float32)(unsafe.Pointer(f.bias)), len(ids))
+	for i, id := range ids {
+		dstIds[i], dstBias[i] = id, bias[id]
+	}
+	registerLogitsFilter(unsafe.Pointer(f), fn)
+	p.logits_filter_callback = C.whisper_logits_filter_callback(C.whisper_logits_filter_cb)
+	p.logits_filter_callback_user_data = unsafe.Pointer(f)
+}
+
+// Return the logits filter, or nil if it is not set by these bindings
+func (p *Params) logitsFilter() *C.whisper_logits_filter {
+	if p.logits_filter_callback != C.whisper_logits_filter_callback(C.whisper_logits_filter_cb) {
+		return nil
+	}
+	return (*C.whisper_logits_filter)(p.logits_filter_callback_user_data)
+}
+
+func cloneCString(str *C.char) *C.char {
+	if str == nil {
+		return nil
+	}
+	return C.strdup(str)
+}
+
 func toBool(v bool) C.bool {
 	if v {
 		return C.bool(true)
@@ -206,18 +600,48 @@ func (p *Params) String() string {
 	str += fmt.Sprintf(" n_max_text_ctx=%d", p.n_max_text_ctx)
 	str += fmt.Sprintf(" offset_ms=%d", p.offset_ms)
 	str += fmt.Sprintf(" duration_ms=%d", p.duration_ms)
+	if p.max_duration_ms > 0 {
+		str += fmt.Sprintf(" max_duration_ms=%d", p.max_duration_ms)
+	}
 	str += fmt.Sprintf(" audio_ctx=%d", p.audio_ctx)
+	str += fmt.Sprintf(" max_initial_ts=%f", p.max_initial_ts)
 	str += fmt.Sprintf(" initial_prompt=%s", C.GoString(p.initial_prompt))
+	if p.prompt_n_tokens > 0 {
+		str += fmt.Sprintf(" prompt_n_tokens=%d", p.prompt_n_tokens)
+	}
+	if p.suppress_regex != nil {
+		str += fmt.Sprintf(" suppress_regex=%s", C.GoString(p.suppress_regex))
+	}
 	str += fmt.Sprintf(" entropy_thold=%f", p.entropy_thold)
+	str += fmt.Sprintf(" logprob_thold=%f", p.logprob_thold)
+	str += fmt.Sprintf(" no_speech_thold=%f", p.no_speech_thold)
 	str += fmt.Sprintf(" temperature=%f", p.temperature)
 	str += fmt.Sprintf(" temperature_inc=%f", p.temperature_inc)
+	if temperatures := p.Temperatures(); len(temperatures) > 0 {
+		str += fmt.Sprintf(" temperatures=%v", temperatures)
+	}
+	if bias := p.LogitBias(); len(bias) > 0 {
+		str += fmt.Sprintf(" logit_bias=%v", bias)
+	}
+	if p.LogitsFilterCallback() != nil {
+		str += " logits_filter_callback"
+	}
 	str += fmt.Sprintf(" beam_size=%d", p.beam_search.beam_size)
+	str += fmt.Sprintf(" patience=%f", p.beam_search.patience)
+	str += fmt.Sprintf(" length_penalty=%f", p.length_penalty)
+	str += fmt.Sprintf(" best_of=%d", p.greedy.best_of)
 	if p.translate {
 		str += " translate"
 	}
+	if p.detect_language {
+		str += " detect_language"
+	}
 	if p.no_context {
 		str += " no_context"
 	}
+	if p.no_timestamps {
+		str += " no_timestamps"
+	}
 	if p.single_segment {
 		str += " single_segment"
 	}
@@ -239,6 +663,12 @@ func (p *Params) String() string {
 	if p.carry_initial_prompt {
 		str += " carry_initial_prompt"
 	}
+	if p.suppress_blank {
+		str += " suppress_blank"
+	}
+	if p.suppress_nst {
+		str += " suppress_nst"
+	}
 
 	return str + ">"
 }
@@ -0,0 +1,63 @@
+package whisper
+
+import (
+	"sync"
+
+	// Bindings
+	whisper "github.com/ggerganov/whisper.cpp/bindings/go"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// AdapterLoader applies the adapter weights (for example a LoRA fine-tune)
+// in the file at path to the native context of a model. The native library
+// does not load adapters itself, so forks which carry adapter patches
+// register a loader with RegisterAdapterLoader to make LoadAdapter work
+// without changing this package.
+type AdapterLoader func(ctx *whisper.Context, path string) error
+
+///////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+var (
+	adapterMu     sync.RWMutex
+	adapterLoader AdapterLoader
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// RegisterAdapterLoader sets the function used by LoadAdapter of
+// AdapterModel, replacing any previous loader. Pass nil to remove the loader.
+func RegisterAdapterLoader(fn AdapterLoader) {
+	adapterMu.Lock()
+	defer adapterMu.Unlock()
+	adapterLoader = fn
+}
+
+// Load adapter weights from a file into the model, using the registered
+// AdapterLoader. Returns ErrAdapterUnsupported if no loader is registered,
+// or ErrStatelessBusy if a context of the model is processing.
+func (model *model) LoadAdapter(path string) error {
+	adapterMu.RLock()
+	fn := adapterLoader
+	adapterMu.RUnlock()
+	if fn == nil {
+		return ErrAdapterUnsupported
+	}
+
+	ctx := model.rlock()
+	if ctx == nil {
+		return ErrInternalAppError
+	}
+	defer model.runlock()
+
+	// Weights must not change while a context is processing
+	if err := model.gate.acquire("adapter"); err != nil {
+		return err
+	}
+	defer model.gate.release()
+
+	return fn(ctx, path)
+}
@@ -0,0 +1,171 @@
+package whisper
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"time"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// TuneConfig is a configuration measured by AutoTune
+type TuneConfig struct {
+	Threads        uint `json:"threads"`
+	GPU            bool `json:"gpu"`
+	FlashAttention bool `json:"flash_attention"`
+}
+
+// TuneResult is the measured performance of a configuration
+type TuneResult struct {
+	TuneConfig
+	Wall time.Duration `json:"wall"` // Wall clock time to process the sample
+	RTF  float64       `json:"rtf"`  // Real-time factor, lower is faster
+	Err  string        `json:"error,omitempty"`
+}
+
+// TuneReport contains the results of AutoTune, and the fastest configuration
+type TuneReport struct {
+	Model   string       `json:"model"`
+	Host    string       `json:"host"`
+	Best    TuneConfig   `json:"best"`
+	Results []TuneResult `json:"results"`
+}
+
+// TuneOptions controls which configurations AutoTune measures
+type TuneOptions struct {
+	// Thread counts to measure. The default is powers of two up to the
+	// number of CPUs, and the number of CPUs itself
+	Threads []uint
+
+	// Whether to measure with the GPU and with flash attention enabled and
+	// disabled. When false, only the default of each is measured.
+	GPU, FlashAttention bool
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// AutoTune measures the time taken to process the sample audio with the
+// model at path across thread counts, GPU and flash attention settings on
+// the current host, and returns the fastest configuration. The model is
+// loaded once for each GPU and flash attention setting. A short sample of
+// a few seconds of speech is enough for a stable measurement.
+func AutoTune(path string, sample []float32, opts TuneOptions) (TuneReport, error) {
+	host, _ := os.Hostname()
+	report := TuneReport{Model: filepath.Base(path), Host: host}
+	if len(sample) == 0 {
+		return report, ErrInvalidParams
+	}
+
+	threads := opts.Threads
+	if len(threads) == 0 {
+		threads = defaultTuneThreads(uint(runtime.NumCPU()))
+	}
+	defaults := NewModelContextParams()
+	gpus, flash := []bool{defaults.params.UseGPU()}, []bool{defaults.params.FlashAttn()}
+	if opts.GPU {
+		gpus = []bool{true, false}
+	}
+	if opts.FlashAttention {
+		flash = []bool{true, false}
+	}
+
+	// Measure each configuration
+	var best *TuneResult
+	for _, gpu := range gpus {
+		for _, fa := range flash {
+			params := NewModelContextParams()
+			params.params.SetUseGPU(gpu)
+			params.params.SetFlashAttn(fa)
+			model, err := NewWithParams(path, params)
+			if err != nil {
+				for _, n := range threads {
+					report.Results = append(report.Results, TuneResult{TuneConfig: TuneConfig{Threads: n, GPU: gpu, FlashAttention: fa}, Err: err.Error()})
+				}
+				continue
+			}
+			for _, n := range threads {
+				result := tune(model, sample, TuneConfig{Threads: n, GPU: gpu, FlashAttention: fa})
+				report.Results = append(report.Results, result)
+			}
+			model.Close()
+		}
+	}
+	for i := range report.Results {
+		if result := &report.Results[i]; result.Err == "" && (best == nil || result.Wall < best.Wall) {
+			best = result
+		}
+	}
+	if best == nil {
+		return report, ErrProcessingFailed
+	}
+	report.Best = best.TuneConfig
+
+	// Return success
+	return report, nil
+}
+
+// Save the report as JSON, creating the directory if necessary, so that it
+// can be loaded on later runs instead of measuring again
+func (report TuneReport) Save(path string) error {
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// Load a report saved with Save
+func LoadTuneReport(path string) (TuneReport, error) {
+	var report TuneReport
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return report, err
+	}
+	err = json.Unmarshal(data, &report)
+	return report, err
+}
+
+// Return the default path for saving a report for a model, within the
+// user cache directory
+func TuneReportPath(model string) (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "whisper", "autotune", filepath.Base(model)+".json"), nil
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+func tune(model Model, sample []float32, config TuneConfig) TuneResult {
+	result := TuneResult{TuneConfig: config}
+	context, err := model.NewContext()
+	if err != nil {
+		result.Err = err.Error()
+		return result
+	}
+	context.SetThreads(config.Threads)
+	if err := context.Process(sample, nil, nil, nil); err != nil {
+		result.Err = err.Error()
+		return result
+	}
+	stats := contextStats(context)
+	result.Wall, result.RTF = stats.Wall, stats.RTF
+	return result
+}
+
+func defaultTuneThreads(cpus uint) []uint {
+	var result []uint
+	for n := uint(1); n < cpus; n *= 2 {
+		result = append(result, n)
+	}
+	return append(result, max(cpus, 1))
+}
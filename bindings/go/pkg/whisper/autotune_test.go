@@ -0,0 +1,26 @@
+package whisper_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/ggerganov/whisper.cpp/bindings/go/pkg/whisper"
+	assert "github.com/stretchr/testify/assert"
+)
+
+func TestAutoTune(t *testing.T) {
+	assert := assert.New(t)
+
+	sample := make([]float32, whisper.SampleRate*2)
+	report, err := whisper.AutoTune(ModelPath, sample, whisper.TuneOptions{Threads: []uint{1, 2}})
+	assert.NoError(err)
+	assert.Len(report.Results, 2)
+	assert.Contains([]uint{1, 2}, report.Best.Threads)
+
+	// Save and load the report
+	path := filepath.Join(t.TempDir(), "autotune", "model.json")
+	assert.NoError(report.Save(path))
+	loaded, err := whisper.LoadTuneReport(path)
+	assert.NoError(err)
+	assert.Equal(report, loaded)
+}
@@ -0,0 +1,106 @@
+package whisper
+
+import (
+	"errors"
+	"fmt"
+
+	// Bindings
+	whisper "github.com/ggerganov/whisper.cpp/bindings/go"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// BackendError is returned by Process when the backend fails to compute the
+// encoder or decoder, for example when the GPU revokes access to its command
+// buffers while an app is in the background. The decoding state is
+// recreated before the error is returned, so that Process can be retried,
+// or the audio can be processed by a model loaded on the CPU instead. It
+// matches ErrBackendFailed with errors.Is
+type BackendError struct {
+	Backend string // Backend the model was loaded on, "CPU" or "GPU"
+	Device  int    // Device index for the GPU backend
+	Code    int    // Code returned by the native library
+	Err     error  // Error recreating the state, in which case the context cannot be retried
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Set a function which is called with the message of a fatal error in the
+// native library, such as a failed assertion in a backend, for example to
+// log it. This is only a diagnostics hook, since the process is still aborted
+// once the function returns. Failures which the backend reports, and which
+// can be recovered from, are returned by Process as a *BackendError instead.
+// Pass nil to print the message to stderr.
+func SetAbortCallback(fn func(message string)) {
+	whisper.Ggml_set_abort_callback(fn)
+}
+
+func (err *BackendError) Error() string {
+	backend := err.Backend
+	if backend == "GPU" {
+		backend = fmt.Sprintf("GPU %d", err.Device)
+	}
+	if err.Err != nil {
+		return fmt.Sprintf("%v on %s (code %d), and the state could not be recreated: %v", ErrBackendFailed, backend, err.Code, err.Err)
+	}
+	return fmt.Sprintf("%v on %s (code %d)", ErrBackendFailed, backend, err.Code)
+}
+
+func (err *BackendError) Unwrap() []error {
+	if err.Err != nil {
+		return []error{ErrBackendFailed, err.Err}
+	}
+	return []error{ErrBackendFailed}
+}
+
+// Return true if the state was recreated, so that Process can be retried
+func (err *BackendError) Retry() bool {
+	return err.Err == nil
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// Return the error of a failed call to process. When the backend failed,
+// the decoding state is recreated, so that its backends are no longer in an
+// error state, and a *BackendError is returned. When the released compute
+// buffers could not be allocated again, the state is kept and an
+// *OutOfMemoryError is returned.
+func (context *context) processError(ctx *whisper.Context, err error) error {
+	var full *whisper.FullError
+	if !errors.As(err, &full) {
+		return err
+	} else if full.OutOfMemory() {
+		context.model.statesMu.Lock()
+		defer context.model.statesMu.Unlock()
+		return context.model.outOfMemory(len(context.model.states))
+	} else if !full.Backend() {
+		return err
+	}
+	result := &BackendError{Backend: "CPU", Code: full.Code}
+	if context.model.params.UseGPU() {
+		result.Backend, result.Device = "GPU", context.model.params.GPUDevice()
+	}
+	context.model.statesMu.Lock()
+	defer context.model.statesMu.Unlock()
+	if !context.stateful {
+		if ctx.Whisper_reset_state() != nil {
+			result.Err = context.model.outOfMemory(len(context.model.states))
+		}
+		return result
+	}
+
+	// Replace the state of a stateful context
+	state, err := ctx.Whisper_init_state()
+	if err != nil {
+		result.Err = context.model.outOfMemory(len(context.model.states))
+		return result
+	}
+	delete(context.model.states, context.state)
+	context.state.Whisper_free_state()
+	context.state = state
+	context.model.states[state] = struct{}{}
+	return result
+}
@@ -0,0 +1,30 @@
+package whisper_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ggerganov/whisper.cpp/bindings/go/pkg/whisper"
+	assert "github.com/stretchr/testify/assert"
+)
+
+func TestBackendError(t *testing.T) {
+	assert := assert.New(t)
+
+	err := error(&whisper.BackendError{Backend: "GPU", Device: 1, Code: -6})
+	assert.ErrorIs(err, whisper.ErrBackendFailed)
+	assert.NotErrorIs(err, whisper.ErrOutOfMemory)
+	assert.Equal("backend failed on GPU 1 (code -6)", err.Error())
+	assert.True(err.(*whisper.BackendError).Retry())
+
+	// The state could not be recreated
+	oom := &whisper.OutOfMemoryError{Backend: "GPU", Device: 1, States: 2}
+	err = &whisper.BackendError{Backend: "GPU", Device: 1, Code: -8, Err: oom}
+	assert.ErrorIs(err, whisper.ErrBackendFailed)
+	assert.ErrorIs(err, whisper.ErrOutOfMemory)
+	var target *whisper.OutOfMemoryError
+	if assert.True(errors.As(err, &target)) {
+		assert.Equal(2, target.States)
+	}
+	assert.False(err.(*whisper.BackendError).Retry())
+}
@@ -0,0 +1,79 @@
+package whisper_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/ggerganov/whisper.cpp/bindings/go/pkg/whisper"
+)
+
+// Number of copies of the sample in the audio of BenchmarkProcessBig
+const bigCopies = 4
+
+// Return a context which has processed the sample
+func processedContext(b *testing.B) whisper.Context {
+	b.Helper()
+	if _, err := os.Stat(ModelPath); os.IsNotExist(err) {
+		b.Skip("Skipping benchmark, model not found:", ModelPath)
+	}
+	data := loadSamples(b, SamplePath)
+
+	model, err := whisper.New(ModelPath)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Cleanup(func() { model.Close() })
+	context, err := model.NewContext()
+	if err != nil {
+		b.Fatal(err)
+	}
+	if err := context.Process(data, nil, nil, nil); err != nil {
+		b.Fatal(err)
+	}
+	return context
+}
+
+func BenchmarkIsText(b *testing.B) {
+	context := processedContext(b)
+	token := whisper.Token{Id: 0}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		context.IsText(token)
+	}
+}
+
+func BenchmarkIsLANG(b *testing.B) {
+	context := processedContext(b)
+	token := whisper.Token{Id: 0}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		context.IsLANG(token, "en")
+	}
+}
+
+// Process audio which is longer than one window, made of copies of the
+// sample. The audio is written as an artifact with -whisper.artifacts.
+func BenchmarkProcessBig(b *testing.B) {
+	if _, err := os.Stat(ModelPath); os.IsNotExist(err) {
+		b.Skip("Skipping benchmark, model not found:", ModelPath)
+	}
+	data := concatSamples(b, SamplePath, bigCopies, whisper.SampleRate/2)
+	writeArtifact(b, "benchmark_out.wav", data)
+
+	model, err := fullModel(whisper.New(ModelPath))
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer model.Close()
+	context, err := fullContext(model.NewContext())
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := context.Process(data, nil, nil, nil); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.ReportMetric(context.Timings().RTF, "rtf")
+}
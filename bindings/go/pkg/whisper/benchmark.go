@@ -0,0 +1,153 @@
+package whisper
+
+import (
+	"bufio"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	// Bindings
+	whisper "github.com/ggerganov/whisper.cpp/bindings/go"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// ComputeBenchmark is the performance of the host measured by the benchmarks
+// of whisper.cpp, as reported by its bench tool, which can fingerprint a
+// host to choose the model size it runs
+type ComputeBenchmark struct {
+	Threads int
+
+	// Memory bandwidth with each number of threads up to Threads
+	Memcpy []MemcpyResult
+
+	// Matrix multiplication of each size and weight type with Threads
+	// threads, the smallest size first
+	MulMat []MulMatResult
+}
+
+// MemcpyResult is the bandwidth of copying memory with a number of threads
+type MemcpyResult struct {
+	Threads int
+	GBps    float64 // Gigabytes per second
+}
+
+// MulMatResult is the throughput of multiplying square matrices of weights
+// of a type by matrices of floats
+type MulMatResult struct {
+	Size   int    // Number of rows and columns of the matrices
+	Type   string // Name of the ggml type of the weights, such as "q5_0" or "f16"
+	GFLOPS float64
+	Runs   int // Number of multiplications measured
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// BenchmarkCompute measures the memory bandwidth and the matrix
+// multiplication throughput of the CPU with a number of threads, or with
+// the recommended number of threads if it is zero. It takes from seconds to
+// minutes depending on the host, and only one benchmark runs at a time.
+func BenchmarkCompute(threads int) (ComputeBenchmark, error) {
+	if threads <= 0 {
+		threads = RecommendedThreads(false)
+	}
+	memcpy := whisper.Whisper_bench_memcpy_str(threads)
+	mulmat := whisper.Whisper_bench_ggml_mul_mat_str(threads)
+	result, err := ParseBenchmark(memcpy, mulmat)
+	result.Threads = threads
+	return result, err
+}
+
+// ParseBenchmark parses the reports of the memcpy and mul_mat benchmarks,
+// as printed by the bench tool with -w 1 and -w 2, such as to compare the
+// hosts of a fleet. Threads is the largest number of threads of memcpy.
+func ParseBenchmark(memcpy, mulmat string) (ComputeBenchmark, error) {
+	var result ComputeBenchmark
+	var err error
+	if result.Memcpy, err = parseMemcpy(memcpy); err != nil {
+		return result, err
+	}
+	for _, r := range result.Memcpy {
+		result.Threads = max(result.Threads, r.Threads)
+	}
+	if result.MulMat, err = parseMulMat(mulmat); err != nil {
+		return result, err
+	}
+	return result, nil
+}
+
+// Return the throughput of the largest matrices with weights of a type,
+// such as "f16" or "q5_0", or zero if the type was not measured
+func (b ComputeBenchmark) GFLOPS(typ string) float64 {
+	for i := len(b.MulMat) - 1; i >= 0; i-- {
+		if b.MulMat[i].Type == typ {
+			return b.MulMat[i].GFLOPS
+		}
+	}
+	return 0
+}
+
+// Return the highest memory bandwidth over the numbers of threads
+func (b ComputeBenchmark) Bandwidth() float64 {
+	var gbps float64
+	for _, r := range b.Memcpy {
+		gbps = max(gbps, r.GBps)
+	}
+	return gbps
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// Parse the report of the memcpy benchmark, with lines such as
+// "memcpy:   10.45 GB/s ( 2 thread)". The heat-up is skipped, and the
+// last measurement of each number of threads is kept.
+func parseMemcpy(report string) ([]MemcpyResult, error) {
+	var results []MemcpyResult
+	scanner := bufio.NewScanner(strings.NewReader(report))
+	for scanner.Scan() {
+		line, found := strings.CutPrefix(scanner.Text(), "memcpy:")
+		if !found || strings.Contains(line, "heat-up") {
+			continue
+		}
+		var r MemcpyResult
+		if _, err := fmt.Sscanf(strings.ReplaceAll(line, "(", " "), "%g GB/s %d thread)", &r.GBps, &r.Threads); err != nil {
+			return nil, fmt.Errorf("memcpy benchmark: %q: %w", scanner.Text(), err)
+		}
+		if i := slices.IndexFunc(results, func(m MemcpyResult) bool { return m.Threads == r.Threads }); i >= 0 {
+			results[i] = r
+		} else {
+			results = append(results, r)
+		}
+	}
+	return results, scanner.Err()
+}
+
+// Parse the report of the matrix multiplication benchmark, with lines such
+// as "  64 x   64: Q4_0    12.3 GFLOPS (128 runs) | Q4_1    11.8 GFLOPS (128 runs)"
+func parseMulMat(report string) ([]MulMatResult, error) {
+	var results []MulMatResult
+	scanner := bufio.NewScanner(strings.NewReader(report))
+	for scanner.Scan() {
+		dims, rest, found := strings.Cut(scanner.Text(), ":")
+		if !found {
+			continue
+		}
+		size, err := strconv.Atoi(strings.TrimSpace(strings.Split(dims, "x")[0]))
+		if err != nil {
+			return nil, fmt.Errorf("mul_mat benchmark: %q: %w", scanner.Text(), err)
+		}
+		for _, part := range strings.Split(rest, "|") {
+			r := MulMatResult{Size: size}
+			if _, err := fmt.Sscanf(part, "%s %g GFLOPS (%d runs)", &r.Type, &r.GFLOPS, &r.Runs); err != nil {
+				return nil, fmt.Errorf("mul_mat benchmark: %q: %w", scanner.Text(), err)
+			}
+			r.Type = strings.ToLower(r.Type)
+			results = append(results, r)
+		}
+	}
+	return results, scanner.Err()
+}
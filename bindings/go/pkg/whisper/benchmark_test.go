@@ -0,0 +1,54 @@
+package whisper_test
+
+import (
+	"testing"
+
+	"github.com/ggerganov/whisper.cpp/bindings/go/pkg/whisper"
+	assert "github.com/stretchr/testify/assert"
+)
+
+// Reports in the format of whisper_bench_memcpy_str and
+// whisper_bench_ggml_mul_mat_str, which take minutes to run
+const (
+	benchMemcpy = `memcpy:    8.21 GB/s (heat-up)
+memcpy:    9.02 GB/s ( 1 thread)
+memcpy:    8.95 GB/s ( 1 thread)
+memcpy:   15.40 GB/s ( 2 thread)
+sum:    -536870997.000000
+`
+	benchMulMat = `  64 x   64: Q4_0     5.1 GFLOPS (128 runs) | Q4_1     4.9 GFLOPS (128 runs)
+  64 x   64: Q5_0     4.2 GFLOPS (128 runs) | Q5_1     4.0 GFLOPS (128 runs) | Q8_0     6.3 GFLOPS (128 runs)
+  64 x   64: F16      7.7 GFLOPS (128 runs) | F32      8.8 GFLOPS (128 runs)
+4096 x 4096: Q4_0    61.2 GFLOPS (  3 runs) | Q4_1    58.0 GFLOPS (  3 runs)
+4096 x 4096: Q5_0    50.4 GFLOPS (  3 runs) | Q5_1    48.1 GFLOPS (  3 runs) | Q8_0    70.9 GFLOPS (  3 runs)
+4096 x 4096: F16     80.3 GFLOPS (  3 runs) | F32     40.6 GFLOPS (  3 runs)
+`
+)
+
+func TestParseBenchmark(t *testing.T) {
+	assert := assert.New(t)
+
+	b, err := whisper.ParseBenchmark(benchMemcpy, benchMulMat)
+	assert.NoError(err)
+	assert.Equal(2, b.Threads)
+
+	// The heat-up is skipped, and the last measurement of each number of
+	// threads is kept
+	assert.Equal([]whisper.MemcpyResult{{Threads: 1, GBps: 8.95}, {Threads: 2, GBps: 15.4}}, b.Memcpy)
+	assert.Equal(15.4, b.Bandwidth())
+
+	// Each size and type is measured
+	if assert.Len(b.MulMat, 14) {
+		assert.Equal(whisper.MulMatResult{Size: 64, Type: "q4_0", GFLOPS: 5.1, Runs: 128}, b.MulMat[0])
+		assert.Equal(whisper.MulMatResult{Size: 4096, Type: "f32", GFLOPS: 40.6, Runs: 3}, b.MulMat[13])
+	}
+	assert.Equal(80.3, b.GFLOPS("f16"))
+	assert.Equal(70.9, b.GFLOPS("q8_0"))
+	assert.Zero(b.GFLOPS("q2_k"))
+
+	// Malformed reports are an error
+	_, err = whisper.ParseBenchmark("memcpy: fast", "")
+	assert.Error(err)
+	_, err = whisper.ParseBenchmark("", "64 x 64: Q4_0 fast")
+	assert.Error(err)
+}
@@ -0,0 +1,264 @@
+package whisper
+
+import (
+	gocontext "context"
+	"sync"
+	"time"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// Budgeter tracks the compute time spent by each tenant on each model, and
+// enforces compute budgets. A request is served by the first model, in
+// order of preference, whose estimated cost fits the budget of its tenant
+// and its own limit. When no model fits the remaining budget, the request
+// waits for the budget to be replenished.
+type Budgeter struct {
+	mu      sync.Mutex
+	tiers   []BudgetTier
+	tenants map[string]*tenantBudget
+}
+
+// BudgetTier is a model which requests can be served by, such as a name for
+// ModelManager.Get
+type BudgetTier struct {
+	Name string
+
+	// Estimated compute time per second of audio, which is the real-time
+	// factor of the model on the host. It is updated from the compute time
+	// of each request which is done.
+	Cost float64
+}
+
+// Budget is the compute time a tenant can spend in each period. The budget
+// is replenished continuously, so that a tenant which has spent it can make
+// a request as soon as enough of it is replenished. A zero period never
+// replenishes it.
+type Budget struct {
+	Compute time.Duration
+	Period  time.Duration
+}
+
+// BudgetRequest is a request to transcribe audio for a tenant
+type BudgetRequest struct {
+	Tenant string
+	Audio  time.Duration
+
+	// Compute time the request can spend, or zero for no limit
+	Limit time.Duration
+}
+
+// BudgetUsage reports the compute time spent by a tenant
+type BudgetUsage struct {
+	Compute   map[string]time.Duration // Compute time spent on each model
+	Requests  int                      // Number of requests done
+	Available time.Duration            // Budget which is not spent or reserved
+	Waiting   int                      // Number of requests waiting for budget
+}
+
+// Reservation is the estimated cost of a request, which is reserved from
+// the budget of its tenant until the request is done
+type Reservation struct {
+	Tier     string        // Model which serves the request
+	Estimate time.Duration // Estimated compute time
+
+	budgeter *Budgeter
+	tenant   string
+	audio    time.Duration
+	done     bool
+}
+
+// The budget and usage of a tenant
+type tenantBudget struct {
+	budget    *Budget // Budget, or nil for no budget
+	available time.Duration
+	updated   time.Time // Time the available budget was last replenished
+	reserved  time.Duration
+	usage     BudgetUsage
+	changed   chan struct{} // Closed when budget is returned or replenished
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+// Weight of each measured cost in the moving average of the cost of a tier
+const budgetSmoothing = 0.2
+
+///////////////////////////////////////////////////////////////////////////////
+// LIFECYCLE
+
+// Return a budgeter for the models, in order of preference, such as from
+// the largest model to the smallest
+func NewBudgeter(tiers ...BudgetTier) *Budgeter {
+	return &Budgeter{tiers: tiers, tenants: make(map[string]*tenantBudget)}
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Set the budget of a tenant, or remove it with nil. Tenants without a
+// budget are served by the preferred model within the limit of each
+// request. The tenant starts with its full budget.
+func (b *Budgeter) SetBudget(tenant string, budget *Budget) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	t := b.tenant(tenant)
+	if budget == nil {
+		t.budget = nil
+	} else {
+		value := *budget
+		t.budget, t.available, t.updated = &value, value.Compute, time.Now()
+	}
+	t.notify()
+}
+
+// Reserve chooses the model for a request and reserves its estimated cost
+// from the budget of the tenant, waiting while no model fits the remaining
+// budget. ErrBudgetExceeded is returned if no model fits the limit of the
+// request or the whole budget of the tenant, and the error of the context
+// if it is cancelled while waiting. Call Done on the reservation once the
+// request is processed.
+func (b *Budgeter) Reserve(ctx gocontext.Context, request BudgetRequest) (*Reservation, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	t := b.tenant(request.Tenant)
+	for {
+		var now time.Time
+		if t.budget != nil {
+			now = time.Now()
+			t.replenish(now)
+		}
+		fits, wait := false, time.Duration(-1)
+		for _, tier := range b.tiers {
+			estimate := time.Duration(tier.Cost * float64(request.Audio))
+			if request.Limit > 0 && estimate > request.Limit {
+				continue
+			}
+			if t.budget != nil && estimate > t.budget.Compute {
+				continue
+			}
+			fits = true
+			if t.budget != nil && estimate > t.available-t.reserved {
+				// Wait for the cheapest model which fits the budget
+				if t.budget.Period > 0 {
+					need := estimate - (t.available - t.reserved)
+					if d := time.Duration(float64(need) / float64(t.budget.Compute) * float64(t.budget.Period)); wait < 0 || d < wait {
+						wait = d
+					}
+				}
+				continue
+			}
+			t.reserved += estimate
+			return &Reservation{Tier: tier.Name, Estimate: estimate, budgeter: b, tenant: request.Tenant, audio: request.Audio}, nil
+		}
+		if !fits || (wait < 0 && t.reserved == 0) {
+			// No model fits, or the budget is spent and never replenished
+			return nil, ErrBudgetExceeded
+		}
+
+		// Wait for the budget to be replenished or returned
+		changed := t.changed
+		var timer <-chan time.Time
+		if wait >= 0 {
+			timer = time.After(max(wait, time.Millisecond))
+		}
+		t.usage.Waiting++
+		b.mu.Unlock()
+		select {
+		case <-ctx.Done():
+		case <-changed:
+		case <-timer:
+		}
+		b.mu.Lock()
+		t.usage.Waiting--
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// Done charges the compute time spent by the request to its tenant, in place
+// of the estimate which was reserved, and updates the estimated cost of the
+// model. Calling Done more than once has no effect.
+func (r *Reservation) Done(compute time.Duration) {
+	b := r.budgeter
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if r.done {
+		return
+	}
+	r.done = true
+	t := b.tenant(r.tenant)
+	t.reserved -= r.Estimate
+	if t.budget != nil {
+		t.replenish(time.Now())
+		t.available -= compute
+	}
+	if t.usage.Compute == nil {
+		t.usage.Compute = make(map[string]time.Duration)
+	}
+	t.usage.Compute[r.Tier] += compute
+	t.usage.Requests++
+	if r.audio > 0 && compute > 0 {
+		for i := range b.tiers {
+			if tier := &b.tiers[i]; tier.Name == r.Tier {
+				tier.Cost += budgetSmoothing * (float64(compute)/float64(r.audio) - tier.Cost)
+			}
+		}
+	}
+	t.notify()
+}
+
+// Return the compute time spent by a tenant
+func (b *Budgeter) Usage(tenant string) BudgetUsage {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	t := b.tenant(tenant)
+	usage := t.usage
+	usage.Compute = make(map[string]time.Duration, len(t.usage.Compute))
+	for name, compute := range t.usage.Compute {
+		usage.Compute[name] = compute
+	}
+	if t.budget != nil {
+		t.replenish(time.Now())
+		usage.Available = t.available - t.reserved
+	}
+	return usage
+}
+
+// Return the models with their estimated costs
+func (b *Budgeter) Tiers() []BudgetTier {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]BudgetTier(nil), b.tiers...)
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// Return a tenant, adding it if needed
+func (b *Budgeter) tenant(name string) *tenantBudget {
+	t, exists := b.tenants[name]
+	if !exists {
+		t = &tenantBudget{changed: make(chan struct{})}
+		b.tenants[name] = t
+	}
+	return t
+}
+
+// Replenish the budget for the time since it was last replenished, up to
+// the whole budget
+func (t *tenantBudget) replenish(now time.Time) {
+	if t.budget.Period > 0 && t.available < t.budget.Compute {
+		elapsed := now.Sub(t.updated)
+		t.available = min(t.available+time.Duration(float64(elapsed)/float64(t.budget.Period)*float64(t.budget.Compute)), t.budget.Compute)
+	}
+	t.updated = now
+}
+
+// Wake the requests of the tenant which are waiting for budget
+func (t *tenantBudget) notify() {
+	close(t.changed)
+	t.changed = make(chan struct{})
+}
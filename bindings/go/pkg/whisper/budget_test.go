@@ -0,0 +1,79 @@
+package whisper_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ggerganov/whisper.cpp/bindings/go/pkg/whisper"
+	assert "github.com/stretchr/testify/assert"
+)
+
+func TestBudgeter(t *testing.T) {
+	assert := assert.New(t)
+	budgeter := whisper.NewBudgeter(whisper.BudgetTier{Name: "large", Cost: 1}, whisper.BudgetTier{Name: "small", Cost: 0.25})
+	budgeter.SetBudget("tenant", &whisper.Budget{Compute: 10 * time.Second})
+	request := whisper.BudgetRequest{Tenant: "tenant", Audio: 8 * time.Second}
+
+	// The preferred model is chosen while it fits the budget, and then a
+	// smaller one
+	large, err := budgeter.Reserve(context.Background(), request)
+	if assert.NoError(err) {
+		assert.Equal("large", large.Tier)
+		assert.Equal(8*time.Second, large.Estimate)
+	}
+	small, err := budgeter.Reserve(context.Background(), request)
+	if assert.NoError(err) {
+		assert.Equal("small", small.Tier)
+	}
+
+	// Requests wait while no model fits the remaining budget
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = budgeter.Reserve(ctx, request)
+	assert.ErrorIs(err, context.DeadlineExceeded)
+
+	// The compute time spent replaces the estimate, and updates the cost
+	large.Done(4 * time.Second)
+	large.Done(4 * time.Second)
+	usage := budgeter.Usage("tenant")
+	assert.Equal(map[string]time.Duration{"large": 4 * time.Second}, usage.Compute)
+	assert.Equal(1, usage.Requests)
+	assert.Equal(4*time.Second, usage.Available)
+	assert.InDelta(0.9, budgeter.Tiers()[0].Cost, 1e-9)
+	small.Done(2 * time.Second)
+
+	// The limit of a request and the whole budget are not waited for
+	limited, err := budgeter.Reserve(context.Background(), whisper.BudgetRequest{Tenant: "tenant", Audio: 8 * time.Second, Limit: 3 * time.Second})
+	if assert.NoError(err) {
+		assert.Equal("small", limited.Tier)
+		limited.Done(0)
+	}
+	_, err = budgeter.Reserve(context.Background(), whisper.BudgetRequest{Tenant: "tenant", Audio: 100 * time.Second})
+	assert.ErrorIs(err, whisper.ErrBudgetExceeded)
+
+	// Tenants without a budget use the preferred model
+	unlimited, err := budgeter.Reserve(context.Background(), whisper.BudgetRequest{Tenant: "other", Audio: time.Hour})
+	if assert.NoError(err) {
+		assert.Equal("large", unlimited.Tier)
+	}
+}
+
+func TestBudgeterReplenish(t *testing.T) {
+	assert := assert.New(t)
+	budgeter := whisper.NewBudgeter(whisper.BudgetTier{Name: "large", Cost: 1}, whisper.BudgetTier{Name: "small", Cost: 0.25})
+	budgeter.SetBudget("tenant", &whisper.Budget{Compute: time.Second, Period: 100 * time.Millisecond})
+	request := whisper.BudgetRequest{Tenant: "tenant", Audio: time.Second}
+
+	// Once the budget is spent, a request waits until it is replenished
+	// enough for the smaller model
+	reservation, err := budgeter.Reserve(context.Background(), request)
+	assert.NoError(err)
+	reservation.Done(time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	reservation, err = budgeter.Reserve(ctx, request)
+	if assert.NoError(err) {
+		assert.Equal("small", reservation.Tier)
+	}
+}
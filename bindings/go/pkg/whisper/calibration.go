@@ -0,0 +1,180 @@
+package whisper
+
+import (
+	"encoding/csv"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// CalibrationSample is a transcription of labelled audio, with the
+// reference text of the audio
+type CalibrationSample struct {
+	Segments  []Segment
+	Reference string
+}
+
+// CalibrationBin summarises the words with a confidence within a range
+type CalibrationBin struct {
+	Low, High  float32 // Range of confidence values in the bin
+	Words      int     // Number of words in the bin
+	Confidence float64 // Mean confidence of the words
+	Accuracy   float64 // Fraction of the words which match the reference
+}
+
+// CalibrationReport relates word confidence to accuracy over a labelled
+// set. Word confidence is the lowest probability of the tokens of a word.
+// When the model is well calibrated, the accuracy of each bin is close to
+// its mean confidence, and confidence thresholds can be chosen from the
+// curve formed by the bins.
+type CalibrationReport struct {
+	Reference  int     // Number of words in the references
+	Hypothesis int     // Number of words transcribed
+	Errors     int     // Substitutions, deletions and insertions
+	WER        float64 // Word error rate
+	ECE        float64 // Expected calibration error over the bins
+	Bins       []CalibrationBin
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Calibrate aligns the words of each transcription with its reference and
+// bins the transcribed words by confidence. Words are compared ignoring
+// case and punctuation. Words of segments without tokens have no
+// confidence, and count towards the error rate but not the bins.
+func Calibrate(samples []CalibrationSample, bins int) CalibrationReport {
+	if bins <= 0 {
+		bins = 10
+	}
+	report := CalibrationReport{Bins: make([]CalibrationBin, bins)}
+	for i := range report.Bins {
+		report.Bins[i].Low = float32(i) / float32(bins)
+		report.Bins[i].High = float32(i+1) / float32(bins)
+	}
+
+	var correct = make([]int, bins)
+	for _, sample := range samples {
+		var hyp []word
+		for _, segment := range sample.Segments {
+			for _, w := range segmentWords(segment) {
+				if w.text = normalizeWord(w.text); w.text != "" {
+					hyp = append(hyp, w)
+				}
+			}
+		}
+		var ref []string
+		for _, text := range strings.Fields(sample.Reference) {
+			if text = normalizeWord(text); text != "" {
+				ref = append(ref, text)
+			}
+		}
+		matches, errors := alignWords(hyp, ref)
+		report.Reference += len(ref)
+		report.Hypothesis += len(hyp)
+		report.Errors += errors
+
+		// Bin the words by confidence
+		for i, w := range hyp {
+			if !w.tokens {
+				continue
+			}
+			n := min(max(int(w.p*float32(bins)), 0), bins-1)
+			report.Bins[n].Words++
+			report.Bins[n].Confidence += float64(w.p)
+			if matches[i] {
+				correct[n]++
+			}
+		}
+	}
+
+	// Compute the calibration curve and error
+	var words int
+	for i := range report.Bins {
+		bin := &report.Bins[i]
+		if bin.Words == 0 {
+			continue
+		}
+		bin.Confidence /= float64(bin.Words)
+		bin.Accuracy = float64(correct[i]) / float64(bin.Words)
+		report.ECE += float64(bin.Words) * math.Abs(bin.Accuracy-bin.Confidence)
+		words += bin.Words
+	}
+	if words > 0 {
+		report.ECE /= float64(words)
+	}
+	if report.Reference > 0 {
+		report.WER = float64(report.Errors) / float64(report.Reference)
+	}
+
+	// Return the report
+	return report
+}
+
+// WriteCSV writes the calibration curve to w as CSV, with one row per bin
+func (report CalibrationReport) WriteCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{"low", "high", "words", "confidence", "accuracy"}); err != nil {
+		return err
+	}
+	for _, bin := range report.Bins {
+		if err := writer.Write([]string{
+			strconv.FormatFloat(float64(bin.Low), 'f', 3, 32),
+			strconv.FormatFloat(float64(bin.High), 'f', 3, 32),
+			strconv.Itoa(bin.Words),
+			strconv.FormatFloat(bin.Confidence, 'f', 4, 64),
+			strconv.FormatFloat(bin.Accuracy, 'f', 4, 64),
+		}); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// Align hypothesis words with reference words by edit distance, returning
+// which hypothesis words match the reference and the number of errors
+func alignWords(hyp []word, ref []string) ([]bool, int) {
+	// dist[i][j] is the edit distance between hyp[:i] and ref[:j]
+	dist := make([][]int, len(hyp)+1)
+	for i := range dist {
+		dist[i] = make([]int, len(ref)+1)
+		dist[i][0] = i
+	}
+	for j := range dist[0] {
+		dist[0][j] = j
+	}
+	for i := 1; i <= len(hyp); i++ {
+		for j := 1; j <= len(ref); j++ {
+			cost := 1
+			if hyp[i-1].text == ref[j-1] {
+				cost = 0
+			}
+			dist[i][j] = min(dist[i-1][j-1]+cost, dist[i-1][j]+1, dist[i][j-1]+1)
+		}
+	}
+
+	// Trace back to find the matching words
+	matches := make([]bool, len(hyp))
+	for i, j := len(hyp), len(ref); i > 0 && j > 0; {
+		switch {
+		case hyp[i-1].text == ref[j-1] && dist[i][j] == dist[i-1][j-1]:
+			matches[i-1] = true
+			i, j = i-1, j-1
+		case dist[i][j] == dist[i-1][j-1]+1:
+			i, j = i-1, j-1
+		case dist[i][j] == dist[i-1][j]+1:
+			i--
+		default:
+			j--
+		}
+	}
+	return matches, dist[len(hyp)][len(ref)]
+}
@@ -0,0 +1,44 @@
+package whisper_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/ggerganov/whisper.cpp/bindings/go/pkg/whisper"
+	assert "github.com/stretchr/testify/assert"
+)
+
+func TestCalibrate(t *testing.T) {
+	assert := assert.New(t)
+
+	samples := []whisper.CalibrationSample{{
+		Reference: "The quick brown fox",
+		Segments: []whisper.Segment{{Tokens: []whisper.Token{
+			{Text: "[_BEG_]", P: 0.1},
+			{Text: " The", P: 0.95},
+			{Text: " quick", P: 0.9},
+			{Text: " crown", P: 0.3},
+			{Text: " fox", P: 0.85},
+			{Text: ".", P: 0.99},
+		}}},
+	}}
+
+	report := whisper.Calibrate(samples, 2)
+	assert.Equal(4, report.Reference)
+	assert.Equal(4, report.Hypothesis)
+	assert.Equal(1, report.Errors)
+	assert.Equal(0.25, report.WER)
+	assert.Len(report.Bins, 2)
+	assert.Equal(1, report.Bins[0].Words)
+	assert.Equal(0.0, report.Bins[0].Accuracy)
+	assert.Equal(3, report.Bins[1].Words)
+	assert.Equal(1.0, report.Bins[1].Accuracy)
+	assert.InDelta(0.15, report.ECE, 1e-6)
+
+	var buf bytes.Buffer
+	assert.NoError(report.WriteCSV(&buf))
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Len(lines, 3)
+	assert.Equal("low,high,words,confidence,accuracy", lines[0])
+}
@@ -0,0 +1,399 @@
+package whisper
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math/bits"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// CaptionMode is the way CEA-608 captions are displayed
+type CaptionMode int
+
+// CaptionOptions configure the encoding of segments as CEA-608 captions
+type CaptionOptions struct {
+	// Display mode, pop-on by default
+	Mode CaptionMode
+
+	// Data channel of the first field, 1 for CC1 (default) or 2 for CC2
+	Channel int
+
+	// Lines of each pop-on caption, from 1 to 4 (default 2). Segments with
+	// more text are split into several captions.
+	Rows int
+}
+
+// CaptionPacket is a sequence of CEA-608 byte pairs with odd parity, which
+// are sent one per frame at 29.97 frames per second from Time. Pairs are
+// in the order they are sent, with control codes sent twice.
+type CaptionPacket struct {
+	Time  time.Duration
+	Pairs [][2]byte
+}
+
+// CaptionEncoder encodes the finalized segments of a stream as CEA-608
+// captions. Packets are scheduled so that they never overlap, and the
+// captions are erased when the next segment is more than a second later.
+type CaptionEncoder struct {
+	opts  CaptionOptions
+	next  int  // Frame after the last packet
+	erase int  // Frame at which the displayed caption ends
+	shown bool // True if a caption is displayed
+}
+
+// Builds the byte pairs of a packet
+type captionBuilder struct {
+	channel byte
+	pairs   [][2]byte
+	half    byte
+	pending bool
+}
+
+// The code of a character which is not in the basic character set, and the
+// basic character which a decoder without extended characters displays
+type captionChar struct {
+	b1, b2   byte
+	fallback byte
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+const (
+	CaptionPopOn   CaptionMode = iota // Captions are loaded off screen and appear at the start of a segment
+	CaptionRollUp2                    // Lines roll up from the bottom, showing two rows
+	CaptionRollUp3                    // Lines roll up from the bottom, showing three rows
+	CaptionRollUp4                    // Lines roll up from the bottom, showing four rows
+)
+
+const (
+	// Columns of a caption row
+	captionColumns = 32
+
+	// Frame rate of the caption data, which is 29.97 frames per second
+	captionFrames, captionSeconds = 30000, 1001
+
+	// Time after the end of a segment that its caption stays up when no
+	// other segment replaces it
+	captionHold = time.Second
+)
+
+// Miscellaneous control codes, for the first data channel
+const (
+	captionRCL = 0x1420 // Resume caption loading
+	captionRU2 = 0x1425 // Roll-up captions, two rows
+	captionEDM = 0x142c // Erase displayed memory
+	captionCR  = 0x142d // Carriage return
+	captionENM = 0x142e // Erase non-displayed memory
+	captionEOC = 0x142f // End of caption, which swaps the memories
+	captionTO1 = 0x1721 // Tab offset of one column
+)
+
+// The first byte and base of the second byte of the preamble address code
+// of each row, from 1 to 15
+var captionRows = [16][2]byte{
+	{}, {0x11, 0x40}, {0x11, 0x60}, {0x12, 0x40}, {0x12, 0x60}, {0x15, 0x40}, {0x15, 0x60}, {0x16, 0x40},
+	{0x16, 0x60}, {0x17, 0x40}, {0x17, 0x60}, {0x10, 0x40}, {0x13, 0x40}, {0x13, 0x60}, {0x14, 0x40}, {0x14, 0x60},
+}
+
+// Characters of the basic character set which differ from ASCII
+var captionBasic = map[rune]byte{
+	'á': 0x2a, 'é': 0x5c, 'í': 0x5e, 'ó': 0x5f, 'ú': 0x60,
+	'ç': 0x7b, '÷': 0x7c, 'Ñ': 0x7d, 'ñ': 0x7e, '█': 0x7f,
+}
+
+// Special and extended characters, which are encoded as control codes. An
+// extended character replaces the basic character before it, which is its
+// fallback for decoders without extended characters.
+var captionChars = func() map[rune]captionChar {
+	result := make(map[rune]captionChar)
+	for _, table := range []struct {
+		b1, b2    byte
+		chars     string
+		fallbacks string
+	}{
+		{0x11, 0x30, "®°½¿™¢£♪à\x00èâêîôû", ""},
+		{0x12, 0x20, "ÁÉÓÚÜü‘¡*’—©℠•“”ÀÂÇÈÊËëÎÏïÔÙùÛ«»", "AEOUUu'!.'-cs.\"\"AACEEEeIIiOUuU\"\""},
+		{0x13, 0x20, "ÃãÍÌìÒòÕõ{}\\^_|~ÄäÖöß¥¤│ÅåØø┌┐└┘", "AaIIiOoOo()/'-!-AaOosYc!AaOo++++"},
+	} {
+		i := 0
+		for _, r := range table.chars {
+			c := captionChar{b1: table.b1, b2: table.b2 + byte(i)}
+			if i < len(table.fallbacks) {
+				c.fallback = table.fallbacks[i]
+			}
+			if r != 0 {
+				result[r] = c
+			}
+			i++
+		}
+	}
+	return result
+}()
+
+///////////////////////////////////////////////////////////////////////////////
+// LIFECYCLE
+
+// Return an encoder of CEA-608 captions with the options
+func NewCaptionEncoder(opts CaptionOptions) *CaptionEncoder {
+	if opts.Channel != 2 {
+		opts.Channel = 1
+	}
+	if opts.Rows < 1 || opts.Rows > 4 {
+		opts.Rows = 2
+	}
+	return &CaptionEncoder{opts: opts}
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+// Return the pairs as four digit hexadecimal words, as in an SCC file
+func (p CaptionPacket) String() string {
+	words := make([]string, 0, len(p.Pairs))
+	for _, pair := range p.Pairs {
+		words = append(words, fmt.Sprintf("%02x%02x", pair[0], pair[1]))
+	}
+	return strings.Join(words, " ")
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Return the packets which caption the segment, which must start no earlier
+// than the segments encoded before it. Pop-on captions are loaded before the
+// start of the segment so that they appear at its start.
+func (e *CaptionEncoder) Encode(segment Segment) []CaptionPacket {
+	lines := captionLines(segment.Text)
+	if len(lines) == 0 {
+		return nil
+	}
+	start, end := captionFrame(segment.Start), max(captionFrame(segment.End), captionFrame(segment.Start)+1)
+
+	// Erase the caption before, unless this segment replaces it soon after
+	var result []CaptionPacket
+	if e.shown && start > e.erase+captionFrame(captionHold) {
+		result = append(result, e.send(e.erase, e.control(captionEDM)))
+	}
+
+	// Split the text into captions, or into lines which roll up, over the
+	// time of the segment
+	if e.opts.Mode == CaptionPopOn {
+		n := (len(lines) + e.opts.Rows - 1) / e.opts.Rows
+		for i := 0; i < n; i++ {
+			rows := lines[i*e.opts.Rows : min((i+1)*e.opts.Rows, len(lines))]
+			b := e.builder()
+			b.control(captionENM)
+			b.control(captionRCL)
+			for k, line := range rows {
+				b.position(16-len(rows)+k, (captionColumns-utf8.RuneCountInString(line))/2)
+				b.text(line)
+			}
+			b.control(captionEOC)
+
+			// The caption appears with the last pair
+			result = append(result, e.send(start+(end-start)*i/n-len(b.pairs)+1, b.pairs))
+		}
+	} else {
+		for i, line := range lines {
+			b := e.builder()
+			b.control(captionRU2 + uint16(e.opts.Mode-CaptionRollUp2))
+			b.control(captionCR)
+			b.position(15, 0)
+			b.text(line)
+			result = append(result, e.send(start+(end-start)*i/len(lines), b.pairs))
+		}
+	}
+	e.shown, e.erase = true, end
+	return result
+}
+
+// Return the packets which erase the caption which is displayed
+func (e *CaptionEncoder) Flush() []CaptionPacket {
+	if !e.shown {
+		return nil
+	}
+	e.shown = false
+	return []CaptionPacket{e.send(e.erase, e.control(captionEDM))}
+}
+
+// Return the 608 compatibility bytes of CEA-708 cc_data for the pairs, which
+// are a marker, type and validity byte followed by each pair, for the first
+// field. A muxer sends one of these triplets in the user data of each frame.
+func (p CaptionPacket) CCData() []byte {
+	result := make([]byte, 0, 3*len(p.Pairs))
+	for _, pair := range p.Pairs {
+		result = append(result, 0xfc, pair[0], pair[1])
+	}
+	return result
+}
+
+// Write the segments as CEA-608 captions in the Scenarist SCC format, with
+// SMPTE drop frame timecodes
+func WriteSCC(w io.Writer, segments []Segment, opts CaptionOptions) error {
+	e := NewCaptionEncoder(opts)
+	buf := bufio.NewWriter(w)
+	buf.WriteString("Scenarist_SCC V1.0\n")
+	write := func(packets []CaptionPacket) {
+		for _, p := range packets {
+			fmt.Fprintf(buf, "\n%s\t%v\n", SMPTETimecode(p.Time), p)
+		}
+	}
+	for _, segment := range segments {
+		write(e.Encode(segment))
+	}
+	write(e.Flush())
+	return buf.Flush()
+}
+
+// Return the SMPTE drop frame timecode of a time at 29.97 frames per second,
+// such as "01:02:03;04"
+func SMPTETimecode(t time.Duration) string {
+	frames := max(captionFrame(t), 0)
+
+	// Frame numbers 0 and 1 are dropped from every minute except every tenth
+	d, m := frames/17982, frames%17982
+	frames += 18 * d
+	if m >= 2 {
+		frames += 2 * ((m - 2) / 1798)
+	}
+	return fmt.Sprintf("%02d:%02d:%02d;%02d", frames/108000, frames/1800%60, frames/30%60, frames%30)
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// Return a packet at the frame, or after the packet before it
+func (e *CaptionEncoder) send(frame int, pairs [][2]byte) CaptionPacket {
+	frame = max(frame, e.next, 0)
+	e.next = frame + len(pairs)
+	return CaptionPacket{Time: time.Duration(frame) * time.Second * captionSeconds / captionFrames, Pairs: pairs}
+}
+
+func (e *CaptionEncoder) builder() *captionBuilder {
+	return &captionBuilder{channel: byte(e.opts.Channel)}
+}
+
+func (e *CaptionEncoder) control(code uint16) [][2]byte {
+	b := e.builder()
+	b.control(code)
+	return b.pairs
+}
+
+// Return the frame of a time, rounded to the nearest frame
+func captionFrame(t time.Duration) int {
+	return int((t*captionFrames + time.Second*captionSeconds/2) / (time.Second * captionSeconds))
+}
+
+// Return the text wrapped into rows, with characters which cannot be
+// displayed removed
+func captionLines(text string) []string {
+	var lines []string
+	var line []rune
+	for _, word := range strings.Fields(text) {
+		var chars []rune
+		for _, r := range word {
+			if captionDisplayable(r) {
+				chars = append(chars, r)
+			}
+		}
+		for len(chars) > 0 {
+			if len(line) > 0 && len(line)+1+len(chars) > captionColumns {
+				lines, line = append(lines, string(line)), nil
+			}
+			if len(line) > 0 {
+				line = append(line, ' ')
+			}
+			n := min(len(chars), captionColumns-len(line))
+			line, chars = append(line, chars[:n]...), chars[n:]
+		}
+	}
+	if len(line) > 0 {
+		lines = append(lines, string(line))
+	}
+	return lines
+}
+
+// Return true if the character is in one of the character sets. Some ASCII
+// characters are replaced by accented letters in the basic character set.
+func captionDisplayable(r rune) bool {
+	if _, exists := captionBasic[r]; exists {
+		return true
+	} else if _, exists := captionChars[r]; exists {
+		return true
+	}
+	switch r {
+	case '*', '\\', '^', '_', '`', '{', '|', '}', '~':
+		return false
+	}
+	return r >= 0x20 && r < 0x7f
+}
+
+// Add a control code, which is sent twice
+func (b *captionBuilder) control(code uint16) {
+	b1, b2 := byte(code>>8), byte(code)
+	if b.channel == 2 {
+		b1 |= 0x08
+	}
+	b.flush()
+	pair := [2]byte{parity(b1), parity(b2)}
+	b.pairs = append(b.pairs, pair, pair)
+}
+
+// Move to the row and column, with a preamble address code for the nearest
+// indent at or before the column and tab offsets for the rest
+func (b *captionBuilder) position(row, column int) {
+	column = min(max(column, 0), captionColumns-1)
+	b.control(uint16(captionRows[row][0])<<8 | uint16(captionRows[row][1]+0x10+byte(column/4)*2))
+	if tab := column % 4; tab > 0 {
+		b.control(captionTO1 + uint16(tab-1))
+	}
+}
+
+// Add the characters of the text
+func (b *captionBuilder) text(text string) {
+	for _, r := range text {
+		if c, exists := captionBasic[r]; exists {
+			b.char(c)
+		} else if c, exists := captionChars[r]; exists {
+			// Extended characters replace the fallback before them
+			if c.b1 != 0x11 {
+				b.char(c.fallback)
+			}
+			b.control(uint16(c.b1)<<8 | uint16(c.b2))
+		} else {
+			b.char(byte(r))
+		}
+	}
+	b.flush()
+}
+
+func (b *captionBuilder) char(c byte) {
+	if b.pending {
+		b.pairs = append(b.pairs, [2]byte{parity(b.half), parity(c)})
+		b.pending = false
+	} else {
+		b.half, b.pending = c, true
+	}
+}
+
+// Pad a single character with a null
+func (b *captionBuilder) flush() {
+	if b.pending {
+		b.char(0)
+	}
+}
+
+// Set the top bit so that the byte has odd parity
+func parity(b byte) byte {
+	if bits.OnesCount8(b)%2 == 0 {
+		return b | 0x80
+	}
+	return b
+}
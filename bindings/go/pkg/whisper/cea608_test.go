@@ -0,0 +1,95 @@
+package whisper_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ggerganov/whisper.cpp/bindings/go/pkg/whisper"
+	assert "github.com/stretchr/testify/assert"
+)
+
+// Return the time of a frame at 29.97 frames per second
+func captionFrame(n int) time.Duration {
+	return time.Duration(n) * time.Second * 1001 / 30000
+}
+
+func TestSMPTETimecode(t *testing.T) {
+	assert := assert.New(t)
+
+	// Frames 0 and 1 are dropped from each minute, except every tenth
+	assert.Equal("00:00:00;00", whisper.SMPTETimecode(0))
+	assert.Equal("00:00:59;29", whisper.SMPTETimecode(captionFrame(1799)))
+	assert.Equal("00:01:00;02", whisper.SMPTETimecode(captionFrame(1800)))
+	assert.Equal("00:10:00;00", whisper.SMPTETimecode(captionFrame(17982)))
+	assert.Equal("01:00:00;00", whisper.SMPTETimecode(captionFrame(6*17982)))
+}
+
+func TestCaptionEncoder(t *testing.T) {
+	assert := assert.New(t)
+
+	// A pop-on caption is loaded so that it appears at the start of the
+	// segment, and is erased at the end
+	e := whisper.NewCaptionEncoder(whisper.CaptionOptions{})
+	packets := e.Encode(whisper.Segment{Start: 2 * time.Second, End: 4 * time.Second, Text: "Hello world"})
+	if assert.Len(packets, 1) {
+		p := packets[0]
+		assert.True(strings.HasPrefix(p.String(), "94ae 94ae 9420 9420 "), p.String())
+		assert.True(strings.HasSuffix(p.String(), " 942f 942f"), p.String())
+		assert.Contains(p.String(), "c8e5")
+		assert.InDelta(2*time.Second, p.Time+captionFrame(len(p.Pairs)-1), float64(captionFrame(1)))
+		assert.Len(p.CCData(), 3*len(p.Pairs))
+		assert.Equal(byte(0xfc), p.CCData()[0])
+	}
+	packets = e.Flush()
+	if assert.Len(packets, 1) {
+		assert.Equal("942c 942c", packets[0].String())
+		assert.InDelta(4*time.Second, packets[0].Time, float64(captionFrame(1)))
+	}
+	assert.Empty(e.Flush())
+
+	// Extended characters follow their fallback, characters which cannot
+	// be displayed are dropped, and the second channel is marked
+	e = whisper.NewCaptionEncoder(whisper.CaptionOptions{Channel: 2})
+	packets = e.Encode(whisper.Segment{Start: 2 * time.Second, End: 4 * time.Second, Text: "Ça`"})
+	if assert.Len(packets, 1) {
+		assert.True(strings.HasPrefix(packets[0].String(), "1cae 1cae"), packets[0].String())
+		assert.Contains(packets[0].String(), "4380 1a32 1a32 6180 ")
+	}
+
+	// Roll-up captions send each line from the bottom row, and packets do
+	// not overlap
+	e = whisper.NewCaptionEncoder(whisper.CaptionOptions{Mode: whisper.CaptionRollUp3})
+	text := strings.Repeat("the quick brown fox jumps over the lazy dog ", 3)
+	packets = e.Encode(whisper.Segment{Start: 0, End: 100 * time.Millisecond, Text: text})
+	assert.Greater(len(packets), 1)
+	for i, p := range packets {
+		assert.True(strings.HasPrefix(p.String(), "9426 9426 94ad 94ad 9470 9470 "), p.String())
+		assert.LessOrEqual(len(p.Pairs), 6+16)
+		if i > 0 {
+			assert.GreaterOrEqual(p.Time, packets[i-1].Time+captionFrame(len(packets[i-1].Pairs)-1))
+		}
+	}
+}
+
+func TestWriteSCC(t *testing.T) {
+	assert := assert.New(t)
+
+	var buf bytes.Buffer
+	assert.NoError(whisper.WriteSCC(&buf, []whisper.Segment{
+		{Start: 2 * time.Second, End: 3 * time.Second, Text: "One."},
+		{Start: 3 * time.Second, End: 4 * time.Second, Text: "Two."},
+		{Start: 10 * time.Second, End: 11 * time.Second, Text: "Three."},
+	}, whisper.CaptionOptions{}))
+
+	// The second caption replaces the first, and the display is erased
+	// before the gap and at the end
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n\n")
+	assert.Equal("Scenarist_SCC V1.0", lines[0])
+	if assert.Len(lines, 6) {
+		assert.True(strings.HasPrefix(lines[1], "00:00:01;"), lines[1])
+		assert.True(strings.HasPrefix(lines[3], "00:00:04;00\t942c 942c"), lines[3])
+		assert.True(strings.HasPrefix(lines[5], "00:00:11;00\t942c 942c"), lines[5])
+	}
+}
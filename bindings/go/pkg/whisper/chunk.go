@@ -0,0 +1,94 @@
+package whisper
+
+import (
+	"io"
+	"time"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// ChunkOptions are the options of ProcessChunks. Zero values select the
+// defaults.
+type ChunkOptions struct {
+	// Length of each chunk of audio passed to Process (default 30s)
+	Length time.Duration
+
+	// Audio which consecutive chunks share, which must be shorter than the
+	// length (default 2s). The stride between chunks is the length less the
+	// overlap.
+	Overlap time.Duration
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// ProcessChunks transcribes long audio in chunks of a fixed length which
+// overlap, for tuning the accuracy at the boundaries of chunks. Segments of
+// each chunk are kept from the middle of the overlap with the chunk before
+// to the middle of the overlap with the chunk after, by the middle of their
+// time span, so that a word cut at the end of one chunk is taken whole from
+// the next. Segment, token and window times are mapped onto the timeline of
+// the audio, segments are numbered sequentially, and each is passed to the
+// callback once its chunk is merged.
+//
+// Within each chunk, whisper still decodes 30 second windows and seeks each
+// window to its last timestamp token rather than by a fixed stride, so chunks
+// longer than 30 seconds have windows which end mid-word like audio passed
+// to Process whole. Chunks of up to 30 seconds are decoded in one window, and
+// their overlap is what prevents errors at their boundaries. A larger
+// overlap is more robust but transcribes more audio twice.
+func ProcessChunks(context Transcriber, data []float32, opts ChunkOptions, callNewSegment SegmentCallback) ([]Segment, error) {
+	if opts.Length <= 0 {
+		opts.Length = 30 * time.Second
+	}
+	if opts.Overlap <= 0 {
+		opts.Overlap = 2 * time.Second
+	}
+	if opts.Overlap >= opts.Length {
+		return nil, ErrInvalidParams
+	}
+	length, stride := durationToSamples(opts.Length), durationToSamples(opts.Length-opts.Overlap)
+
+	var result []Segment
+	var from time.Duration // Middle of the overlap with the chunk before
+	for i := 0; i < len(data); i += stride {
+		j := min(i+length, len(data))
+		if err := context.Process(data[i:j], nil, nil, nil); err != nil {
+			return result, err
+		}
+
+		// Keep the segments up to the middle of the overlap with the next
+		// chunk, which is the last when it reaches the end of the audio
+		offset := samplesToDuration(i)
+		to := time.Duration(-1)
+		if j < len(data) {
+			to = samplesToDuration(j) - opts.Overlap/2
+		}
+		for {
+			segment, err := context.NextSegment()
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				return result, err
+			}
+			segment.shift(offset)
+			segment.Window += offset
+			if mid := segment.Start + (segment.End-segment.Start)/2; mid < from || (to >= 0 && mid >= to) {
+				continue
+			}
+			segment.Num = len(result)
+			if callNewSegment != nil {
+				callNewSegment(segment)
+			}
+			result = append(result, segment)
+		}
+		if j == len(data) {
+			break
+		}
+		from = to
+	}
+
+	// Return success
+	return result, nil
+}
@@ -0,0 +1,70 @@
+package whisper_test
+
+import (
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/ggerganov/whisper.cpp/bindings/go/pkg/whisper"
+	assert "github.com/stretchr/testify/assert"
+)
+
+// A transcriber which returns a segment for each second of the data, whose
+// text is the second of the audio it came from. Each sample of the audio is
+// its position in seconds.
+type secondsTranscriber struct {
+	next  []whisper.Segment
+	calls int
+}
+
+func (f *secondsTranscriber) Process(data []float32, _ whisper.EncoderBeginCallback, _ whisper.SegmentCallback, _ whisper.ProgressCallback) error {
+	f.calls++
+	f.next = nil
+	for i := 0; i < len(data); i += whisper.SampleRate {
+		start := time.Duration(i) * time.Second / whisper.SampleRate
+		end := min(start+time.Second, time.Duration(len(data))*time.Second/whisper.SampleRate)
+		f.next = append(f.next, whisper.Segment{Start: start, End: end, Text: fmt.Sprint(int(data[i]))})
+	}
+	return nil
+}
+
+func (f *secondsTranscriber) NextSegment() (whisper.Segment, error) {
+	if len(f.next) == 0 {
+		return whisper.Segment{}, io.EOF
+	}
+	segment := f.next[0]
+	f.next = f.next[1:]
+	return segment, nil
+}
+
+func TestProcessChunks(t *testing.T) {
+	assert := assert.New(t)
+
+	data := make([]float32, 25*whisper.SampleRate)
+	for i := range data {
+		data[i] = float32(i / whisper.SampleRate)
+	}
+
+	// Each second of the audio is kept once, from one of the chunks which
+	// share it, on the timeline of the audio
+	transcriber := &secondsTranscriber{}
+	var called int
+	segments, err := whisper.ProcessChunks(transcriber, data, whisper.ChunkOptions{Length: 10 * time.Second, Overlap: 2 * time.Second}, func(whisper.Segment) {
+		called++
+	})
+	assert.NoError(err)
+	assert.Equal(3, transcriber.calls)
+	if assert.Len(segments, 25) {
+		for i, segment := range segments {
+			assert.Equal(i, segment.Num)
+			assert.Equal(fmt.Sprint(i), segment.Text)
+			assert.Equal(time.Duration(i)*time.Second, segment.Start)
+		}
+	}
+	assert.Equal(25, called)
+
+	// The overlap must be shorter than the chunks
+	_, err = whisper.ProcessChunks(transcriber, data, whisper.ChunkOptions{Length: time.Second, Overlap: time.Second}, nil)
+	assert.ErrorIs(err, whisper.ErrInvalidParams)
+}
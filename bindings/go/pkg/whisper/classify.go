@@ -0,0 +1,293 @@
+package whisper
+
+import (
+	"cmp"
+	"math"
+	"slices"
+	"time"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// AudioClass is the kind of sound which is most likely in a window of audio
+type AudioClass int
+
+// AudioScores are the probabilities of the kinds of sound in a window of
+// audio. They need not add up to one, and the rest of the window is silent.
+type AudioScores struct {
+	Speech float32
+	Music  float32
+	Noise  float32
+
+	// Label of the non-speech sound, such as "applause", or empty to label
+	// the window as music or noise
+	Event string
+}
+
+// AudioClassifier scores the kinds of sound in a window of mono audio,
+// so that non-speech regions can be skipped before they are transcribed.
+// A music or sound event model can be plugged in with AudioClassifierFunc.
+type AudioClassifier interface {
+	ClassifyAudio([]float32) (AudioScores, error)
+}
+
+// AudioClassifierFunc adapts a function to an AudioClassifier
+type AudioClassifierFunc func([]float32) (AudioScores, error)
+
+// EnergyClassifier is a simple classifier which does not require a model.
+// The speech score is the part of the window detected as speech by the VAD,
+// and the rest of the window is scored by the RMS energy of its frames,
+// as music where the energy is steady and as noise where it is not.
+type EnergyClassifier struct {
+	// VAD which detects the speech in the window (default EnergyVAD). The
+	// EnergyVAD detects any loud sound as speech, so a Silero VAD is needed
+	// to tell music and noise apart from speech.
+	VAD VAD
+
+	// RMS energy threshold below which a frame is silent (default 0.02)
+	Threshold float32
+
+	// Analysis frame length (default 30ms)
+	Frame time.Duration
+}
+
+// AudioEvent is a non-speech sound in the audio, such as music
+type AudioEvent struct {
+	SpeechRegion
+	Class AudioClass
+	Label string // Annotation in the transcript, such as "[music]"
+}
+
+// AudioPrefilter classifies audio in fixed windows before it is transcribed,
+// so that only speech is passed to the model, and the rest is reported as
+// non-speech events. It implements VAD, so it can also be passed to
+// ProcessSpeechRegions.
+type AudioPrefilter struct {
+	classifier AudioClassifier
+	window     time.Duration
+}
+
+// Regions which have already been detected
+type regionsVAD []SpeechRegion
+
+///////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+const (
+	ClassSilence AudioClass = iota
+	ClassSpeech
+	ClassMusic
+	ClassNoise
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// LIFECYCLE
+
+// Return a prefilter which classifies windows of the given length, or of one
+// second if zero, with the classifier, or with an EnergyClassifier if nil
+func NewAudioPrefilter(classifier AudioClassifier, window time.Duration) *AudioPrefilter {
+	if classifier == nil {
+		classifier = EnergyClassifier{}
+	}
+	if window <= 0 {
+		window = time.Second
+	}
+	return &AudioPrefilter{classifier: classifier, window: window}
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (c AudioClass) String() string {
+	switch c {
+	case ClassSilence:
+		return "silence"
+	case ClassSpeech:
+		return "speech"
+	case ClassMusic:
+		return "music"
+	case ClassNoise:
+		return "noise"
+	default:
+		return "unknown"
+	}
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+func (fn AudioClassifierFunc) ClassifyAudio(data []float32) (AudioScores, error) {
+	return fn(data)
+}
+
+// Return the most likely kind of sound, or ClassSilence if all the scores
+// are zero
+func (s AudioScores) Class() AudioClass {
+	switch {
+	case s.Speech <= 0 && s.Music <= 0 && s.Noise <= 0:
+		return ClassSilence
+	case s.Speech >= s.Music && s.Speech >= s.Noise:
+		return ClassSpeech
+	case s.Music >= s.Noise:
+		return ClassMusic
+	default:
+		return ClassNoise
+	}
+}
+
+// Score the window with the VAD and the energy of its frames
+func (c EnergyClassifier) ClassifyAudio(data []float32) (AudioScores, error) {
+	var scores AudioScores
+	if len(data) == 0 {
+		return scores, nil
+	}
+	vad, threshold, frame := c.VAD, c.Threshold, durationToSamples(c.Frame)
+	if vad == nil {
+		vad = EnergyVAD{}
+	}
+	if threshold <= 0 {
+		threshold = 0.02
+	}
+	if frame <= 0 {
+		frame = durationToSamples(30 * time.Millisecond)
+	}
+
+	// Mark the samples of speech
+	regions, err := vad.DetectSpeech(data)
+	if err != nil {
+		return scores, err
+	}
+	speech := make([]bool, len(data))
+	n := 0
+	for _, region := range regions {
+		i, j := max(durationToSamples(region.Start), 0), min(durationToSamples(region.End), len(data))
+		for k := i; k < j; k++ {
+			if !speech[k] {
+				speech[k] = true
+				n++
+			}
+		}
+	}
+	scores.Speech = float32(n) / float32(len(data))
+
+	// Measure the energy of the frames which are not speech
+	var energy []float64
+	loud := 0
+	for i := 0; i < len(data); i += frame {
+		j := min(i+frame, len(data))
+		if slices.Contains(speech[i:j], true) {
+			continue
+		}
+		e := rms(data[i:j])
+		if e >= threshold {
+			loud++
+		}
+		energy = append(energy, float64(e))
+	}
+	if loud == 0 {
+		return scores, nil
+	}
+
+	// Steady energy is scored as music, and varying energy as noise, by the
+	// coefficient of variation of the frame energy
+	var mean, variance float64
+	for _, e := range energy {
+		mean += e
+	}
+	mean /= float64(len(energy))
+	for _, e := range energy {
+		variance += (e - mean) * (e - mean)
+	}
+	steady := float32(max(0, 1-math.Sqrt(variance/float64(len(energy)))/mean))
+	sound := (1 - scores.Speech) * float32(loud) / float32(len(energy))
+	scores.Music = sound * steady
+	scores.Noise = sound * (1 - steady)
+	return scores, nil
+}
+
+// Return the regions of consecutive windows which are classified as speech
+func (f *AudioPrefilter) DetectSpeech(data []float32) ([]SpeechRegion, error) {
+	regions, _, err := f.Classify(data)
+	return regions, err
+}
+
+// Classify each window of the audio, and return the regions of consecutive
+// windows which are classified as speech, and the events of consecutive
+// windows with the same non-speech sound. Silent windows are in neither.
+func (f *AudioPrefilter) Classify(data []float32) ([]SpeechRegion, []AudioEvent, error) {
+	var regions []SpeechRegion
+	var events []AudioEvent
+	window := durationToSamples(f.window)
+	for i := 0; i < len(data); i += window {
+		j := min(i+window, len(data))
+		scores, err := f.classifier.ClassifyAudio(data[i:j])
+		if err != nil {
+			return nil, nil, err
+		}
+		region := SpeechRegion{Start: samplesToDuration(i), End: samplesToDuration(j)}
+		switch class := scores.Class(); class {
+		case ClassSilence:
+			continue
+		case ClassSpeech:
+			if n := len(regions); n > 0 && regions[n-1].End == region.Start {
+				regions[n-1].End = region.End
+			} else {
+				regions = append(regions, region)
+			}
+		default:
+			label := scores.Event
+			if label == "" {
+				label = class.String()
+			}
+			label = "[" + label + "]"
+			if n := len(events); n > 0 && events[n-1].End == region.Start && events[n-1].Label == label {
+				events[n-1].End = region.End
+			} else {
+				events = append(events, AudioEvent{SpeechRegion: region, Class: class, Label: label})
+			}
+		}
+	}
+	return regions, events, nil
+}
+
+// ProcessClassified classifies the audio with the prefilter, transcribes
+// only the speech regions with the context, and returns the segments with
+// the non-speech events annotated in order of time, such as a segment with
+// the text [music]. Segments are numbered sequentially.
+func ProcessClassified(context Transcriber, prefilter *AudioPrefilter, data []float32) ([]Segment, SpeechStats, error) {
+	regions, events, err := prefilter.Classify(data)
+	if err != nil {
+		return nil, SpeechStats{Audio: samplesToDuration(len(data))}, err
+	}
+	result, stats, err := ProcessSpeechRegions(context, regionsVAD(regions), data, nil)
+	var segments []Segment
+	for _, region := range result {
+		segments = append(segments, region.Segments...)
+	}
+	return AnnotateEvents(segments, events), stats, err
+}
+
+// Return the segments with a segment for each event, whose text is the
+// label of the event and whose Events is the event, in order of start time
+// and numbered sequentially
+func AnnotateEvents(segments []Segment, events []AudioEvent) []Segment {
+	result := slices.Clone(segments)
+	for _, event := range events {
+		result = append(result, Segment{Start: event.Start, End: event.End, Text: event.Label, Events: []AudioEvent{event}})
+	}
+	slices.SortStableFunc(result, func(a, b Segment) int {
+		return cmp.Compare(a.Start, b.Start)
+	})
+	for i := range result {
+		result[i].Num = i
+	}
+	return result
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+func (vad regionsVAD) DetectSpeech([]float32) ([]SpeechRegion, error) {
+	return vad, nil
+}
@@ -0,0 +1,95 @@
+package whisper_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ggerganov/whisper.cpp/bindings/go/pkg/whisper"
+	"github.com/ggerganov/whisper.cpp/bindings/go/pkg/whisper/testsupport"
+	assert "github.com/stretchr/testify/assert"
+)
+
+// A VAD which detects no speech
+type noSpeechVAD struct{}
+
+func (noSpeechVAD) DetectSpeech([]float32) ([]whisper.SpeechRegion, error) {
+	return nil, nil
+}
+
+// Return a second of tone with the amplitude, which is silent every other
+// frame of 30ms when bursts is true
+func classifyAudio(amplitude float32, bursts bool) []float32 {
+	if bursts {
+		return testsupport.Bursts(440, amplitude, 30*time.Millisecond, time.Second)
+	}
+	return testsupport.Tone(440, amplitude, time.Second)
+}
+
+func TestEnergyClassifier(t *testing.T) {
+	assert := assert.New(t)
+
+	// A steady tone is music, and bursts of it are noise
+	classifier := whisper.EnergyClassifier{VAD: noSpeechVAD{}}
+	scores, err := classifier.ClassifyAudio(classifyAudio(0.5, false))
+	assert.NoError(err)
+	assert.Equal(whisper.ClassMusic, scores.Class())
+	assert.Zero(scores.Speech)
+	scores, err = classifier.ClassifyAudio(classifyAudio(0.5, true))
+	assert.NoError(err)
+	assert.Equal(whisper.ClassNoise, scores.Class())
+
+	// Silence has no scores
+	scores, err = classifier.ClassifyAudio(classifyAudio(0, false))
+	assert.NoError(err)
+	assert.Equal(whisper.ClassSilence, scores.Class())
+
+	// The energy VAD detects the tone as speech
+	scores, err = whisper.EnergyClassifier{}.ClassifyAudio(classifyAudio(0.5, false))
+	assert.NoError(err)
+	assert.Equal(whisper.ClassSpeech, scores.Class())
+	assert.InDelta(1, scores.Speech, 1e-6)
+}
+
+func TestProcessClassified(t *testing.T) {
+	assert := assert.New(t)
+
+	// Loud windows are speech and quiet windows are applause
+	classifier := whisper.AudioClassifierFunc(func(data []float32) (whisper.AudioScores, error) {
+		var peak float32
+		for _, v := range data {
+			peak = max(peak, v)
+		}
+		switch {
+		case peak > 0.3:
+			return whisper.AudioScores{Speech: 1}, nil
+		case peak > 0.05:
+			return whisper.AudioScores{Noise: 1, Event: "applause"}, nil
+		default:
+			return whisper.AudioScores{}, nil
+		}
+	})
+
+	// Speech, applause, silence, then two seconds of speech
+	var data []float32
+	for _, amplitude := range []float32{0.5, 0.1, 0, 0.5, 0.5} {
+		data = append(data, classifyAudio(amplitude, false)...)
+	}
+
+	transcriber := new(fakeTranscriber)
+	segments, stats, err := whisper.ProcessClassified(transcriber, whisper.NewAudioPrefilter(classifier, 0), data)
+	assert.NoError(err)
+	assert.Equal([]int{whisper.SampleRate, 2 * whisper.SampleRate}, transcriber.samples)
+	assert.Equal(2*time.Second, stats.Skipped)
+	if assert.Len(segments, 3) {
+		assert.Equal("speech", segments[0].Text)
+		assert.Equal("[applause]", segments[1].Text)
+		assert.Equal(time.Second, segments[1].Start)
+		assert.Equal(2*time.Second, segments[1].End)
+		assert.Equal("speech", segments[2].Text)
+		assert.Equal(3*time.Second, segments[2].Start)
+		assert.Equal(5*time.Second, segments[2].End)
+		for i, segment := range segments {
+			assert.Equal(i, segment.Num)
+		}
+	}
+}
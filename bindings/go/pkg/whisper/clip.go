@@ -0,0 +1,76 @@
+package whisper
+
+import (
+	"encoding/binary"
+	"io"
+	"math"
+	"time"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// ExtractClip returns the audio samples of a segment, widened by pad on each
+// side and clamped to the bounds of the samples. The segment timestamps must
+// be relative to the start of the samples, as they are for the audio passed
+// to Process. The result shares memory with samples.
+func ExtractClip(samples []float32, segment Segment, pad time.Duration) []float32 {
+	return ExtractRange(samples, segment.Start, segment.End, pad)
+}
+
+// ExtractRange returns the audio samples between start and end, for example
+// the times of a token, widened by pad on each side and clamped to the
+// bounds of the samples. The result shares memory with samples.
+func ExtractRange(samples []float32, start, end, pad time.Duration) []float32 {
+	i := min(max(durationToSamples(start-pad), 0), len(samples))
+	j := min(max(durationToSamples(end+pad), i), len(samples))
+	return samples[i:j]
+}
+
+// WriteWAV writes mono samples to w as a 16-bit PCM WAV file at SampleRate.
+// Samples are clamped to the range [-1, 1].
+func WriteWAV(w io.Writer, samples []float32) error {
+	const channels, bits = 1, 16
+	size := uint32(len(samples) * bits / 8)
+	header := struct {
+		Riff       [4]byte
+		ChunkSize  uint32
+		Wave       [4]byte
+		Fmt        [4]byte
+		FmtSize    uint32
+		Format     uint16
+		Channels   uint16
+		SampleRate uint32
+		ByteRate   uint32
+		BlockAlign uint16
+		Bits       uint16
+		Data       [4]byte
+		DataSize   uint32
+	}{
+		Riff:       [4]byte{'R', 'I', 'F', 'F'},
+		ChunkSize:  36 + size,
+		Wave:       [4]byte{'W', 'A', 'V', 'E'},
+		Fmt:        [4]byte{'f', 'm', 't', ' '},
+		FmtSize:    16,
+		Format:     1,
+		Channels:   channels,
+		SampleRate: SampleRate,
+		ByteRate:   SampleRate * channels * bits / 8,
+		BlockAlign: channels * bits / 8,
+		Bits:       bits,
+		Data:       [4]byte{'d', 'a', 't', 'a'},
+		DataSize:   size,
+	}
+	if err := binary.Write(w, binary.LittleEndian, &header); err != nil {
+		return err
+	}
+
+	// Convert samples to 16-bit PCM
+	data := make([]byte, size)
+	for i, v := range samples {
+		v = max(-1, min(v, 1))
+		binary.LittleEndian.PutUint16(data[i*2:], uint16(int16(math.Round(float64(v)*math.MaxInt16))))
+	}
+	_, err := w.Write(data)
+	return err
+}
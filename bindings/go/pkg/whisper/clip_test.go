@@ -0,0 +1,46 @@
+package whisper_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/ggerganov/whisper.cpp/bindings/go/pkg/whisper"
+	wav "github.com/go-audio/wav"
+	assert "github.com/stretchr/testify/assert"
+)
+
+func TestExtractClip(t *testing.T) {
+	assert := assert.New(t)
+
+	samples := make([]float32, whisper.SampleRate*2)
+	segment := whisper.Segment{Start: 500 * time.Millisecond, End: time.Second}
+
+	clip := whisper.ExtractClip(samples, segment, 0)
+	assert.Len(clip, whisper.SampleRate/2)
+
+	clip = whisper.ExtractClip(samples, segment, 100*time.Millisecond)
+	assert.Len(clip, whisper.SampleRate*7/10)
+
+	// Padding is clamped to the bounds of the samples
+	clip = whisper.ExtractRange(samples, 0, 3*time.Second, time.Second)
+	assert.Len(clip, len(samples))
+	clip = whisper.ExtractRange(samples, 3*time.Second, 4*time.Second, 0)
+	assert.Len(clip, 0)
+}
+
+func TestWriteWAV(t *testing.T) {
+	assert := assert.New(t)
+
+	samples := []float32{0, 0.5, -0.5, 1, -1, 2}
+	var buf bytes.Buffer
+	assert.NoError(whisper.WriteWAV(&buf, samples))
+
+	dec := wav.NewDecoder(bytes.NewReader(buf.Bytes()))
+	pcm, err := dec.FullPCMBuffer()
+	assert.NoError(err)
+	assert.Equal(uint32(whisper.SampleRate), dec.SampleRate)
+	assert.Equal(uint16(1), dec.NumChans)
+	assert.Equal(uint16(16), dec.BitDepth)
+	assert.Equal([]int{0, 16384, -16384, 32767, -32767, 32767}, pcm.Data)
+}
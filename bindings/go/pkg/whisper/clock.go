@@ -0,0 +1,131 @@
+package whisper
+
+import (
+	"cmp"
+	"slices"
+	"sync"
+	"time"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// Clock maps a position in the audio of a stream to a timestamp on the
+// timeline of the output, such as the presentation timestamps of a video
+// which live captions are muxed with, or the wall clock time the audio was
+// captured. Segments, tokens and events of a stream are mapped by its clock.
+type Clock interface {
+	Timestamp(audio time.Duration) time.Duration
+}
+
+// ClockFunc is a function which implements Clock
+type ClockFunc func(audio time.Duration) time.Duration
+
+// WallClock maps the audio of a live stream to the wall clock time it was
+// captured, as a duration since an epoch. The stream manager marks the
+// clock with the time each write of the stream arrived, and the capture time
+// of earlier audio is found by counting back from the next mark, so that
+// the time spent waiting for an utterance to end is not included.
+type WallClock struct {
+	mu    sync.Mutex
+	epoch time.Time
+	marks []clockMark
+}
+
+// The audio written up to a position, and the time it arrived
+type clockMark struct {
+	audio time.Duration
+	time  time.Time
+}
+
+// A clock which is marked with the arrival of audio
+type marker interface {
+	Mark(audio time.Duration, t time.Time)
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+// Audio behind the last mark of a wall clock for which marks are kept
+const wallClockHistory = 5 * time.Minute
+
+///////////////////////////////////////////////////////////////////////////////
+// LIFECYCLE
+
+// Return a wall clock whose timestamps are durations since the epoch, such
+// as the start of a broadcast
+func NewWallClock(epoch time.Time) *WallClock {
+	return &WallClock{epoch: epoch}
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Return the timestamp of the audio position
+func (fn ClockFunc) Timestamp(audio time.Duration) time.Duration {
+	return fn(audio)
+}
+
+// Return a clock which moves the audio by an offset, such as the time a
+// participant joined a meeting or the presentation timestamp of the first
+// audio sample
+func OffsetClock(offset time.Duration) Clock {
+	return ClockFunc(func(audio time.Duration) time.Duration {
+		return audio + offset
+	})
+}
+
+// Record that the audio of the stream up to the position arrived at t. The
+// stream manager marks the clock on each write, and other writers can mark
+// it with the capture time reported by their audio device.
+func (c *WallClock) Mark(audio time.Duration, t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.marks = append(c.marks, clockMark{audio: audio, time: t})
+
+	// Forget marks which are far behind the last one
+	i, _ := slices.BinarySearchFunc(c.marks, audio-wallClockHistory, func(m clockMark, audio time.Duration) int {
+		return cmp.Compare(m.audio, audio)
+	})
+	if i > 0 && i < len(c.marks) {
+		c.marks = slices.Delete(c.marks, 0, i)
+	}
+}
+
+// Return the time the audio position was captured as a duration since the
+// epoch, counting back from the first mark at or after it, or forward from
+// the last mark if there is none
+func (c *WallClock) Timestamp(audio time.Duration) time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.marks) == 0 {
+		return audio
+	}
+	i, _ := slices.BinarySearchFunc(c.marks, audio, func(m clockMark, audio time.Duration) int {
+		return cmp.Compare(m.audio, audio)
+	})
+	i = min(i, len(c.marks)-1)
+	mark := c.marks[i]
+	return mark.time.Sub(c.epoch) - (mark.audio - audio)
+}
+
+// Return the wall clock time of a timestamp of the clock
+func (c *WallClock) Time(timestamp time.Duration) time.Time {
+	return c.epoch.Add(timestamp)
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// Map the times of the segment, its tokens and its events with the clock
+func (segment *Segment) retime(clock Clock) {
+	segment.Start, segment.End = clock.Timestamp(segment.Start), clock.Timestamp(segment.End)
+	for k := range segment.Tokens {
+		segment.Tokens[k].Start = clock.Timestamp(segment.Tokens[k].Start)
+		segment.Tokens[k].End = clock.Timestamp(segment.Tokens[k].End)
+	}
+	for k := range segment.Events {
+		segment.Events[k].Start = clock.Timestamp(segment.Events[k].Start)
+		segment.Events[k].End = clock.Timestamp(segment.Events[k].End)
+	}
+}
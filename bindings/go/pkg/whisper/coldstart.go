@@ -0,0 +1,69 @@
+package whisper
+
+import (
+	"sync"
+	"time"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// ColdStartStats reports the latency of bringing a model into service,
+// for servers which need to decide when a model is ready for requests
+type ColdStartStats struct {
+	Load         time.Duration // Time taken to load the model
+	Warm         bool          // True once Warmup has completed
+	Warmup       time.Duration // Time taken by Warmup
+	FirstProcess time.Duration // Wall clock time of the first call to Process, excluding warmup
+}
+
+type coldStart struct {
+	sync.Mutex
+	stats ColdStartStats
+	first bool
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Warmup processes a second of silence, so that buffers are allocated and
+// backends are initialised before the first request. It returns the same
+// errors as Process.
+func (model *model) Warmup() error {
+	ctx, err := model.NewContext()
+	if err != nil {
+		return err
+	}
+	context := ctx.(*context)
+	context.SetLabel("warmup")
+	context.warmup = true
+	start := time.Now()
+	if err := context.Process(make([]float32, SampleRate), nil, nil, nil); err != nil {
+		return err
+	}
+	model.coldStart.Lock()
+	defer model.coldStart.Unlock()
+	model.coldStart.stats.Warm = true
+	model.coldStart.stats.Warmup = time.Since(start)
+	return nil
+}
+
+// Return cold start latency statistics
+func (model *model) ColdStart() ColdStartStats {
+	model.coldStart.Lock()
+	defer model.coldStart.Unlock()
+	return model.coldStart.stats
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// Record the wall clock time of a call to Process, if it is the first
+func (cs *coldStart) process(wall time.Duration) {
+	cs.Lock()
+	defer cs.Unlock()
+	if !cs.first {
+		cs.first = true
+		cs.stats.FirstProcess = wall
+	}
+}
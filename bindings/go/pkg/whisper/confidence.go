@@ -0,0 +1,56 @@
+package whisper
+
+import (
+	"math"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Return the average log probability of the text tokens of the segment, as
+// whisper compares with its log probability threshold, and false if the
+// segment has no text tokens. Special tokens such as timestamps are not
+// counted.
+func (segment Segment) AvgLogprob() (float64, bool) {
+	return avgLogprob(segment)
+}
+
+// Return the confidence in the text of the segment between zero and one,
+// which is the geometric mean of the probabilities of its text tokens, or -1
+// if the segment has no text tokens. Segments with a low confidence can be
+// flagged for review.
+func (segment Segment) Confidence() float64 {
+	return confidence(segment)
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// Return the average log probability of the text tokens of the segments,
+// and false if they have none
+func avgLogprob(segments ...Segment) (float64, bool) {
+	var sum float64
+	var n int
+	for _, segment := range segments {
+		for _, token := range segment.Tokens {
+			if isSpecialText(token.Text) {
+				continue
+			}
+			sum += math.Log(max(float64(token.P), math.SmallestNonzeroFloat64))
+			n++
+		}
+	}
+	if n == 0 {
+		return 0, false
+	}
+	return sum / float64(n), true
+}
+
+// Return the geometric mean of the probabilities of the text tokens of the
+// segments, or -1 if there are none
+func confidence(segments ...Segment) float64 {
+	if logprob, ok := avgLogprob(segments...); ok {
+		return math.Exp(logprob)
+	}
+	return -1
+}
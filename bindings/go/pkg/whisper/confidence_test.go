@@ -0,0 +1,31 @@
+package whisper_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/ggerganov/whisper.cpp/bindings/go/pkg/whisper"
+	assert "github.com/stretchr/testify/assert"
+)
+
+func TestSegmentConfidence(t *testing.T) {
+	assert := assert.New(t)
+
+	// Special tokens are not counted
+	segment := whisper.Segment{Text: "hello world", Tokens: []whisper.Token{
+		{Text: "[_BEG_]", P: 0.01},
+		{Text: " hello", P: 0.9},
+		{Text: " world", P: 0.4},
+		{Text: "[_TT_50]", P: 0.01},
+	}}
+	logprob, ok := segment.AvgLogprob()
+	assert.True(ok)
+	assert.InDelta((math.Log(0.9)+math.Log(0.4))/2, logprob, 1e-6)
+	assert.InDelta(0.6, segment.Confidence(), 1e-6)
+
+	// Segments without text tokens have no confidence
+	segment = whisper.Segment{Text: "hello", Tokens: []whisper.Token{{Text: "<|endoftext|>", P: 1}}}
+	_, ok = segment.AvgLogprob()
+	assert.False(ok)
+	assert.Equal(-1.0, segment.Confidence())
+}
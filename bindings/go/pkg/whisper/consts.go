@@ -16,6 +16,17 @@ var (
 	ErrProcessingFailed     = errors.New("processing failed")
 	ErrUnsupportedLanguage  = errors.New("unsupported language")
 	ErrModelNotMultilingual = errors.New("model is not multilingual")
+	ErrStatelessBusy        = errors.New("model is busy processing in another context")
+	ErrInvalidToken         = errors.New("invalid token")
+	ErrAdapterUnsupported   = errors.New("adapter loading is not supported")
+	ErrInvalidDevice        = errors.New("invalid device")
+	ErrUnknownStream        = errors.New("unknown stream")
+	ErrBackendFailed        = errors.New("backend failed")
+	ErrInvalidFileType      = errors.New("invalid file type")
+	ErrBudgetExceeded       = errors.New("compute budget exceeded")
+	ErrInvalidParams        = whisper.ErrInvalidParams
+	ErrOutOfMemory          = whisper.ErrOutOfMemory
+	ErrTranslateUnsupported = whisper.ErrTranslateUnsupported
 )
 
 ///////////////////////////////////////////////////////////////////////////////
@@ -1,10 +1,16 @@
 package whisper
 
 import (
+	"bytes"
+	"cmp"
 	"fmt"
 	"io"
+	"math"
 	"runtime"
+	"slices"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	// Bindings
@@ -18,38 +24,112 @@ type context struct {
 	n      int
 	model  *model
 	params whisper.Params
+	stats  ProcessStats
+	label  string
+	warmup bool
+
+	// The decoding state of a stateful context, and the gate which
+	// serializes its use. Other contexts share the state of the model.
+	stateful bool
+	state    *whisper.State
+	gate     *gate
+
+	// Set by Close, after which methods which use the state fail
+	closed atomic.Bool
+
+	// The scheduler which interleaves the encoder windows of the context
+	// with other contexts, and the priority of the context
+	scheduler *Scheduler
+	priority  Priority
+
+	// Callback which can skip ahead before each window
+	seek SeekCallback
+
+	// Options of the repetition detector, or nil when it is disabled, and
+	// the repeats found by the last call to Process
+	repetition *RepetitionOptions
+	repeats    *repetitions
+
+	// True when the timestamp sanitizer is enabled, and the segments it
+	// repaired in the last call to Process
+	sanitize bool
+	repaired *timestampRepairs
+
+	// Guards params, which may be set while another goroutine processes
+	paramsMu sync.Mutex
+
+	// Logit bias set explicitly, for hotwords and for suppressed tokens,
+	// which are merged into the parameters
+	bias, hotwords, suppress map[whisper.Token]float32
+
+	// Sequence number of the first segment of the last call to Process, the
+	// number of segments it produced, and the window which produced each
+	seq     uint64
+	nseq    int
+	windows []time.Duration
+
+	// Audio processed since the timings were reset
+	audio atomic.Int64
 }
 
-// Make sure context adheres to the interface
-var _ Context = (*context)(nil)
+// Make sure context adheres to the interfaces
+var (
+	_ Context              = (*context)(nil)
+	_ Transcriber          = (*context)(nil)
+	_ Configurable         = (*context)(nil)
+	_ Labeled              = (*context)(nil)
+	_ LanguageDetector     = (*context)(nil)
+	_ DecodingConfigurable = (*context)(nil)
+	_ TokenSuppressor      = (*context)(nil)
+	_ LogitsBiaser         = (*context)(nil)
+	_ ProcessHooks         = (*context)(nil)
+	_ TextAppender         = (*context)(nil)
+	_ StatsReporter        = (*context)(nil)
+	_ TimingsReporter      = (*context)(nil)
+	_ ComputeReleaser      = (*context)(nil)
+	_ MemoryReporter       = (*context)(nil)
+	_ RawAccessor          = (*context)(nil)
+	_ io.Closer            = (*context)(nil)
+)
 
 ///////////////////////////////////////////////////////////////////////////////
 // LIFECYCLE
 
-func newContext(model *model, params whisper.Params) (Context, error) {
+func newContext(model *model, params whisper.Params) *context {
 	context := new(context)
 	context.model = model
 	context.params = params
+	context.label = fmt.Sprintf("context-%d", model.contexts.Add(1))
+	context.gate = &model.gate
+
+	// Free the parameters if the context is not closed
+	runtime.SetFinalizer(context, finalizeContext)
 
 	// Return success
-	return context, nil
+	return context
 }
 
 ///////////////////////////////////////////////////////////////////////////////
 // PUBLIC METHODS
 
-// Set the language to use for speech recognition.
+// Set the language to use for speech recognition. The language is a
+// whisper language id, a language name such as "portuguese", or a BCP-47
+// tag such as "pt-BR", of which only the primary language is used.
 func (context *context) SetLanguage(lang string) error {
-	if context.model.ctx == nil {
+	ctx := context.rlock()
+	if ctx == nil {
 		return ErrInternalAppError
 	}
-	if !context.model.IsMultilingual() {
+	defer context.model.runlock()
+	if !context.model.meta.Load().multilingual {
 		return ErrModelNotMultilingual
 	}
 
-	if lang == "auto" {
+	context.paramsMu.Lock()
+	defer context.paramsMu.Unlock()
+	if lang = normalizeLanguage(lang); lang == "auto" {
 		context.params.SetLanguage(-1)
-	} else if id := context.model.ctx.Whisper_lang_id(lang); id < 0 {
+	} else if id := ctx.Whisper_lang_id(lang); id < 0 {
 		return ErrUnsupportedLanguage
 	} else if err := context.params.SetLanguage(id); err != nil {
 		return err
@@ -58,155 +138,379 @@ func (context *context) SetLanguage(lang string) error {
 	return nil
 }
 
+// Set the label which identifies the context in errors
+func (context *context) SetLabel(label string) {
+	context.label = label
+}
+
+// Get the label which identifies the context
+func (context *context) Label() string {
+	return context.label
+}
+
 func (context *context) IsMultilingual() bool {
 	return context.model.IsMultilingual()
 }
 
 // Get language
 func (context *context) Language() string {
-	id := context.params.Language()
+	params := context.snapshot()
+	id := params.Language()
 	if id == -1 {
 		return "auto"
 	}
-	return whisper.Whisper_lang_str(context.params.Language())
+	return whisper.Whisper_lang_str(id)
 }
 
 func (context *context) DetectedLanguage() string {
-	return whisper.Whisper_lang_str(context.model.ctx.Whisper_full_lang_id())
+	ctx := context.rlock()
+	if ctx == nil {
+		return ""
+	}
+	defer context.model.runlock()
+	if err := context.acquire(context.label); err != nil {
+		return ""
+	}
+	defer context.gate.release()
+	return whisper.Whisper_lang_str(context.results(ctx).Whisper_full_lang_id())
+}
+
+// Set detect language flag. When set, Process only detects the language of
+// the audio, which is then returned by DetectedLanguage, and no segments are
+// produced.
+func (context *context) SetDetectLanguage(v bool) {
+	context.update(func(p *whisper.Params) { p.SetDetectLanguage(v) })
 }
 
 // Set translate flag
 func (context *context) SetTranslate(v bool) {
-	context.params.SetTranslate(v)
+	context.update(func(p *whisper.Params) { p.SetTranslate(v) })
 }
 
 // Voice Activity Detection (VAD)
 func (context *context) SetVAD(v bool) {
-	context.params.SetVAD(v)
+	context.update(func(p *whisper.Params) { p.SetVAD(v) })
 }
 
 func (context *context) SetVADModelPath(path string) {
-	context.params.SetVADModelPath(path)
+	context.update(func(p *whisper.Params) { p.SetVADModelPath(path) })
 }
 
 func (context *context) SetVADThreshold(t float32) {
-	context.params.SetVADThreshold(t)
+	context.update(func(p *whisper.Params) { p.SetVADThreshold(t) })
 }
 
 func (context *context) SetVADMinSpeechMs(ms int) {
-	context.params.SetVADMinSpeechMs(ms)
+	context.update(func(p *whisper.Params) { p.SetVADMinSpeechMs(ms) })
 }
 
 func (context *context) SetVADMinSilenceMs(ms int) {
-	context.params.SetVADMinSilenceMs(ms)
+	context.update(func(p *whisper.Params) { p.SetVADMinSilenceMs(ms) })
 }
 
 func (context *context) SetVADMaxSpeechSec(s float32) {
-	context.params.SetVADMaxSpeechSec(s)
+	context.update(func(p *whisper.Params) { p.SetVADMaxSpeechSec(s) })
 }
 
 func (context *context) SetVADSpeechPadMs(ms int) {
-	context.params.SetVADSpeechPadMs(ms)
+	context.update(func(p *whisper.Params) { p.SetVADSpeechPadMs(ms) })
 }
 
 func (context *context) SetVADSamplesOverlap(sec float32) {
-	context.params.SetVADSamplesOverlap(sec)
+	context.update(func(p *whisper.Params) { p.SetVADSamplesOverlap(sec) })
 }
 
 func (context *context) SetSplitOnWord(v bool) {
-	context.params.SetSplitOnWord(v)
+	context.update(func(p *whisper.Params) { p.SetSplitOnWord(v) })
 }
 
 // Set number of threads to use
 func (context *context) SetThreads(v uint) {
-	context.params.SetThreads(int(v))
+	context.update(func(p *whisper.Params) { p.SetThreads(int(v)) })
+}
+
+// Set the number of threads to the recommended count for the backend the
+// model was loaded with
+func (context *context) SetThreadsAuto() {
+	n := RecommendedThreads(context.model.params.UseGPU())
+	context.update(func(p *whisper.Params) { p.SetThreads(n) })
 }
 
 // Set time offset
 func (context *context) SetOffset(v time.Duration) {
-	context.params.SetOffset(int(v.Milliseconds()))
+	context.update(func(p *whisper.Params) { p.SetOffset(int(v.Milliseconds())) })
 }
 
 // Set duration of audio to process
 func (context *context) SetDuration(v time.Duration) {
-	context.params.SetDuration(int(v.Milliseconds()))
+	context.update(func(p *whisper.Params) { p.SetDuration(int(v.Milliseconds())) })
+}
+
+// Set the latest time the first segment of each window may start at, which
+// stops the decoder from skipping far into the window on noisy audio
+func (context *context) SetMaxInitialTimestamp(v time.Duration) {
+	context.update(func(p *whisper.Params) { p.SetMaxInitialTs(float32(v.Seconds())) })
 }
 
 // Set timestamp token probability threshold (~0.01)
 func (context *context) SetTokenThreshold(t float32) {
-	context.params.SetTokenThreshold(t)
+	context.update(func(p *whisper.Params) { p.SetTokenThreshold(t) })
 }
 
 // Set timestamp token sum probability threshold (~0.01)
 func (context *context) SetTokenSumThreshold(t float32) {
-	context.params.SetTokenSumThreshold(t)
+	context.update(func(p *whisper.Params) { p.SetTokenSumThreshold(t) })
 }
 
 // Set max segment length in characters
 func (context *context) SetMaxSegmentLength(n uint) {
-	context.params.SetMaxSegmentLength(int(n))
+	context.update(func(p *whisper.Params) { p.SetMaxSegmentLength(int(n)) })
 }
 
 // Set token timestamps flag
 func (context *context) SetTokenTimestamps(b bool) {
-	context.params.SetTokenTimestamps(b)
+	context.update(func(p *whisper.Params) { p.SetTokenTimestamps(b) })
+}
+
+// Set no timestamps flag, which decodes text only
+func (context *context) SetNoTimestamps(v bool) {
+	context.update(func(p *whisper.Params) { p.SetNoTimestamps(v) })
 }
 
 // Set max tokens per segment (0 = no limit)
 func (context *context) SetMaxTokensPerSegment(n uint) {
-	context.params.SetMaxTokensPerSegment(int(n))
+	context.update(func(p *whisper.Params) { p.SetMaxTokensPerSegment(int(n)) })
+}
+
+// Set max segment duration (0 = no limit), for subtitles. Longer segments
+// are split before the last word which starts within the limit, using token
+// timestamps, which are enabled together with split on word.
+func (context *context) SetMaxSegmentDuration(d time.Duration) {
+	context.update(func(p *whisper.Params) {
+		p.SetMaxSegmentDuration(int(d / time.Millisecond))
+		if d > 0 {
+			p.SetTokenTimestamps(true)
+			p.SetSplitOnWord(true)
+		}
+	})
 }
 
 // Set audio encoder context
 func (context *context) SetAudioCtx(n uint) {
-	context.params.SetAudioCtx(int(n))
+	context.update(func(p *whisper.Params) { p.SetAudioCtx(int(n)) })
 }
 
-// Set maximum number of text context tokens to store
+// Set the maximum number of tokens of previous text used as the prompt,
+// which is clamped between zero and the maximum of the model
 func (context *context) SetMaxContext(n int) {
-	context.params.SetMaxContext(n)
+	n = max(n, 0)
+	if limit := context.model.MaxContext(); limit > 0 {
+		n = min(n, limit)
+	}
+	context.update(func(p *whisper.Params) { p.SetMaxContext(n) })
+}
+
+// Get the maximum number of tokens of previous text used as the prompt
+func (context *context) MaxContext() int {
+	params := context.snapshot()
+	n := params.MaxContext()
+	if limit := context.model.MaxContext(); limit > 0 {
+		n = min(n, limit)
+	}
+	return n
 }
 
 // Set Beam Size
 func (context *context) SetBeamSize(n int) {
-	context.params.SetBeamSize(n)
+	context.update(func(p *whisper.Params) { p.SetBeamSize(n) })
+}
+
+// Set beam search patience factor
+func (context *context) SetPatience(t float32) {
+	context.update(func(p *whisper.Params) { p.SetPatience(t) })
+}
+
+// Set the length penalty used to rank beam search candidates
+// Pass -1.0 to rank by the simple length normalized log probability
+func (context *context) SetLengthPenalty(t float32) {
+	context.update(func(p *whisper.Params) { p.SetLengthPenalty(t) })
+}
+
+// Set number of candidates to sample when decoding with non-zero temperature
+func (context *context) SetBestOf(n int) {
+	context.update(func(p *whisper.Params) { p.SetBestOf(n) })
 }
 
 // Set Entropy threshold
 func (context *context) SetEntropyThold(t float32) {
-	context.params.SetEntropyThold(t)
+	context.update(func(p *whisper.Params) { p.SetEntropyThold(t) })
+}
+
+// Set average log probability threshold, below which decoding is retried
+// at a higher temperature
+func (context *context) SetLogprobThold(t float32) {
+	context.update(func(p *whisper.Params) { p.SetLogprobThold(t) })
+}
+
+// Set no speech probability threshold, above which a window is treated as
+// silence
+func (context *context) SetNoSpeechThold(t float32) {
+	context.update(func(p *whisper.Params) { p.SetNoSpeechThold(t) })
 }
 
 // Set Temperature
 func (context *context) SetTemperature(t float32) {
-	context.params.SetTemperature(t)
+	context.update(func(p *whisper.Params) { p.SetTemperature(t) })
 }
 
 // Set the fallback temperature incrementation
 // Pass -1.0 to disable this feature
 func (context *context) SetTemperatureFallback(t float32) {
-	context.params.SetTemperatureFallback(t)
+	context.update(func(p *whisper.Params) { p.SetTemperatureFallback(t) })
+}
+
+// Set an explicit temperature fallback schedule, such as [0, 0.2, 0.4, 0.8],
+// used instead of the temperature and fallback incrementation. Pass nil to
+// return to those.
+func (context *context) SetTemperatureSchedule(temperatures []float32) error {
+	for _, t := range temperatures {
+		if t < 0 || math.IsNaN(float64(t)) {
+			return fmt.Errorf("%w: temperature %v", ErrInvalidParams, t)
+		}
+	}
+	context.update(func(p *whisper.Params) { p.SetTemperatures(temperatures) })
+	return nil
 }
 
 // Set initial prompt
 func (context *context) SetInitialPrompt(prompt string) {
-	context.params.SetInitialPrompt(prompt)
+	context.update(func(p *whisper.Params) { p.SetInitialPrompt(prompt) })
+}
+
+// Set the initial prompt as token ids, which takes precedence over the
+// initial prompt text. Pass nil to clear.
+func (context *context) SetInitialPromptTokens(tokens []int) error {
+	meta := context.model.metadata()
+	if meta == nil {
+		return ErrInternalAppError
+	}
+	result := make([]whisper.Token, len(tokens))
+	for i, id := range tokens {
+		if id < 0 || id >= meta.vocab {
+			return fmt.Errorf("%w: %d", ErrInvalidToken, id)
+		}
+		result[i] = whisper.Token(id)
+	}
+	context.update(func(p *whisper.Params) { p.SetInitialPromptTokens(result) })
+	return nil
+}
+
+// Set a bias added to the logits of token ids during decoding. Pass nil to
+// clear.
+func (context *context) SetLogitBias(bias map[int]float32) error {
+	meta := context.model.metadata()
+	if meta == nil {
+		return ErrInternalAppError
+	}
+	result := make(map[whisper.Token]float32, len(bias))
+	for id, v := range bias {
+		if id < 0 || id >= meta.vocab {
+			return fmt.Errorf("%w: %d", ErrInvalidToken, id)
+		} else if math.IsNaN(float64(v)) || math.IsInf(float64(v), 1) {
+			return fmt.Errorf("%w: logit bias %v", ErrInvalidParams, v)
+		}
+		result[whisper.Token(id)] = v
+	}
+	context.paramsMu.Lock()
+	defer context.paramsMu.Unlock()
+	context.bias = result
+	context.applyBias()
+	return nil
+}
+
+// Set a callback which can modify the logits before each token is sampled
+func (context *context) SetLogitsFilterCallback(fn LogitsFilterCallback) {
+	var cb whisper.LogitsFilterCallback
+	if fn != nil {
+		cb = func(tokens []whisper.TokenData, logits []float32) {
+			ids := make([]int, len(tokens))
+			for i, token := range tokens {
+				ids[i] = int(token.Id())
+			}
+			fn(ids, logits)
+		}
+	}
+	context.update(func(p *whisper.Params) { p.SetLogitsFilterCallback(cb) })
+}
+
+// Set a callback which can skip ahead before each window is encoded
+func (context *context) SetSeekCallback(fn SeekCallback) {
+	context.paramsMu.Lock()
+	defer context.paramsMu.Unlock()
+	context.seek = fn
+}
+
+// Detect repetition loops, or disable the detector when opts is nil
+func (context *context) SetRepetitionDetector(opts *RepetitionOptions) {
+	context.paramsMu.Lock()
+	defer context.paramsMu.Unlock()
+	if opts != nil {
+		v := *opts
+		opts = &v
+	}
+	context.repetition = opts
+}
+
+// Enforce monotonic, non-overlapping segment times, or disable the sanitizer
+func (context *context) SetTimestampSanitizer(v bool) {
+	context.paramsMu.Lock()
+	defer context.paramsMu.Unlock()
+	context.sanitize = v
+}
+
+// Suppress blank outputs at the beginning of the sampling
+func (context *context) SetSuppressBlank(v bool) {
+	context.update(func(p *whisper.Params) { p.SetSuppressBlank(v) })
+}
+
+// Suppress non-speech tokens (punctuation, symbols and bracketed annotations)
+func (context *context) SetSuppressNonSpeechTokens(v bool) {
+	context.update(func(p *whisper.Params) { p.SetSuppressNonSpeechTokens(v) })
+}
+
+// Set a regular expression matching tokens to suppress, for example
+// `^\s*\[.*\]$` to suppress bracketed sound descriptions
+func (context *context) SetSuppressRegex(regex string) {
+	context.update(func(p *whisper.Params) { p.SetSuppressRegex(regex) })
 }
 
 // ResetTimings resets the mode timings. Should be called before processing
 func (context *context) ResetTimings() {
-	context.model.ctx.Whisper_reset_timings()
+	if ctx := context.rlock(); ctx != nil {
+		defer context.model.runlock()
+		context.model.statesMu.Lock()
+		defer context.model.statesMu.Unlock()
+		if context.closed.Load() {
+			return
+		}
+		ctx.Whisper_reset_timings_with_state(context.state)
+		context.audio.Store(0)
+	}
 }
 
 // PrintTimings prints the model timings to stdout.
 func (context *context) PrintTimings() {
-	context.model.ctx.Whisper_print_timings()
+	if ctx := context.rlock(); ctx != nil {
+		defer context.model.runlock()
+		ctx.Whisper_print_timings()
+	}
 }
 
 // SystemInfo returns the system information
 func (context *context) SystemInfo() string {
+	params := context.snapshot()
 	return fmt.Sprintf("system_info: n_threads = %d / %d | %s\n",
-		context.params.Threads(),
+		params.Threads(),
 		runtime.NumCPU(),
 		whisper.Whisper_print_system_info(),
 	)
@@ -216,13 +520,55 @@ func (context *context) SystemInfo() string {
 // Make sure to call whisper_pcm_to_mel() or whisper_set_mel() first.
 // Returns the probabilities of all languages.
 func (context *context) WhisperLangAutoDetect(offset_ms int, n_threads int) ([]float32, error) {
-	langProbs, err := context.model.ctx.Whisper_lang_auto_detect(offset_ms, n_threads)
+	ctx := context.rlock()
+	if ctx == nil {
+		return nil, ErrInternalAppError
+	}
+	defer context.model.runlock()
+	if err := context.acquire(context.label); err != nil {
+		return nil, err
+	}
+	defer context.gate.release()
+	context.model.yield.enter()
+	defer context.model.yield.exit()
+	var langProbs []float32
+	var err error
+	if context.stateful {
+		langProbs, err = ctx.Whisper_lang_auto_detect_with_state(context.state, offset_ms, n_threads)
+	} else {
+		langProbs, err = ctx.Whisper_lang_auto_detect(offset_ms, n_threads)
+	}
 	if err != nil {
 		return nil, err
 	}
 	return langProbs, nil
 }
 
+// Detect the language from the mel data of the last call to Process, and
+// return the n most likely languages, most likely first
+func (context *context) DetectLanguageTop(n int) ([]LanguageProb, error) {
+	params := context.snapshot()
+	probs, err := context.WhisperLangAutoDetect(0, params.Threads())
+	if err != nil {
+		return nil, err
+	}
+	result := make([]LanguageProb, len(probs))
+	for id, p := range probs {
+		result[id] = LanguageProb{
+			Code: whisper.Whisper_lang_str(id),
+			Name: whisper.Whisper_lang_str_full(id),
+			P:    p,
+		}
+	}
+	slices.SortStableFunc(result, func(a, b LanguageProb) int {
+		return cmp.Compare(b.P, a.P)
+	})
+	if n > 0 && n < len(result) {
+		result = result[:n]
+	}
+	return result, nil
+}
+
 // Process new sample data and return any errors
 func (context *context) Process(
 	data []float32,
@@ -230,86 +576,328 @@ func (context *context) Process(
 	callNewSegment SegmentCallback,
 	callProgress ProgressCallback,
 ) error {
-	if context.model.ctx == nil {
+	ctx := context.rlock()
+	if ctx == nil {
 		return ErrInternalAppError
 	}
-	// If the callback is defined then we force on single_segment mode
+	defer context.model.runlock()
+	if err := context.acquire(context.label); err != nil {
+		return err
+	}
+	defer context.gate.release()
+
+	// Take a copy of the parameters, so that setters called during
+	// processing take effect on the next call. If the callback is defined
+	// then we force on single_segment mode for this call.
+	params := context.clone()
+	defer params.Free()
 	if callNewSegment != nil {
-		context.params.SetSingleSegment(true)
+		params.SetSingleSegment(true)
+	}
+	if params.Translate() && !context.model.meta.Load().multilingual {
+		return ErrTranslateUnsupported
 	}
 
-	// We don't do parallel processing at the moment
-	processors := 0
-	if processors > 1 {
-		if err := context.model.ctx.Whisper_full_parallel(context.params, data, processors, callEncoderBegin,
-			func(new int) {
-				if callNewSegment != nil {
-					num_segments := context.model.ctx.Whisper_full_n_segments()
-					s0 := num_segments - new
-					for i := s0; i < num_segments; i++ {
-						callNewSegment(toSegment(context.model.ctx, i))
-					}
+	// Reset statistics, and record whether the encoder begin callback aborts
+	context.stats = ProcessStats{}
+	start := time.Now()
+	aborted := false
+	if callEncoderBegin != nil {
+		fn := callEncoderBegin
+		callEncoderBegin = func() bool {
+			if !fn() {
+				aborted = true
+				return false
+			}
+			return true
+		}
+	}
+
+	// Skip ahead when the seek callback asks to, which calls the encoder
+	// begin callback again for the window skipped to
+	r := context.results(ctx)
+	context.paramsMu.Lock()
+	seek := context.seek
+	context.paramsMu.Unlock()
+	var skipped time.Duration
+	var skipTo int64
+	if seek != nil {
+		fn := callEncoderBegin
+		callEncoderBegin = func() bool {
+			if fn != nil && !fn() {
+				return false
+			}
+			window := time.Duration(r.Whisper_full_get_seek()) * time.Millisecond * 10
+			skipTo = 0
+			if next := seek(window); next > window {
+				skipTo = int64(next / (time.Millisecond * 10))
+				r.Whisper_full_set_seek(skipTo)
+				skipped += min(next, samplesToDuration(len(data))) - window
+			}
+			return true
+		}
+	}
+
+	// Skip past a repetition loop found in the last window, by at least one
+	// frame so that its text context is dropped, unless the seek callback
+	// already skips further
+	context.paramsMu.Lock()
+	repetition := context.repetition
+	context.paramsMu.Unlock()
+	context.repeats = &repetitions{dropped: make(map[int][]bool)}
+	reseek, reseeks := false, 0
+	if repetition != nil {
+		fn := callEncoderBegin
+		callEncoderBegin = func() bool {
+			if fn != nil && !fn() {
+				return false
+			}
+			if reseek {
+				if next := max(int64(context.repeats.end/(time.Millisecond*10)), r.Whisper_full_get_seek()+1); next > skipTo {
+					r.Whisper_full_set_seek(next)
 				}
-			}); err != nil {
-			return err
+				reseek = false
+				reseeks++
+			}
+			return true
+		}
+	}
+
+	// Repair the times of new segments when the sanitizer is enabled
+	context.paramsMu.Lock()
+	sanitize := context.sanitize
+	context.paramsMu.Unlock()
+	context.repaired = nil
+	if sanitize {
+		context.repaired = &timestampRepairs{times: make(map[int][2]time.Duration)}
+	}
+
+	// Wait while the device is yielded, and pause before each window
+	context.model.yield.enter()
+	defer context.model.yield.exit()
+	callEncoderBegin = context.model.yield.wrap(callEncoderBegin)
+
+	// Wait for a turn of the scheduler before each window
+	context.paramsMu.Lock()
+	scheduler, priority := context.scheduler, context.priority
+	context.paramsMu.Unlock()
+	if scheduler != nil {
+		var done func()
+		callEncoderBegin, done = scheduler.wrap(priority, &context.model.yield, callEncoderBegin)
+		defer done()
+	}
+
+	// Number segments after those of the last call
+	context.seq += uint64(context.nseq)
+	context.nseq = 0
+	context.windows = context.windows[:0]
+
+	// Record the window of new segments, and pass them and progress to
+	// the callbacks
+	newSegment := func(new int) {
+		num_segments := r.Whisper_full_n_segments()
+		window := time.Duration(r.Whisper_full_get_seek()) * time.Millisecond * 10
+		for len(context.windows) < num_segments {
+			context.windows = append(context.windows, window)
 		}
-	} else if err := context.model.ctx.Whisper_full(context.params, data, callEncoderBegin,
-		func(new int) {
-			if callNewSegment != nil {
-				num_segments := context.model.ctx.Whisper_full_n_segments()
-				s0 := num_segments - new
-				for i := s0; i < num_segments; i++ {
-					callNewSegment(toSegment(context.model.ctx, i))
+		context.nseq = num_segments
+		s0 := num_segments - new
+		if repetition != nil && new > 0 {
+			segments := make([]Segment, 0, new)
+			for i := s0; i < num_segments; i++ {
+				segments = append(segments, toSegment(r, i))
+			}
+			if found := findRepetitions(segments, s0, *repetition); found.loops > 0 {
+				for i, dropped := range found.dropped {
+					context.repeats.dropped[i] = dropped
 				}
+				context.repeats.loops += found.loops
+				context.repeats.tokens += found.tokens
+				context.repeats.end = found.end
+				reseek = true
 			}
-		}, func(progress int) {
-			if callProgress != nil {
-				callProgress(progress)
+		}
+		if context.repaired != nil {
+			for i := s0; i < num_segments; i++ {
+				if segment, ok := context.collapse(r, i); ok {
+					context.repaired.add(segment, i)
+				}
 			}
-		}); err != nil {
-		return err
+		}
+		if callNewSegment != nil {
+			for i := s0; i < num_segments; i++ {
+				if segment, ok := context.toSegment(r, i); ok {
+					callNewSegment(segment)
+				}
+			}
+		}
+	}
+	progress := func(progress int) {
+		if callProgress != nil {
+			callProgress(progress)
+		}
+	}
+
+	// We don't do parallel processing at the moment
+	processors := 0
+	if processors > 1 && !context.stateful {
+		if err := ctx.Whisper_full_parallel(params, data, processors, callEncoderBegin, newSegment); err != nil {
+			return context.processError(ctx, err)
+		}
+	} else if context.stateful {
+		if err := ctx.Whisper_full_with_state(context.state, params, data, callEncoderBegin, newSegment, progress); err != nil {
+			return context.processError(ctx, err)
+		}
+	} else if err := ctx.Whisper_full(params, data, callEncoderBegin, newSegment, progress); err != nil {
+		return context.processError(ctx, err)
 	}
 
 	// Reset n so that more Segments can be available within NextSegment call
 	context.n = 0
+	context.nseq = r.Whisper_full_n_segments()
+
+	// Update statistics
+	context.stats = newProcessStats(r, params, context.model.meta.Load().eot, len(data), aborted, time.Since(start))
+	context.stats.Skipped = skipped
+	context.audio.Add(int64(context.stats.Processed))
+	context.stats.Repetitions, context.stats.RepeatedTokens, context.stats.Reseeks = context.repeats.loops, context.repeats.tokens, reseeks
+	if context.repaired != nil {
+		context.stats.TimestampRepairs = context.repaired.repairs
+	}
+	if !context.warmup {
+		context.model.coldStart.process(context.stats.Wall)
+	}
 
 	// Return success
 	return nil
 }
 
+// Check the parameters of the context against the model, returning an
+// error which wraps ErrInvalidParams for each invalid setting
+func (context *context) Validate() error {
+	ctx := context.rlock()
+	if ctx == nil {
+		return ErrInternalAppError
+	}
+	defer context.model.runlock()
+	params := context.snapshot()
+	return params.Validate(ctx)
+}
+
+// Return a handle to the native whisper context of the model, and the state
+// of a stateful context. The context is held until the handle is released,
+// so Process returns a *BusyError meanwhile.
+func (context *context) UnsafeRaw() (*RawHandle, error) {
+	ctx := context.rlock()
+	if ctx == nil {
+		return nil, ErrInternalAppError
+	}
+	defer context.model.runlock()
+	if err := context.acquire("raw"); err != nil {
+		return nil, err
+	}
+	if !context.model.acquireRaw() {
+		context.gate.release()
+		return nil, ErrInternalAppError
+	}
+	return newRawHandle(ctx, context.state, func() {
+		context.gate.release()
+		context.model.releaseRaw()
+	}), nil
+}
+
+// Return performance statistics for the last call to Process
+func (context *context) Stats() ProcessStats {
+	return context.stats
+}
+
 // Return the next segment of tokens
 func (context *context) NextSegment() (Segment, error) {
-	if context.model.ctx == nil {
+	ctx := context.rlock()
+	if ctx == nil {
 		return Segment{}, ErrInternalAppError
 	}
-	if context.n >= context.model.ctx.Whisper_full_n_segments() {
-		return Segment{}, io.EOF
+	defer context.model.runlock()
+	if err := context.acquire(context.label); err != nil {
+		return Segment{}, err
 	}
+	defer context.gate.release()
+	r := context.results(ctx)
 
-	// Populate result
-	result := toSegment(context.model.ctx, context.n)
+	// Populate result, skipping segments which only contained repeats
+	for ; context.n < r.Whisper_full_n_segments(); context.n++ {
+		if result, ok := context.toSegment(r, context.n); ok {
+			context.n++
+			return result, nil
+		}
+	}
+	return Segment{}, io.EOF
+}
 
-	// Increment the cursor
-	context.n++
+// Append the text of a segment to dst, with leading and trailing whitespace
+// removed, and return the extended buffer
+func (context *context) AppendSegmentText(dst []byte, segment int) ([]byte, error) {
+	ctx := context.rlock()
+	if ctx == nil {
+		return dst, ErrInternalAppError
+	}
+	defer context.model.runlock()
+	if err := context.acquire(context.label); err != nil {
+		return dst, err
+	}
+	defer context.gate.release()
+	r := context.results(ctx)
+	if segment < 0 || segment >= r.Whisper_full_n_segments() {
+		return dst, io.EOF
+	}
 
-	// Return success
-	return result, nil
+	// Trim the appended text in place
+	n := len(dst)
+	dst = r.Whisper_full_append_segment_text(dst, segment)
+	text := bytes.TrimSpace(dst[n:])
+	return append(dst[:n], text...), nil
+}
+
+// Append the text of a token within a segment to dst and return the
+// extended buffer
+func (context *context) AppendTokenText(dst []byte, segment, token int) ([]byte, error) {
+	ctx := context.rlock()
+	if ctx == nil {
+		return dst, ErrInternalAppError
+	}
+	defer context.model.runlock()
+	if err := context.acquire(context.label); err != nil {
+		return dst, err
+	}
+	defer context.gate.release()
+	r := context.results(ctx)
+	if segment < 0 || segment >= r.Whisper_full_n_segments() {
+		return dst, io.EOF
+	}
+	if token < 0 || token >= r.Whisper_full_n_tokens(segment) {
+		return dst, io.EOF
+	}
+	return r.Whisper_full_append_token_text(dst, segment, token), nil
 }
 
 // Test for text tokens
 func (context *context) IsText(t Token) bool {
-	switch {
-	case context.IsBEG(t):
+	meta := context.model.metadata()
+	if meta == nil {
+		return false
+	}
+	switch id := whisper.Token(t.Id); {
+	case id == meta.beg:
 		return false
-	case context.IsSOT(t):
+	case id == meta.sot:
 		return false
-	case whisper.Token(t.Id) >= context.model.ctx.Whisper_token_eot():
+	case id >= meta.eot:
 		return false
-	case context.IsPREV(t):
+	case id == meta.prev:
 		return false
-	case context.IsSOLM(t):
+	case id == meta.solm:
 		return false
-	case context.IsNOT(t):
+	case id == meta.not:
 		return false
 	default:
 		return true
@@ -318,38 +906,40 @@ func (context *context) IsText(t Token) bool {
 
 // Test for "begin" token
 func (context *context) IsBEG(t Token) bool {
-	return whisper.Token(t.Id) == context.model.ctx.Whisper_token_beg()
+	return context.model.isToken(t, func(meta *modelMeta) whisper.Token { return meta.beg })
 }
 
 // Test for "start of transcription" token
 func (context *context) IsSOT(t Token) bool {
-	return whisper.Token(t.Id) == context.model.ctx.Whisper_token_sot()
+	return context.model.isToken(t, func(meta *modelMeta) whisper.Token { return meta.sot })
 }
 
 // Test for "end of transcription" token
 func (context *context) IsEOT(t Token) bool {
-	return whisper.Token(t.Id) == context.model.ctx.Whisper_token_eot()
+	return context.model.isToken(t, func(meta *modelMeta) whisper.Token { return meta.eot })
 }
 
 // Test for "start of prev" token
 func (context *context) IsPREV(t Token) bool {
-	return whisper.Token(t.Id) == context.model.ctx.Whisper_token_prev()
+	return context.model.isToken(t, func(meta *modelMeta) whisper.Token { return meta.prev })
 }
 
 // Test for "start of lm" token
 func (context *context) IsSOLM(t Token) bool {
-	return whisper.Token(t.Id) == context.model.ctx.Whisper_token_solm()
+	return context.model.isToken(t, func(meta *modelMeta) whisper.Token { return meta.solm })
 }
 
 // Test for "No timestamps" token
 func (context *context) IsNOT(t Token) bool {
-	return whisper.Token(t.Id) == context.model.ctx.Whisper_token_not()
+	return context.model.isToken(t, func(meta *modelMeta) whisper.Token { return meta.not })
 }
 
 // Test for token associated with a specific language
 func (context *context) IsLANG(t Token, lang string) bool {
-	if id := context.model.ctx.Whisper_lang_id(lang); id >= 0 {
-		return whisper.Token(t.Id) == context.model.ctx.Whisper_token_lang(id)
+	if meta := context.model.metadata(); meta == nil {
+		return false
+	} else if token, exists := meta.lang[normalizeLanguage(lang)]; exists {
+		return whisper.Token(t.Id) == token
 	} else {
 		return false
 	}
@@ -358,17 +948,165 @@ func (context *context) IsLANG(t Token, lang string) bool {
 ///////////////////////////////////////////////////////////////////////////////
 // PRIVATE METHODS
 
-func toSegment(ctx *whisper.Context, n int) Segment {
-	return Segment{
+// Lock the native context of the model for reading as model.rlock does, or
+// return nil if the context has been closed
+func (context *context) rlock() *whisper.Context {
+	if context.closed.Load() {
+		return nil
+	}
+	return context.model.rlock()
+}
+
+// Acquire the gate of the context, failing with ErrInternalAppError once the
+// context has been closed
+func (context *context) acquire(label string) error {
+	if err := context.gate.acquire(label); err != nil {
+		return err
+	}
+	if context.closed.Load() {
+		context.gate.release()
+		return ErrInternalAppError
+	}
+	return nil
+}
+
+// Return the results of the last call to Process
+func (context *context) results(ctx *whisper.Context) results {
+	if context.stateful {
+		return stateResults{State: context.state, ctx: ctx}
+	}
+	return ctx
+}
+
+// Modify the parameters
+func (context *context) update(fn func(*whisper.Params)) {
+	context.paramsMu.Lock()
+	defer context.paramsMu.Unlock()
+	fn(&context.params)
+}
+
+// Merge the logit bias for hotwords, the explicit logit bias and the bias
+// for suppressed tokens, each taking precedence over the one before. Must be
+// called with paramsMu held.
+func (context *context) applyBias() {
+	bias := make(map[whisper.Token]float32, len(context.bias)+len(context.hotwords)+len(context.suppress))
+	for _, m := range []map[whisper.Token]float32{context.hotwords, context.bias, context.suppress} {
+		for id, v := range m {
+			bias[id] = v
+		}
+	}
+	context.params.SetLogitBias(bias)
+}
+
+// Return a copy of the parameters. Setters replace rather than modify any
+// C memory the parameters refer to, so the copy is not affected by them,
+// except that a logits filter which is replaced is freed, so the copy is only
+// used to read the settings.
+func (context *context) snapshot() whisper.Params {
+	context.paramsMu.Lock()
+	defer context.paramsMu.Unlock()
+	return context.params
+}
+
+// Return a copy of the parameters with its own C memory and reference to the
+// logits filter, which must be freed
+func (context *context) clone() whisper.Params {
+	context.paramsMu.Lock()
+	defer context.paramsMu.Unlock()
+	return context.params.Clone()
+}
+
+func newProcessStats(ctx results, params whisper.Params, eot whisper.Token, samples int, aborted bool, wall time.Duration) ProcessStats {
+	stats := ProcessStats{
+		Audio:    time.Duration(samples) * time.Second / SampleRate,
+		Aborted:  aborted,
+		Wall:     wall,
+		Segments: ctx.Whisper_full_n_segments(),
+	}
+	for i := 0; i < stats.Segments; i++ {
+		for j := 0; j < ctx.Whisper_full_n_tokens(i); j++ {
+			if ctx.Whisper_full_get_token_id(i, j) < eot {
+				stats.Tokens++
+			}
+		}
+	}
+
+	// Determine the span of the input which was processed. When aborted, the
+	// end of the last segment is as far as the decoder got.
+	begin := min(time.Duration(params.Offset())*time.Millisecond, stats.Audio)
+	end := stats.Audio
+	if duration := params.Duration(); duration > 0 {
+		end = min(begin+time.Duration(duration)*time.Millisecond, end)
+	}
+	if aborted {
+		if stats.Segments > 0 {
+			end = min(time.Duration(ctx.Whisper_full_get_segment_t1(stats.Segments-1))*time.Millisecond*10, end)
+		} else {
+			end = begin
+		}
+	}
+	stats.Offset, stats.Processed, stats.Speech = begin, max(end-begin, 0), max(end-begin, 0)
+
+	// With VAD, only the speech within the span was passed to the model
+	if params.VAD() {
+		stats.Speech = 0
+		for i := 0; i < ctx.Whisper_full_n_vad_segments(); i++ {
+			t0 := max(time.Duration(ctx.Whisper_full_get_vad_segment_t0(i))*time.Millisecond*10, begin)
+			t1 := min(time.Duration(ctx.Whisper_full_get_vad_segment_t1(i))*time.Millisecond*10, end)
+			stats.Speech += max(t1-t0, 0)
+		}
+	}
+	if stats.Processed > 0 {
+		stats.RTF = wall.Seconds() / stats.Processed.Seconds()
+	}
+	if wall > 0 {
+		stats.TokensPerSecond = float64(stats.Tokens) / wall.Seconds()
+	}
+	return stats
+}
+
+// Return a segment of the last call to Process, with its sequence number and
+// the window which produced it, with the repeats found by the repetition
+// detector removed and the times repaired by the timestamp sanitizer, or
+// false if only repeats remain
+func (context *context) toSegment(r results, n int) (Segment, bool) {
+	segment, ok := context.collapse(r, n)
+	if ok && context.repaired != nil {
+		segment = context.repaired.apply(segment, n)
+	}
+	return segment, ok
+}
+
+// Return a segment of the last call to Process, with its sequence number and
+// the window which produced it, and with the repeats found by the repetition
+// detector removed, or false if only repeats remain
+func (context *context) collapse(r results, n int) (Segment, bool) {
+	segment := toSegment(r, n)
+	segment.Seq = context.seq + uint64(n)
+	if n < len(context.windows) {
+		segment.Window = context.windows[n]
+	}
+	if context.repeats != nil {
+		return context.repeats.collapse(segment, n)
+	}
+	return segment, true
+}
+
+func toSegment(ctx results, n int) Segment {
+	segment := Segment{
 		Num:    n,
 		Text:   strings.TrimSpace(ctx.Whisper_full_get_segment_text(n)),
 		Start:  time.Duration(ctx.Whisper_full_get_segment_t0(n)) * time.Millisecond * 10,
 		End:    time.Duration(ctx.Whisper_full_get_segment_t1(n)) * time.Millisecond * 10,
 		Tokens: toTokens(ctx, n),
 	}
+	segment.Events = SegmentEvents(segment)
+	segment.ProcessLanguage = whisper.Whisper_lang_str(ctx.Whisper_full_lang_id())
+	segment.Script = DetectScript(segment.Text)
+	return segment
 }
 
-func toTokens(ctx *whisper.Context, n int) []Token {
+func toTokens(ctx results, n int) []Token {
 	result := make([]Token, ctx.Whisper_full_n_tokens(n))
 	for i := 0; i < len(result); i++ {
 		data := ctx.Whisper_full_get_token_data(n, i)
@@ -379,6 +1117,12 @@ func toTokens(ctx *whisper.Context, n int) []Token {
 			P:     ctx.Whisper_full_get_token_p(n, i),
 			Start: time.Duration(data.T0()) * time.Millisecond * 10,
 			End:   time.Duration(data.T1()) * time.Millisecond * 10,
+			Pt:    data.Pt(),
+			PtSum: data.PtSum(),
+			DTW:   -1,
+		}
+		if t := data.TDTW(); t >= 0 {
+			result[i].DTW = time.Duration(t) * time.Millisecond * 10
 		}
 	}
 	return result
@@ -1,9 +1,16 @@
 package whisper_test
 
 import (
+	"fmt"
+	"io"
+	"math"
 	"os"
+	"runtime"
+	"slices"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/ggerganov/whisper.cpp/bindings/go/pkg/whisper"
 	"github.com/go-audio/wav"
@@ -140,6 +147,108 @@ func TestProcessMaxTokensPerSegment(t *testing.T) {
 	assert.Contains(text.String(), "country")
 }
 
+func TestProcessMaxSegmentDuration(t *testing.T) {
+	assert := assert.New(t)
+
+	if _, err := os.Stat(ModelPath); os.IsNotExist(err) {
+		t.Skip("Skipping test, model not found:", ModelPath)
+	}
+
+	fh, err := os.Open(SamplePath)
+	assert.NoError(err)
+	defer fh.Close()
+
+	// Decode the WAV file - load the full buffer
+	dec := wav.NewDecoder(fh)
+	buf, err := dec.FullPCMBuffer()
+	assert.NoError(err)
+	data := buf.AsFloat32Buffer().Data
+
+	model, err := fullModel(whisper.New(ModelPath))
+	assert.NoError(err)
+	assert.NotNil(model)
+	defer model.Close()
+
+	context, err := fullContext(model.NewContext())
+	assert.NoError(err)
+	context.SetMaxSegmentDuration(2 * time.Second)
+	assert.NoError(context.Validate())
+	assert.NoError(context.Process(data, nil, nil, nil))
+
+	// Segments with more than one word are within the limit
+	for {
+		segment, err := context.NextSegment()
+		if err != nil {
+			break
+		}
+		if len(strings.Fields(segment.Text)) > 1 {
+			assert.LessOrEqual(segment.End-segment.Start, 2*time.Second, segment.Text)
+		}
+	}
+}
+
+func TestTimings(t *testing.T) {
+	assert := assert.New(t)
+
+	if _, err := os.Stat(ModelPath); os.IsNotExist(err) {
+		t.Skip("Skipping test, model not found:", ModelPath)
+	}
+
+	fh, err := os.Open(SamplePath)
+	assert.NoError(err)
+	defer fh.Close()
+
+	// Decode the WAV file - load the full buffer
+	dec := wav.NewDecoder(fh)
+	buf, err := dec.FullPCMBuffer()
+	assert.NoError(err)
+	data := buf.AsFloat32Buffer().Data
+
+	model, err := fullModel(whisper.New(ModelPath))
+	assert.NoError(err)
+	assert.NotNil(model)
+	defer model.Close()
+
+	context, err := fullContext(model.NewContext())
+	assert.NoError(err)
+	context.ResetTimings()
+	assert.NoError(context.Process(data, nil, nil, nil))
+
+	// The timings cover the audio processed since they were reset
+	timings := context.Timings()
+	assert.Positive(timings.Load)
+	assert.Positive(timings.Encode.Runs)
+	assert.Positive(timings.Encode.PerRun())
+	assert.Equal(context.Stats().Processed, timings.Audio)
+	assert.Positive(timings.RTF)
+	assert.GreaterOrEqual(timings.Total, timings.Encode.Time)
+
+	// The timings are formatted as whisper_print_timings
+	lines := strings.Split(strings.TrimSuffix(timings.String(), "\n"), "\n")
+	if assert.Len(lines, 9) {
+		assert.Regexp(`^whisper_print_timings:     load time = +\d+\.\d\d ms$`, lines[0])
+		assert.Regexp(`^whisper_print_timings:     fallbacks = +\d+ p / +\d+ h$`, lines[1])
+		assert.Regexp(`^whisper_print_timings:   encode time = +\d+\.\d\d ms / +\d+ runs \( +\d+\.\d\d ms per run\)$`, lines[4])
+		assert.Regexp(`^whisper_print_timings:    total time = +\d+\.\d\d ms$`, lines[8])
+	}
+
+	// The timings can be written to a Go writer in the same format
+	var w strings.Builder
+	assert.NoError(context.WriteTimings(&w))
+	written := strings.Split(strings.TrimSpace(w.String()), "\n")
+	if assert.Len(written, 9) {
+		assert.Equal(lines[0], written[0])
+		assert.Equal(lines[4], written[4])
+	}
+
+	// Resetting clears the runs and the audio
+	context.ResetTimings()
+	timings = context.Timings()
+	assert.Zero(timings.Encode)
+	assert.Zero(timings.Audio)
+	assert.Zero(timings.RTF)
+}
+
 func TestDetectedLanguage(t *testing.T) {
 	assert := assert.New(t)
 
@@ -170,3 +279,709 @@ func TestDetectedLanguage(t *testing.T) {
 	actualLanguage := context.DetectedLanguage()
 	assert.Equal(expectedLanguage, actualLanguage)
 }
+
+func TestProcessStats(t *testing.T) {
+	assert := assert.New(t)
+
+	fh, err := os.Open(SamplePath)
+	assert.NoError(err)
+	defer fh.Close()
+
+	// Decode the WAV file - load the full buffer
+	dec := wav.NewDecoder(fh)
+	buf, err := dec.FullPCMBuffer()
+	assert.NoError(err)
+	data := buf.AsFloat32Buffer().Data
+
+	model, err := fullModel(whisper.New(ModelPath))
+	assert.NoError(err)
+	assert.NotNil(model)
+	defer model.Close()
+
+	context, err := fullContext(model.NewContext())
+	assert.NoError(err)
+
+	err = context.Process(data, nil, nil, nil)
+	assert.NoError(err)
+
+	stats := context.Stats()
+	assert.InDelta(11.0, stats.Audio.Seconds(), 0.1)
+	assert.Equal(stats.Audio, stats.Processed)
+	assert.Equal(stats.Audio, stats.Speech)
+	assert.False(stats.Aborted)
+	assert.Greater(stats.Wall, time.Duration(0))
+	assert.Greater(stats.RTF, 0.0)
+	assert.Greater(stats.Tokens, 0)
+	assert.GreaterOrEqual(stats.Segments, 1)
+
+	// Only text tokens are counted
+	tokens := 0
+	for {
+		segment, err := context.NextSegment()
+		if err != nil {
+			break
+		}
+		for _, token := range segment.Tokens {
+			if context.IsText(token) {
+				tokens++
+			}
+		}
+	}
+	assert.Equal(tokens, stats.Tokens)
+}
+
+func TestProcessBusy(t *testing.T) {
+	assert := assert.New(t)
+
+	fh, err := os.Open(SamplePath)
+	assert.NoError(err)
+	defer fh.Close()
+
+	// Decode the WAV file - load the full buffer
+	dec := wav.NewDecoder(fh)
+	buf, err := dec.FullPCMBuffer()
+	assert.NoError(err)
+	data := buf.AsFloat32Buffer().Data
+
+	model, err := fullModel(whisper.New(ModelPath))
+	assert.NoError(err)
+	assert.NotNil(model)
+	defer model.Close()
+
+	first, err := fullContext(model.NewContext())
+	assert.NoError(err)
+	first.SetLabel("first")
+	second, err := fullContext(model.NewContext())
+	assert.NoError(err)
+
+	// Processing in the second context fails while the first holds the model
+	var busy *whisper.BusyError
+	err = first.Process(data, func() bool {
+		err := second.Process(data, nil, nil, nil)
+		assert.ErrorIs(err, whisper.ErrStatelessBusy)
+		if assert.ErrorAs(err, &busy) {
+			assert.Equal("first", busy.Holder)
+		}
+		return false
+	}, nil, nil)
+	assert.NoError(err)
+
+	stats := model.GateStats()
+	assert.Equal(uint64(1), stats.Acquired)
+	assert.Equal(uint64(1), stats.Rejected)
+	assert.Empty(stats.Holder)
+}
+
+func TestQueriesDuringProcess(t *testing.T) {
+	assert := assert.New(t)
+
+	fh, err := os.Open(SamplePath)
+	assert.NoError(err)
+	defer fh.Close()
+
+	// Decode the WAV file - load the full buffer
+	dec := wav.NewDecoder(fh)
+	buf, err := dec.FullPCMBuffer()
+	assert.NoError(err)
+	data := buf.AsFloat32Buffer().Data
+
+	model, err := whisper.New(ModelPath)
+	assert.NoError(err)
+	assert.NotNil(model)
+	defer model.Close()
+
+	first, err := model.NewContext()
+	assert.NoError(err)
+	second, err := model.NewContext()
+	assert.NoError(err)
+
+	// Read-only queries succeed while the first context holds the model,
+	// but reading results from the shared state does not
+	err = first.Process(data, func() bool {
+		assert.NotEmpty(model.Languages())
+		assert.False(second.IsMultilingual())
+		assert.True(second.IsText(whisper.Token{Id: 0}))
+		_, err := second.NextSegment()
+		assert.ErrorIs(err, whisper.ErrStatelessBusy)
+		return false
+	}, nil, nil)
+	assert.NoError(err)
+}
+
+func TestQueriesDuringClose(t *testing.T) {
+	assert := assert.New(t)
+
+	model, err := fullModel(whisper.New(ModelPath))
+	assert.NoError(err)
+	context, err := fullContext(model.NewContext())
+	assert.NoError(err)
+
+	// Callbacks can query the model while Close waits for processing to
+	// finish
+	closed := make(chan struct{})
+	done := make(chan error)
+	go func() {
+		done <- context.Process(loadSamples(t, SamplePath), func() bool {
+			go func() {
+				model.Close()
+				close(closed)
+			}()
+			time.Sleep(100 * time.Millisecond)
+			assert.True(context.IsText(whisper.Token{Id: 0}))
+			assert.False(model.IsMultilingual())
+			assert.Empty(model.Warnings())
+			return false
+		}, nil, nil)
+	}()
+	select {
+	case err := <-done:
+		assert.NoError(err)
+	case <-time.After(time.Minute):
+		t.Fatal("Process deadlocked with a pending Close")
+	}
+	<-closed
+	assert.Nil(model.Languages())
+}
+
+func TestAppendSegmentText(t *testing.T) {
+	assert := assert.New(t)
+
+	fh, err := os.Open(SamplePath)
+	assert.NoError(err)
+	defer fh.Close()
+
+	// Decode the WAV file - load the full buffer
+	dec := wav.NewDecoder(fh)
+	buf, err := dec.FullPCMBuffer()
+	assert.NoError(err)
+	data := buf.AsFloat32Buffer().Data
+
+	model, err := fullModel(whisper.New(ModelPath))
+	assert.NoError(err)
+	assert.NotNil(model)
+	defer model.Close()
+
+	context, err := fullContext(model.NewContext())
+	assert.NoError(err)
+	assert.NoError(context.Process(data, nil, nil, nil))
+
+	// The appended text matches the segment text
+	segment, err := context.NextSegment()
+	assert.NoError(err)
+	text, err := context.AppendSegmentText([]byte("> "), segment.Num)
+	assert.NoError(err)
+	assert.Equal("> "+segment.Text, string(text))
+
+	text, err = context.AppendTokenText(nil, segment.Num, 0)
+	assert.NoError(err)
+	assert.Equal(segment.Tokens[0].Text, string(text))
+
+	_, err = context.AppendSegmentText(nil, -1)
+	assert.ErrorIs(err, io.EOF)
+}
+
+func TestProcessStatsOffset(t *testing.T) {
+	assert := assert.New(t)
+
+	fh, err := os.Open(SamplePath)
+	assert.NoError(err)
+	defer fh.Close()
+
+	// Decode the WAV file - load the full buffer
+	dec := wav.NewDecoder(fh)
+	buf, err := dec.FullPCMBuffer()
+	assert.NoError(err)
+	data := buf.AsFloat32Buffer().Data
+
+	model, err := fullModel(whisper.New(ModelPath))
+	assert.NoError(err)
+	assert.NotNil(model)
+	defer model.Close()
+
+	context, err := fullContext(model.NewContext())
+	assert.NoError(err)
+
+	context.SetOffset(2 * time.Second)
+	context.SetDuration(5 * time.Second)
+	assert.NoError(context.Process(data, nil, nil, nil))
+	stats := context.Stats()
+	assert.Equal(2*time.Second, stats.Offset)
+	assert.Equal(5*time.Second, stats.Processed)
+
+	// Aborting before the first window processes nothing
+	assert.NoError(context.Process(data, func() bool { return false }, nil, nil))
+	stats = context.Stats()
+	assert.True(stats.Aborted)
+	assert.Equal(time.Duration(0), stats.Processed)
+}
+
+func TestValidate(t *testing.T) {
+	assert := assert.New(t)
+
+	model, err := fullModel(whisper.New(ModelPath))
+	assert.NoError(err)
+	defer model.Close()
+
+	context, err := fullContext(model.NewContext())
+	assert.NoError(err)
+	assert.NoError(context.Validate())
+
+	// Beam size is ignored by the default greedy strategy
+	context.SetBeamSize(5)
+	assert.ErrorIs(context.Validate(), whisper.ErrInvalidParams)
+	context.SetBeamSize(-1)
+
+	// VAD requires a model path
+	context.SetVAD(true)
+	assert.ErrorIs(context.Validate(), whisper.ErrInvalidParams)
+	context.SetVADModelPath(VADModelPath)
+	assert.NoError(context.Validate())
+
+	// Audio context cannot exceed that of the model
+	context.SetAudioCtx(100000)
+	assert.ErrorIs(context.Validate(), whisper.ErrInvalidParams)
+}
+
+func TestNewContextWithOptions(t *testing.T) {
+	assert := assert.New(t)
+
+	model, err := fullModel(whisper.New(ModelPath))
+	assert.NoError(err)
+	defer model.Close()
+
+	context, err := fullContext(whisper.NewContextWithOptions(model,
+		whisper.WithThreads(2),
+		whisper.WithBeamSize(5),
+		whisper.WithVAD(VADModelPath),
+		whisper.WithLabel("options"),
+	))
+	assert.NoError(err)
+	assert.Equal("options", context.Label())
+	assert.NoError(context.Validate())
+
+	// The model is not multilingual
+	_, err = whisper.NewContextWithOptions(model, whisper.WithLanguage("de"))
+	assert.ErrorIs(err, whisper.ErrModelNotMultilingual)
+
+	_, err = whisper.NewContextWithOptions(model, whisper.WithBeamSize(0))
+	assert.ErrorIs(err, whisper.ErrInvalidParams)
+}
+
+func TestValidateOptions(t *testing.T) {
+	assert := assert.New(t)
+
+	model, err := whisper.New(ModelPath)
+	assert.NoError(err)
+	defer model.Close()
+
+	assert.NoError(whisper.ValidateOptions(model, whisper.WithBeamSize(5), whisper.WithVAD(VADModelPath)))
+
+	// Options which fail are reported as NewContextWithOptions reports them
+	assert.ErrorIs(whisper.ValidateOptions(model, whisper.WithLanguage("de")), whisper.ErrModelNotMultilingual)
+
+	// Settings which are only invalid in combination with the model
+	assert.ErrorIs(whisper.ValidateOptions(model, whisper.WithTranslate()), whisper.ErrTranslateUnsupported)
+	assert.ErrorIs(whisper.ValidateOptions(model, whisper.WithTemperature(0.5), whisper.WithVAD("")), whisper.ErrInvalidParams)
+
+	// The model is not usable once closed
+	assert.NoError(model.Close())
+	assert.ErrorIs(whisper.ValidateOptions(model), whisper.ErrInternalAppError)
+}
+
+func TestProcessParamsSnapshot(t *testing.T) {
+	assert := assert.New(t)
+
+	model, err := whisper.New(ModelPath)
+	assert.NoError(err)
+	defer model.Close()
+
+	context, err := model.NewContext()
+	assert.NoError(err)
+
+	// Setters called while processing do not block or affect the call
+	var once sync.Once
+	err = context.Process(make([]float32, whisper.SampleRate), func() bool {
+		once.Do(func() {
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				context.SetThreads(1)
+				context.SetInitialPrompt("changed")
+			}()
+			<-done
+		})
+		return true
+	}, nil, nil)
+	assert.NoError(err)
+	assert.Contains(context.SystemInfo(), "n_threads = 1 ")
+}
+
+func TestSetTemperatureSchedule(t *testing.T) {
+	assert := assert.New(t)
+
+	model, err := fullModel(whisper.New(ModelPath))
+	assert.NoError(err)
+	defer model.Close()
+
+	context, err := fullContext(model.NewContext())
+	assert.NoError(err)
+	assert.NoError(context.SetTemperatureSchedule([]float32{0, 0.2, 0.4, 0.8}))
+	assert.ErrorIs(context.SetTemperatureSchedule([]float32{0, -1}), whisper.ErrInvalidParams)
+
+	data := make([]float32, whisper.SampleRate)
+	assert.NoError(context.Process(data, nil, nil, nil))
+}
+
+func TestSetHotwords(t *testing.T) {
+	assert := assert.New(t)
+
+	model, err := fullModel(whisper.New(ModelPath))
+	assert.NoError(err)
+	defer model.Close()
+
+	context, err := fullContext(model.NewContext())
+	assert.NoError(err)
+	assert.NoError(context.SetLogitBias(map[int]float32{220: -1}))
+	assert.ErrorIs(context.SetLogitBias(map[int]float32{-1: 1}), whisper.ErrInvalidToken)
+	assert.NoError(context.SetHotwords([]string{"whisper.cpp", "Kubernetes", ""}, 2))
+	assert.ErrorIs(context.SetHotwords([]string{"Kubernetes"}, float32(math.NaN())), whisper.ErrInvalidParams)
+
+	data := make([]float32, whisper.SampleRate)
+	assert.NoError(context.Process(data, nil, nil, nil))
+	assert.NoError(context.SetHotwords(nil, 0))
+}
+
+func TestSetLogitsFilterCallback(t *testing.T) {
+	assert := assert.New(t)
+
+	model, err := fullModel(whisper.New(ModelPath))
+	assert.NoError(err)
+	defer model.Close()
+
+	context, err := fullContext(model.NewContext())
+	assert.NoError(err)
+	assert.NoError(context.SetLogitBias(map[int]float32{220: -1}))
+
+	// Only allow the end of text token to be sampled
+	calls := 0
+	context.SetLogitsFilterCallback(func(tokens []int, logits []float32) {
+		calls++
+		for i := range logits {
+			if i != 50256 {
+				logits[i] = float32(math.Inf(-1))
+			}
+		}
+	})
+	assert.NoError(context.Process(make([]float32, whisper.SampleRate), nil, nil, nil))
+	assert.NotZero(calls)
+
+	// Cleared callbacks are no longer called
+	calls = 0
+	context.SetLogitsFilterCallback(nil)
+	assert.NoError(context.Process(make([]float32, whisper.SampleRate), nil, nil, nil))
+	assert.Zero(calls)
+
+	// Closing a context which shares the state of the model releases its
+	// callback
+	collected := make(chan struct{})
+	func() {
+		calls := new(int)
+		runtime.SetFinalizer(calls, func(*int) { close(collected) })
+		context.SetLogitsFilterCallback(func(tokens []int, logits []float32) { *calls++ })
+	}()
+	assert.NoError(context.Close())
+	assert.ErrorIs(context.Process(make([]float32, whisper.SampleRate), nil, nil, nil), whisper.ErrInternalAppError)
+	for i := 0; i < 10; i++ {
+		runtime.GC()
+		select {
+		case <-collected:
+			return
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	t.Error("callback was not released")
+}
+
+func TestSetSuppressTokens(t *testing.T) {
+	assert := assert.New(t)
+
+	model, err := fullModel(whisper.New(ModelPath))
+	assert.NoError(err)
+	defer model.Close()
+
+	context, err := fullContext(model.NewContext())
+	assert.NoError(err)
+	assert.Nil(context.SuppressedTokens())
+
+	// Keep bracketed annotations
+	texts := slices.DeleteFunc(whisper.NonSpeechTokens(), func(text string) bool {
+		return text == "[" || text == "]"
+	})
+	assert.NoError(context.SetSuppressTokens(texts))
+	all := context.SuppressedTokens()
+	assert.NotEmpty(all)
+	assert.NoError(context.SetSuppressTokens(whisper.NonSpeechTokens()))
+	assert.Greater(len(context.SuppressedTokens()), len(all))
+	assert.NoError(context.Process(make([]float32, whisper.SampleRate), nil, nil, nil))
+
+	assert.NoError(context.SetSuppressTokens(nil))
+	assert.Nil(context.SuppressedTokens())
+}
+
+func TestMaxContext(t *testing.T) {
+	assert := assert.New(t)
+
+	model, err := fullModel(whisper.New(ModelPath))
+	assert.NoError(err)
+	defer model.Close()
+
+	// The model uses at most half its text context as the prompt
+	assert.Equal(224, model.MaxContext())
+	context, err := fullContext(model.NewContext())
+	assert.NoError(err)
+	assert.Equal(224, context.MaxContext())
+
+	// Values are clamped to the model
+	context.SetMaxContext(64)
+	assert.Equal(64, context.MaxContext())
+	context.SetMaxContext(100000)
+	assert.Equal(224, context.MaxContext())
+	context.SetMaxContext(-1)
+	assert.Equal(0, context.MaxContext())
+	assert.NoError(context.Validate())
+}
+
+func TestSetThreadsAuto(t *testing.T) {
+	assert := assert.New(t)
+
+	// The recommended count is within GOMAXPROCS, and fewer threads drive a GPU
+	cpu, gpu := whisper.RecommendedThreads(false), whisper.RecommendedThreads(true)
+	assert.GreaterOrEqual(cpu, 1)
+	assert.LessOrEqual(cpu, runtime.GOMAXPROCS(0))
+	assert.GreaterOrEqual(gpu, 1)
+	assert.LessOrEqual(gpu, cpu)
+
+	model, err := fullModel(whisper.New(ModelPath))
+	assert.NoError(err)
+	defer model.Close()
+	context, err := fullContext(model.NewContext())
+	assert.NoError(err)
+
+	context.SetThreads(1)
+	context.SetThreadsAuto()
+	info := context.SystemInfo()
+	assert.True(strings.Contains(info, fmt.Sprintf("n_threads = %d ", cpu)) || strings.Contains(info, fmt.Sprintf("n_threads = %d ", gpu)), info)
+}
+
+func TestLanguageTags(t *testing.T) {
+	assert := assert.New(t)
+
+	model, err := whisper.New(ModelPath)
+	assert.NoError(err)
+	defer model.Close()
+	context, err := model.NewContext()
+	assert.NoError(err)
+
+	// Language tokens follow the start of transcription token, in the order
+	// of whisper language ids, where Portuguese is 8
+	sot := whisper.Token{}
+	for ; !context.IsSOT(sot); sot.Id++ {
+	}
+	en, pt := whisper.Token{Id: sot.Id + 1}, whisper.Token{Id: sot.Id + 9}
+	for _, tag := range []string{"en", "en-US", "EN_gb", "English"} {
+		assert.True(context.IsLANG(en, tag), tag)
+	}
+	for _, tag := range []string{"pt", "pt-BR", "pt_PT", "Portuguese", " portuguese "} {
+		assert.True(context.IsLANG(pt, tag), tag)
+		assert.False(context.IsLANG(en, tag), tag)
+	}
+	assert.False(context.IsLANG(pt, "xx-BR"))
+
+	// The model is not multilingual, so tags are rejected after normalizing
+	assert.ErrorIs(context.SetLanguage("en-US"), whisper.ErrModelNotMultilingual)
+}
+
+func TestTranslateUnsupported(t *testing.T) {
+	assert := assert.New(t)
+
+	model, err := fullModel(whisper.New(ModelPath))
+	assert.NoError(err)
+	defer model.Close()
+	context, err := fullContext(model.NewContext())
+	assert.NoError(err)
+
+	// The model is English-only, so translation is rejected
+	context.SetTranslate(true)
+	assert.ErrorIs(context.Validate(), whisper.ErrTranslateUnsupported)
+	assert.ErrorIs(context.Validate(), whisper.ErrInvalidParams)
+	assert.ErrorIs(context.Process(make([]float32, whisper.SampleRate), nil, nil, nil), whisper.ErrTranslateUnsupported)
+
+	context.SetTranslate(false)
+	assert.NoError(context.Validate())
+}
+
+func TestDetectLanguageTop(t *testing.T) {
+	assert := assert.New(t)
+
+	fh, err := os.Open(SamplePath)
+	assert.NoError(err)
+	defer fh.Close()
+	buf, err := wav.NewDecoder(fh).FullPCMBuffer()
+	assert.NoError(err)
+
+	model, err := fullModel(whisper.New(ModelPath))
+	assert.NoError(err)
+	defer model.Close()
+	context, err := fullContext(model.NewContext())
+	assert.NoError(err)
+	assert.NoError(context.Process(buf.AsFloat32Buffer().Data, nil, nil, nil))
+
+	// Candidates are ranked by probability
+	top, err := context.DetectLanguageTop(3)
+	assert.NoError(err)
+	assert.Len(top, 3)
+	for i, lang := range top {
+		assert.NotEmpty(lang.Code)
+		assert.NotEmpty(lang.Name)
+		if i > 0 {
+			assert.GreaterOrEqual(top[i-1].P, lang.P)
+		}
+	}
+
+	// All languages are returned when n is not positive
+	all, err := context.DetectLanguageTop(0)
+	assert.NoError(err)
+	assert.Greater(len(all), 3)
+	assert.Equal(top, all[:3])
+}
+
+func TestSegmentSequence(t *testing.T) {
+	assert := assert.New(t)
+
+	fh, err := os.Open(SamplePath)
+	assert.NoError(err)
+	defer fh.Close()
+	buf, err := wav.NewDecoder(fh).FullPCMBuffer()
+	assert.NoError(err)
+	data := buf.AsFloat32Buffer().Data
+
+	model, err := whisper.New(ModelPath)
+	assert.NoError(err)
+	defer model.Close()
+	context, err := model.NewContext()
+	assert.NoError(err)
+
+	// Segments passed to the callback are numbered in order, and each
+	// starts within the window which produced it
+	var segments []whisper.Segment
+	assert.NoError(context.Process(data, nil, func(segment whisper.Segment) {
+		segments = append(segments, segment)
+	}, nil))
+	assert.NotEmpty(segments)
+	for i, segment := range segments {
+		assert.Equal(uint64(i), segment.Seq)
+		assert.LessOrEqual(segment.Window, segment.Start)
+	}
+
+	// NextSegment returns the same numbers and windows
+	for _, expected := range segments {
+		segment, err := context.NextSegment()
+		assert.NoError(err)
+		assert.Equal(expected.Seq, segment.Seq)
+		assert.Equal(expected.Window, segment.Window)
+	}
+
+	// Numbering continues on the next call
+	assert.NoError(context.Process(data, nil, nil, nil))
+	segment, err := context.NextSegment()
+	assert.NoError(err)
+	assert.Equal(uint64(len(segments)), segment.Seq)
+}
+
+func TestTokenDTW(t *testing.T) {
+	assert := assert.New(t)
+
+	fh, err := os.Open(SamplePath)
+	assert.NoError(err)
+	defer fh.Close()
+	buf, err := wav.NewDecoder(fh).FullPCMBuffer()
+	assert.NoError(err)
+	data := buf.AsFloat32Buffer().Data
+
+	tokens := func(params *whisper.ModelContextParams) []whisper.Token {
+		model, err := whisper.NewWithParams(ModelPath, params)
+		assert.NoError(err)
+		defer model.Close()
+		context, err := model.NewContext()
+		assert.NoError(err)
+		context.SetTokenTimestamps(true)
+		assert.NoError(context.Process(data, nil, nil, nil))
+		var result []whisper.Token
+		for {
+			segment, err := context.NextSegment()
+			if err != nil {
+				break
+			}
+			for _, token := range segment.Tokens {
+				if context.IsText(token) {
+					result = append(result, token)
+				}
+			}
+		}
+		return result
+	}
+
+	// Without DTW there are no DTW timestamps
+	for _, token := range tokens(whisper.NewModelContextParams()) {
+		assert.Negative(token.DTW)
+	}
+
+	// With DTW each text token has a time within the audio
+	params := whisper.NewModelContextParams()
+	params.SetUseFlashAttention(false)
+	params.SetDTWTokenTimestamps(true)
+	params.SetDTWAheadsPreset(whisper.AlignmentHeadsNTopMost)
+	params.SetDTWNTop(1)
+	result := tokens(params)
+	assert.NotEmpty(result)
+	duration := time.Duration(len(data)) * time.Second / whisper.SampleRate
+	for _, token := range result {
+		assert.GreaterOrEqual(token.DTW, time.Duration(0))
+		assert.LessOrEqual(token.DTW, duration+time.Second)
+	}
+}
+
+func TestSeekCallback(t *testing.T) {
+	assert := assert.New(t)
+
+	model, err := fullModel(whisper.New(ModelPath))
+	assert.NoError(err)
+	defer model.Close()
+	context, err := fullContext(model.NewContext())
+	assert.NoError(err)
+
+	// Skip from the first window over the next 40 seconds, and encode the
+	// window skipped to
+	data := make([]float32, 45*whisper.SampleRate)
+	var windows []time.Duration
+	context.SetSeekCallback(func(window time.Duration) time.Duration {
+		windows = append(windows, window)
+		if window == 0 {
+			return 40 * time.Second
+		}
+		return window
+	})
+	assert.NoError(context.Process(data, nil, nil, nil))
+	if assert.GreaterOrEqual(len(windows), 2) {
+		assert.Equal([]time.Duration{0, 40 * time.Second}, windows[:2])
+	}
+	assert.Equal(40*time.Second, context.Stats().Skipped)
+	for _, window := range windows[1:] {
+		assert.GreaterOrEqual(window, 40*time.Second)
+	}
+
+	// Without the callback, every window is encoded
+	context.SetSeekCallback(nil)
+	assert.NoError(context.Process(data[:whisper.SampleRate], nil, nil, nil))
+	assert.Zero(context.Stats().Skipped)
+}
@@ -0,0 +1,58 @@
+package whisper
+
+import (
+	// Bindings
+	whisper "github.com/ggerganov/whisper.cpp/bindings/go"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Return true if the encoder of the model runs with Core ML, which is false
+// when the library is built without Core ML, or when the Core ML model could
+// not be loaded and the library falls back to the ggml encoder
+func (model *model) UsesCoreML() bool {
+	ctx := model.rlock()
+	if ctx == nil {
+		return false
+	}
+	defer model.runlock()
+	return ctx.Whisper_uses_coreml()
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// Load a model from a file. When a Core ML model or cache is set, the model
+// is loaded without a state, so that the default state is allocated with
+// the Core ML encoder. Returns nil on failure.
+func initFromFile(path string, params *ModelContextParams) *whisper.Context {
+	if params.coreml == "" && params.cache == "" {
+		return whisper.Whisper_init_with_params(path, params.params)
+	}
+	return initCoreML(whisper.Whisper_init_with_params_no_state(path, params.params), params)
+}
+
+// Load a model from a buffer, as initFromFile
+func initFromBuffer(buf []byte, params *ModelContextParams) *whisper.Context {
+	if params.coreml == "" && params.cache == "" {
+		return whisper.Whisper_init_from_buffer_with_params(buf, params.params)
+	}
+	return initCoreML(whisper.Whisper_init_from_buffer_with_params_no_state(buf, params.params), params)
+}
+
+// Set the Core ML encoder of a model loaded without a state, and allocate
+// the default state
+func initCoreML(ctx *whisper.Context, params *ModelContextParams) *whisper.Context {
+	if ctx == nil {
+		return nil
+	}
+
+	// The paths are ignored when the library is built without Core ML
+	_ = ctx.Whisper_ctx_set_coreml_encoder(params.coreml, params.cache)
+	if err := ctx.Whisper_reset_state(); err != nil {
+		ctx.Whisper_free()
+		return nil
+	}
+	return ctx
+}
@@ -0,0 +1,158 @@
+package whisper
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// DatasetWriter exports transcribed audio as a training-style dataset: one
+// WAV clip per segment in a directory, and a JSONL manifest describing the
+// clips. It is not safe for concurrent use.
+type DatasetWriter struct {
+	// Padding added before and after the audio of each segment
+	Pad time.Duration
+
+	// Segments shorter than this are not exported
+	MinDuration time.Duration
+
+	dir      string
+	manifest *os.File
+	buf      *bufio.Writer
+	n        int
+}
+
+// DatasetEntry is a line of the dataset manifest
+type DatasetEntry struct {
+	Audio    string  `json:"audio"`             // Path of the clip, relative to the dataset directory
+	Source   string  `json:"source,omitempty"`  // Source of the audio the clip was extracted from
+	Speaker  string  `json:"speaker,omitempty"` // Speaker of the segment, if known
+	Text     string  `json:"text"`              // Transcribed text
+	Start    float64 `json:"start"`             // Start of the segment within the source, in seconds
+	End      float64 `json:"end"`               // End of the segment within the source, in seconds
+	Duration float64 `json:"duration"`          // Duration of the clip including padding, in seconds
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+// Name of the manifest file within the dataset directory
+const DatasetManifest = "manifest.jsonl"
+
+///////////////////////////////////////////////////////////////////////////////
+// LIFECYCLE
+
+// NewDatasetWriter creates the dataset directory if it does not exist, and
+// a new manifest within it, replacing any existing manifest.
+func NewDatasetWriter(dir string) (*DatasetWriter, error) {
+	if err := os.MkdirAll(filepath.Join(dir, "clips"), 0o755); err != nil {
+		return nil, err
+	}
+	manifest, err := os.Create(filepath.Join(dir, DatasetManifest))
+	if err != nil {
+		return nil, err
+	}
+
+	// Return success
+	return &DatasetWriter{
+		dir:      dir,
+		manifest: manifest,
+		buf:      bufio.NewWriter(manifest),
+	}, nil
+}
+
+// Flush and close the manifest
+func (w *DatasetWriter) Close() error {
+	if w.manifest == nil {
+		return nil
+	}
+	err := w.buf.Flush()
+	if cerr := w.manifest.Close(); err == nil {
+		err = cerr
+	}
+
+	// Release resources
+	w.manifest = nil
+	w.buf = nil
+
+	// Return any errors
+	return err
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Write exports the segments transcribed from samples, which is the audio
+// passed to Process. The source and speaker are recorded in the manifest
+// and may be empty. Segments without text are skipped.
+func (w *DatasetWriter) Write(source, speaker string, samples []float32, segments []Segment) error {
+	if w.manifest == nil {
+		return ErrInternalAppError
+	}
+	for _, segment := range segments {
+		text := strings.TrimSpace(segment.Text)
+		if text == "" || segment.End-segment.Start < w.MinDuration {
+			continue
+		}
+		clip := ExtractClip(samples, segment, w.Pad)
+		if len(clip) == 0 {
+			continue
+		}
+
+		// Write the clip
+		entry := DatasetEntry{
+			Audio:    filepath.ToSlash(filepath.Join("clips", fmt.Sprintf("%06d.wav", w.n))),
+			Source:   source,
+			Speaker:  speaker,
+			Text:     text,
+			Start:    segment.Start.Seconds(),
+			End:      segment.End.Seconds(),
+			Duration: samplesToDuration(len(clip)).Seconds(),
+		}
+		if err := w.writeClip(filepath.Join(w.dir, filepath.FromSlash(entry.Audio)), clip); err != nil {
+			return err
+		}
+
+		// Write the manifest entry
+		if data, err := json.Marshal(entry); err != nil {
+			return err
+		} else if _, err := w.buf.Write(append(data, '\n')); err != nil {
+			return err
+		}
+		w.n++
+	}
+
+	// Return success
+	return nil
+}
+
+// Return the number of clips exported
+func (w *DatasetWriter) Count() int {
+	return w.n
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+func (w *DatasetWriter) writeClip(path string, clip []float32) error {
+	fh, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	buf := bufio.NewWriter(fh)
+	err = WriteWAV(buf, clip)
+	if err == nil {
+		err = buf.Flush()
+	}
+	if cerr := fh.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
@@ -0,0 +1,57 @@
+package whisper_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ggerganov/whisper.cpp/bindings/go/pkg/whisper"
+	assert "github.com/stretchr/testify/assert"
+)
+
+func TestDatasetWriter(t *testing.T) {
+	assert := assert.New(t)
+	dir := t.TempDir()
+
+	w, err := whisper.NewDatasetWriter(dir)
+	assert.NoError(err)
+	w.Pad = 100 * time.Millisecond
+
+	samples := make([]float32, whisper.SampleRate*3)
+	segments := []whisper.Segment{
+		{Num: 0, Start: 0, End: time.Second, Text: " Hello world"},
+		{Num: 1, Start: time.Second, End: 2 * time.Second, Text: " "},
+		{Num: 2, Start: 2 * time.Second, End: 3 * time.Second, Text: "Goodbye"},
+	}
+	assert.NoError(w.Write("input.wav", "alice", samples, segments))
+	assert.Equal(2, w.Count())
+	assert.NoError(w.Close())
+
+	// Read the manifest
+	fh, err := os.Open(filepath.Join(dir, whisper.DatasetManifest))
+	assert.NoError(err)
+	defer fh.Close()
+	var entries []whisper.DatasetEntry
+	scanner := bufio.NewScanner(fh)
+	for scanner.Scan() {
+		var entry whisper.DatasetEntry
+		assert.NoError(json.Unmarshal(scanner.Bytes(), &entry))
+		entries = append(entries, entry)
+	}
+	assert.Len(entries, 2)
+	assert.Equal("clips/000000.wav", entries[0].Audio)
+	assert.Equal("Hello world", entries[0].Text)
+	assert.Equal("alice", entries[0].Speaker)
+	assert.InDelta(1.1, entries[0].Duration, 1e-6)
+	assert.Equal("clips/000001.wav", entries[1].Audio)
+	assert.Equal(2.0, entries[1].Start)
+
+	// Clips exist
+	for _, entry := range entries {
+		_, err := os.Stat(filepath.Join(dir, entry.Audio))
+		assert.NoError(err)
+	}
+}
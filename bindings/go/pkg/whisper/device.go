@@ -0,0 +1,125 @@
+package whisper
+
+import (
+	"fmt"
+
+	// Bindings
+	whisper "github.com/ggerganov/whisper.cpp/bindings/go"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// DeviceType is the kind of a compute device
+type DeviceType int
+
+// DeviceInfo describes a compute device of the backends the library was
+// built with
+type DeviceInfo struct {
+	Name        string // Name of the device, such as CPU or CUDA0
+	Description string
+	Backend     string // Name of the backend, such as CPU, CUDA or Metal
+	Type        DeviceType
+
+	// Index of the device to pass to SetGPUDevice, or -1 if the device is
+	// not a GPU
+	GPU int
+
+	// Memory of the device in bytes
+	Total, Free uint64
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+const (
+	DeviceCPU   DeviceType = iota
+	DeviceGPU              // GPU with dedicated memory
+	DeviceIGPU             // Integrated GPU using host memory
+	DeviceAccel            // Accelerator used together with the CPU, such as BLAS
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Return the compute devices available to the library, so that a GPU can be
+// chosen with SetGPUDevice before a model is loaded. Free memory is measured
+// when this is called.
+func Devices() []DeviceInfo {
+	var result []DeviceInfo
+	gpu := 0
+	for i := 0; i < whisper.Ggml_backend_dev_count(); i++ {
+		dev := whisper.Ggml_backend_dev_get(i)
+		if dev == nil {
+			continue
+		}
+		info := DeviceInfo{
+			Name:        dev.Name(),
+			Description: dev.Description(),
+			Backend:     dev.Backend(),
+			Type:        deviceType(dev.Type()),
+			GPU:         -1,
+		}
+		info.Free, info.Total = dev.Memory()
+		if dev.Type().IsGPU() {
+			info.GPU = gpu
+			gpu++
+		}
+		result = append(result, info)
+	}
+	return result
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (t DeviceType) String() string {
+	switch t {
+	case DeviceCPU:
+		return "cpu"
+	case DeviceGPU:
+		return "gpu"
+	case DeviceIGPU:
+		return "igpu"
+	case DeviceAccel:
+		return "accel"
+	default:
+		return fmt.Sprintf("device(%d)", int(t))
+	}
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+func deviceType(t whisper.BackendDeviceType) DeviceType {
+	switch t {
+	case whisper.DEVICE_TYPE_GPU:
+		return DeviceGPU
+	case whisper.DEVICE_TYPE_IGPU:
+		return DeviceIGPU
+	case whisper.DEVICE_TYPE_ACCEL:
+		return DeviceAccel
+	default:
+		return DeviceCPU
+	}
+}
+
+// Return an error if the GPU device of the parameters does not exist. The
+// first GPU is always accepted, as the library falls back to the CPU when
+// there is none.
+func checkGPUDevice(params whisper.ContextParams) error {
+	n := params.GPUDevice()
+	if !params.UseGPU() || n == 0 {
+		return nil
+	}
+	gpus := 0
+	for _, device := range Devices() {
+		if device.GPU >= 0 {
+			gpus++
+		}
+	}
+	if n < 0 || n >= gpus {
+		return fmt.Errorf("%w: gpu device %d of %d", ErrInvalidDevice, n, gpus)
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package whisper_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ggerganov/whisper.cpp/bindings/go/pkg/whisper"
+	assert "github.com/stretchr/testify/assert"
+)
+
+func TestDevices(t *testing.T) {
+	assert := assert.New(t)
+
+	devices := whisper.Devices()
+	if !assert.NotEmpty(devices) {
+		t.FailNow()
+	}
+
+	// GPUs are numbered from zero in the order they are listed
+	gpus, cpus := 0, 0
+	for _, device := range devices {
+		t.Logf("%s (%s, %v): %s free=%d total=%d", device.Name, device.Backend, device.Type, device.Description, device.Free, device.Total)
+		assert.NotEmpty(device.Name)
+		switch device.Type {
+		case whisper.DeviceGPU, whisper.DeviceIGPU:
+			assert.Equal(gpus, device.GPU)
+			gpus++
+		case whisper.DeviceCPU:
+			assert.Equal(-1, device.GPU)
+			cpus++
+		default:
+			assert.Equal(-1, device.GPU)
+		}
+	}
+
+	// The CPU backend is always built
+	assert.Equal(1, cpus)
+}
+
+func TestInvalidGPUDevice(t *testing.T) {
+	assert := assert.New(t)
+
+	gpus := 0
+	for _, device := range whisper.Devices() {
+		if device.GPU >= 0 {
+			gpus++
+		}
+	}
+
+	params := whisper.NewModelContextParams()
+	params.SetUseGPU(true)
+	params.SetGPUDevice(gpus + 1)
+	assert.Equal(gpus+1, params.GPUDevice())
+	_, err := whisper.NewWithParams(ModelPath, params)
+	assert.True(errors.Is(err, whisper.ErrInvalidDevice), err)
+
+	// The device is not checked when the GPU is not used
+	params.SetUseGPU(false)
+	model, err := whisper.NewWithParams(ModelPath, params)
+	if assert.NoError(err) {
+		model.Close()
+	}
+}
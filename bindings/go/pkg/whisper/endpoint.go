@@ -0,0 +1,242 @@
+package whisper
+
+import (
+	"io"
+	"strings"
+	"time"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// Endpointer splits a live stream of audio into utterances for dictation.
+// Audio is written as it arrives, and an utterance is ended when the speech
+// is followed by a long enough silence, when a shorter silence follows text
+// which whisper predicts is complete, or when the utterance reaches its
+// maximum length. Each ended utterance is transcribed and returned.
+type Endpointer struct {
+	transcriber Transcriber
+	opts        EndpointOptions
+
+	buf     []float32 // Audio of the current utterance
+	start   int       // Position of the first sample of buf in the stream
+	checked int       // Length of buf when the text was last checked for completion
+}
+
+// EndpointOptions are the options of an Endpointer. Zero values select the
+// defaults.
+type EndpointOptions struct {
+	// Detects speech in the audio (default EnergyVAD)
+	VAD VAD
+
+	// Silence after speech which ends an utterance (default 800ms)
+	Silence time.Duration
+
+	// Shorter silence after speech which ends an utterance when the
+	// probability that the text is complete is at least EOTThreshold
+	// (default 300ms)
+	ShortSilence time.Duration
+	EOTThreshold float32 // (default 0.8)
+
+	// Length at which an utterance is ended, whether or not the speaker
+	// has paused (default 20s)
+	MaxUtterance time.Duration
+}
+
+// EndReason is the reason an utterance was ended
+type EndReason int
+
+// Utterance is a span of speech ended by an Endpointer, with its text
+type Utterance struct {
+	// Time span of the utterance within the stream
+	Start, End time.Duration
+
+	// Text of the utterance, and its segments with timestamps relative to
+	// the start of the stream
+	Text     string
+	Segments []Segment
+
+	// Probability that the text is complete, from the timestamp token which
+	// closes the last segment
+	EOT float32
+
+	// Reason the utterance was ended
+	Reason EndReason
+}
+
+const (
+	EndSilence   EndReason = iota // Silence after speech
+	EndEOT                        // Short silence after complete text
+	EndMaxLength                  // Maximum length of an utterance
+	EndFlush                      // End of the stream
+)
+
+// Audio kept while no speech is detected, which is longer than the VAD
+// needs to detect the start of speech
+const endpointLeadIn = 500 * time.Millisecond
+
+///////////////////////////////////////////////////////////////////////////////
+// LIFECYCLE
+
+// Return an endpointer which transcribes utterances with the transcriber,
+// such as a Context
+func NewEndpointer(transcriber Transcriber, opts EndpointOptions) *Endpointer {
+	if opts.VAD == nil {
+		opts.VAD = EnergyVAD{}
+	}
+	if opts.Silence <= 0 {
+		opts.Silence = 800 * time.Millisecond
+	}
+	if opts.ShortSilence <= 0 {
+		opts.ShortSilence = 300 * time.Millisecond
+	}
+	if opts.EOTThreshold <= 0 {
+		opts.EOTThreshold = 0.8
+	}
+	if opts.MaxUtterance <= 0 {
+		opts.MaxUtterance = 20 * time.Second
+	}
+	return &Endpointer{transcriber: transcriber, opts: opts}
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (r EndReason) String() string {
+	switch r {
+	case EndSilence:
+		return "silence"
+	case EndEOT:
+		return "eot"
+	case EndMaxLength:
+		return "max_length"
+	case EndFlush:
+		return "flush"
+	default:
+		return "unknown"
+	}
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Write mono audio data of the stream, and return the utterances it ended
+func (e *Endpointer) Write(data []float32) ([]Utterance, error) {
+	e.buf = append(e.buf, data...)
+	regions, err := e.opts.VAD.DetectSpeech(e.buf)
+	if err != nil {
+		return nil, err
+	}
+
+	// Without speech, keep only a short lead-in
+	if len(regions) == 0 {
+		if n := len(e.buf) - durationToSamples(endpointLeadIn); n > 0 {
+			e.buf = append(e.buf[:0], e.buf[n:]...)
+			e.start += n
+		}
+		e.checked = 0
+		return nil, nil
+	}
+
+	speech := samplesToDuration(len(e.buf)) - regions[0].Start
+	silence := samplesToDuration(len(e.buf)) - regions[len(regions)-1].End
+	switch {
+	case speech >= e.opts.MaxUtterance:
+		return e.end(EndMaxLength, nil)
+	case silence >= e.opts.Silence:
+		return e.end(EndSilence, nil)
+	case silence >= e.opts.ShortSilence && e.checked == 0:
+		// Check once per pause whether the text is complete
+		e.checked = len(e.buf)
+		utterance, err := e.transcribe()
+		if err != nil {
+			return nil, err
+		}
+		if utterance.EOT >= e.opts.EOTThreshold {
+			return e.end(EndEOT, &utterance)
+		}
+	case silence < e.opts.ShortSilence:
+		e.checked = 0
+	}
+	return nil, nil
+}
+
+// End the current utterance at the end of the stream, and return it if it
+// contains speech
+func (e *Endpointer) Flush() ([]Utterance, error) {
+	regions, err := e.opts.VAD.DetectSpeech(e.buf)
+	if err != nil {
+		return nil, err
+	}
+	if len(regions) == 0 {
+		e.reset()
+		return nil, nil
+	}
+	return e.end(EndFlush, nil)
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// End the current utterance, transcribing it unless that is already done,
+// and start the next one
+func (e *Endpointer) end(reason EndReason, utterance *Utterance) ([]Utterance, error) {
+	if utterance == nil {
+		u, err := e.transcribe()
+		if err != nil {
+			return nil, err
+		}
+		utterance = &u
+	}
+	e.reset()
+	utterance.Reason = reason
+	if utterance.Text == "" {
+		return nil, nil
+	}
+	return []Utterance{*utterance}, nil
+}
+
+func (e *Endpointer) reset() {
+	e.start += len(e.buf)
+	e.buf = e.buf[:0]
+	e.checked = 0
+}
+
+// Transcribe the current utterance
+func (e *Endpointer) transcribe() (Utterance, error) {
+	offset := samplesToDuration(e.start)
+	utterance := Utterance{Start: offset, End: offset + samplesToDuration(len(e.buf))}
+	if err := e.transcriber.Process(e.buf, nil, nil, nil); err != nil {
+		return utterance, err
+	}
+
+	// Collect segments and shift them onto the timeline of the stream
+	var text []string
+	for {
+		segment, err := e.transcriber.NextSegment()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return utterance, err
+		}
+		segment.shift(offset)
+		if segment.Text != "" {
+			text = append(text, segment.Text)
+		}
+		utterance.Segments = append(utterance.Segments, segment)
+	}
+	utterance.Text = strings.Join(text, " ")
+
+	// The probability of a timestamp token after the last text token is the
+	// probability that whisper would end the text there
+	if n := len(utterance.Segments); n > 0 {
+		tokens := utterance.Segments[n-1].Tokens
+		for i := len(tokens) - 1; i >= 0; i-- {
+			if strings.HasPrefix(tokens[i].Text, "[_TT_") {
+				utterance.EOT = tokens[i].PtSum
+				break
+			}
+		}
+	}
+	return utterance, nil
+}
@@ -0,0 +1,134 @@
+package whisper_test
+
+import (
+	"io"
+	"testing"
+	"time"
+
+	"github.com/ggerganov/whisper.cpp/bindings/go/pkg/whisper"
+	"github.com/ggerganov/whisper.cpp/bindings/go/pkg/whisper/testsupport"
+	assert "github.com/stretchr/testify/assert"
+)
+
+// A transcriber which returns one segment for each call, closed by a
+// timestamp token with the given probability of ending the text
+type eotTranscriber struct {
+	eot   float32
+	calls int
+	next  []whisper.Segment
+}
+
+func (f *eotTranscriber) Process(data []float32, _ whisper.EncoderBeginCallback, _ whisper.SegmentCallback, _ whisper.ProgressCallback) error {
+	f.calls++
+	end := time.Duration(len(data)) * time.Second / whisper.SampleRate
+	f.next = []whisper.Segment{{End: end, Text: "hello world", Tokens: []whisper.Token{
+		{Text: "[_BEG_]"},
+		{Text: " hello", End: end / 2},
+		{Text: " world", Start: end / 2, End: end},
+		{Text: "[_TT_100]", PtSum: f.eot},
+	}}}
+	return nil
+}
+
+func (f *eotTranscriber) NextSegment() (whisper.Segment, error) {
+	if len(f.next) == 0 {
+		return whisper.Segment{}, io.EOF
+	}
+	segment := f.next[0]
+	f.next = f.next[1:]
+	return segment, nil
+}
+
+// Return audio of the given length, with a tone if speech is true
+func endpointAudio(d time.Duration, speech bool) []float32 {
+	if speech {
+		return testsupport.Tone(440, 0.5, d)
+	}
+	return testsupport.Silence(d)
+}
+
+// Write audio in chunks of 100ms, and return the utterances with the time at
+// which each was ended
+func endpointWrite(t *testing.T, e *whisper.Endpointer, at *time.Duration, d time.Duration, speech bool) ([]whisper.Utterance, []time.Duration) {
+	var utterances []whisper.Utterance
+	var times []time.Duration
+	chunk := 100 * time.Millisecond
+	for i := time.Duration(0); i < d; i += chunk {
+		result, err := e.Write(endpointAudio(chunk, speech))
+		if err != nil {
+			t.Fatal(err)
+		}
+		*at += chunk
+		for range result {
+			times = append(times, *at)
+		}
+		utterances = append(utterances, result...)
+	}
+	return utterances, times
+}
+
+func TestEndpointerSilence(t *testing.T) {
+	assert := assert.New(t)
+
+	// Text which is not complete waits for the full silence
+	transcriber := &eotTranscriber{eot: 0.1}
+	e := whisper.NewEndpointer(transcriber, whisper.EndpointOptions{})
+	var at time.Duration
+	utterances, _ := endpointWrite(t, e, &at, 2*time.Second, false)
+	assert.Empty(utterances)
+	utterances, _ = endpointWrite(t, e, &at, time.Second, true)
+	assert.Empty(utterances)
+	utterances, times := endpointWrite(t, e, &at, 2*time.Second, false)
+	if assert.Len(utterances, 1) {
+		assert.Equal(whisper.EndSilence, utterances[0].Reason)
+		assert.Equal("hello world", utterances[0].Text)
+		assert.InDelta(float32(0.1), utterances[0].EOT, 1e-6)
+		assert.InDelta(3900*time.Millisecond, times[0], float64(200*time.Millisecond))
+
+		// Times are relative to the start of the stream
+		assert.InDelta(1700*time.Millisecond, utterances[0].Start, float64(100*time.Millisecond))
+		assert.Equal(utterances[0].Start, utterances[0].Segments[0].Start)
+		assert.Equal(utterances[0].Start+(utterances[0].End-utterances[0].Start)/2, utterances[0].Segments[0].Tokens[2].Start)
+	}
+
+	// The text was checked once at the short silence, and again at the end
+	assert.Equal(2, transcriber.calls)
+}
+
+func TestEndpointerEOT(t *testing.T) {
+	assert := assert.New(t)
+
+	// Complete text ends at the short silence
+	transcriber := &eotTranscriber{eot: 0.95}
+	e := whisper.NewEndpointer(transcriber, whisper.EndpointOptions{})
+	var at time.Duration
+	endpointWrite(t, e, &at, time.Second, true)
+	utterances, times := endpointWrite(t, e, &at, time.Second, false)
+	if assert.Len(utterances, 1) {
+		assert.Equal(whisper.EndEOT, utterances[0].Reason)
+		assert.InDelta(1400*time.Millisecond, times[0], float64(200*time.Millisecond))
+	}
+	assert.Equal(1, transcriber.calls)
+}
+
+func TestEndpointerMaxLength(t *testing.T) {
+	assert := assert.New(t)
+
+	e := whisper.NewEndpointer(&eotTranscriber{}, whisper.EndpointOptions{MaxUtterance: 2 * time.Second})
+	var at time.Duration
+	utterances, _ := endpointWrite(t, e, &at, 5*time.Second, true)
+	if assert.Len(utterances, 2) {
+		assert.Equal(whisper.EndMaxLength, utterances[0].Reason)
+		assert.Equal(utterances[0].End, utterances[1].Start)
+	}
+
+	// The rest of the speech is ended by a flush
+	utterances, err := e.Flush()
+	assert.NoError(err)
+	if assert.Len(utterances, 1) {
+		assert.Equal(whisper.EndFlush, utterances[0].Reason)
+	}
+	utterances, err = e.Flush()
+	assert.NoError(err)
+	assert.Empty(utterances)
+}
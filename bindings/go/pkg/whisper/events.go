@@ -0,0 +1,45 @@
+package whisper
+
+import (
+	"regexp"
+	"strings"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+// Annotations of non-speech sound in the text, such as [Music], (applause),
+// *laughs* or lyrics between music notes
+var reEvent = regexp.MustCompile(`\[[^\[\]]+\]|\([^()]+\)|\*[^*\s][^*]*\*|[♩♪♫♬]+(?:[^♩♪♫♬]*[♩♪♫♬]+)?`)
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Return the non-speech events annotated in the text of a segment, with the
+// time span of their tokens, as set in the Events of segments returned by
+// a context. Music notes and annotations which mention music or singing are
+// ClassMusic, and other annotations are ClassNoise. Special tokens, which
+// are in the text when the native library prints them, are not events.
+func SegmentEvents(segment Segment) []AudioEvent {
+	var result []AudioEvent
+	var offsets []int
+	for _, loc := range reEvent.FindAllStringIndex(segment.Text, -1) {
+		label := segment.Text[loc[0]:loc[1]]
+		if isSpecialText(label) {
+			continue
+		}
+		if offsets == nil {
+			offsets = tokenOffsets(segment)
+		}
+		event := AudioEvent{Class: ClassNoise, Label: label}
+		event.Start, event.End = segment.Start, segment.End
+		if offsets != nil {
+			event.Start, event.End = tokenSpan(segment, offsets, loc[0], loc[1])
+		}
+		if lower := strings.ToLower(label); strings.ContainsAny(label, "♩♪♫♬") || strings.Contains(lower, "music") || strings.Contains(lower, "sing") {
+			event.Class = ClassMusic
+		}
+		result = append(result, event)
+	}
+	return result
+}
@@ -0,0 +1,47 @@
+package whisper_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ggerganov/whisper.cpp/bindings/go/pkg/whisper"
+	assert "github.com/stretchr/testify/assert"
+)
+
+func TestSegmentEvents(t *testing.T) {
+	assert := assert.New(t)
+
+	segment := whisper.Segment{Start: time.Second, End: 5 * time.Second, Text: "[Music] hello (applause)", Tokens: []whisper.Token{
+		{Text: "[_BEG_]"},
+		{Text: " [", Start: time.Second, End: 1200 * time.Millisecond},
+		{Text: "Music", Start: 1200 * time.Millisecond, End: 1800 * time.Millisecond},
+		{Text: "]", Start: 1800 * time.Millisecond, End: 2 * time.Second},
+		{Text: " hello", Start: 2 * time.Second, End: 3 * time.Second},
+		{Text: " (", Start: 3 * time.Second, End: 3500 * time.Millisecond},
+		{Text: "applause", Start: 3500 * time.Millisecond, End: 4500 * time.Millisecond},
+		{Text: ")", Start: 4500 * time.Millisecond, End: 5 * time.Second},
+		{Text: "[_TT_250]"},
+	}}
+	events := whisper.SegmentEvents(segment)
+	if assert.Len(events, 2) {
+		assert.Equal("[Music]", events[0].Label)
+		assert.Equal(whisper.ClassMusic, events[0].Class)
+		assert.Equal(time.Second, events[0].Start)
+		assert.Equal(2*time.Second, events[0].End)
+		assert.Equal("(applause)", events[1].Label)
+		assert.Equal(whisper.ClassNoise, events[1].Class)
+		assert.Equal(3*time.Second, events[1].Start)
+		assert.Equal(5*time.Second, events[1].End)
+	}
+
+	// Special tokens in the text are not events, and events without tokens
+	// span the segment
+	events = whisper.SegmentEvents(whisper.Segment{Start: time.Second, End: 2 * time.Second, Text: "[_BEG_] ♪ la la ♪ [_TT_50]"})
+	if assert.Len(events, 1) {
+		assert.Equal("♪ la la ♪", events[0].Label)
+		assert.Equal(whisper.ClassMusic, events[0].Class)
+		assert.Equal(time.Second, events[0].Start)
+		assert.Equal(2*time.Second, events[0].End)
+	}
+	assert.Empty(whisper.SegmentEvents(whisper.Segment{Text: "no events here"}))
+}
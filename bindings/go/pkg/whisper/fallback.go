@@ -0,0 +1,95 @@
+package whisper
+
+import (
+	"fmt"
+
+	// Bindings
+	whisper "github.com/ggerganov/whisper.cpp/bindings/go"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// LoadError is returned when a model cannot be loaded, with the backend it
+// was loaded on. It matches ErrUnableToLoadModel with errors.Is
+type LoadError struct {
+	Path    string
+	Backend string // Backend requested when loading the model, "CPU" or "GPU"
+	Device  int    // Device index for the GPU backend
+}
+
+// FallbackWarning is reported by the Warnings of a ModelDescriber when the
+// model could not be loaded on the GPU and was loaded on the CPU instead,
+// which happens when SetCPUFallback is enabled
+type FallbackWarning struct {
+	Device int   // GPU the model was loaded on first
+	Err    error // Reason the GPU could not be used
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Return warnings from loading the model, such as a *FallbackWarning. They
+// are only added while the model is loaded, so no lock is needed.
+func (model *model) Warnings() []error {
+	return append([]error(nil), model.warnings...)
+}
+
+func (err *LoadError) Error() string {
+	backend := err.Backend
+	if backend == "GPU" {
+		backend = fmt.Sprintf("GPU %d", err.Device)
+	}
+	return fmt.Sprintf("%v: %q on %s", ErrUnableToLoadModel, err.Path, backend)
+}
+
+func (err *LoadError) Unwrap() error {
+	return ErrUnableToLoadModel
+}
+
+func (warning *FallbackWarning) Error() string {
+	return fmt.Sprintf("loaded on CPU after GPU %d failed: %v", warning.Device, warning.Err)
+}
+
+func (warning *FallbackWarning) Unwrap() error {
+	return warning.Err
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// Load the model with the parameters, and when it cannot be loaded on the
+// GPU and fallback is enabled, load it again on the CPU. The parameters the
+// model was loaded with are recorded.
+func (model *model) load(path string, params *ModelContextParams, open func(*ModelContextParams) *whisper.Context) (*whisper.Context, error) {
+	err := checkGPUDevice(params.params)
+	if err == nil {
+		if ctx := open(params); ctx != nil {
+			model.params = params.params
+			return ctx, nil
+		}
+		err = newLoadError(path, params.params)
+	}
+	if !params.params.UseGPU() || !params.fallback {
+		return nil, err
+	}
+
+	// Retry on the CPU
+	cpu := *params
+	cpu.params.SetUseGPU(false)
+	ctx := open(&cpu)
+	if ctx == nil {
+		return nil, newLoadError(path, cpu.params)
+	}
+	model.params = cpu.params
+	model.warnings = append(model.warnings, &FallbackWarning{Device: params.params.GPUDevice(), Err: err})
+	return ctx, nil
+}
+
+func newLoadError(path string, params whisper.ContextParams) error {
+	err := &LoadError{Path: path, Backend: "CPU"}
+	if params.UseGPU() {
+		err.Backend, err.Device = "GPU", params.GPUDevice()
+	}
+	return err
+}
@@ -0,0 +1,54 @@
+package whisper
+
+import (
+	"log"
+	"sync/atomic"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+// Models and stateful contexts which were garbage collected without being
+// closed
+var leaks atomic.Uint64
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Return the number of models and stateful contexts which were garbage
+// collected without being closed. Their native memory is freed when they
+// are collected and a warning is logged, but memory on the device is held
+// until then, so a service should close them and can monitor this count.
+func Leaks() uint64 {
+	return leaks.Load()
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// Free the native context of a model which was not closed
+func finalizeModel(model *model) {
+	if model.ctx == nil {
+		return
+	}
+	leaked(model.String())
+	model.Close()
+}
+
+// Free the decoding state of a stateful context which was not closed, and
+// the parameters of a context which shares the state of the model. The
+// latter hold no device memory, so they are not counted as leaks.
+func finalizeContext(context *context) {
+	if context.closed.Load() {
+		return
+	}
+	if context.stateful {
+		leaked(context.label)
+	}
+	context.Close()
+}
+
+func leaked(what string) {
+	leaks.Add(1)
+	log.Printf("whisper: %s was garbage collected without being closed, freeing its native memory", what)
+}
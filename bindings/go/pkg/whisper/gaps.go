@@ -0,0 +1,41 @@
+package whisper
+
+import (
+	"time"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// Gap is a pause between segments, where no segment has text
+type Gap struct {
+	Start, End time.Duration
+
+	// Index of the segment which follows the gap
+	Before int
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Return the length of the gap
+func (gap Gap) Duration() time.Duration {
+	return gap.End - gap.Start
+}
+
+// Gaps returns the pauses of at least shortest between segments in order of
+// time, including a pause before the first segment, so that an editor can
+// show them or tighten the timings of subtitles without the audio. The
+// timestamps of the segments must be relative to the start of the audio,
+// and segments which overlap have no gap between them.
+func Gaps(segments []Segment, shortest time.Duration) []Gap {
+	var gaps []Gap
+	var end time.Duration
+	for i, segment := range segments {
+		if segment.Start-end >= max(shortest, 1) {
+			gaps = append(gaps, Gap{Start: end, End: segment.Start, Before: i})
+		}
+		end = max(end, segment.End)
+	}
+	return gaps
+}
@@ -0,0 +1,33 @@
+package whisper_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ggerganov/whisper.cpp/bindings/go/pkg/whisper"
+	assert "github.com/stretchr/testify/assert"
+)
+
+func TestGaps(t *testing.T) {
+	assert := assert.New(t)
+
+	segments := []whisper.Segment{
+		{Start: time.Second, End: 3 * time.Second},
+		{Start: 3 * time.Second, End: 5 * time.Second},
+		{Start: 5200 * time.Millisecond, End: 8 * time.Second},
+		{Start: 7 * time.Second, End: 9 * time.Second},
+		{Start: 12 * time.Second, End: 13 * time.Second},
+	}
+
+	// Pauses shorter than the minimum and overlapping segments have no gap
+	gaps := whisper.Gaps(segments, 500*time.Millisecond)
+	assert.Equal([]whisper.Gap{
+		{Start: 0, End: time.Second, Before: 0},
+		{Start: 9 * time.Second, End: 12 * time.Second, Before: 4},
+	}, gaps)
+	assert.Equal(3*time.Second, gaps[1].Duration())
+
+	// Without a minimum, every pause is a gap
+	assert.Len(whisper.Gaps(segments, 0), 3)
+	assert.Empty(whisper.Gaps(nil, 0))
+}
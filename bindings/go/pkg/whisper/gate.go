@@ -0,0 +1,86 @@
+package whisper
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// All contexts created from a model share the native decoding state, so
+// only one of them can process audio at a time. The gate rejects a call to
+// Process while another context holds the model.
+type gate struct {
+	sync.Mutex
+	held   bool
+	holder string
+	since  time.Time
+	stats  GateStats
+}
+
+// GateStats reports how the contexts of a model contend for it
+type GateStats struct {
+	Acquired uint64        // Number of calls to Process which acquired the model
+	Rejected uint64        // Number of calls to Process rejected as busy
+	Busy     time.Duration // Total time the model has been held
+	Holder   string        // Label of the context currently holding the model
+	Since    time.Time     // Time at which the current holder acquired the model
+}
+
+// BusyError is returned when the model is held by another context. It
+// matches ErrStatelessBusy with errors.Is
+type BusyError struct {
+	Holder string    // Label of the context holding the model
+	Since  time.Time // Time at which the holder acquired the model
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+func (err *BusyError) Error() string {
+	return fmt.Sprintf("%v: held by %q since %v", ErrStatelessBusy, err.Holder, err.Since.Format(time.RFC3339Nano))
+}
+
+func (err *BusyError) Unwrap() error {
+	return ErrStatelessBusy
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+func (gate *gate) acquire(label string) error {
+	gate.Lock()
+	defer gate.Unlock()
+	if gate.held {
+		gate.stats.Rejected++
+		return &BusyError{Holder: gate.holder, Since: gate.since}
+	}
+	gate.held = true
+	gate.holder = label
+	gate.since = time.Now()
+	gate.stats.Acquired++
+	return nil
+}
+
+func (gate *gate) release() {
+	gate.Lock()
+	defer gate.Unlock()
+	gate.stats.Busy += time.Since(gate.since)
+	gate.held = false
+	gate.holder = ""
+	gate.since = time.Time{}
+}
+
+func (gate *gate) Stats() GateStats {
+	gate.Lock()
+	defer gate.Unlock()
+	stats := gate.stats
+	stats.Holder = gate.holder
+	stats.Since = gate.since
+	if gate.held {
+		stats.Busy += time.Since(gate.since)
+	}
+	return stats
+}
@@ -0,0 +1,231 @@
+package whisper_test
+
+import (
+	"encoding/json"
+	"flag"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ggerganov/whisper.cpp/bindings/go/pkg/whisper"
+	assert "github.com/stretchr/testify/assert"
+)
+
+// The golden corpus is a set of public domain recordings, which are
+// transcribed with parameter presets and compared with the output recorded
+// for the model. It is run when WHISPER_GOLDEN is the directory with the
+// recordings of testdata/golden/corpus.json as 16kHz mono wav files. The
+// model is WHISPER_GOLDEN_MODEL, or the test model if not set.
+//
+// The output for a model is recorded in testdata/golden/<model> by running
+// the suite with -whisper.golden.update, and should be checked in when the
+// native library is updated and the differences have been reviewed.
+const (
+	goldenDir    = "testdata/golden"
+	goldenCorpus = goldenDir + "/corpus.json"
+)
+
+var goldenUpdate = flag.Bool("whisper.golden.update", false, "record the output of the golden corpus")
+
+// The recordings of the corpus, and the tolerances when comparing with the
+// golden output
+type goldenManifest struct {
+	Tolerance  goldenTolerance   `json:"tolerance"`
+	Recordings []goldenRecording `json:"recordings"`
+}
+
+type goldenTolerance struct {
+	WER     float64 `json:"wer"`     // Word error rate against the golden text
+	Time    float64 `json:"time"`    // Difference in the start of a segment, in seconds
+	Aligned float64 `json:"aligned"` // Fraction of golden segments which start within Time of a segment
+}
+
+type goldenRecording struct {
+	Name    string   `json:"name"`
+	File    string   `json:"file"`
+	Source  string   `json:"source"`
+	License string   `json:"license"`
+	Presets []string `json:"presets"`
+	WER     float64  `json:"wer,omitempty"` // Word error rate for this recording, if it is harder
+}
+
+// The recorded output of a recording with a preset
+type goldenOutput struct {
+	Model    string          `json:"model"`
+	Segments []goldenSegment `json:"segments"`
+}
+
+type goldenSegment struct {
+	Start float64 `json:"start"`
+	End   float64 `json:"end"`
+	Text  string  `json:"text"`
+}
+
+// Parameter presets of the corpus
+var goldenPresets = map[string]func(whisper.Context){
+	"greedy": func(whisper.Context) {},
+	"beam": func(context whisper.Context) {
+		context.SetBeamSize(5)
+	},
+	"split": func(context whisper.Context) {
+		context.SetTokenTimestamps(true)
+		context.SetMaxSegmentLength(40)
+		context.SetSplitOnWord(true)
+	},
+}
+
+// Return the name of the model the output is recorded for, such as
+// "small.en-f16"
+func goldenModel(t *testing.T, path string) string {
+	header, err := whisper.InspectModel(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	name := header.Type
+	if !header.Multilingual {
+		name += ".en"
+	}
+	return name + "-" + header.FileType.String()
+}
+
+func TestGoldenCorpus(t *testing.T) {
+	dir := os.Getenv("WHISPER_GOLDEN")
+	if dir == "" {
+		t.Skip("Skipping golden corpus, WHISPER_GOLDEN is not set")
+	}
+	path := os.Getenv("WHISPER_GOLDEN_MODEL")
+	if path == "" {
+		path = ModelPath
+	}
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		t.Skip("Skipping golden corpus, model not found:", path)
+	}
+	data, err := os.ReadFile(goldenCorpus)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var manifest goldenManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		t.Fatal(err)
+	}
+
+	name := goldenModel(t, path)
+	model, err := whisper.New(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer model.Close()
+	for _, recording := range manifest.Recordings {
+		for _, preset := range recording.Presets {
+			t.Run(recording.Name+"/"+preset, func(t *testing.T) {
+				apply, exists := goldenPresets[preset]
+				if !exists {
+					t.Fatalf("unknown preset %q", preset)
+				}
+				samples := loadSamples(t, filepath.Join(dir, recording.File))
+				context, err := model.NewContext()
+				if err != nil {
+					t.Fatal(err)
+				}
+				apply(context)
+				start := time.Now()
+				if err := context.Process(samples, nil, nil, nil); err != nil {
+					t.Fatal(err)
+				}
+				segments := collectSegments(t, context)
+				t.Logf("Transcribed %v of audio in %v", time.Duration(len(samples))*time.Second/whisper.SampleRate, time.Since(start).Truncate(time.Millisecond))
+
+				file := filepath.Join(goldenDir, name, recording.Name+"."+preset+".json")
+				if *goldenUpdate {
+					writeGolden(t, file, name, segments)
+					return
+				}
+				golden := readGolden(t, file)
+				compareGolden(t, manifest.Tolerance, recording, golden, segments)
+			})
+		}
+	}
+}
+
+func collectSegments(t *testing.T, context whisper.Context) []whisper.Segment {
+	var segments []whisper.Segment
+	for {
+		segment, err := context.NextSegment()
+		if err == io.EOF {
+			return segments
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		segments = append(segments, segment)
+	}
+}
+
+func readGolden(t *testing.T, file string) goldenOutput {
+	var golden goldenOutput
+	data, err := os.ReadFile(file)
+	if os.IsNotExist(err) {
+		t.Skip("Skipping, no golden output, run with -whisper.golden.update to record it:", file)
+	} else if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(data, &golden); err != nil {
+		t.Fatal(err)
+	}
+	return golden
+}
+
+func writeGolden(t *testing.T, file, model string, segments []whisper.Segment) {
+	golden := goldenOutput{Model: model, Segments: make([]goldenSegment, 0, len(segments))}
+	for _, segment := range segments {
+		golden.Segments = append(golden.Segments, goldenSegment{
+			Start: segment.Start.Seconds(),
+			End:   segment.End.Seconds(),
+			Text:  segment.Text,
+		})
+	}
+	data, err := json.MarshalIndent(golden, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(file, append(data, '\n'), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Log("Wrote", file)
+}
+
+// Compare the text of the segments by word error rate, and their timing by
+// the fraction of golden segments which start close to a segment
+func compareGolden(t *testing.T, tolerance goldenTolerance, recording goldenRecording, golden goldenOutput, segments []whisper.Segment) {
+	assert := assert.New(t)
+	text := make([]string, 0, len(golden.Segments))
+	for _, segment := range golden.Segments {
+		text = append(text, segment.Text)
+	}
+	wer := tolerance.WER
+	if recording.WER > 0 {
+		wer = recording.WER
+	}
+	report := whisper.Calibrate([]whisper.CalibrationSample{{Segments: segments, Reference: strings.Join(text, " ")}}, 1)
+	assert.LessOrEqual(report.WER, wer, "word error rate against %s", golden.Model)
+
+	aligned := 0
+	for _, want := range golden.Segments {
+		for _, segment := range segments {
+			if math.Abs(segment.Start.Seconds()-want.Start) <= tolerance.Time {
+				aligned++
+				break
+			}
+		}
+	}
+	if len(golden.Segments) > 0 {
+		fraction := float64(aligned) / float64(len(golden.Segments))
+		assert.GreaterOrEqual(fraction, tolerance.Aligned, "%d of %d segments aligned with %s", aligned, len(golden.Segments), golden.Model)
+	}
+}
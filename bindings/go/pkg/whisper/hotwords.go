@@ -0,0 +1,65 @@
+package whisper
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	// Bindings
+	whisper "github.com/ggerganov/whisper.cpp/bindings/go"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Boost the tokens of phrases during decoding. Each phrase is tokenized both
+// as it is and with a leading space, which is how words after the first one
+// in a segment are tokenized.
+func (context *context) SetHotwords(phrases []string, boost float32) error {
+	if math.IsNaN(float64(boost)) || math.IsInf(float64(boost), 0) {
+		return fmt.Errorf("%w: hotword boost %v", ErrInvalidParams, boost)
+	}
+	ctx := context.rlock()
+	if ctx == nil {
+		return ErrInternalAppError
+	}
+	defer context.model.runlock()
+
+	// Tokenize the phrases
+	hotwords := make(map[whisper.Token]float32)
+	for _, phrase := range phrases {
+		phrase = strings.TrimSpace(phrase)
+		if phrase == "" {
+			continue
+		}
+		for _, text := range []string{phrase, " " + phrase} {
+			tokens, err := tokenize(ctx, text)
+			if err != nil {
+				return fmt.Errorf("%w: %q", err, phrase)
+			}
+			for _, token := range tokens {
+				hotwords[token] = boost
+			}
+		}
+	}
+
+	// Apply the bias
+	context.paramsMu.Lock()
+	defer context.paramsMu.Unlock()
+	context.hotwords = hotwords
+	context.applyBias()
+	return nil
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// Return the tokens of text. There are never more tokens than bytes.
+func tokenize(ctx *whisper.Context, text string) ([]whisper.Token, error) {
+	tokens := make([]whisper.Token, len(text)+1)
+	n, err := ctx.Whisper_tokenize(text, tokens)
+	if err != nil {
+		return nil, err
+	}
+	return tokens[:n], nil
+}
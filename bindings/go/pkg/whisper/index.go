@@ -0,0 +1,147 @@
+package whisper
+
+import (
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// Occurrence is a place in a transcript where a word was said
+type Occurrence struct {
+	Segment    int           // Number of the segment containing the word
+	Start, End time.Duration // Time span of the word within the audio
+}
+
+// Index is an inverted index from the words of a transcript to the times at
+// which they were said, for finding where a word occurs in the audio
+type Index struct {
+	words map[string][]Occurrence
+}
+
+// A word of a segment, joined from its tokens
+type word struct {
+	text       string
+	start, end time.Duration
+	p          float32 // Lowest probability of the tokens of the word
+	pt, ptsum  float32 // Lowest timestamp confidence of the tokens of the word
+	tokens     bool    // True if the word was joined from tokens
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// LIFECYCLE
+
+// NewIndex builds an index over the words of the segments. Word times are
+// taken from the tokens of each segment, so token timestamps should be
+// enabled for accurate results. Segments without tokens are indexed with
+// the time span of the whole segment.
+func NewIndex(segments []Segment) *Index {
+	index := &Index{words: make(map[string][]Occurrence)}
+	for _, segment := range segments {
+		for _, word := range segmentWords(segment) {
+			index.add(word.text, Occurrence{Segment: segment.Num, Start: word.start, End: word.end})
+		}
+	}
+	return index
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Return the occurrences of a word in time order. The lookup ignores case
+// and leading or trailing punctuation.
+func (index *Index) Lookup(word string) []Occurrence {
+	return index.words[normalizeWord(word)]
+}
+
+// Return the words of the segment. Tokens are joined into words, where a
+// token starting with a space begins a new word, and special tokens are
+// skipped. A segment without tokens is split on whitespace, and each word
+// has the time span of the whole segment.
+func (segment Segment) Words() []Word {
+	words := segmentWords(segment)
+	result := make([]Word, len(words))
+	for i, w := range words {
+		result[i] = Word{Text: strings.TrimSpace(w.text), Start: w.start, End: w.end, P: w.p, Pt: w.pt, PtSum: w.ptsum}
+	}
+	return result
+}
+
+// Return all words in the index in sorted order
+func (index *Index) Words() []string {
+	result := make([]string, 0, len(index.words))
+	for word := range index.words {
+		result = append(result, word)
+	}
+	sort.Strings(result)
+	return result
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+func (index *Index) add(word string, occurrence Occurrence) {
+	if word = normalizeWord(word); word == "" {
+		return
+	}
+	occurrences := index.words[word]
+	i := sort.Search(len(occurrences), func(i int) bool {
+		return occurrences[i].Start > occurrence.Start
+	})
+	occurrences = append(occurrences, Occurrence{})
+	copy(occurrences[i+1:], occurrences[i:])
+	occurrences[i] = occurrence
+	index.words[word] = occurrences
+}
+
+// Return the words of a segment. Tokens are joined into words, where a token
+// starting with a space begins a new word. Segments without tokens are split
+// on whitespace, and each word has the time span of the whole segment.
+func segmentWords(segment Segment) []word {
+	var result []word
+	if len(segment.Tokens) == 0 {
+		for _, text := range strings.Fields(segment.Text) {
+			result = append(result, word{text: text, start: segment.Start, end: segment.End})
+		}
+		return result
+	}
+	var text strings.Builder
+	var current word
+	for _, token := range segment.Tokens {
+		if isSpecialText(token.Text) {
+			continue
+		}
+		if text.Len() > 0 && strings.HasPrefix(token.Text, " ") {
+			current.text = text.String()
+			result = append(result, current)
+			text.Reset()
+		}
+		if text.Len() == 0 {
+			current = word{start: token.Start, p: token.P, pt: token.Pt, ptsum: token.PtSum, tokens: true}
+		}
+		text.WriteString(token.Text)
+		current.end = token.End
+		current.p = min(current.p, token.P)
+		current.pt = min(current.pt, token.Pt)
+		current.ptsum = min(current.ptsum, token.PtSum)
+	}
+	if text.Len() > 0 {
+		current.text = text.String()
+		result = append(result, current)
+	}
+	return result
+}
+
+func normalizeWord(word string) string {
+	return strings.ToLower(strings.TrimFunc(word, func(r rune) bool {
+		return unicode.IsSpace(r) || unicode.IsPunct(r)
+	}))
+}
+
+// Special tokens such as [_BEG_] and <|endoftext|> have bracketed text
+func isSpecialText(text string) bool {
+	return strings.HasPrefix(text, "[_") || strings.HasPrefix(text, "<|")
+}
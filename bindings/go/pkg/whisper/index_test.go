@@ -0,0 +1,59 @@
+package whisper_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ggerganov/whisper.cpp/bindings/go/pkg/whisper"
+	assert "github.com/stretchr/testify/assert"
+)
+
+func TestIndex(t *testing.T) {
+	assert := assert.New(t)
+
+	segments := []whisper.Segment{
+		{Num: 0, Start: 0, End: 2 * time.Second, Text: "Hello, world.", Tokens: []whisper.Token{
+			{Text: "[_BEG_]"},
+			{Text: " Hello", Start: 0, End: 500 * time.Millisecond},
+			{Text: ",", Start: 500 * time.Millisecond, End: 600 * time.Millisecond},
+			{Text: " wor", Start: 700 * time.Millisecond, End: 900 * time.Millisecond},
+			{Text: "ld.", Start: 900 * time.Millisecond, End: 1200 * time.Millisecond},
+			{Text: "[_TT_100]"},
+		}},
+		{Num: 1, Start: 2 * time.Second, End: 4 * time.Second, Text: "The world is round"},
+	}
+
+	index := whisper.NewIndex(segments)
+	assert.Equal([]string{"hello", "is", "round", "the", "world"}, index.Words())
+	assert.Equal([]whisper.Occurrence{{Segment: 0, Start: 0, End: 600 * time.Millisecond}}, index.Lookup("HELLO!"))
+	assert.Equal([]whisper.Occurrence{
+		{Segment: 0, Start: 700 * time.Millisecond, End: 1200 * time.Millisecond},
+		{Segment: 1, Start: 2 * time.Second, End: 4 * time.Second},
+	}, index.Lookup("world"))
+	assert.Nil(index.Lookup("missing"))
+}
+
+func TestSegmentWords(t *testing.T) {
+	assert := assert.New(t)
+
+	segment := whisper.Segment{Start: 0, End: 2 * time.Second, Text: "Hello, world.", Tokens: []whisper.Token{
+		{Text: "[_BEG_]", P: 0.1, Pt: 0.1, PtSum: 0.1},
+		{Text: " Hello", P: 0.9, Pt: 0.8, PtSum: 0.9, Start: 0, End: 500 * time.Millisecond},
+		{Text: ",", P: 0.7, Pt: 0.6, PtSum: 0.95, Start: 500 * time.Millisecond, End: 600 * time.Millisecond},
+		{Text: " wor", P: 0.9, Pt: 0.2, PtSum: 0.4, Start: 700 * time.Millisecond, End: 900 * time.Millisecond},
+		{Text: "ld.", P: 0.8, Pt: 0.9, PtSum: 0.9, Start: 900 * time.Millisecond, End: 1200 * time.Millisecond},
+	}}
+
+	// The confidence of a word is that of its weakest token
+	assert.Equal([]whisper.Word{
+		{Text: "Hello,", Start: 0, End: 600 * time.Millisecond, P: 0.7, Pt: 0.6, PtSum: 0.9},
+		{Text: "world.", Start: 700 * time.Millisecond, End: 1200 * time.Millisecond, P: 0.8, Pt: 0.2, PtSum: 0.4},
+	}, segment.Words())
+
+	// Without tokens, words span the segment and have no confidence
+	segment.Tokens = nil
+	assert.Equal([]whisper.Word{
+		{Text: "Hello,", Start: 0, End: 2 * time.Second},
+		{Text: "world.", Start: 0, End: 2 * time.Second},
+	}, segment.Words())
+}
@@ -0,0 +1,183 @@
+package whisper
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+
+	// Bindings
+	whisper "github.com/ggerganov/whisper.cpp/bindings/go"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// FileType is the type of the weights of a model file, which is either a
+// floating point type or a quantization
+type FileType int32
+
+// ModelHeader describes a model file as read from its header, without
+// loading the weights of the model
+type ModelHeader struct {
+	Path string // Path of the model file
+	Size int64  // Size of the model file in bytes
+
+	// Model type such as "small" or "large-v3", or empty if the model has an
+	// unknown number of layers
+	Type string
+
+	// True if the model is multilingual, and the languages it recognizes
+	Multilingual bool
+	Languages    []string
+
+	// Type of the weights, and the version of the quantization format
+	FileType            FileType
+	QuantizationVersion int
+
+	// Hyperparameters of the model
+	Vocab                                       int
+	AudioCtx, AudioState, AudioHead, AudioLayer int
+	TextCtx, TextState, TextHead, TextLayer     int
+	Mels                                        int
+}
+
+// The header of a model file, before the mel filters and vocabulary
+type modelFileHeader struct {
+	Magic                                       uint32
+	Vocab                                       int32
+	AudioCtx, AudioState, AudioHead, AudioLayer int32
+	TextCtx, TextState, TextHead, TextLayer     int32
+	Mels                                        int32
+	Ftype                                       int32
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+const (
+	FileTypeF32  FileType = 0
+	FileTypeF16  FileType = 1
+	FileTypeQ4_0 FileType = 2
+	FileTypeQ4_1 FileType = 3
+	FileTypeQ8_0 FileType = 7
+	FileTypeQ5_0 FileType = 8
+	FileTypeQ5_1 FileType = 9
+	FileTypeQ2_K FileType = 10
+	FileTypeQ3_K FileType = 11
+	FileTypeQ4_K FileType = 12
+	FileTypeQ5_K FileType = 13
+	FileTypeQ6_K FileType = 14
+)
+
+const (
+	ggmlFileMagic        = 0x67676d6c // "ggml"
+	ggmlQntVersionFactor = 1000
+	vocabMultilingual    = 51865 // Smallest vocabulary of a multilingual model
+	vocabLargeV3         = 51866
+)
+
+var fileTypeNames = map[FileType]string{
+	FileTypeF32:  "f32",
+	FileTypeF16:  "f16",
+	FileTypeQ4_0: "q4_0",
+	FileTypeQ4_1: "q4_1",
+	FileTypeQ8_0: "q8_0",
+	FileTypeQ5_0: "q5_0",
+	FileTypeQ5_1: "q5_1",
+	FileTypeQ2_K: "q2_k",
+	FileTypeQ3_K: "q3_k",
+	FileTypeQ4_K: "q4_k",
+	FileTypeQ5_K: "q5_k",
+	FileTypeQ6_K: "q6_k",
+}
+
+// Model types by the number of audio layers
+var modelTypes = map[int]string{
+	4:  "tiny",
+	6:  "base",
+	12: "small",
+	24: "medium",
+	32: "large",
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (t FileType) String() string {
+	if name, exists := fileTypeNames[t]; exists {
+		return name
+	}
+	return fmt.Sprintf("ftype(%d)", int32(t))
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Return true if the weights of the model are quantized
+func (t FileType) IsQuantized() bool {
+	return t != FileTypeF32 && t != FileTypeF16
+}
+
+// InspectModel reads the header of a model file, so that the type, languages
+// and quantization of a model can be shown before it is loaded. Only the
+// first bytes of the file are read, and no memory is allocated for the
+// weights. An error wrapping ErrUnableToLoadModel is returned if the file is
+// not a model.
+func InspectModel(path string) (ModelHeader, error) {
+	header := ModelHeader{Path: path}
+	fh, err := os.Open(path)
+	if err != nil {
+		return header, err
+	}
+	defer fh.Close()
+	info, err := fh.Stat()
+	if err != nil {
+		return header, err
+	}
+	header.Size = info.Size()
+
+	var hdr modelFileHeader
+	if err := binary.Read(fh, binary.LittleEndian, &hdr); err == io.EOF || err == io.ErrUnexpectedEOF {
+		return header, fmt.Errorf("%w: %s: truncated header", ErrUnableToLoadModel, path)
+	} else if err != nil {
+		return header, err
+	}
+	if hdr.Magic != ggmlFileMagic {
+		return header, fmt.Errorf("%w: %s: bad magic", ErrUnableToLoadModel, path)
+	}
+	return header.fill(hdr), nil
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// Set the fields of the description from the header of the file, in the
+// same way as whisper_model_load
+func (header ModelHeader) fill(hdr modelFileHeader) ModelHeader {
+	header.Vocab = int(hdr.Vocab)
+	header.AudioCtx, header.AudioState = int(hdr.AudioCtx), int(hdr.AudioState)
+	header.AudioHead, header.AudioLayer = int(hdr.AudioHead), int(hdr.AudioLayer)
+	header.TextCtx, header.TextState = int(hdr.TextCtx), int(hdr.TextState)
+	header.TextHead, header.TextLayer = int(hdr.TextHead), int(hdr.TextLayer)
+	header.Mels = int(hdr.Mels)
+	header.FileType = FileType(hdr.Ftype % ggmlQntVersionFactor)
+	header.QuantizationVersion = int(hdr.Ftype / ggmlQntVersionFactor)
+
+	header.Type = modelTypes[header.AudioLayer]
+	if header.Type == "large" && header.Vocab == vocabLargeV3 {
+		header.Type = "large-v3"
+	}
+
+	// The language tokens follow the end of text token in the vocabulary
+	header.Multilingual = header.Vocab >= vocabMultilingual
+	if !header.Multilingual {
+		header.Languages = []string{"en"}
+		return header
+	}
+	n := min(header.Vocab-vocabMultilingual+99, whisper.Whisper_lang_max_id()+1)
+	for i := 0; i < n; i++ {
+		header.Languages = append(header.Languages, whisper.Whisper_lang_str(i))
+	}
+	return header
+}
@@ -20,8 +20,22 @@ type ProgressCallback func(int)
 // continue processing. It is called during the Process function
 type EncoderBeginCallback func() bool
 
+// LogitsFilterCallback is called before each token is sampled, with the token
+// ids of the current sequence and the logits of the vocabulary, which it can
+// modify in place. It is called during the Process function
+type LogitsFilterCallback func(tokens []int, logits []float32)
+
+// SeekCallback is called before each window is encoded, with the offset of
+// the window within the audio, and returns the offset to continue from. An
+// offset later than the window skips ahead, for example over music which a
+// classifier has found, and any other offset encodes the window. It is
+// called during the Process function
+type SeekCallback func(window time.Duration) time.Duration
+
 // Model is the interface to a whisper model. Create a new model with the
-// function whisper.New(string)
+// function whisper.New(string). Models returned by this package also
+// implement the optional interfaces below, which callers check for with a
+// type assertion.
 type Model interface {
 	io.Closer
 
@@ -35,7 +49,101 @@ type Model interface {
 	Languages() []string
 }
 
-// Context is the speech recognition context.
+// StatefulModel returns contexts with their own decoding state
+type StatefulModel interface {
+	// Return a new speech-to-text context with its own decoding state, which
+	// can process at the same time as other contexts. The context must be
+	// closed to free the state.
+	NewStatefulContext() (Context, error)
+}
+
+// ModelDescriber describes a loaded model
+type ModelDescriber interface {
+	// Return the maximum number of tokens of previous text a context can
+	// use as the prompt of each window, which is half the text context.
+	MaxContext() int
+
+	// Return the type of the weights of the model, and the number and size
+	// of the weight tensors of each type.
+	Quantization() Quantization
+
+	// Return warnings from loading the model, such as a *FallbackWarning
+	// when the model was loaded on the CPU because the GPU failed.
+	Warnings() []error
+
+	// Return true if the encoder runs with Core ML.
+	UsesCoreML() bool
+
+	// Return statistics on contention for the model between its contexts.
+	GateStats() GateStats
+}
+
+// Warmer brings a model into service before the first request
+type Warmer interface {
+	// Process a short silence so that the first request does not pay for
+	// backend initialisation, and return the latency of bringing the model
+	// into service.
+	Warmup() error
+	ColdStart() ColdStartStats
+}
+
+// Yielder gives the device back to the host application
+type Yielder interface {
+	// Pause processing at the end of the current window and free the
+	// compute buffers, keeping the weights. Resume continues processing.
+	YieldGPU() error
+	Resume()
+}
+
+// AdapterModel loads adapter weights into a model
+type AdapterModel interface {
+	// Load adapter weights into the model with the registered AdapterLoader.
+	LoadAdapter(path string) error
+}
+
+// MemoryReporter reports the native memory of a model or context
+type MemoryReporter interface {
+	// Return the memory on each backend. For a model, this is the memory of
+	// the weights, and of the KV caches and compute buffers of the state
+	// shared by contexts from NewContext. For a context, it is the memory
+	// of the KV caches and compute buffers of its decoding state.
+	MemoryUsage() MemoryUsage
+}
+
+// RawAccessor returns the native handles of a model or context
+type RawAccessor interface {
+	// Return a handle to the native whisper context of the model, and the
+	// decoding state of a stateful context, which keeps the model open and
+	// holds the context until the handle is released.
+	UnsafeRaw() (*RawHandle, error)
+}
+
+// Processor processes audio data. Context is a Processor.
+type Processor interface {
+	Process([]float32, EncoderBeginCallback, SegmentCallback, ProgressCallback) error
+}
+
+// SegmentSource returns the segments of the last call to Process until the
+// end of the stream is reached, when io.EOF is returned. Context is a
+// SegmentSource.
+type SegmentSource interface {
+	NextSegment() (Segment, error)
+}
+
+// Transcriber processes audio data and returns its segments. Functions which
+// only transcribe accept a Transcriber, so that they can be tested with a
+// fake in place of a model.
+type Transcriber interface {
+	Processor
+	SegmentSource
+}
+
+// Context is the speech recognition context. It is also a Transcriber.
+// Contexts returned by this package also implement the optional interfaces
+// below, which callers check for with a type assertion, and io.Closer, which
+// frees the decoding state of a stateful context, and the parameters of any
+// context. Contexts which are not closed free their parameters when they are
+// garbage collected.
 type Context interface {
 	SetLanguage(string) error // Set the language to use for speech recognition, use "auto" for auto detect language.
 	SetTranslate(bool)        // Set translate flag
@@ -71,7 +179,10 @@ type Context interface {
 
 	// Process mono audio data and return any errors.
 	// If defined, newly generated segments are passed to the
-	// callback function during processing.
+	// callback function during processing. If another context of the same
+	// model is processing, a *BusyError is returned. The parameters are
+	// copied when processing starts, so setters called while processing
+	// take effect on the next call.
 	Process([]float32, EncoderBeginCallback, SegmentCallback, ProgressCallback) error
 
 	// After process is called, return segments until the end of the stream
@@ -94,6 +205,145 @@ type Context interface {
 	SystemInfo() string
 }
 
+// Configurable is the part of a context which configures the language and
+// resources of processing, and checks the configuration against the model.
+type Configurable interface {
+	SetLanguage(string) error
+	SetTranslate(bool)
+	SetThreads(uint)
+
+	// Check the parameters against the model before processing, returning
+	// an error for each invalid combination of settings. ValidateOptions
+	// checks options against a model before a context is kept.
+	Validate() error
+}
+
+// Labeled contexts are identified by a label in errors
+type Labeled interface {
+	SetLabel(string) // Set the label which identifies the context in errors
+	Label() string   // Get the label which identifies the context
+}
+
+// LanguageDetector detects the spoken language of the audio
+type LanguageDetector interface {
+	// Set detect language only flag, the result is returned by
+	// DetectedLanguage
+	SetDetectLanguage(bool)
+
+	// After process is called, detect the spoken language from the start of
+	// the audio and return the n most likely languages, most likely first.
+	// All languages are returned when n is zero or less.
+	DetectLanguageTop(n int) ([]LanguageProb, error)
+}
+
+// DecodingConfigurable sets the parameters of decoding which are not part of
+// Context
+type DecodingConfigurable interface {
+	SetThreadsAuto()                      // Set recommended number of threads for the backend
+	SetNoTimestamps(bool)                 // Set no timestamps flag, for text only output
+	MaxContext() int                      // Get maximum number of text context tokens used
+	SetPatience(t float32)                // Set beam search patience
+	SetLengthPenalty(t float32)           // Set beam search length penalty
+	SetBestOf(n int)                      // Set number of candidates when sampling
+	SetLogprobThold(t float32)            // Set average log probability threshold
+	SetNoSpeechThold(t float32)           // Set no speech probability threshold
+	SetMaxInitialTimestamp(time.Duration) // Set the latest time the first segment of each window may start at
+
+	// Set the maximum duration of a segment, for subtitles, splitting longer
+	// segments on word boundaries (0 = no limit)
+	SetMaxSegmentDuration(time.Duration)
+
+	// Set the initial prompt as token ids, for example from the tokens of a
+	// previous segment, in place of the prompt set with SetInitialPrompt.
+	// Returns ErrInvalidToken if a token is outside the vocabulary.
+	SetInitialPromptTokens([]int) error
+
+	// Set an explicit temperature fallback schedule, used on entropy and
+	// log probability failures in place of SetTemperatureFallback.
+	SetTemperatureSchedule([]float32) error
+}
+
+// TokenSuppressor prevents tokens from being sampled
+type TokenSuppressor interface {
+	SetSuppressBlank(bool)           // Set suppress blank outputs flag
+	SetSuppressNonSpeechTokens(bool) // Set suppress non-speech tokens flag
+	SetSuppressRegex(string)         // Set regular expression matching tokens to suppress
+
+	// Suppress the tokens with the given texts, in place of the stock list
+	// of SetSuppressNonSpeechTokens, which is turned off. Start with
+	// NonSpeechTokens and remove "[" and "]" to keep annotations such as
+	// [music], or add tokens for the language being transcribed. Pass nil
+	// to clear. SuppressedTokens returns the ids of the tokens suppressed.
+	SetSuppressTokens(texts []string) error
+	SuppressedTokens() []int
+}
+
+// LogitsBiaser changes the logits of tokens before they are sampled
+type LogitsBiaser interface {
+	// Set a bias added to the logits of token ids during decoding, to boost
+	// (positive) or suppress (negative) product names and jargon. A bias of
+	// negative infinity prevents a token from being sampled. Returns
+	// ErrInvalidToken if a token is outside the vocabulary.
+	SetLogitBias(map[int]float32) error
+
+	// Boost the tokens of phrases, such as names and jargon, so that they
+	// are more likely to be recognized. The phrases are tokenized with the
+	// vocabulary of the model, and a bias set with SetLogitBias takes
+	// precedence for the same token. Pass nil to clear.
+	SetHotwords(phrases []string, boost float32) error
+
+	// Set a callback which can modify the logits before each token is
+	// sampled, after the logit bias is applied. Pass nil to clear.
+	SetLogitsFilterCallback(LogitsFilterCallback)
+}
+
+// ProcessHooks change how windows are chosen and how segments are repaired
+type ProcessHooks interface {
+	// Set a callback which can skip ahead before each window is encoded.
+	// Pass nil to clear.
+	SetSeekCallback(SeekCallback)
+
+	// Detect decoder repetition loops, removing their repeats from the
+	// segments and skipping past them before the next window, which drops
+	// the text context that keeps the loop going. Pass nil to disable.
+	SetRepetitionDetector(*RepetitionOptions)
+
+	// Enforce monotonic, non-overlapping segment times, moving a segment
+	// which overlaps the one before it to start with its first token. The
+	// number of segments repaired is reported in ProcessStats.
+	SetTimestampSanitizer(v bool)
+}
+
+// TextAppender appends the text of the results without allocating strings
+type TextAppender interface {
+	// Append the text of a segment, or of a token within a segment, to a
+	// buffer. The segment text has leading and trailing whitespace removed,
+	// as with Segment.Text.
+	AppendSegmentText(dst []byte, segment int) ([]byte, error)
+	AppendTokenText(dst []byte, segment, token int) ([]byte, error)
+}
+
+// StatsReporter reports the statistics of processing
+type StatsReporter interface {
+	// Return performance statistics for the last call to Process
+	Stats() ProcessStats
+}
+
+// TimingsReporter reports the native timings of processing
+type TimingsReporter interface {
+	Timings() Timings
+	WriteTimings(io.Writer) error
+}
+
+// ComputeReleaser frees the compute buffers of an idle context
+type ComputeReleaser interface {
+	// Free the compute buffers of the decoding state while the context is
+	// idle, keeping the results of the last call to Process. Reacquire
+	// allocates them again, which otherwise happens on the next Process.
+	ReleaseComputeBuffers() error
+	Reacquire() error
+}
+
 // Segment is the text result of a speech recognition.
 type Segment struct {
 	// Segment Number
@@ -107,6 +357,32 @@ type Segment struct {
 
 	// The tokens of the segment.
 	Tokens []Token
+
+	// Sequence number of the segment, which increases across calls to
+	// Process on the same context, so that segments from several sources
+	// can be ordered. A segment has the same number whether it is passed
+	// to the callback or returned by NextSegment.
+	Seq uint64
+
+	// Start of the window of audio which produced the segment, relative to
+	// the start of the audio data
+	Window time.Duration
+	// Non-speech events annotated in the text, such as [Music] or
+	// (applause), with the time span of their tokens
+	Events []AudioEvent
+
+	// Language the call to Process which produced the segment decoded in,
+	// such as "en", which is the language set on the context or, when it is
+	// auto, the language detected once from the start of the audio. The
+	// language is not detected for each segment, so audio which switches
+	// language within one call is reported in the language it starts in.
+	// Process regions or utterances in separate calls to index the language
+	// of each.
+	ProcessLanguage string
+
+	// ISO 15924 code of the script the text is written in, as returned by
+	// DetectScript, such as "Latn" or "Cyrl"
+	Script string
 }
 
 // Token is a text or special token
@@ -115,4 +391,76 @@ type Token struct {
 	Text       string
 	P          float32
 	Start, End time.Duration
+
+	// Probability of the most likely timestamp token at the position of the
+	// token, and the sum of the probabilities of all timestamp tokens, which
+	// measure the confidence in the timing of the token
+	Pt, PtSum float32
+
+	// Time of the token from DTW, which is more accurate than Start and End
+	// when DTW is enabled with SetDTWTokenTimestamps, and negative otherwise
+	DTW time.Duration
+}
+
+// Word is a word of a segment, joined from its tokens
+type Word struct {
+	Text       string
+	Start, End time.Duration
+
+	// Lowest probability of the tokens of the word
+	P float32
+
+	// Lowest timestamp confidence of the tokens of the word, as in Token.
+	// Consumers can hide the timing of words with low confidence. Both are
+	// zero when the segment has no tokens.
+	Pt, PtSum float32
+}
+
+// LanguageProb is a candidate language returned by DetectLanguageTop
+type LanguageProb struct {
+	Code string  // Language id, such as "en"
+	Name string  // Language name in English, such as "english"
+	P    float32 // Probability of the language
+}
+
+// ProcessStats contains performance statistics for a call to Process
+type ProcessStats struct {
+	// Duration of the input audio
+	Audio time.Duration
+
+	// Span of the input which was processed, starting at Offset. This takes
+	// into account the offset and duration parameters, and processing which
+	// was aborted early by the encoder begin callback.
+	Offset, Processed time.Duration
+
+	// Duration of audio within the processed span which was passed to the
+	// model. This is less than Processed when VAD removed silence.
+	Speech time.Duration
+
+	// True if processing was aborted by the encoder begin callback
+	Aborted bool
+
+	// Audio which the seek callback skipped over
+	Skipped time.Duration
+
+	// Repetition loops found by the repetition detector, the tokens which
+	// were removed as repeats, and the windows which skipped past a loop
+	Repetitions, RepeatedTokens, Reseeks int
+
+	// Segments whose times were repaired by the timestamp sanitizer
+	TimestampRepairs int
+
+	// Wall clock time spent processing
+	Wall time.Duration
+
+	// Real-time factor, the ratio of wall clock time to processed duration
+	RTF float64
+
+	// Number of text tokens generated, without timestamp and other special
+	// tokens, and text tokens generated per second
+	Tokens          int
+	TokensPerSecond float64
+
+	// Number of segments produced
+	Segments int
 }
@@ -0,0 +1,140 @@
+package whisper
+
+import (
+	"strings"
+
+	// Bindings
+	whisper "github.com/ggerganov/whisper.cpp/bindings/go"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// LanguageInfo describes a language which whisper recognizes, for
+// presenting it in a language picker
+type LanguageInfo struct {
+	// Code of the language in whisper, such as "zh", which is returned by
+	// Model.Languages
+	Code string
+
+	// English name of the language, such as "Chinese"
+	Name string
+
+	// BCP-47 tag of the language, which differs from the code for
+	// languages whisper names differently, such as "jv" for Javanese
+	Tag string
+
+	// Variants of the language which a picker can offer, such as zh-Hans
+	// and zh-Hant for Chinese. Whisper does not distinguish them, and each
+	// is accepted by SetLanguage for the language.
+	Variants []LanguageVariant
+}
+
+// LanguageVariant is a script or regional variant of a language
+type LanguageVariant struct {
+	Tag  string // BCP-47 tag, such as "zh-Hant"
+	Name string // English name, such as "Chinese (Traditional)"
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+// Language subtags which whisper names differently, including deprecated
+// ISO 639 codes which browsers and operating systems still report
+var languageAliases = map[string]string{
+	"iw":  "he", // Hebrew
+	"in":  "id", // Indonesian
+	"ji":  "yi", // Yiddish
+	"jv":  "jw", // Javanese
+	"nb":  "no", // Norwegian Bokmål
+	"fil": "tl", // Filipino
+	"mo":  "ro", // Moldavian
+}
+
+// Codes which are not the BCP-47 tags of their languages
+var languageTags = map[string]string{
+	"jw": "jv", // Javanese
+}
+
+// English names which differ from the names of the native library
+var languageNames = map[string]string{
+	"my": "Burmese",
+	"nn": "Norwegian Nynorsk",
+	"no": "Norwegian",
+}
+
+// Variants of languages for language pickers
+var languageVariants = map[string][]LanguageVariant{
+	"en":  {{"en-US", "English (United States)"}, {"en-GB", "English (United Kingdom)"}},
+	"es":  {{"es-ES", "Spanish (Spain)"}, {"es-419", "Spanish (Latin America)"}},
+	"pa":  {{"pa-Guru", "Punjabi (Gurmukhi)"}, {"pa-Arab", "Punjabi (Shahmukhi)"}},
+	"pt":  {{"pt-BR", "Portuguese (Brazil)"}, {"pt-PT", "Portuguese (Portugal)"}},
+	"sr":  {{"sr-Cyrl", "Serbian (Cyrillic)"}, {"sr-Latn", "Serbian (Latin)"}},
+	"uz":  {{"uz-Latn", "Uzbek (Latin)"}, {"uz-Cyrl", "Uzbek (Cyrillic)"}},
+	"yue": {{"yue-Hant", "Cantonese (Traditional)"}, {"yue-Hans", "Cantonese (Simplified)"}},
+	"zh":  {{"zh-Hans", "Chinese (Simplified)"}, {"zh-Hant", "Chinese (Traditional)"}},
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Return every language which the native library recognizes, in the order
+// of their ids. Multilingual models recognize all of them, and English-only
+// models only English.
+func KnownLanguages() []LanguageInfo {
+	languages := make([]LanguageInfo, 0, whisper.Whisper_lang_max_id()+1)
+	for id := 0; id <= whisper.Whisper_lang_max_id(); id++ {
+		languages = append(languages, languageInfo(id))
+	}
+	return languages
+}
+
+// Return the language of a whisper code, a BCP-47 tag such as "pt-BR", or
+// an English name, and false if whisper does not recognize it
+func LookupLanguage(lang string) (LanguageInfo, bool) {
+	tag := normalizeLanguage(lang)
+	for id := 0; id <= whisper.Whisper_lang_max_id(); id++ {
+		if tag == whisper.Whisper_lang_str(id) || tag == whisper.Whisper_lang_str_full(id) {
+			return languageInfo(id), true
+		}
+	}
+	return LanguageInfo{}, false
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// Return the description of a language id
+func languageInfo(id int) LanguageInfo {
+	code := whisper.Whisper_lang_str(id)
+	info := LanguageInfo{Code: code, Name: languageNames[code], Tag: code, Variants: languageVariants[code]}
+	if info.Name == "" {
+		words := strings.Fields(whisper.Whisper_lang_str_full(id))
+		for i, word := range words {
+			words[i] = strings.ToUpper(word[:1]) + word[1:]
+		}
+		info.Name = strings.Join(words, " ")
+	}
+	if tag, exists := languageTags[code]; exists {
+		info.Tag = tag
+	}
+	return info
+}
+
+// Normalize a language to the form whisper recognizes. A BCP-47 tag such as
+// "pt-BR" or "zh_Hant_TW" is reduced to its primary language subtag, and
+// language names such as "Portuguese" are lower-cased. Any other input is
+// returned unchanged, so that it is reported as unsupported.
+func normalizeLanguage(lang string) string {
+	tag := strings.ToLower(strings.TrimSpace(lang))
+	if tag == "" {
+		return lang
+	}
+	if i := strings.IndexAny(tag, "-_"); i > 0 {
+		tag = tag[:i]
+	}
+	if alias, exists := languageAliases[tag]; exists {
+		return alias
+	}
+	return tag
+}
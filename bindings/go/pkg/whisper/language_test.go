@@ -0,0 +1,89 @@
+package whisper_test
+
+import (
+	"io"
+	"os"
+	"testing"
+
+	"github.com/ggerganov/whisper.cpp/bindings/go/pkg/whisper"
+	assert "github.com/stretchr/testify/assert"
+)
+
+func TestKnownLanguages(t *testing.T) {
+	assert := assert.New(t)
+
+	languages := whisper.KnownLanguages()
+	assert.Greater(len(languages), 90)
+	assert.Equal(whisper.LanguageInfo{Code: "en", Name: "English", Tag: "en", Variants: []whisper.LanguageVariant{
+		{Tag: "en-US", Name: "English (United States)"},
+		{Tag: "en-GB", Name: "English (United Kingdom)"},
+	}}, languages[0])
+	for _, language := range languages {
+		assert.NotEmpty(language.Code)
+		assert.NotEmpty(language.Name)
+	}
+
+	// Languages are found by code, tag or name
+	for _, lang := range []string{"zh", "zh-Hant", "Chinese"} {
+		language, ok := whisper.LookupLanguage(lang)
+		if assert.True(ok, lang) {
+			assert.Equal("zh", language.Code)
+			assert.Equal("zh-Hans", language.Variants[0].Tag)
+		}
+	}
+	language, ok := whisper.LookupLanguage("jv-ID")
+	assert.True(ok)
+	assert.Equal(whisper.LanguageInfo{Code: "jw", Name: "Javanese", Tag: "jv"}, language)
+	language, _ = whisper.LookupLanguage("ht")
+	assert.Equal("Haitian Creole", language.Name)
+	_, ok = whisper.LookupLanguage("klingon")
+	assert.False(ok)
+}
+
+func TestDetectScript(t *testing.T) {
+	assert := assert.New(t)
+	for text, script := range map[string]string{
+		"And so my fellow Americans":   "Latn",
+		"Привет, мир":                  "Cyrl",
+		"你好，世界":                        "Hani",
+		"こんにちは世界":                      "Jpan",
+		"안녕하세요 世界":                     "Kore",
+		"नमस्ते दुनिया":                "Deva",
+		"مرحبا بالعالم":                "Arab",
+		"Hello, Ελλάδα and more Latin": "Latn",
+		"123 ...":                      "",
+	} {
+		assert.Equal(script, whisper.DetectScript(text), text)
+	}
+}
+
+func TestSegmentLanguage(t *testing.T) {
+	assert := assert.New(t)
+	if _, err := os.Stat(ModelPath); os.IsNotExist(err) {
+		t.Skip("Skipping test, model not found:", ModelPath)
+	}
+	if _, err := os.Stat(SamplePath); os.IsNotExist(err) {
+		t.Skip("Skipping test, sample not found:", SamplePath)
+	}
+	model, err := whisper.New(ModelPath)
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+	defer model.Close()
+	context, err := model.NewContext()
+	assert.NoError(err)
+
+	// Segments have the language of the call to Process, and the script of
+	// their text
+	assert.NoError(context.Process(loadSamples(t, SamplePath), nil, nil, nil))
+	for {
+		segment, err := context.NextSegment()
+		if err == io.EOF {
+			break
+		} else if !assert.NoError(err) {
+			break
+		}
+		assert.Equal("en", segment.ProcessLanguage)
+		assert.Equal(whisper.DetectScript(segment.Text), segment.Script)
+	}
+}
@@ -0,0 +1,325 @@
+package whisper
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// ModelManager finds models by name in a directory, such as one populated
+// by the go-model-download example. It loads the models on demand for a
+// server which serves several of them, and when their memory exceeds a
+// limit, it closes the least recently used models which are not in use.
+type ModelManager struct {
+	mu     sync.Mutex
+	cond   *sync.Cond
+	dir    string
+	limit  uint64 // Resident memory of the loaded models, or zero
+	models map[string]*managedModel
+	seq    uint64 // Incremented on each use of a model
+	closed bool
+
+	// Files which replace the paths of models, and replaced models which are
+	// still in use
+	files    map[string]string
+	draining []*managedModel
+}
+
+// A model loaded by a ModelManager
+type managedModel struct {
+	key   string // Path of the name of the model
+	path  string // File the model is loaded from
+	model Model
+	size  uint64 // Memory of the model, estimated from the file until loaded
+	refs  int    // Number of calls to Get which have not been returned
+	used  uint64 // Sequence number of the last use
+
+	// Closed once the model is loaded, with the error if loading failed
+	ready chan struct{}
+	err   error
+}
+
+// ModelInfo describes where a model was downloaded from. It is read from a
+// sidecar file next to the model, which has the filename of the model with
+// a .json extension.
+type ModelInfo struct {
+	Name       string    `json:"name"`               // Filename of the model
+	URL        string    `json:"url"`                // Location the model was downloaded from
+	Revision   string    `json:"revision,omitempty"` // Repository commit or entity tag of the download
+	SHA256     string    `json:"sha256"`             // Hex encoded SHA-256 digest of the model file
+	Size       int64     `json:"size"`               // Size of the model file in bytes
+	Downloaded time.Time `json:"downloaded"`         // Time the download completed
+}
+
+const (
+	modelPrefix = "ggml-"
+	modelExt    = ".bin"
+	infoExt     = ".json"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// LIFECYCLE
+
+// Return a manager for the models in a directory
+func NewModelManager(dir string) *ModelManager {
+	m := &ModelManager{dir: dir, models: make(map[string]*managedModel)}
+	m.cond = sync.NewCond(&m.mu)
+	return m
+}
+
+// Close the models of the manager, including those in use
+func (m *ModelManager) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.closed = true
+	for key, managed := range m.models {
+		if managed.model != nil {
+			managed.model.Close()
+		}
+		delete(m.models, key)
+	}
+	m.cond.Broadcast()
+	return nil
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Return the path of a model. The name is either a path, which is returned
+// unchanged, or a model name such as "tiny.en" or "ggml-tiny.en.bin" in the
+// directory of the manager.
+func (m *ModelManager) Path(name string) string {
+	if strings.ContainsRune(name, os.PathSeparator) || strings.ContainsRune(name, '/') {
+		return name
+	}
+	if !strings.HasPrefix(name, modelPrefix) {
+		name = modelPrefix + name
+	}
+	if filepath.Ext(name) != modelExt {
+		name += modelExt
+	}
+	return filepath.Join(m.dir, name)
+}
+
+// Return the sidecar metadata of a model. An error wrapping fs.ErrNotExist
+// is returned if the model was not downloaded with a sidecar.
+func (m *ModelManager) Describe(name string) (ModelInfo, error) {
+	var info ModelInfo
+	path := m.Path(name)
+	data, err := os.ReadFile(path + infoExt)
+	if err != nil {
+		return info, err
+	}
+	if err := json.Unmarshal(data, &info); err != nil {
+		return info, fmt.Errorf("%s%s: %w", path, infoExt, err)
+	}
+	return info, nil
+}
+
+// Set the limit in bytes of the memory of loaded models, or zero for no
+// limit. The memory of a model is the MemoryUsage of the model, its weights
+// and default state. Least recently used models are closed to keep within
+// the limit, but models in use are not, so the limit is exceeded while the
+// models in use do not fit.
+func (m *ModelManager) SetMemoryLimit(limit uint64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.limit = limit
+	m.evict(0)
+}
+
+// Get returns a model by name or path as for Path, loading it if it is not
+// loaded. The model is shared by callers, which must not close it but return
+// it with Put when they are done, so that it can be closed when it is least
+// recently used. Models which are not loaded yet are estimated from the size
+// of their file, and least recently used models are closed before loading
+// them to make room.
+func (m *ModelManager) Get(name string) (Model, error) {
+	key := m.Path(name)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for {
+		if m.closed {
+			return nil, ErrInternalAppError
+		}
+		managed, exists := m.models[key]
+		if !exists {
+			// Make room for the model and load it
+			managed = &managedModel{key: key, path: m.file(key), refs: 1, ready: make(chan struct{})}
+			if info, err := os.Stat(managed.path); err == nil {
+				managed.size = uint64(info.Size())
+			}
+			m.evict(managed.size)
+			m.models[key] = managed
+			m.mu.Unlock()
+			model, err := New(managed.path)
+			m.mu.Lock()
+			managed.err = err
+			close(managed.ready)
+			if err != nil {
+				if m.models[key] == managed {
+					delete(m.models, key)
+				}
+				return nil, err
+			}
+			if m.models[key] != managed {
+				// The manager was closed, or the model was swapped, while
+				// the model loaded
+				model.Close()
+				continue
+			}
+			managed.model = model
+			managed.size = modelMemory(model).Total()
+		} else if managed.refs++; managed.model == nil {
+			// Wait for another call to load the model
+			m.mu.Unlock()
+			<-managed.ready
+			m.mu.Lock()
+			if managed.err != nil {
+				managed.refs--
+				return nil, managed.err
+			}
+			if m.models[key] != managed {
+				managed.refs--
+				continue
+			}
+		}
+		m.seq++
+		managed.used = m.seq
+		m.evict(0)
+		return managed.model, nil
+	}
+}
+
+// Swap replaces the model of a name with the model at a path, such as a new
+// version of it. The new model is loaded first, and the name returns it from
+// Get once it is loaded. The old model is closed once every caller which got
+// it has returned it with Put, so Swap waits until then, and processing
+// which is in flight on the old model is not interrupted.
+func (m *ModelManager) Swap(name, path string) error {
+	key := m.Path(name)
+	model, err := New(path)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		model.Close()
+		return ErrInternalAppError
+	}
+	if m.files == nil {
+		m.files = make(map[string]string)
+	}
+	m.files[key] = path
+
+	// Replace the old model, and wait for it to be returned
+	old := m.models[key]
+	m.seq++
+	m.models[key] = &managedModel{key: key, path: path, model: model, size: modelMemory(model).Total(), used: m.seq}
+	m.evict(0)
+	if old == nil || old.model == nil {
+		return nil
+	}
+	m.draining = append(m.draining, old)
+	for old.refs > 0 && !m.closed {
+		m.cond.Wait()
+	}
+	m.draining = slices.DeleteFunc(m.draining, func(managed *managedModel) bool {
+		return managed == old
+	})
+	return old.model.Close()
+}
+
+// Put returns a model obtained from Get
+func (m *ModelManager) Put(model Model) {
+	if model == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, managed := range m.models {
+		if managed.model == model && managed.refs > 0 {
+			managed.refs--
+			break
+		}
+	}
+	for _, managed := range m.draining {
+		if managed.model == model && managed.refs > 0 {
+			managed.refs--
+			m.cond.Broadcast()
+			break
+		}
+	}
+	m.evict(0)
+}
+
+// Return the paths of the loaded models, most recently used first
+func (m *ModelManager) Loaded() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	models := make([]*managedModel, 0, len(m.models))
+	for _, managed := range m.models {
+		if managed.model != nil {
+			models = append(models, managed)
+		}
+	}
+	slices.SortFunc(models, func(a, b *managedModel) int {
+		return cmp.Compare(b.used, a.used)
+	})
+	paths := make([]string, len(models))
+	for i, managed := range models {
+		paths[i] = managed.path
+	}
+	return paths
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// Return the file of a model, which is its path unless it was swapped
+func (m *ModelManager) file(key string) string {
+	if path, exists := m.files[key]; exists {
+		return path
+	}
+	return key
+}
+
+// Close the least recently used models which are not in use, until the
+// memory of the models and of a model to load fits into the limit
+func (m *ModelManager) evict(size uint64) {
+	if m.limit == 0 {
+		return
+	}
+	for {
+		total, lru := size, (*managedModel)(nil)
+		for _, managed := range m.models {
+			total += managed.size
+			if managed.refs == 0 && (lru == nil || managed.used < lru.used) {
+				lru = managed
+			}
+		}
+		if total <= m.limit || lru == nil {
+			return
+		}
+		lru.model.Close()
+		delete(m.models, lru.key)
+	}
+}
+
+// Return the memory of a model which implements MemoryReporter
+func modelMemory(model Model) MemoryUsage {
+	if reporter, ok := model.(MemoryReporter); ok {
+		return reporter.MemoryUsage()
+	}
+	return MemoryUsage{}
+}
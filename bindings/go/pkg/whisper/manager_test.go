@@ -0,0 +1,161 @@
+package whisper_test
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ggerganov/whisper.cpp/bindings/go/pkg/whisper"
+	assert "github.com/stretchr/testify/assert"
+)
+
+func TestModelManagerDescribe(t *testing.T) {
+	assert := assert.New(t)
+
+	dir := t.TempDir()
+	sidecar := `{
+  "name": "ggml-tiny.en.bin",
+  "url": "https://huggingface.co/ggerganov/whisper.cpp/resolve/main/ggml-tiny.en.bin",
+  "revision": "5359861c739e955e79d9a303bcbc70fb988958b1",
+  "sha256": "921e4cf8686fdd993dcd081a5da5b6c365bfde1162e72b08d75ac75289920b1f",
+  "size": 77704715,
+  "downloaded": "2025-01-02T03:04:05Z"
+}
+`
+	assert.NoError(os.WriteFile(filepath.Join(dir, "ggml-tiny.en.bin.json"), []byte(sidecar), 0644))
+
+	// Names resolve to the model file in the directory
+	manager := whisper.NewModelManager(dir)
+	path := filepath.Join(dir, "ggml-tiny.en.bin")
+	assert.Equal(path, manager.Path("tiny.en"))
+	assert.Equal(path, manager.Path("ggml-tiny.en"))
+	assert.Equal(path, manager.Path("ggml-tiny.en.bin"))
+	assert.Equal("models/ggml-base.bin", manager.Path("models/ggml-base.bin"))
+
+	for _, name := range []string{"tiny.en", "ggml-tiny.en.bin"} {
+		info, err := manager.Describe(name)
+		assert.NoError(err)
+		assert.Equal("ggml-tiny.en.bin", info.Name)
+		assert.Equal("5359861c739e955e79d9a303bcbc70fb988958b1", info.Revision)
+		assert.Equal(int64(77704715), info.Size)
+		assert.True(info.Downloaded.Equal(time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)))
+	}
+
+	// Models without a sidecar are not described
+	_, err := manager.Describe("base")
+	assert.ErrorIs(err, fs.ErrNotExist)
+}
+
+func TestModelManagerEviction(t *testing.T) {
+	assert := assert.New(t)
+	if _, err := os.Stat(ModelPath); os.IsNotExist(err) {
+		t.Skip("Skipping test, model not found:", ModelPath)
+	}
+
+	// Two names for the same model file
+	dir := t.TempDir()
+	model, err := filepath.Abs(ModelPath)
+	assert.NoError(err)
+	for _, name := range []string{"ggml-a.bin", "ggml-b.bin"} {
+		assert.NoError(os.Symlink(model, filepath.Join(dir, name)))
+	}
+	manager := whisper.NewModelManager(dir)
+	defer manager.Close()
+
+	// Models are loaded once and shared
+	a, err := manager.Get("a")
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+	again, err := manager.Get("a")
+	assert.NoError(err)
+	assert.Same(a, again)
+	manager.Put(again)
+	manager.Put(a)
+	assert.Equal([]string{manager.Path("a")}, manager.Loaded())
+
+	// Only one model fits, so the least recently used one is closed before
+	// the next is loaded
+	size := a.(whisper.MemoryReporter).MemoryUsage().Total()
+	manager.SetMemoryLimit(size + size/2)
+	b, err := manager.Get("b")
+	assert.NoError(err)
+	assert.Equal([]string{manager.Path("b")}, manager.Loaded())
+
+	// Models in use are not closed, even when they exceed the limit, until
+	// they are returned
+	a, err = manager.Get("a")
+	assert.NoError(err)
+	assert.Equal([]string{manager.Path("a"), manager.Path("b")}, manager.Loaded())
+	manager.Put(b)
+	assert.Equal([]string{manager.Path("a")}, manager.Loaded())
+	manager.Put(a)
+
+	// Closing the manager closes the models
+	assert.NoError(manager.Close())
+	assert.Empty(manager.Loaded())
+	_, err = manager.Get("a")
+	assert.ErrorIs(err, whisper.ErrInternalAppError)
+}
+
+func TestModelManagerSwap(t *testing.T) {
+	assert := assert.New(t)
+	if _, err := os.Stat(ModelPath); os.IsNotExist(err) {
+		t.Skip("Skipping test, model not found:", ModelPath)
+	}
+
+	dir := t.TempDir()
+	model, err := filepath.Abs(ModelPath)
+	assert.NoError(err)
+	for _, name := range []string{"ggml-v1.bin", "ggml-v2.bin"} {
+		assert.NoError(os.Symlink(model, filepath.Join(dir, name)))
+	}
+	manager := whisper.NewModelManager(dir)
+	defer manager.Close()
+	v1, err := manager.Get("v1")
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+
+	// The swap waits for the old model to be returned, while the name
+	// returns the new model
+	done := make(chan error)
+	go func() {
+		done <- manager.Swap("v1", manager.Path("v2"))
+	}()
+	var v2 whisper.Model
+	assert.Eventually(func() bool {
+		m, err := manager.Get("v1")
+		if err != nil {
+			return false
+		} else if m == v1 {
+			manager.Put(m)
+			return false
+		}
+		v2 = m
+		return true
+	}, 10*time.Second, 10*time.Millisecond)
+	select {
+	case <-done:
+		assert.Fail("swap did not wait for the old model")
+	case <-time.After(100 * time.Millisecond):
+	}
+	assert.NotZero(v1.(whisper.MemoryReporter).MemoryUsage().Total())
+
+	// The old model is closed once returned
+	manager.Put(v1)
+	assert.NoError(<-done)
+	assert.Zero(v1.(whisper.MemoryReporter).MemoryUsage().Total())
+	assert.NotZero(v2.(whisper.MemoryReporter).MemoryUsage().Total())
+	assert.Equal([]string{manager.Path("v2")}, manager.Loaded())
+	manager.Put(v2)
+
+	// Swapping a missing file keeps the model
+	assert.Error(manager.Swap("v1", filepath.Join(dir, "missing.bin")))
+	m, err := manager.Get("v1")
+	assert.NoError(err)
+	assert.Same(v2, m)
+	manager.Put(m)
+}
@@ -0,0 +1,180 @@
+package whisper
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	// Bindings
+	whisper "github.com/ggerganov/whisper.cpp/bindings/go"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// MemoryUsage is the memory allocated by the native library for a model or
+// a decoding state, in bytes, which is logged to stderr when they are loaded
+type MemoryUsage struct {
+	// Totals over all backends
+	Weights, KVCache, Compute uint64
+
+	// Memory on each backend, in the order of the backends of the state
+	Backends []BackendMemory
+
+	// Sizes of the KV caches and compute buffers of the state
+	State StateSize
+}
+
+// BackendMemory is the memory allocated on one backend, in bytes
+type BackendMemory struct {
+	// Name of the buffer type of the backend, such as "CPU" or "CUDA0"
+	Name string
+
+	// Model weights, KV caches, and compute buffers which are zero while
+	// they are released
+	Weights, KVCache, Compute uint64
+}
+
+// StateSize is the size of each buffer of a decoding state in bytes, as
+// logged when the state is allocated
+type StateSize struct {
+	// KV caches. The self-attention cache grows when more decoders are used
+	// by beam search or best of sampling.
+	KVSelf, KVCross, KVPad uint64
+
+	// Compute buffers of each graph, which are zero while they are released
+	Conv, Encode, Cross, Decode uint64
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+// Return the total followed by the breakdown, such as
+// "245.3 MiB (weights 141.1 MiB, KV cache 16.5 MiB, compute 87.7 MiB)"
+func (m MemoryUsage) String() string {
+	mib := func(n uint64) string {
+		return fmt.Sprintf("%.1f MiB", float64(n)/(1<<20))
+	}
+	parts := make([]string, 0, 3)
+	for _, part := range []struct {
+		name  string
+		bytes uint64
+	}{{"weights", m.Weights}, {"KV cache", m.KVCache}, {"compute", m.Compute}} {
+		if part.bytes > 0 {
+			parts = append(parts, part.name+" "+mib(part.bytes))
+		}
+	}
+	return fmt.Sprintf("%s (%s)", mib(m.Total()), strings.Join(parts, ", "))
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Return the total memory over all backends
+func (m MemoryUsage) Total() uint64 {
+	return m.Weights + m.KVCache + m.Compute
+}
+
+// Return the size of the KV caches
+func (s StateSize) KVCache() uint64 {
+	return s.KVSelf + s.KVCross + s.KVPad
+}
+
+// Return the size of the compute buffers
+func (s StateSize) Compute() uint64 {
+	return s.Conv + s.Encode + s.Cross + s.Decode
+}
+
+// Return the size of the state
+func (s StateSize) Total() uint64 {
+	return s.KVCache() + s.Compute()
+}
+
+// Return the number of states of this size which fit into free bytes of
+// memory, for admission control of stateful contexts. The size must be of a
+// state whose compute buffers are allocated. It returns zero if the size is
+// zero.
+func (s StateSize) Fit(free uint64) int {
+	if s.Total() == 0 {
+		return 0
+	}
+	return int(free / s.Total())
+}
+
+// Return the memory of the model weights, and of the KV caches and compute
+// buffers of the state which contexts from NewContext share. The memory of
+// stateful contexts is returned by their MemoryUsage.
+func (model *model) MemoryUsage() MemoryUsage {
+	var m MemoryUsage
+	ctx := model.rlock()
+	if ctx == nil {
+		return m
+	}
+	defer model.runlock()
+	m.addState(ctx.Whisper_memory_usage())
+	m.State = stateSize(ctx.Whisper_get_state_sizes())
+	for _, buf := range ctx.Whisper_model_buffers() {
+		m.backend(buf.Name).Weights += buf.Bytes
+		m.Weights += buf.Bytes
+	}
+	return m
+}
+
+// Return the memory of the KV caches and compute buffers of the decoding
+// state of the context, which for a context sharing the state of the model
+// is the state of the model. The weights are returned by the MemoryUsage of
+// the model.
+func (context *context) MemoryUsage() MemoryUsage {
+	var m MemoryUsage
+	ctx := context.rlock()
+	if ctx == nil {
+		return m
+	}
+	defer context.model.runlock()
+	if context.stateful {
+		m.addState(context.state.Whisper_memory_usage())
+		m.State = stateSize(context.state.Whisper_get_state_sizes())
+	} else {
+		m.addState(ctx.Whisper_memory_usage())
+		m.State = stateSize(ctx.Whisper_get_state_sizes())
+	}
+	return m
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// Add the memory of a state on each of its backends
+func (m *MemoryUsage) addState(backends []whisper.BackendMemory) {
+	for _, b := range backends {
+		backend := m.backend(b.Name)
+		backend.KVCache += b.KVCache
+		backend.Compute += b.Compute
+		m.KVCache += b.KVCache
+		m.Compute += b.Compute
+	}
+}
+
+// Return the memory of the backend with the name, adding it if needed
+func (m *MemoryUsage) backend(name string) *BackendMemory {
+	i := slices.IndexFunc(m.Backends, func(b BackendMemory) bool {
+		return b.Name == name
+	})
+	if i < 0 {
+		i = len(m.Backends)
+		m.Backends = append(m.Backends, BackendMemory{Name: name})
+	}
+	return &m.Backends[i]
+}
+
+func stateSize(s whisper.StateSizes) StateSize {
+	return StateSize{
+		KVSelf:  s.KVSelf,
+		KVCross: s.KVCross,
+		KVPad:   s.KVPad,
+		Conv:    s.Conv,
+		Encode:  s.Encode,
+		Cross:   s.Cross,
+		Decode:  s.Decode,
+	}
+}
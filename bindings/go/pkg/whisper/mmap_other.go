@@ -0,0 +1,16 @@
+//go:build !unix
+
+package whisper
+
+import (
+	// Bindings
+	whisper "github.com/ggerganov/whisper.cpp/bindings/go"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// Load a model. Files are not mapped into memory on this platform.
+func load(path string, params *ModelContextParams) *whisper.Context {
+	return initFromFile(path, params)
+}
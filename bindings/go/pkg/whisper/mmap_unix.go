@@ -0,0 +1,38 @@
+//go:build unix
+
+package whisper
+
+import (
+	"os"
+	"syscall"
+
+	// Bindings
+	whisper "github.com/ggerganov/whisper.cpp/bindings/go"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// Load a model, mapping the file into memory when requested. Returns nil
+// on failure.
+func load(path string, params *ModelContextParams) *whisper.Context {
+	if !params.mmap {
+		return initFromFile(path, params)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil || info.Size() == 0 {
+		return nil
+	}
+	buf, err := syscall.Mmap(int(f.Fd()), 0, int(info.Size()), syscall.PROT_READ, syscall.MAP_PRIVATE)
+	if err != nil {
+		// Fall back to reading the file
+		return initFromFile(path, params)
+	}
+	defer syscall.Munmap(buf)
+	return initFromBuffer(buf, params)
+}
@@ -1,9 +1,15 @@
 package whisper
 
 import (
+	gocontext "context"
 	"fmt"
+	"io"
 	"os"
 	"runtime"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	// Bindings
 	whisper "github.com/ggerganov/whisper.cpp/bindings/go"
@@ -15,36 +21,193 @@ import (
 type model struct {
 	path string
 	ctx  *whisper.Context
+	meta atomic.Pointer[modelMeta]
+
+	// Held for writing by Close, and for reading by everything else which
+	// uses the native context
+	lifetime sync.RWMutex
+
+	// Raw handles which have not been released, which Close waits for
+	// before it takes the lifetime lock, so that they can call the wrappers
+	rawMu   sync.Mutex
+	rawCond sync.Cond
+	raw     int
+	closing bool
+
+	// Serializes processing between contexts which share the model
+	gate     gate
+	contexts atomic.Uint64
+
+	// Load, warmup and first process latency
+	coldStart coldStart
+
+	// Pauses processing while the device is yielded to the host application
+	yield yielder
+
+	// Parameters the model was loaded with, and the decoding states of
+	// stateful contexts, which are freed when the model is closed
+	params   whisper.ContextParams
+	statesMu sync.Mutex
+	states   map[*whisper.State]struct{}
+
+	// Warnings from loading the model
+	warnings []error
 }
 
-// Make sure model adheres to the interface
-var _ Model = (*model)(nil)
+// Metadata which is read from the model once it is loaded, so that queries
+// do not need to call into the native library
+type modelMeta struct {
+	multilingual bool
+	languages    []string
+	lang         map[string]whisper.Token
+	vocab        int
+	textCtx      int
+
+	// Special tokens
+	eot, sot, prev, solm, not, beg whisper.Token
+	translate, transcribe          whisper.Token
+
+	// Token ids by text, which are read on first use
+	textOnce sync.Once
+	text     map[string]whisper.Token
+}
+
+// RawHandle provides access to the native whisper context, for calling
+// functions which are not wrapped by this package. The model cannot be
+// closed until the handle is released, and a handle of a context also holds
+// the context, so that it does not process audio while the native state is
+// used. The handle can be used from any goroutine.
+type RawHandle struct {
+	mu    sync.Mutex
+	ctx   *whisper.Context
+	state *whisper.State
+	fn    func()
+}
+
+// A reader which fails once its context is done
+type contextReader struct {
+	ctx gocontext.Context
+	r   io.Reader
+}
+
+// Make sure model adheres to the interfaces
+var (
+	_ Model          = (*model)(nil)
+	_ StatefulModel  = (*model)(nil)
+	_ ModelDescriber = (*model)(nil)
+	_ Warmer         = (*model)(nil)
+	_ Yielder        = (*model)(nil)
+	_ AdapterModel   = (*model)(nil)
+	_ MemoryReporter = (*model)(nil)
+	_ RawAccessor    = (*model)(nil)
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+// Largest model which NewFromReader reads into memory
+const readerBufferSize = 64 << 20
 
 ///////////////////////////////////////////////////////////////////////////////
 // LIFECYCLE
 
 func New(path string) (Model, error) {
-	model := new(model)
+	return NewWithParams(path, NewModelContextParams())
+}
+
+// Load a model with the given parameters
+func NewWithParams(path string, params *ModelContextParams) (Model, error) {
+	return NewWithContext(gocontext.Background(), path, params)
+}
+
+// Load a model with the given parameters, cancelling loading when ctx is
+// done, in which case the error of ctx is returned
+func NewWithContext(ctx gocontext.Context, path string, params *ModelContextParams) (Model, error) {
+	if params == nil {
+		params = NewModelContextParams()
+	}
 	if _, err := os.Stat(path); err != nil {
 		return nil, err
-	} else if ctx := whisper.Whisper_init(path); ctx == nil {
-		return nil, ErrUnableToLoadModel
-	} else {
-		model.ctx = ctx
-		model.path = path
 	}
+	return newModel(ctx, path, params, func(params *ModelContextParams) *whisper.Context {
+		return load(path, params)
+	})
+}
 
-	// Return success
-	return model, nil
+// Load a model from a stream with the given parameters, such as the body of
+// a download from object storage. Models of up to 64 MiB are read into
+// memory, and larger models are spilled to a temporary file, which can be
+// mapped into memory, and which is removed once the model is loaded.
+func NewFromReader(r io.Reader, params *ModelContextParams) (Model, error) {
+	return NewFromReaderWithContext(gocontext.Background(), r, params)
+}
+
+// Load a model from a stream as NewFromReader, cancelling reading the
+// stream and loading the model when ctx is done
+func NewFromReaderWithContext(ctx gocontext.Context, r io.Reader, params *ModelContextParams) (Model, error) {
+	if params == nil {
+		params = NewModelContextParams()
+	}
+	r = &contextReader{ctx: ctx, r: r}
+	buf, err := io.ReadAll(io.LimitReader(r, readerBufferSize+1))
+	if err != nil {
+		return nil, err
+	} else if len(buf) <= readerBufferSize {
+		return newModel(ctx, "", params, func(params *ModelContextParams) *whisper.Context {
+			return initFromBuffer(buf, params)
+		})
+	}
+
+	// Spill the stream to a temporary file
+	f, err := os.CreateTemp("", "whisper-model-*.bin")
+	if err != nil {
+		return nil, err
+	}
+	path := f.Name()
+	defer os.Remove(path)
+	if _, err := f.Write(buf); err != nil {
+		f.Close()
+		return nil, err
+	} else if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return nil, err
+	} else if err := f.Close(); err != nil {
+		return nil, err
+	}
+	// Release the buffer before the model is loaded
+	buf = nil
+	return newModel(ctx, "", params, func(params *ModelContextParams) *whisper.Context {
+		return load(path, params)
+	})
 }
 
 func (model *model) Close() error {
+	// Refuse new raw handles, and wait for those in use to be released
+	model.rawMu.Lock()
+	model.closing = true
+	for model.raw > 0 {
+		model.rawCond.Wait()
+	}
+	model.rawMu.Unlock()
+
+	model.lifetime.Lock()
+	defer model.lifetime.Unlock()
+
+	model.statesMu.Lock()
+	for state := range model.states {
+		state.Whisper_free_state()
+	}
+	model.states = nil
+	model.statesMu.Unlock()
 	if model.ctx != nil {
 		model.ctx.Whisper_free()
 	}
 
 	// Release resources
 	model.ctx = nil
+	model.meta.Store(nil)
+	runtime.SetFinalizer(model, nil)
+	untrack(&tracking.models, model)
 
 	// Return success
 	return nil
@@ -66,36 +229,279 @@ func (model *model) String() string {
 
 // Return true if model is multilingual (language and translation options are supported)
 func (model *model) IsMultilingual() bool {
-	return model.ctx.Whisper_is_multilingual() != 0
+	if meta := model.metadata(); meta != nil {
+		return meta.multilingual
+	}
+	return false
 }
 
 // Return all recognized languages. Initially it is set to auto-detect
 func (model *model) Languages() []string {
-	result := make([]string, 0, whisper.Whisper_lang_max_id())
-	for i := 0; i < whisper.Whisper_lang_max_id(); i++ {
-		str := whisper.Whisper_lang_str(i)
-		if model.ctx.Whisper_lang_id(str) >= 0 {
-			result = append(result, str)
-		}
+	if meta := model.metadata(); meta != nil {
+		return slices.Clone(meta.languages)
 	}
-	return result
+	return nil
+}
+
+// Return the maximum number of tokens of previous text used as a prompt
+func (model *model) MaxContext() int {
+	if meta := model.metadata(); meta != nil {
+		return meta.textCtx / 2
+	}
+	return 0
+}
+
+// Return a handle to the native whisper context. Release must be called
+// when the handle is no longer used, or Close will block forever.
+func (model *model) UnsafeRaw() (*RawHandle, error) {
+	ctx := model.rlock()
+	if ctx == nil {
+		return nil, ErrInternalAppError
+	}
+	defer model.runlock()
+	if !model.acquireRaw() {
+		return nil, ErrInternalAppError
+	}
+	return newRawHandle(ctx, nil, model.releaseRaw), nil
+}
+
+// Return statistics on contention for the model between its contexts
+func (model *model) GateStats() GateStats {
+	return model.gate.Stats()
 }
 
 func (model *model) NewContext() (Context, error) {
-	if model.ctx == nil {
+	ctx := model.rlock()
+	if ctx == nil {
 		return nil, ErrInternalAppError
 	}
+	defer model.runlock()
+
+	// Return new context
+	return model.newContext(ctx), nil
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// Load a model with the open function, and read its metadata
+func newModel(ctx gocontext.Context, path string, params *ModelContextParams, open func(*ModelContextParams) *whisper.Context) (Model, error) {
+	model := new(model)
+	model.rawCond.L = &model.rawMu
+	start := time.Now()
+
+	// Report progress after each tensor, and cancel loading when the
+	// context is done, which also prevents the fallback to the CPU
+	if params.progress != nil || ctx.Done() != nil {
+		p := *params
+		p.params.SetLoadProgressCallback(func(bytes, totalBytes uint64, tensors, totalTensors int) bool {
+			if p.progress != nil {
+				p.progress(LoadProgress{Bytes: bytes, TotalBytes: totalBytes, Tensors: tensors, TotalTensors: totalTensors})
+			}
+			return ctx.Err() == nil
+		})
+		defer p.params.SetLoadProgressCallback(nil)
+		fn := open
+		params, open = &p, func(params *ModelContextParams) *whisper.Context {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fn(params)
+		}
+	}
+	if native, err := model.load(path, params, open); err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, err
+	} else {
+		model.ctx = native
+		model.path = path
+		model.coldStart.stats.Load = time.Since(start)
+	}
+
+	// Apply special token overrides
+	meta := newModelMeta(model.ctx)
+	if err := meta.override(params.specials); err != nil {
+		model.ctx.Whisper_free()
+		return nil, err
+	}
+	model.meta.Store(meta)
+
+	// Free the native context if the model is not closed
+	runtime.SetFinalizer(model, finalizeModel)
+	track(&tracking.models, model)
 
-	// Create new context
-	params := model.ctx.Whisper_full_default_params(whisper.SAMPLING_GREEDY)
+	// Return success
+	return model, nil
+}
+
+func (r *contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.r.Read(p)
+}
+
+// Return a new context with default parameters
+func (model *model) newContext(ctx *whisper.Context) *context {
+	params := ctx.Whisper_full_default_params(whisper.SAMPLING_GREEDY)
 	params.SetTranslate(false)
 	params.SetPrintSpecial(false)
 	params.SetPrintProgress(false)
 	params.SetPrintRealtime(false)
 	params.SetPrintTimestamps(false)
-	params.SetThreads(runtime.NumCPU())
+	params.SetThreads(RecommendedThreads(model.params.UseGPU()))
 	params.SetNoContext(true)
 
 	// Return new context
 	return newContext(model, params)
 }
+
+// Native calls fall into two groups. Queries of the vocabulary and the model
+// hyperparameters only read data which is immutable once the model is loaded,
+// so they hold the lifetime lock for reading and are safe to run while another
+// context is processing. Calls which use the shared decoding state, such as
+// processing and reading back results, also need to hold the gate. The lock
+// must not be taken again by a goroutine which holds it, since a pending Close
+// blocks new readers, so callbacks from processing only use the metadata,
+// which is read without the lock.
+
+// Lock the native context for reading and return it, or return nil if the
+// model has been closed. When the result is not nil, runlock must be called.
+func (model *model) rlock() *whisper.Context {
+	model.lifetime.RLock()
+	if model.ctx == nil {
+		model.lifetime.RUnlock()
+		return nil
+	}
+	return model.ctx
+}
+
+func (model *model) runlock() {
+	model.lifetime.RUnlock()
+}
+
+// Return the cached metadata, or nil if the model has been closed. The
+// metadata is never modified, so it can be used while the model is closed.
+func (model *model) metadata() *modelMeta {
+	return model.meta.Load()
+}
+
+// Compare a token with a special token of the model
+func (model *model) isToken(t Token, fn func(*modelMeta) whisper.Token) bool {
+	if meta := model.metadata(); meta != nil {
+		return whisper.Token(t.Id) == fn(meta)
+	}
+	return false
+}
+
+func newModelMeta(ctx *whisper.Context) *modelMeta {
+	meta := &modelMeta{
+		multilingual: ctx.Whisper_is_multilingual() != 0,
+		lang:         make(map[string]whisper.Token),
+		vocab:        ctx.Whisper_n_vocab(),
+		textCtx:      ctx.Whisper_n_text_ctx(),
+		eot:          ctx.Whisper_token_eot(),
+		sot:          ctx.Whisper_token_sot(),
+		prev:         ctx.Whisper_token_prev(),
+		solm:         ctx.Whisper_token_solm(),
+		not:          ctx.Whisper_token_not(),
+		beg:          ctx.Whisper_token_beg(),
+		translate:    ctx.Whisper_token_translate(),
+		transcribe:   ctx.Whisper_token_transcribe(),
+	}
+	for i := 0; i < whisper.Whisper_lang_max_id(); i++ {
+		str := whisper.Whisper_lang_str(i)
+		if ctx.Whisper_lang_id(str) >= 0 {
+			meta.languages = append(meta.languages, str)
+		}
+	}
+	for i := 0; i <= whisper.Whisper_lang_max_id(); i++ {
+		token := ctx.Whisper_token_lang(i)
+		meta.lang[whisper.Whisper_lang_str(i)] = token
+		meta.lang[whisper.Whisper_lang_str_full(i)] = token
+	}
+	return meta
+}
+
+// Replace special tokens with the given overrides, which must be within
+// the vocabulary
+func (meta *modelMeta) override(specials map[SpecialToken]whisper.Token) error {
+	for kind, id := range specials {
+		if id < 0 || int(id) >= meta.vocab {
+			return fmt.Errorf("%w: %v=%d", ErrInvalidToken, kind, id)
+		}
+		switch kind {
+		case TokenEOT:
+			meta.eot = id
+		case TokenSOT:
+			meta.sot = id
+		case TokenPREV:
+			meta.prev = id
+		case TokenSOLM:
+			meta.solm = id
+		case TokenNOT:
+			meta.not = id
+		case TokenBEG:
+			meta.beg = id
+		case TokenTranslate:
+			meta.translate = id
+		case TokenTranscribe:
+			meta.transcribe = id
+		default:
+			return fmt.Errorf("%w: %v", ErrInvalidToken, kind)
+		}
+	}
+	return nil
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// RAW HANDLE
+
+// Return the native whisper context, or nil if the handle has been released
+func (handle *RawHandle) Context() *whisper.Context {
+	handle.mu.Lock()
+	defer handle.mu.Unlock()
+	return handle.ctx
+}
+
+// Return the native decoding state of a stateful context, or nil for the
+// model and for contexts which share its state
+func (handle *RawHandle) State() *whisper.State {
+	handle.mu.Lock()
+	defer handle.mu.Unlock()
+	return handle.state
+}
+
+// Release the handle. The native context must not be used afterwards.
+func (handle *RawHandle) Release() {
+	handle.mu.Lock()
+	defer handle.mu.Unlock()
+	if handle.fn != nil {
+		handle.fn()
+	}
+	handle.ctx, handle.state, handle.fn = nil, nil, nil
+}
+
+func newRawHandle(ctx *whisper.Context, state *whisper.State, release func()) *RawHandle {
+	return &RawHandle{ctx: ctx, state: state, fn: release}
+}
+
+// Count a new raw handle, or return false if the model is being closed
+func (model *model) acquireRaw() bool {
+	model.rawMu.Lock()
+	defer model.rawMu.Unlock()
+	if model.closing {
+		return false
+	}
+	model.raw++
+	return true
+}
+
+func (model *model) releaseRaw() {
+	model.rawMu.Lock()
+	defer model.rawMu.Unlock()
+	model.raw--
+	model.rawCond.Broadcast()
+}
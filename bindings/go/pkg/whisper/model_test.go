@@ -1,8 +1,16 @@
 package whisper_test
 
 import (
+	gocontext "context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
 	"testing"
+	"time"
 
+	whisperlib "github.com/ggerganov/whisper.cpp/bindings/go"
 	"github.com/ggerganov/whisper.cpp/bindings/go/pkg/whisper"
 	assert "github.com/stretchr/testify/assert"
 )
@@ -25,6 +33,309 @@ func TestNew(t *testing.T) {
 	})
 }
 
+func TestNewFromReader(t *testing.T) {
+	assert := assert.New(t)
+	t.Run("valid model stream", func(t *testing.T) {
+		fh, err := os.Open(ModelPath)
+		if !assert.NoError(err) {
+			t.FailNow()
+		}
+		defer fh.Close()
+		model, err := whisper.NewFromReader(fh, nil)
+		if assert.NoError(err) {
+			defer model.Close()
+			assert.NotEmpty(model.Languages())
+		}
+	})
+
+	t.Run("invalid model stream", func(t *testing.T) {
+		model, err := whisper.NewFromReader(strings.NewReader("not a model"), nil)
+		assert.ErrorIs(err, whisper.ErrUnableToLoadModel)
+		assert.Nil(model)
+	})
+}
+
+func TestLoadProgress(t *testing.T) {
+	assert := assert.New(t)
+
+	// Progress is reported for each tensor, up to all of the weights
+	var progress []whisper.LoadProgress
+	params := whisper.NewModelContextParams()
+	params.SetLoadProgressCallback(func(p whisper.LoadProgress) {
+		progress = append(progress, p)
+	})
+	model, err := whisper.NewWithParams(ModelPath, params)
+	if assert.NoError(err) {
+		model.Close()
+	}
+	if assert.NotEmpty(progress) {
+		last := progress[len(progress)-1]
+		assert.Len(progress, last.TotalTensors)
+		assert.Equal(last.TotalBytes, last.Bytes)
+		assert.Equal(1.0, last.Fraction())
+	}
+
+	// Loading is cancelled once the context is done
+	ctx, cancel := gocontext.WithCancel(gocontext.Background())
+	n := 0
+	params.SetLoadProgressCallback(func(whisper.LoadProgress) {
+		n++
+		cancel()
+	})
+	model, err = whisper.NewWithContext(ctx, ModelPath, params)
+	assert.ErrorIs(err, gocontext.Canceled)
+	assert.Nil(model)
+	assert.Equal(1, n)
+
+	// A context which is already done does not load the model
+	model, err = whisper.NewWithContext(ctx, ModelPath, nil)
+	assert.ErrorIs(err, gocontext.Canceled)
+	assert.Nil(model)
+}
+
+func TestInspectModel(t *testing.T) {
+	assert := assert.New(t)
+	if _, err := os.Stat(ModelPath); os.IsNotExist(err) {
+		t.Skip("Skipping test, model not found:", ModelPath)
+	}
+
+	// The header agrees with the loaded model
+	header, err := whisper.InspectModel(ModelPath)
+	assert.NoError(err)
+	info, err := os.Stat(ModelPath)
+	assert.NoError(err)
+	assert.Equal(info.Size(), header.Size)
+	assert.NotEmpty(header.Type)
+	assert.False(header.Multilingual)
+	assert.Equal([]string{"en"}, header.Languages)
+	assert.Equal(80, header.Mels)
+	assert.NotEmpty(header.FileType.String())
+
+	model, err := fullModel(whisper.New(ModelPath))
+	assert.NoError(err)
+	defer model.Close()
+	assert.Equal(model.IsMultilingual(), header.Multilingual)
+	assert.Equal(model.MaxContext(), header.TextCtx/2)
+
+	// Files which are not models are rejected
+	_, err = whisper.InspectModel(SamplePath)
+	assert.ErrorIs(err, whisper.ErrUnableToLoadModel)
+	_, err = whisper.InspectModel(filepath.Join(t.TempDir(), "missing.bin"))
+	assert.ErrorIs(err, os.ErrNotExist)
+}
+
+func TestQuantization(t *testing.T) {
+	assert := assert.New(t)
+	if _, err := os.Stat(ModelPath); os.IsNotExist(err) {
+		t.Skip("Skipping test, model not found:", ModelPath)
+	}
+	header, err := whisper.InspectModel(ModelPath)
+	assert.NoError(err)
+	model, err := fullModel(whisper.New(ModelPath))
+	assert.NoError(err)
+	defer model.Close()
+
+	// The file type is the one in the header, and the weights of that type
+	// are the largest
+	q := model.Quantization()
+	assert.Equal(header.FileType, q.FileType)
+	if assert.NotEmpty(q.Types) {
+		assert.Equal(q.FileType.String(), q.Types[0].Type)
+		assert.Positive(q.Types[0].Tensors)
+		assert.Positive(q.Types[0].Bytes)
+	}
+	assert.True(strings.HasPrefix(q.String(), q.FileType.String()+" ("))
+
+	// The weights are not reported once the model is closed
+	assert.NoError(model.Close())
+	assert.Empty(model.Quantization().Types)
+}
+
+func TestMemoryUsage(t *testing.T) {
+	assert := assert.New(t)
+	if _, err := os.Stat(ModelPath); os.IsNotExist(err) {
+		t.Skip("Skipping test, model not found:", ModelPath)
+	}
+	model, err := fullModel(whisper.New(ModelPath))
+	assert.NoError(err)
+	defer model.Close()
+
+	// The weights are as large as the weight tensors, and the state of the
+	// model has KV caches and compute buffers
+	var weights uint64
+	for _, t := range model.Quantization().Types {
+		weights += t.Bytes
+	}
+	m := model.MemoryUsage()
+	assert.GreaterOrEqual(m.Weights, weights)
+	assert.Positive(m.KVCache)
+	assert.Positive(m.Compute)
+	assert.Equal(m.Weights+m.KVCache+m.Compute, m.Total())
+	if assert.NotEmpty(m.Backends) {
+		assert.NotEmpty(m.Backends[0].Name)
+	}
+	assert.Contains(m.String(), "weights")
+
+	// The sizes of the state add up to less than the memory of its buffers,
+	// which also hold the meta data of the graphs
+	assert.Positive(m.State.KVSelf)
+	assert.Positive(m.State.KVCross)
+	assert.Positive(m.State.Decode)
+	assert.LessOrEqual(m.State.KVCache(), m.KVCache)
+	assert.Equal(m.State.KVCache()+m.State.Compute(), m.State.Total())
+	assert.Equal(2, m.State.Fit(2*m.State.Total()+1))
+	assert.Zero(whisper.StateSize{}.Fit(1 << 30))
+
+	// A stateful context reports its own state, without the weights, and
+	// none of its compute buffers once they are released
+	context, err := fullContext(model.NewStatefulContext())
+	assert.NoError(err)
+	defer context.Close()
+	state := context.MemoryUsage()
+	assert.Zero(state.Weights)
+	assert.Equal(m.KVCache, state.KVCache)
+	assert.Positive(state.Compute)
+	assert.Equal(m.State, state.State)
+	assert.NoError(context.ReleaseComputeBuffers())
+	assert.Zero(context.MemoryUsage().Compute)
+	assert.Zero(context.MemoryUsage().State.Compute())
+	assert.Equal(state.KVCache, context.MemoryUsage().KVCache)
+	assert.Equal(m.Compute, model.MemoryUsage().Compute)
+
+	// Nothing is reported once the model is closed
+	assert.NoError(model.Close())
+	assert.Zero(model.MemoryUsage().Total())
+}
+
+func TestFinalizer(t *testing.T) {
+	assert := assert.New(t)
+	if _, err := os.Stat(ModelPath); os.IsNotExist(err) {
+		t.Skip("Skipping test, model not found:", ModelPath)
+	}
+
+	// Return once the leak count reaches n, collecting garbage until then
+	collect := func(n uint64, timeout time.Duration) bool {
+		for deadline := time.Now().Add(timeout); time.Now().Before(deadline); {
+			runtime.GC()
+			if whisper.Leaks() >= n {
+				return true
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		return false
+	}
+
+	// A stateful context which is not closed is freed when it is collected,
+	// and so is a model
+	leaks := whisper.Leaks()
+	func() {
+		model, err := fullModel(whisper.New(ModelPath))
+		assert.NoError(err)
+		_, err = fullContext(model.NewStatefulContext())
+		assert.NoError(err)
+	}()
+	assert.True(collect(leaks+2, 10*time.Second), "model and context were not finalized")
+
+	// Closed models and contexts are not leaks, once the models which other
+	// tests did not close have been collected
+	for leaks = 0; leaks != whisper.Leaks(); {
+		leaks = whisper.Leaks()
+		collect(leaks+1, 100*time.Millisecond)
+	}
+	func() {
+		model, err := fullModel(whisper.New(ModelPath))
+		assert.NoError(err)
+		context, err := fullContext(model.NewStatefulContext())
+		assert.NoError(err)
+		assert.NoError(context.Close())
+		assert.NoError(model.Close())
+	}()
+	assert.False(collect(leaks+1, 500*time.Millisecond))
+}
+
+func TestDebugTracking(t *testing.T) {
+	assert := assert.New(t)
+	if _, err := os.Stat(ModelPath); os.IsNotExist(err) {
+		t.Skip("Skipping test, model not found:", ModelPath)
+	}
+	whisper.SetDebugTracking(true)
+	defer whisper.SetDebugTracking(false)
+
+	// Models and stateful contexts are live until they are closed, and are
+	// reported with the stack which created them
+	model, err := fullModel(whisper.New(ModelPath))
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+	defer model.Close()
+	context, err := fullContext(model.NewStatefulContext())
+	assert.NoError(err)
+	shared, err := fullContext(model.NewContext())
+	assert.NoError(err)
+	assert.NoError(shared.Close())
+	objects := whisper.LiveObjects()
+	if assert.Len(objects, 2) {
+		assert.Equal("model", objects[0].Kind)
+		assert.Equal(ModelPath, objects[0].Name)
+		assert.Equal("context", objects[1].Kind)
+		assert.Equal(context.Label(), objects[1].Name)
+		assert.Contains(objects[1].Stack, "TestDebugTracking")
+		assert.NotContains(objects[1].Stack, "whisper.track")
+	}
+	assert.NoError(context.Close())
+	assert.NoError(model.Close())
+	assert.Empty(whisper.LiveObjects())
+
+	// Objects created while tracking is off are not recorded
+	whisper.SetDebugTracking(false)
+	model, err = fullModel(whisper.New(ModelPath))
+	assert.NoError(err)
+	assert.Empty(whisper.LiveObjects())
+}
+
+func TestQuantizeModel(t *testing.T) {
+	assert := assert.New(t)
+	if _, err := os.Stat(ModelPath); os.IsNotExist(err) {
+		t.Skip("Skipping test, model not found:", ModelPath)
+	}
+	ftype, err := whisper.ParseFileType("Q8_0")
+	assert.NoError(err)
+	assert.Equal(whisper.FileTypeQ8_0, ftype)
+	_, err = whisper.ParseFileType("q9_9")
+	assert.ErrorIs(err, whisper.ErrInvalidFileType)
+
+	// The quantized model is smaller, and has weights of the file type
+	dir := t.TempDir()
+	path := filepath.Join(dir, "ggml-q8_0.bin")
+	assert.NoError(whisper.QuantizeModel(ModelPath, path, ftype))
+	header, err := whisper.InspectModel(path)
+	assert.NoError(err)
+	assert.Equal(whisper.FileTypeQ8_0, header.FileType)
+	assert.Positive(header.QuantizationVersion)
+	original, err := whisper.InspectModel(ModelPath)
+	assert.NoError(err)
+	assert.Less(header.Size, original.Size)
+
+	model, err := fullModel(whisper.New(path))
+	if assert.NoError(err) {
+		defer model.Close()
+		q := model.Quantization()
+		if assert.NotEmpty(q.Types) {
+			assert.Equal("q8_0", q.Types[0].Type)
+		}
+		context, err := fullContext(model.NewContext())
+		assert.NoError(err)
+		assert.NoError(context.Process(make([]float32, whisper.SampleRate), nil, nil, nil))
+	}
+
+	// Nothing is written when the model cannot be quantized
+	assert.ErrorIs(whisper.QuantizeModel(ModelPath, filepath.Join(dir, "f16.bin"), whisper.FileTypeF16), whisper.ErrInvalidFileType)
+	assert.ErrorIs(whisper.QuantizeModel(SamplePath, filepath.Join(dir, "wav.bin"), ftype), whisper.ErrUnableToLoadModel)
+	entries, err := os.ReadDir(dir)
+	assert.NoError(err)
+	assert.Len(entries, 1)
+}
+
 func TestClose(t *testing.T) {
 	assert := assert.New(t)
 
@@ -89,3 +400,255 @@ func TestLanguages(t *testing.T) {
 
 	assert.Equal(expectedLanguages, actualLanguages)
 }
+
+func TestUnsafeRaw(t *testing.T) {
+	assert := assert.New(t)
+
+	model, err := fullModel(whisper.New(ModelPath))
+	assert.NoError(err)
+	assert.NotNil(model)
+
+	handle, err := model.UnsafeRaw()
+	assert.NoError(err)
+	assert.NotNil(handle.Context())
+	assert.Equal(model.IsMultilingual(), handle.Context().Whisper_is_multilingual() != 0)
+
+	assert.Nil(handle.State())
+
+	// Close blocks until the handle is released, and the wrappers can be
+	// called meanwhile
+	closed := make(chan struct{})
+	go func() {
+		model.Close()
+		close(closed)
+	}()
+	time.Sleep(100 * time.Millisecond)
+	select {
+	case <-closed:
+		t.Fatal("Close returned before the handle was released")
+	default:
+	}
+	_, err = fullContext(model.NewContext())
+	assert.NoError(err)
+	handle.Release()
+	handle.Release()
+	<-closed
+	assert.Nil(handle.Context())
+
+	// No handles are returned once the model is closed
+	_, err = model.UnsafeRaw()
+	assert.ErrorIs(err, whisper.ErrInternalAppError)
+}
+
+func TestContextUnsafeRaw(t *testing.T) {
+	assert := assert.New(t)
+
+	model, err := fullModel(whisper.New(ModelPath))
+	assert.NoError(err)
+	context, err := fullContext(model.NewStatefulContext())
+	assert.NoError(err)
+
+	// The handle of a stateful context has its state, and holds the context
+	handle, err := context.UnsafeRaw()
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+	assert.NotNil(handle.Context())
+	assert.NotNil(handle.State())
+	assert.ErrorIs(context.Process(loadSamples(t, SamplePath), nil, nil, nil), whisper.ErrStatelessBusy)
+	_, err = context.UnsafeRaw()
+	assert.ErrorIs(err, whisper.ErrStatelessBusy)
+
+	// Close blocks until the handle is released
+	closed := make(chan struct{})
+	go func() {
+		model.Close()
+		close(closed)
+	}()
+	time.Sleep(100 * time.Millisecond)
+	select {
+	case <-closed:
+		t.Fatal("Close returned before the handle was released")
+	default:
+	}
+	handle.Release()
+	<-closed
+	assert.Nil(handle.State())
+}
+
+func TestMetadataAfterClose(t *testing.T) {
+	assert := assert.New(t)
+
+	model, err := whisper.New(ModelPath)
+	assert.NoError(err)
+	assert.NotNil(model)
+
+	context, err := model.NewContext()
+	assert.NoError(err)
+	assert.NotEmpty(model.Languages())
+
+	// Cached metadata is dropped when the model is closed
+	assert.NoError(model.Close())
+	assert.False(model.IsMultilingual())
+	assert.Nil(model.Languages())
+	assert.False(context.IsText(whisper.Token{Id: 0}))
+}
+
+func TestLoadAdapter(t *testing.T) {
+	assert := assert.New(t)
+
+	model, err := fullModel(whisper.New(ModelPath))
+	assert.NoError(err)
+	assert.NotNil(model)
+	defer model.Close()
+
+	// Without a registered loader
+	assert.ErrorIs(model.LoadAdapter("adapter.bin"), whisper.ErrAdapterUnsupported)
+
+	// With a registered loader
+	var loaded string
+	whisper.RegisterAdapterLoader(func(ctx *whisperlib.Context, path string) error {
+		assert.NotNil(ctx)
+		loaded = path
+		return nil
+	})
+	defer whisper.RegisterAdapterLoader(nil)
+	assert.NoError(model.LoadAdapter("adapter.bin"))
+	assert.Equal("adapter.bin", loaded)
+}
+
+func TestColdStart(t *testing.T) {
+	assert := assert.New(t)
+
+	model, err := fullModel(whisper.New(ModelPath))
+	assert.NoError(err)
+	defer model.Close()
+
+	stats := model.ColdStart()
+	assert.Greater(stats.Load, time.Duration(0))
+	assert.False(stats.Warm)
+
+	assert.NoError(model.Warmup())
+	stats = model.ColdStart()
+	assert.True(stats.Warm)
+	assert.Greater(stats.Warmup, time.Duration(0))
+	assert.Zero(stats.FirstProcess)
+
+	context, err := fullContext(model.NewContext())
+	assert.NoError(err)
+	assert.NoError(context.Process(make([]float32, whisper.SampleRate), nil, nil, nil))
+	assert.Greater(model.ColdStart().FirstProcess, time.Duration(0))
+}
+
+func TestYieldGPU(t *testing.T) {
+	assert := assert.New(t)
+
+	model, err := fullModel(whisper.New(ModelPath))
+	assert.NoError(err)
+	defer model.Close()
+
+	context, err := fullContext(model.NewStatefulContext())
+	assert.NoError(err)
+	defer context.Close()
+
+	// Processing waits until the model is resumed
+	assert.NoError(model.YieldGPU())
+	assert.NoError(model.YieldGPU())
+	done := make(chan error)
+	go func() {
+		done <- context.Process(make([]float32, whisper.SampleRate), nil, nil, nil)
+	}()
+	select {
+	case <-done:
+		t.Fatal("Process did not wait for Resume")
+	case <-time.After(100 * time.Millisecond):
+	}
+	model.Resume()
+	assert.NoError(<-done)
+
+	// The compute buffers are allocated again after a yield
+	assert.NoError(model.YieldGPU())
+	model.Resume()
+	assert.NoError(context.Process(make([]float32, whisper.SampleRate), nil, nil, nil))
+}
+
+func TestLoadError(t *testing.T) {
+	assert := assert.New(t)
+
+	// A file which is not a model
+	path := filepath.Join(t.TempDir(), "ggml-invalid.bin")
+	assert.NoError(os.WriteFile(path, []byte("not a model"), 0o644))
+
+	params := whisper.NewModelContextParams()
+	params.SetUseGPU(false)
+	_, err := whisper.NewWithParams(path, params)
+	assert.ErrorIs(err, whisper.ErrUnableToLoadModel)
+	var loadErr *whisper.LoadError
+	if assert.True(errors.As(err, &loadErr)) {
+		assert.Equal(path, loadErr.Path)
+		assert.Equal("CPU", loadErr.Backend)
+	}
+
+	// The fallback fails on the CPU too
+	params.SetUseGPU(true)
+	params.SetCPUFallback(true)
+	_, err = whisper.NewWithParams(path, params)
+	if assert.True(errors.As(err, &loadErr)) {
+		assert.Equal("CPU", loadErr.Backend)
+	}
+}
+
+func TestCPUFallback(t *testing.T) {
+	assert := assert.New(t)
+
+	// A GPU which does not exist
+	params := whisper.NewModelContextParams()
+	params.SetUseGPU(true)
+	params.SetGPUDevice(len(whisper.Devices()) + 1)
+	params.SetCPUFallback(true)
+	assert.Contains(params.String(), "cpu_fallback")
+	model, err := fullModel(whisper.NewWithParams(ModelPath, params))
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+	defer model.Close()
+
+	warnings := model.Warnings()
+	var warning *whisper.FallbackWarning
+	if assert.Len(warnings, 1) && assert.True(errors.As(warnings[0], &warning)) {
+		assert.Equal(len(whisper.Devices())+1, warning.Device)
+		assert.ErrorIs(warning, whisper.ErrInvalidDevice)
+	}
+
+	// Models loaded as requested have no warnings
+	other, err := fullModel(whisper.New(ModelPath))
+	if assert.NoError(err) {
+		assert.Empty(other.Warnings())
+		other.Close()
+	}
+}
+
+func TestCoreMLPath(t *testing.T) {
+	assert := assert.New(t)
+
+	encoder := filepath.Join(t.TempDir(), "encoder.mlmodelc")
+	params := whisper.NewModelContextParams()
+	params.SetCoreMLPath(encoder)
+	params.SetCoreMLCacheDir(t.TempDir())
+	assert.Equal(encoder, params.CoreMLPath())
+	assert.Contains(params.String(), "coreml=")
+	assert.Contains(params.String(), "coreml_cache=")
+
+	// The model loads, and can process, with or without Core ML in the build
+	model, err := fullModel(whisper.NewWithParams(ModelPath, params))
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+	defer model.Close()
+	if !model.UsesCoreML() {
+		context, err := fullContext(model.NewContext())
+		if assert.NoError(err) {
+			assert.NoError(context.Process(make([]float32, whisper.SampleRate), nil, nil, nil))
+		}
+	}
+}
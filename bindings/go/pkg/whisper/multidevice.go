@@ -0,0 +1,214 @@
+package whisper
+
+import (
+	"errors"
+	"sync"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// Placement selects the device which runs a job submitted to a
+// MultiDeviceManager
+type Placement int
+
+// MultiDeviceManager holds one copy of a model for each GPU, and runs the
+// jobs submitted to it with a pool of stateful contexts on each device, so
+// that throughput scales with the number of GPUs. Jobs are placed on the
+// devices in turn, or on the device running the fewest jobs.
+type MultiDeviceManager struct {
+	mu        sync.Mutex
+	devices   []*deviceModel
+	placement Placement
+	next      int
+	closed    bool
+}
+
+// Job is the work of one request, which is run by Submit with a context on
+// one of the devices. The context is returned to its pool once the job
+// returns, and must not be used afterwards.
+type Job func(Context) error
+
+// DeviceLoad reports the jobs of one device of a MultiDeviceManager
+type DeviceLoad struct {
+	Device int    // Index of the model in the manager
+	Active int    // Number of jobs running or waiting for a context
+	Total  uint64 // Number of jobs submitted
+}
+
+type deviceModel struct {
+	model  Model
+	pool   *ContextPool
+	active int
+	total  uint64
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+const (
+	PlacementRoundRobin  Placement = iota // Each device in turn
+	PlacementLeastLoaded                  // The device with the fewest active jobs
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// LIFECYCLE
+
+// Load the model at path once for each of the GPUs, which are the GPU
+// fields of devices returned by Devices, with up to capacity contexts on
+// each. When no GPUs are given, every GPU is used, and when there are none
+// the model is loaded once with the parameters as they are. The parameters
+// are not modified.
+func NewMultiDeviceManager(path string, params *ModelContextParams, capacity int, gpus ...int) (*MultiDeviceManager, error) {
+	if params == nil {
+		params = NewModelContextParams()
+	}
+	if len(gpus) == 0 {
+		for _, device := range Devices() {
+			if device.GPU >= 0 {
+				gpus = append(gpus, device.GPU)
+			}
+		}
+	}
+
+	// Load the models, releasing those already loaded on error
+	var models []Model
+	for _, gpu := range gpus {
+		p := *params
+		p.params.SetUseGPU(true)
+		p.params.SetGPUDevice(gpu)
+		model, err := NewWithParams(path, &p)
+		if err != nil {
+			for _, model := range models {
+				model.Close()
+			}
+			return nil, err
+		}
+		models = append(models, model)
+	}
+	if len(models) == 0 {
+		model, err := NewWithParams(path, params)
+		if err != nil {
+			return nil, err
+		}
+		models = append(models, model)
+	}
+	return NewMultiDeviceManagerForModels(capacity, models...)
+}
+
+// Return a manager for models which are already loaded, such as copies of
+// a model with different parameters for each device, with up to capacity
+// contexts for each model. The models are closed with the manager.
+func NewMultiDeviceManagerForModels(capacity int, models ...Model) (*MultiDeviceManager, error) {
+	if len(models) == 0 {
+		return nil, ErrInvalidParams
+	}
+	manager := new(MultiDeviceManager)
+	for _, model := range models {
+		manager.devices = append(manager.devices, &deviceModel{model: model, pool: NewContextPool(model, capacity)})
+	}
+	return manager, nil
+}
+
+// Close the pools and the models. Jobs which are running complete, but any
+// further jobs are rejected.
+func (manager *MultiDeviceManager) Close() error {
+	manager.mu.Lock()
+	defer manager.mu.Unlock()
+	if manager.closed {
+		return nil
+	}
+	manager.closed = true
+	var result error
+	for _, device := range manager.devices {
+		result = errors.Join(result, device.pool.Close(), device.model.Close())
+	}
+	return result
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (p Placement) String() string {
+	switch p {
+	case PlacementRoundRobin:
+		return "round_robin"
+	case PlacementLeastLoaded:
+		return "least_loaded"
+	default:
+		return "unknown"
+	}
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Set how jobs are placed on the devices
+func (manager *MultiDeviceManager) SetPlacement(placement Placement) {
+	manager.mu.Lock()
+	defer manager.mu.Unlock()
+	manager.placement = placement
+}
+
+// Run the job with a context on one of the devices, waiting for a context
+// if all of those on the device are in use, and return the error of the
+// job. Submit is called from as many goroutines as there are requests to
+// run at once.
+func (manager *MultiDeviceManager) Submit(job Job) error {
+	device, err := manager.place()
+	if err != nil {
+		return err
+	}
+	defer manager.done(device)
+
+	context, err := device.pool.Get()
+	if err != nil {
+		return err
+	}
+	defer device.pool.Put(context)
+	return job(context)
+}
+
+// Return the load of each device
+func (manager *MultiDeviceManager) Stats() []DeviceLoad {
+	manager.mu.Lock()
+	defer manager.mu.Unlock()
+	result := make([]DeviceLoad, len(manager.devices))
+	for i, device := range manager.devices {
+		result[i] = DeviceLoad{Device: i, Active: device.active, Total: device.total}
+	}
+	return result
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// Choose the device for a job and count the job as active on it. Least
+// loaded placement breaks ties in turn, so that idle devices share the jobs.
+func (manager *MultiDeviceManager) place() (*deviceModel, error) {
+	manager.mu.Lock()
+	defer manager.mu.Unlock()
+	if manager.closed {
+		return nil, ErrInternalAppError
+	}
+	n := len(manager.devices)
+	start := manager.next
+	manager.next = (manager.next + 1) % n
+	result := manager.devices[start]
+	if manager.placement == PlacementLeastLoaded {
+		for i := 1; i < n; i++ {
+			if device := manager.devices[(start+i)%n]; device.active < result.active {
+				result = device
+			}
+		}
+	}
+	result.active++
+	result.total++
+	return result, nil
+}
+
+func (manager *MultiDeviceManager) done(device *deviceModel) {
+	manager.mu.Lock()
+	defer manager.mu.Unlock()
+	device.active--
+}
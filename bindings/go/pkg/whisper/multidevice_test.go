@@ -0,0 +1,102 @@
+package whisper_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/ggerganov/whisper.cpp/bindings/go/pkg/whisper"
+	assert "github.com/stretchr/testify/assert"
+)
+
+func TestMultiDeviceManagerRoundRobin(t *testing.T) {
+	assert := assert.New(t)
+
+	models := []*streamModel{{}, {}}
+	manager, err := whisper.NewMultiDeviceManagerForModels(1, models[0], models[1])
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+	defer manager.Close()
+
+	for range 4 {
+		assert.NoError(manager.Submit(func(context whisper.Context) error {
+			return context.Process(endpointAudio(0, false), nil, nil, nil)
+		}))
+	}
+	stats := manager.Stats()
+	if assert.Len(stats, 2) {
+		assert.Equal(uint64(2), stats[0].Total)
+		assert.Equal(uint64(2), stats[1].Total)
+		assert.Zero(stats[0].Active + stats[1].Active)
+	}
+
+	// Each device created its own context
+	assert.Equal(1, models[0].n)
+	assert.Equal(1, models[1].n)
+}
+
+func TestMultiDeviceManagerLeastLoaded(t *testing.T) {
+	assert := assert.New(t)
+
+	manager, err := whisper.NewMultiDeviceManagerForModels(2, &streamModel{}, &streamModel{})
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+	defer manager.Close()
+	manager.SetPlacement(whisper.PlacementLeastLoaded)
+
+	// Hold a job on the first device
+	var wg sync.WaitGroup
+	started, release := make(chan struct{}), make(chan struct{})
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		manager.Submit(func(whisper.Context) error {
+			close(started)
+			<-release
+			return nil
+		})
+	}()
+	<-started
+	assert.Equal(1, manager.Stats()[0].Active)
+
+	// The next jobs go to the idle device, even when it is not its turn
+	for range 2 {
+		assert.NoError(manager.Submit(func(whisper.Context) error { return nil }))
+	}
+	assert.Equal(uint64(1), manager.Stats()[0].Total)
+	assert.Equal(uint64(2), manager.Stats()[1].Total)
+	close(release)
+	wg.Wait()
+
+	// Closed managers reject jobs
+	manager.Close()
+	assert.Error(manager.Submit(func(whisper.Context) error { return nil }))
+}
+
+func TestMultiDeviceManagerNoModels(t *testing.T) {
+	_, err := whisper.NewMultiDeviceManagerForModels(1)
+	assert.ErrorIs(t, err, whisper.ErrInvalidParams)
+}
+
+func TestMultiDeviceManagerLoad(t *testing.T) {
+	assert := assert.New(t)
+
+	// Without GPUs the model is loaded once
+	manager, err := whisper.NewMultiDeviceManager(ModelPath, nil, 1)
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+	defer manager.Close()
+	gpus := 0
+	for _, device := range whisper.Devices() {
+		if device.GPU >= 0 {
+			gpus++
+		}
+	}
+	assert.Len(manager.Stats(), max(gpus, 1))
+	assert.NoError(manager.Submit(func(context whisper.Context) error {
+		context.SetThreads(1)
+		return nil
+	}))
+}
@@ -0,0 +1,167 @@
+package whisper
+
+import (
+	"time"
+
+	// Bindings
+	whisper "github.com/ggerganov/whisper.cpp/bindings/go"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// ContextOption configures a context created with NewContextWithOptions
+type ContextOption func(*context) error
+
+///////////////////////////////////////////////////////////////////////////////
+// LIFECYCLE
+
+// NewContextWithOptions returns a new context for the model, configured with
+// the options in order. An error is returned if any option fails, for
+// example when setting a language on a model which is not multilingual.
+func NewContextWithOptions(model Model, opts ...ContextOption) (Context, error) {
+	ctx, err := model.NewContext()
+	if err != nil {
+		return nil, err
+	}
+	if err := ApplyOptions(ctx, opts...); err != nil {
+		return nil, err
+	}
+
+	// Return success
+	return ctx, nil
+}
+
+// ValidateOptions checks the options against the model before a context is
+// kept for processing, returning the error of the first option which fails,
+// or an error which wraps ErrInvalidParams for each invalid setting. The
+// options are applied to a stateless context, which holds no decoding state
+// and is closed before returning.
+func ValidateOptions(model Model, opts ...ContextOption) error {
+	ctx, err := model.NewContext()
+	if err != nil {
+		return err
+	}
+	context, ok := ctx.(*context)
+	if !ok {
+		return ErrInternalAppError
+	}
+	defer context.Close()
+	if err := ApplyOptions(context, opts...); err != nil {
+		return err
+	}
+
+	// Return any invalid settings
+	return context.Validate()
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// ApplyOptions configures an existing context, such as one returned by
+// NewStatefulContext, with the options in order
+func ApplyOptions(ctx Context, opts ...ContextOption) error {
+	context, ok := ctx.(*context)
+	if !ok {
+		return ErrInternalAppError
+	}
+	for _, opt := range opts {
+		if err := opt(context); err != nil {
+			return err
+		}
+	}
+
+	// Return success
+	return nil
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// OPTIONS
+
+// Set the language, or "auto" to detect the language
+func WithLanguage(lang string) ContextOption {
+	return func(context *context) error {
+		return context.SetLanguage(lang)
+	}
+}
+
+// Translate the speech into English
+func WithTranslate() ContextOption {
+	return func(context *context) error {
+		context.SetTranslate(true)
+		return nil
+	}
+}
+
+// Set the number of threads
+func WithThreads(n uint) ContextOption {
+	return func(context *context) error {
+		context.SetThreads(n)
+		return nil
+	}
+}
+
+// Use beam search with the given beam size, instead of greedy sampling
+func WithBeamSize(n int) ContextOption {
+	return func(context *context) error {
+		if n < 1 {
+			return ErrInvalidParams
+		}
+		context.update(func(p *whisper.Params) {
+			p.SetStrategy(whisper.SAMPLING_BEAM_SEARCH)
+			p.SetBeamSize(n)
+		})
+		return nil
+	}
+}
+
+// Enable voice activity detection with the VAD model at path
+func WithVAD(path string) ContextOption {
+	return func(context *context) error {
+		context.SetVAD(true)
+		context.SetVADModelPath(path)
+		return nil
+	}
+}
+
+// Set the initial prompt
+func WithInitialPrompt(prompt string) ContextOption {
+	return func(context *context) error {
+		context.SetInitialPrompt(prompt)
+		return nil
+	}
+}
+
+// Enable token timestamps
+func WithTokenTimestamps() ContextOption {
+	return func(context *context) error {
+		context.SetTokenTimestamps(true)
+		return nil
+	}
+}
+
+// Set the sampling temperature
+func WithTemperature(t float32) ContextOption {
+	return func(context *context) error {
+		context.SetTemperature(t)
+		return nil
+	}
+}
+
+// Process only part of the audio, starting at offset for duration. A zero
+// duration processes until the end of the audio.
+func WithWindow(offset, duration time.Duration) ContextOption {
+	return func(context *context) error {
+		context.SetOffset(offset)
+		context.SetDuration(duration)
+		return nil
+	}
+}
+
+// Set the label which identifies the context in errors
+func WithLabel(label string) ContextOption {
+	return func(context *context) error {
+		context.SetLabel(label)
+		return nil
+	}
+}
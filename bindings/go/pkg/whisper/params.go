@@ -0,0 +1,335 @@
+package whisper
+
+import (
+	"fmt"
+
+	// Bindings
+	whisper "github.com/ggerganov/whisper.cpp/bindings/go"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// ModelContextParams are the parameters used when loading a model with
+// NewWithParams
+type ModelContextParams struct {
+	params   whisper.ContextParams
+	specials map[SpecialToken]whisper.Token
+	mmap     bool
+	fallback bool
+	coreml   string
+	cache    string
+	progress LoadProgressCallback
+}
+
+// LoadProgress reports how much of a model has been loaded
+type LoadProgress struct {
+	Bytes, TotalBytes     uint64 // Bytes of weights loaded, and in total
+	Tensors, TotalTensors int    // Tensors loaded, and in total
+}
+
+// LoadProgressCallback is called after each tensor of a model is loaded,
+// from the goroutine which loads the model
+type LoadProgressCallback func(LoadProgress)
+
+// SpecialToken identifies one of the special tokens of the vocabulary
+type SpecialToken int
+
+// AlignmentHead identifies an attention head used to align tokens with the
+// audio for token-level timestamps with DTW
+type AlignmentHead struct {
+	TextLayer int // Text (decoder) layer, counting from zero
+	Head      int // Head within the layer, counting from zero
+}
+
+// AlignmentHeadsPreset selects the alignment heads used for DTW
+type AlignmentHeadsPreset whisper.AheadsPreset
+
+///////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+const (
+	TokenEOT        SpecialToken = iota // End of transcription
+	TokenSOT                            // Start of transcription
+	TokenPREV                           // Start of previous text
+	TokenSOLM                           // Start of language model
+	TokenNOT                            // No timestamps
+	TokenBEG                            // Beginning of timestamps
+	TokenTranslate                      // Translate task
+	TokenTranscribe                     // Transcribe task
+)
+
+const (
+	AlignmentHeadsNone         = AlignmentHeadsPreset(whisper.AHEADS_NONE)
+	AlignmentHeadsNTopMost     = AlignmentHeadsPreset(whisper.AHEADS_N_TOP_MOST) // All heads of the top text layers
+	AlignmentHeadsCustom       = AlignmentHeadsPreset(whisper.AHEADS_CUSTOM)     // Heads set with SetDTWAheads
+	AlignmentHeadsTinyEn       = AlignmentHeadsPreset(whisper.AHEADS_TINY_EN)
+	AlignmentHeadsTiny         = AlignmentHeadsPreset(whisper.AHEADS_TINY)
+	AlignmentHeadsBaseEn       = AlignmentHeadsPreset(whisper.AHEADS_BASE_EN)
+	AlignmentHeadsBase         = AlignmentHeadsPreset(whisper.AHEADS_BASE)
+	AlignmentHeadsSmallEn      = AlignmentHeadsPreset(whisper.AHEADS_SMALL_EN)
+	AlignmentHeadsSmall        = AlignmentHeadsPreset(whisper.AHEADS_SMALL)
+	AlignmentHeadsMediumEn     = AlignmentHeadsPreset(whisper.AHEADS_MEDIUM_EN)
+	AlignmentHeadsMedium       = AlignmentHeadsPreset(whisper.AHEADS_MEDIUM)
+	AlignmentHeadsLargeV1      = AlignmentHeadsPreset(whisper.AHEADS_LARGE_V1)
+	AlignmentHeadsLargeV2      = AlignmentHeadsPreset(whisper.AHEADS_LARGE_V2)
+	AlignmentHeadsLargeV3      = AlignmentHeadsPreset(whisper.AHEADS_LARGE_V3)
+	AlignmentHeadsLargeV3Turbo = AlignmentHeadsPreset(whisper.AHEADS_LARGE_V3_TURBO)
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// LIFECYCLE
+
+// Return the default parameters for loading a model
+func NewModelContextParams() *ModelContextParams {
+	return &ModelContextParams{
+		params: whisper.Whisper_context_default_params(),
+	}
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Set custom alignment heads for token-level timestamps with DTW, for
+// fine-tuned models whose alignment heads differ from the stock checkpoints.
+// This enables DTW token-level timestamps. Pass nil to disable.
+func (p *ModelContextParams) SetDTWAheads(heads []AlignmentHead) {
+	aheads := make([]whisper.Ahead, len(heads))
+	for i, head := range heads {
+		aheads[i] = whisper.Ahead{TextLayer: head.TextLayer, Head: head.Head}
+	}
+	p.params.SetDTWAheads(aheads)
+}
+
+// Return the custom alignment heads, or nil if none are set
+func (p *ModelContextParams) DTWAheads() []AlignmentHead {
+	aheads := p.params.DTWAheads()
+	if aheads == nil {
+		return nil
+	}
+	result := make([]AlignmentHead, len(aheads))
+	for i, head := range aheads {
+		result[i] = AlignmentHead{TextLayer: head.TextLayer, Head: head.Head}
+	}
+	return result
+}
+
+// Override the id of a special token, for fine-tuned models which repurpose
+// tokens of the stock vocabulary. The override applies to the token tests of
+// contexts created from the model, such as IsText and IsBEG, not to decoding.
+// Pass a negative id to restore the stock token.
+func (p *ModelContextParams) SetSpecialToken(kind SpecialToken, id int) {
+	if id < 0 {
+		delete(p.specials, kind)
+		return
+	}
+	if p.specials == nil {
+		p.specials = make(map[SpecialToken]whisper.Token)
+	}
+	p.specials[kind] = whisper.Token(id)
+}
+
+// Return the overridden id of a special token, and false if the stock
+// token is used
+func (p *ModelContextParams) SpecialToken(kind SpecialToken) (int, bool) {
+	id, exists := p.specials[kind]
+	return int(id), exists
+}
+
+// Enable or disable token-level timestamps with DTW, which are more accurate
+// than the timestamps derived from timestamp tokens. The alignment heads are
+// set with SetDTWAheadsPreset or SetDTWAheads. DTW is not supported with
+// flash attention, which is on by default, so SetUseFlashAttention(false)
+// must also be called.
+func (p *ModelContextParams) SetDTWTokenTimestamps(v bool) {
+	p.params.SetDTWTokenTimestamps(v)
+}
+
+// Return true if token-level timestamps with DTW are enabled
+func (p *ModelContextParams) DTWTokenTimestamps() bool {
+	return p.params.DTWTokenTimestamps()
+}
+
+// Use the alignment heads of a stock checkpoint for DTW, which must match
+// the model, or all heads of the top SetDTWNTop text layers with
+// AlignmentHeadsNTopMost
+func (p *ModelContextParams) SetDTWAheadsPreset(preset AlignmentHeadsPreset) {
+	p.params.SetDTWAheadsPreset(whisper.AheadsPreset(preset))
+}
+
+// Return the alignment heads preset
+func (p *ModelContextParams) DTWAheadsPreset() AlignmentHeadsPreset {
+	return AlignmentHeadsPreset(p.params.DTWAheadsPreset())
+}
+
+// Set the number of top text layers whose heads are used with
+// AlignmentHeadsNTopMost, between one and the number of text layers
+func (p *ModelContextParams) SetDTWNTop(n int) {
+	p.params.SetDTWNTop(n)
+}
+
+// Return the number of top text layers used with AlignmentHeadsNTopMost
+func (p *ModelContextParams) DTWNTop() int {
+	return p.params.DTWNTop()
+}
+
+// Set the size in bytes of the memory used to compute DTW, which limits the
+// length of a window which can be aligned
+func (p *ModelContextParams) SetDTWMemSize(n uint64) {
+	p.params.SetDTWMemSize(n)
+}
+
+// Return the size in bytes of the memory used to compute DTW
+func (p *ModelContextParams) DTWMemSize() uint64 {
+	return p.params.DTWMemSize()
+}
+
+// Map the model file into memory and load the weights from the mapping,
+// rather than reading the file in small chunks. On network filesystems this
+// replaces many small reads with paging in the file, and on hosts short of
+// memory the pages can be dropped again as soon as the weights are copied
+// into the backend. The mapping is released once the model is loaded. This
+// is ignored on platforms which do not support mmap.
+func (p *ModelContextParams) SetUseMmap(v bool) {
+	p.mmap = v
+}
+
+// Return true if the model file is mapped into memory when loading
+func (p *ModelContextParams) UseMmap() bool {
+	return p.mmap
+}
+
+// Use flash attention, when supported by the backend, which is the default.
+// The native library does not support DTW token-level timestamps with flash
+// attention, and disables them when both are set.
+func (p *ModelContextParams) SetUseFlashAttention(v bool) {
+	p.params.SetFlashAttn(v)
+}
+
+// Return true if flash attention is used
+func (p *ModelContextParams) UseFlashAttention() bool {
+	return p.params.FlashAttn()
+}
+
+// Use the GPU for inference, when the library is built with GPU support,
+// which is the default
+func (p *ModelContextParams) SetUseGPU(v bool) {
+	p.params.SetUseGPU(v)
+}
+
+// Return true if the GPU is used for inference
+func (p *ModelContextParams) UseGPU() bool {
+	return p.params.UseGPU()
+}
+
+// Select the GPU used for inference, which is the GPU field of one of the
+// devices returned by Devices. Loading a model fails with ErrInvalidDevice
+// if there is no such device.
+func (p *ModelContextParams) SetGPUDevice(n int) {
+	p.params.SetGPUDevice(n)
+}
+
+// Return the GPU used for inference
+func (p *ModelContextParams) GPUDevice() int {
+	return p.params.GPUDevice()
+}
+
+// Load the model on the CPU when it cannot be loaded on the GPU, because
+// the driver is missing, the device does not exist or the weights do not
+// fit into its memory. The fallback is reported by ModelDescriber.Warnings.
+func (p *ModelContextParams) SetCPUFallback(v bool) {
+	p.fallback = v
+}
+
+// Return true if the model is loaded on the CPU when the GPU fails
+func (p *ModelContextParams) CPUFallback() bool {
+	return p.fallback
+}
+
+// Return the fraction of the weights which have been loaded, from 0 to 1
+func (p LoadProgress) Fraction() float64 {
+	if p.TotalBytes == 0 {
+		return 0
+	}
+	return float64(p.Bytes) / float64(p.TotalBytes)
+}
+
+// Set a callback which reports the progress of loading the model, for
+// example to show a loading bar. Pass nil to clear.
+func (p *ModelContextParams) SetLoadProgressCallback(fn LoadProgressCallback) {
+	p.progress = fn
+}
+
+// Set the path of the Core ML encoder model, which is either compiled
+// (.mlmodelc) or compiled when the model is loaded (.mlmodel or .mlpackage).
+// By default the compiled model next to the model file is used, for example
+// ggml-base.en-encoder.mlmodelc. This is ignored when the library is built
+// without Core ML.
+func (p *ModelContextParams) SetCoreMLPath(path string) {
+	p.coreml = path
+}
+
+// Return the path of the Core ML encoder model, or empty for the default
+func (p *ModelContextParams) CoreMLPath() string {
+	return p.coreml
+}
+
+// Set the directory where Core ML models are kept once they are compiled,
+// so that a model is only compiled again when it changes. By default a model
+// which is not compiled is compiled every time it is loaded.
+func (p *ModelContextParams) SetCoreMLCacheDir(dir string) {
+	p.cache = dir
+}
+
+// Return the directory of compiled Core ML models, or empty if not set
+func (p *ModelContextParams) CoreMLCacheDir() string {
+	return p.cache
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (p *ModelContextParams) String() string {
+	str := p.params.String()
+	if p.mmap {
+		str += " use_mmap"
+	}
+	if p.fallback {
+		str += " cpu_fallback"
+	}
+	if p.coreml != "" {
+		str += fmt.Sprintf(" coreml=%q", p.coreml)
+	}
+	if p.cache != "" {
+		str += fmt.Sprintf(" coreml_cache=%q", p.cache)
+	}
+	for kind := TokenEOT; kind <= TokenTranscribe; kind++ {
+		if id, exists := p.specials[kind]; exists {
+			str += fmt.Sprintf(" %v=%d", kind, id)
+		}
+	}
+	return str
+}
+
+func (kind SpecialToken) String() string {
+	switch kind {
+	case TokenEOT:
+		return "eot"
+	case TokenSOT:
+		return "sot"
+	case TokenPREV:
+		return "prev"
+	case TokenSOLM:
+		return "solm"
+	case TokenNOT:
+		return "not"
+	case TokenBEG:
+		return "beg"
+	case TokenTranslate:
+		return "translate"
+	case TokenTranscribe:
+		return "transcribe"
+	default:
+		return fmt.Sprintf("token(%d)", int(kind))
+	}
+}
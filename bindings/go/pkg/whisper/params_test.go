@@ -0,0 +1,125 @@
+package whisper_test
+
+import (
+	"testing"
+
+	"github.com/ggerganov/whisper.cpp/bindings/go/pkg/whisper"
+	assert "github.com/stretchr/testify/assert"
+)
+
+func TestModelContextParamsDTWAheads(t *testing.T) {
+	assert := assert.New(t)
+
+	params := whisper.NewModelContextParams()
+	assert.Nil(params.DTWAheads())
+
+	heads := []whisper.AlignmentHead{{TextLayer: 2, Head: 3}, {TextLayer: 3, Head: 1}}
+	params.SetDTWAheads(heads)
+	assert.Equal(heads, params.DTWAheads())
+	assert.Contains(params.String(), "dtw_token_timestamps")
+
+	params.SetDTWAheads(nil)
+	assert.Nil(params.DTWAheads())
+}
+
+func TestModelContextParamsDTW(t *testing.T) {
+	assert := assert.New(t)
+
+	params := whisper.NewModelContextParams()
+	assert.False(params.DTWTokenTimestamps())
+	assert.Equal(whisper.AlignmentHeadsNone, params.DTWAheadsPreset())
+	params.SetUseFlashAttention(false)
+	params.SetDTWTokenTimestamps(true)
+	params.SetDTWAheadsPreset(whisper.AlignmentHeadsNTopMost)
+	params.SetDTWNTop(1)
+	params.SetDTWMemSize(64 * 1024 * 1024)
+	assert.True(params.DTWTokenTimestamps())
+	assert.Equal(whisper.AlignmentHeadsNTopMost, params.DTWAheadsPreset())
+	assert.Equal(1, params.DTWNTop())
+	assert.Equal(uint64(64*1024*1024), params.DTWMemSize())
+	assert.Contains(params.String(), "dtw_token_timestamps dtw_n_top=1")
+
+	// Custom heads select the custom preset
+	params.SetDTWAheads([]whisper.AlignmentHead{{TextLayer: 0, Head: 0}})
+	assert.Equal(whisper.AlignmentHeadsCustom, params.DTWAheadsPreset())
+	params.SetDTWAheadsPreset(whisper.AlignmentHeadsNTopMost)
+
+	model, err := whisper.NewWithParams(ModelPath, params)
+	assert.NoError(err)
+	defer model.Close()
+	context, err := model.NewContext()
+	assert.NoError(err)
+	context.SetTokenTimestamps(true)
+	assert.NoError(context.Process(make([]float32, whisper.SampleRate), nil, nil, nil))
+}
+
+func TestModelContextParamsSpecialToken(t *testing.T) {
+	assert := assert.New(t)
+
+	params := whisper.NewModelContextParams()
+	_, exists := params.SpecialToken(whisper.TokenBEG)
+	assert.False(exists)
+
+	params.SetSpecialToken(whisper.TokenBEG, 50400)
+	id, exists := params.SpecialToken(whisper.TokenBEG)
+	assert.True(exists)
+	assert.Equal(50400, id)
+	assert.Contains(params.String(), "beg=50400")
+
+	params.SetSpecialToken(whisper.TokenBEG, -1)
+	_, exists = params.SpecialToken(whisper.TokenBEG)
+	assert.False(exists)
+}
+
+func TestModelContextParamsMmap(t *testing.T) {
+	assert := assert.New(t)
+
+	params := whisper.NewModelContextParams()
+	assert.False(params.UseMmap())
+	params.SetUseMmap(true)
+	assert.True(params.UseMmap())
+	assert.Contains(params.String(), "use_mmap")
+
+	model, err := whisper.NewWithParams(ModelPath, params)
+	assert.NoError(err)
+	defer model.Close()
+	context, err := model.NewContext()
+	assert.NoError(err)
+	assert.NoError(context.Process(make([]float32, whisper.SampleRate), nil, nil, nil))
+}
+
+func TestModelContextParamsFlashAttention(t *testing.T) {
+	assert := assert.New(t)
+
+	params := whisper.NewModelContextParams()
+	params.SetUseFlashAttention(false)
+	assert.False(params.UseFlashAttention())
+	assert.NotContains(params.String(), "flash_attn")
+	params.SetUseFlashAttention(true)
+	assert.True(params.UseFlashAttention())
+	assert.Contains(params.String(), "flash_attn")
+
+	model, err := whisper.NewWithParams(ModelPath, params)
+	assert.NoError(err)
+	defer model.Close()
+	context, err := model.NewContext()
+	assert.NoError(err)
+	assert.NoError(context.Process(make([]float32, whisper.SampleRate), nil, nil, nil))
+}
+
+func TestFileResidency(t *testing.T) {
+	assert := assert.New(t)
+
+	// The file is in the page cache once a model has been loaded from it
+	params := whisper.NewModelContextParams()
+	params.SetUseMmap(true)
+	model, err := whisper.NewWithParams(ModelPath, params)
+	assert.NoError(err)
+	defer model.Close()
+
+	residency, err := whisper.FileResidency(ModelPath)
+	assert.NoError(err)
+	assert.Greater(residency.Size, int64(0))
+	assert.Greater(residency.Fraction(), 0.0)
+	assert.LessOrEqual(residency.Resident, residency.Size)
+}
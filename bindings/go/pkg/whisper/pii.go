@@ -0,0 +1,252 @@
+package whisper
+
+import (
+	"cmp"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// PIIDetector finds personal information in the text of a segment. Regular
+// expressions are provided with RegexpDetector, and a named entity
+// recognizer can be plugged in with PIIDetectorFunc.
+type PIIDetector interface {
+	DetectPII(text string) []PIIMatch
+}
+
+// PIIDetectorFunc adapts a function, such as a call to a named entity
+// recognizer, to a PIIDetector
+type PIIDetectorFunc func(text string) []PIIMatch
+
+// PIIMatch is personal information found by a detector, as a range of byte
+// offsets into the text
+type PIIMatch struct {
+	Kind       string // Kind of information, such as EMAIL or PERSON
+	Start, End int
+}
+
+// RegexpDetector reports each match of a regular expression as the kind
+type RegexpDetector struct {
+	Kind    string
+	Pattern *regexp.Regexp
+}
+
+// PIISpan is personal information which was removed from a transcript, with
+// where and when it was said, for auditing the redaction
+type PIISpan struct {
+	Kind       string
+	Text       string        // Text which was removed
+	Segment    int           // Number of the segment
+	Offset     int           // Byte offset of the text in the original segment text
+	Start, End time.Duration // Time span of the text within the audio
+}
+
+// Redactor replaces personal information in the text and tokens of segments
+// with a mask, such as [EMAIL]. Detectors run in order, and where matches
+// overlap the one which starts first, or is longest, is kept.
+type Redactor struct {
+	detectors []PIIDetector
+	mask      func(kind string) string
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+const (
+	PIIEmail      = "EMAIL"
+	PIIPhone      = "PHONE"
+	PIICreditCard = "CREDIT_CARD"
+	PIISSN        = "SSN"
+	PIIIPAddress  = "IP_ADDRESS"
+)
+
+var (
+	reEmail      = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)
+	rePhone      = regexp.MustCompile(`(?:\+\d{1,3}[ .-]?)?(?:\(\d{3}\)|\b\d{3})[ .-]?\d{3}[ .-]?\d{4}\b`)
+	reCreditCard = regexp.MustCompile(`\b(?:\d[ -]?){12,15}\d\b`)
+	reSSN        = regexp.MustCompile(`\b\d{3}-\d{2}-\d{4}\b`)
+	reIPAddress  = regexp.MustCompile(`\b(?:\d{1,3}\.){3}\d{1,3}\b`)
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// LIFECYCLE
+
+// Return the detectors for email addresses, phone numbers, credit card
+// numbers, US social security numbers and IPv4 addresses
+func DefaultPIIDetectors() []PIIDetector {
+	return []PIIDetector{
+		RegexpDetector{Kind: PIIEmail, Pattern: reEmail},
+		RegexpDetector{Kind: PIICreditCard, Pattern: reCreditCard},
+		RegexpDetector{Kind: PIISSN, Pattern: reSSN},
+		RegexpDetector{Kind: PIIPhone, Pattern: rePhone},
+		RegexpDetector{Kind: PIIIPAddress, Pattern: reIPAddress},
+	}
+}
+
+// Return a redactor with the detectors, or with the default detectors if
+// none are given
+func NewRedactor(detectors ...PIIDetector) *Redactor {
+	if len(detectors) == 0 {
+		detectors = DefaultPIIDetectors()
+	}
+	return &Redactor{detectors: detectors, mask: func(kind string) string {
+		return "[" + kind + "]"
+	}}
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+func (fn PIIDetectorFunc) DetectPII(text string) []PIIMatch {
+	return fn(text)
+}
+
+func (d RegexpDetector) DetectPII(text string) []PIIMatch {
+	var result []PIIMatch
+	for _, loc := range d.Pattern.FindAllStringIndex(text, -1) {
+		result = append(result, PIIMatch{Kind: d.Kind, Start: loc[0], End: loc[1]})
+	}
+	return result
+}
+
+// Set the function which returns the text replacing information of a kind
+func (r *Redactor) SetMask(fn func(kind string) string) {
+	r.mask = fn
+}
+
+// Return copies of the segments with personal information replaced in their
+// text and tokens, and the spans which were removed in order. Times of the
+// spans are taken from the tokens, or are the time span of the segment when
+// it has no tokens.
+func (r *Redactor) Redact(segments []Segment) ([]Segment, []PIISpan) {
+	var spans []PIISpan
+	result := make([]Segment, len(segments))
+	for i, segment := range segments {
+		matches := r.detect(segment.Text)
+		result[i] = segment
+		if len(matches) == 0 {
+			continue
+		}
+		offsets := tokenOffsets(segment)
+		for _, match := range matches {
+			span := PIISpan{
+				Kind:    match.Kind,
+				Text:    segment.Text[match.Start:match.End],
+				Segment: segment.Num,
+				Offset:  match.Start,
+				Start:   segment.Start,
+				End:     segment.End,
+			}
+			if offsets != nil {
+				span.Start, span.End = tokenSpan(segment, offsets, match.Start, match.End)
+			}
+			spans = append(spans, span)
+		}
+		result[i].Text = r.replace(segment.Text, matches, 0)
+		if offsets != nil {
+			result[i].Tokens = slices.Clone(segment.Tokens)
+			for k, token := range segment.Tokens {
+				if !isSpecialText(token.Text) {
+					result[i].Tokens[k].Text = r.replace(token.Text, matches, offsets[k])
+				}
+			}
+		}
+	}
+	return result, spans
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// Run the detectors and return the matches in order, without overlaps
+func (r *Redactor) detect(text string) []PIIMatch {
+	var matches []PIIMatch
+	for _, detector := range r.detectors {
+		for _, match := range detector.DetectPII(text) {
+			if match.Start >= 0 && match.End <= len(text) && match.Start < match.End {
+				matches = append(matches, match)
+			}
+		}
+	}
+	slices.SortStableFunc(matches, func(a, b PIIMatch) int {
+		return cmp.Or(cmp.Compare(a.Start, b.Start), cmp.Compare(b.End, a.End))
+	})
+	var result []PIIMatch
+	for _, match := range matches {
+		if len(result) == 0 || match.Start >= result[len(result)-1].End {
+			result = append(result, match)
+		}
+	}
+	return result
+}
+
+// Replace the matches within a part of the text which starts at offset. The
+// mask is written into the part where the match starts, and the rest of the
+// match is removed from the parts which follow.
+func (r *Redactor) replace(part string, matches []PIIMatch, offset int) string {
+	var str strings.Builder
+	pos := 0
+	for _, match := range matches {
+		start, end := match.Start-offset, match.End-offset
+		if end <= 0 || start >= len(part) {
+			continue
+		}
+		str.WriteString(part[pos:max(start, 0)])
+		if start >= 0 {
+			str.WriteString(r.mask(match.Kind))
+		}
+		pos = min(end, len(part))
+	}
+	str.WriteString(part[pos:])
+	return str.String()
+}
+
+// Return the byte offset of each token within the text of the segment,
+// which is negative for a leading space the segment text does not have, or
+// nil when the text of the tokens does not match the text of the segment.
+// Special tokens are not part of the text.
+func tokenOffsets(segment Segment) []int {
+	if len(segment.Tokens) == 0 {
+		return nil
+	}
+	var joined strings.Builder
+	offsets := make([]int, len(segment.Tokens))
+	for k, token := range segment.Tokens {
+		offsets[k] = joined.Len()
+		if isSpecialText(token.Text) {
+			continue
+		}
+		joined.WriteString(token.Text)
+	}
+
+	// Segment text may differ from the tokens in leading whitespace
+	text := joined.String()
+	if strings.TrimLeft(text, " ") != strings.TrimLeft(segment.Text, " ") {
+		return nil
+	}
+	shift := (len(segment.Text) - len(strings.TrimLeft(segment.Text, " "))) - (len(text) - len(strings.TrimLeft(text, " ")))
+	for k := range offsets {
+		offsets[k] += shift
+	}
+	return offsets
+}
+
+// Return the time span of the tokens which overlap a range of byte offsets
+// into the text of the segment, or the time span of the segment if none do
+func tokenSpan(segment Segment, offsets []int, start, end int) (time.Duration, time.Duration) {
+	t0, t1, first := segment.Start, segment.End, true
+	for k, token := range segment.Tokens {
+		if isSpecialText(token.Text) || offsets[k] >= end || offsets[k]+len(token.Text) <= start {
+			continue
+		}
+		if first {
+			t0, first = token.Start, false
+		}
+		t1 = token.End
+	}
+	return t0, t1
+}
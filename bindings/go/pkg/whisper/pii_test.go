@@ -0,0 +1,71 @@
+package whisper_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ggerganov/whisper.cpp/bindings/go/pkg/whisper"
+	assert "github.com/stretchr/testify/assert"
+)
+
+func TestRedactor(t *testing.T) {
+	assert := assert.New(t)
+
+	segments := []whisper.Segment{
+		{Num: 0, Start: 0, End: 3 * time.Second, Text: "Mail bob@example.com today.", Tokens: []whisper.Token{
+			{Text: "[_BEG_]"},
+			{Text: " Mail", Start: 0, End: 500 * time.Millisecond},
+			{Text: " bob", Start: 500 * time.Millisecond, End: 800 * time.Millisecond},
+			{Text: "@example", Start: 800 * time.Millisecond, End: 1200 * time.Millisecond},
+			{Text: ".com", Start: 1200 * time.Millisecond, End: 1500 * time.Millisecond},
+			{Text: " today.", Start: 1500 * time.Millisecond, End: 2 * time.Second},
+			{Text: "[_TT_100]"},
+		}},
+		{Num: 1, Start: 3 * time.Second, End: 6 * time.Second, Text: " Call 555-123-4567 or use card 4111 1111 1111 1111"},
+		{Num: 2, Start: 6 * time.Second, End: 7 * time.Second, Text: " Nothing to see here"},
+	}
+
+	redacted, spans := whisper.NewRedactor().Redact(segments)
+	assert.Equal("Mail [EMAIL] today.", redacted[0].Text)
+	assert.Equal(" Call [PHONE] or use card [CREDIT_CARD]", redacted[1].Text)
+	assert.Equal(segments[2], redacted[2])
+
+	// Tokens are redacted, and the original segments are unchanged
+	var tokens []string
+	for _, token := range redacted[0].Tokens {
+		tokens = append(tokens, token.Text)
+	}
+	assert.Equal([]string{"[_BEG_]", " Mail", " [EMAIL]", "", "", " today.", "[_TT_100]"}, tokens)
+	assert.Equal("@example", segments[0].Tokens[3].Text)
+
+	// Spans record what was removed, and when it was said
+	if assert.Len(spans, 3) {
+		assert.Equal(whisper.PIISpan{Kind: whisper.PIIEmail, Text: "bob@example.com", Segment: 0, Offset: 5, Start: 500 * time.Millisecond, End: 1500 * time.Millisecond}, spans[0])
+		assert.Equal(whisper.PIIPhone, spans[1].Kind)
+		assert.Equal("555-123-4567", spans[1].Text)
+		assert.Equal(3*time.Second, spans[1].Start)
+		assert.Equal(whisper.PIICreditCard, spans[2].Kind)
+		assert.Equal("4111 1111 1111 1111", spans[2].Text)
+	}
+}
+
+func TestRedactorDetectorFunc(t *testing.T) {
+	assert := assert.New(t)
+
+	// A named entity recognizer which knows one name
+	ner := whisper.PIIDetectorFunc(func(text string) []whisper.PIIMatch {
+		if i := strings.Index(text, "Alice"); i >= 0 {
+			return []whisper.PIIMatch{{Kind: "PERSON", Start: i, End: i + len("Alice")}}
+		}
+		return nil
+	})
+	redactor := whisper.NewRedactor(ner)
+	redactor.SetMask(func(string) string { return "***" })
+	redacted, spans := redactor.Redact([]whisper.Segment{{Num: 4, Text: " Alice said 555-123-4567"}})
+	assert.Equal(" *** said 555-123-4567", redacted[0].Text)
+	if assert.Len(spans, 1) {
+		assert.Equal("PERSON", spans[0].Kind)
+		assert.Equal(4, spans[0].Segment)
+	}
+}
@@ -0,0 +1,359 @@
+package whisper
+
+import (
+	"errors"
+	"io"
+	"sync"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// ContextPool hands out stateful contexts of a model, so that requests can
+// be processed concurrently. Contexts are created on demand up to the
+// capacity of the pool. When a state cannot be allocated because the backend
+// is out of memory, the pool shrinks its capacity to the number of contexts
+// it holds and waits for one of them to be returned, rather than failing
+// the request.
+type ContextPool struct {
+	mu       sync.Mutex
+	cond     *sync.Cond
+	model    Model
+	idle     []Context
+	size     int // Number of contexts created, idle or in use
+	capacity int
+	closed   bool
+	oom      error // Error which last shrank the capacity
+	limit    int   // Capacity at which the backend ran out of memory, or zero
+
+	// Free the compute buffers of idle contexts
+	release bool
+
+	// Auto-scaling, when enabled
+	scale   *AutoScale
+	waiting int     // Number of calls to Get waiting for a context
+	rtf     float64 // Moving average of the real-time factor
+}
+
+// AutoScale adjusts the capacity of a ContextPool between Min and Max as
+// contexts are returned. Capacity grows while requests are waiting and the
+// average real-time factor of processing is below TargetRTF, and shrinks
+// when it rises above TargetRTF, which happens when concurrent contexts
+// compete for the same compute. If Memory is set, capacity also shrinks
+// while it reports a fraction of memory in use above MaxMemory.
+type AutoScale struct {
+	Min, Max  int
+	TargetRTF float64 // Target real-time factor (default 0.5)
+
+	// Weight of each new measurement in the moving average of the real-time
+	// factor, between zero and one (default 0.2)
+	Smoothing float64
+
+	// Return the fraction of memory in use, between zero and one
+	Memory    func() float64
+	MaxMemory float64 // Fraction of memory above which capacity shrinks (default 0.9)
+}
+
+// PoolStats reports the state of a ContextPool
+type PoolStats struct {
+	Size     int     // Number of contexts created, idle or in use
+	Idle     int     // Number of idle contexts
+	Capacity int     // Current capacity
+	Waiting  int     // Number of calls to Get waiting for a context
+	RTF      float64 // Moving average of the real-time factor, with auto-scaling
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// LIFECYCLE
+
+// NewContextPool returns a pool of up to capacity stateful contexts for
+// the model, which must implement StatefulModel. No contexts are created
+// until they are needed.
+func NewContextPool(model Model, capacity int) *ContextPool {
+	pool := &ContextPool{model: model, capacity: max(capacity, 1)}
+	pool.cond = sync.NewCond(&pool.mu)
+	return pool
+}
+
+// Close the idle contexts of the pool. Contexts in use are closed when they
+// are returned.
+func (pool *ContextPool) Close() error {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+	pool.closed = true
+	for _, context := range pool.idle {
+		closeContext(context)
+	}
+	pool.size -= len(pool.idle)
+	pool.idle = nil
+	pool.cond.Broadcast()
+	return nil
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Get returns an idle context, creating one if the pool is below capacity,
+// or waits until a context is returned. An error is returned if the pool is
+// closed, or if no context can be created at all.
+func (pool *ContextPool) Get() (Context, error) {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+	for {
+		switch {
+		case pool.closed:
+			return nil, ErrInternalAppError
+		case len(pool.idle) > 0:
+			context := pool.idle[len(pool.idle)-1]
+			pool.idle = pool.idle[:len(pool.idle)-1]
+			if !pool.release {
+				return context, nil
+			}
+			pool.mu.Unlock()
+			err := reacquire(context)
+			pool.mu.Lock()
+			if err == nil {
+				return context, nil
+			}
+
+			// Close a context whose compute buffers do not fit into memory
+			// rather than handing it out again
+			closeContext(context)
+			pool.size--
+			pool.cond.Broadcast()
+			if !isOutOfMemory(err) || pool.size == 0 {
+				return nil, err
+			}
+
+			// Shrink to the contexts which fit into memory, and wait
+			pool.capacity, pool.oom, pool.limit = pool.size, err, pool.size
+		case pool.size < pool.capacity:
+			pool.size++
+			pool.mu.Unlock()
+			context, err := newStatefulContext(pool.model)
+			pool.mu.Lock()
+			if err == nil {
+				return context, nil
+			}
+			pool.size--
+			if !isOutOfMemory(err) || pool.size == 0 {
+				return nil, err
+			}
+
+			// Shrink to the contexts which fit into memory, and wait
+			pool.capacity, pool.oom, pool.limit = pool.size, err, pool.size
+		default:
+			pool.waiting++
+			pool.cond.Wait()
+			pool.waiting--
+		}
+	}
+}
+
+// Put returns a context obtained from Get to the pool
+func (pool *ContextPool) Put(context Context) {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+	if pool.scale != nil {
+		pool.autoscale(contextStats(context))
+	}
+	if pool.closed || pool.size > pool.capacity {
+		closeContext(context)
+		pool.size--
+	} else {
+		var err error
+		if pool.release {
+			err = releaseCompute(context)
+		}
+		pool.idleOrClose(context, err)
+	}
+	pool.cond.Broadcast()
+}
+
+// Free the compute buffers of contexts while they are idle in the pool, so
+// that they only hold their KV caches. The buffers are allocated again by
+// Get, or for the idle contexts when this is turned off. When they do not
+// fit into memory, the context is closed and the pool shrinks and waits, as
+// when a context cannot be created, and Get returns an *OutOfMemoryError
+// only if the pool has no other contexts. Contexts whose buffers cannot be
+// freed are closed.
+func (pool *ContextPool) SetReleaseIdle(v bool) {
+	pool.mu.Lock()
+	pool.release = v
+	idle := pool.idle
+	pool.idle = nil
+	pool.mu.Unlock()
+
+	// Free or allocate the buffers without holding the pool
+	errs := make([]error, len(idle))
+	for i, context := range idle {
+		if v {
+			errs[i] = releaseCompute(context)
+		} else {
+			errs[i] = reacquire(context)
+		}
+	}
+
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+	for i, context := range idle {
+		pool.idleOrClose(context, errs[i])
+	}
+	pool.cond.Broadcast()
+}
+
+// Enable auto-scaling of the capacity between scale.Min and scale.Max, or
+// disable it with nil. The capacity is first clamped to the range.
+func (pool *ContextPool) SetAutoScale(scale *AutoScale) {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+	if scale == nil {
+		pool.scale = nil
+		return
+	}
+	s := *scale
+	s.Min = max(s.Min, 1)
+	s.Max = max(s.Max, s.Min)
+	if s.TargetRTF <= 0 {
+		s.TargetRTF = 0.5
+	}
+	if s.Smoothing <= 0 || s.Smoothing > 1 {
+		s.Smoothing = 0.2
+	}
+	if s.MaxMemory <= 0 {
+		s.MaxMemory = 0.9
+	}
+	pool.scale = &s
+	pool.rtf = 0
+	pool.capacity = min(max(pool.capacity, s.Min), pool.maxCapacity())
+	pool.cond.Broadcast()
+}
+
+// Return statistics for the pool
+func (pool *ContextPool) Stats() PoolStats {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+	return PoolStats{
+		Size:     pool.size,
+		Idle:     len(pool.idle),
+		Capacity: pool.capacity,
+		Waiting:  pool.waiting,
+		RTF:      pool.rtf,
+	}
+}
+
+// Return the number of contexts the pool can hold, which is less than the
+// capacity it was created with if the backend ran out of memory
+func (pool *ContextPool) Capacity() int {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+	return pool.capacity
+}
+
+// Return the number of contexts created, both idle and in use
+func (pool *ContextPool) Size() int {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+	return pool.size
+}
+
+// Return the out of memory error which last shrank the capacity of the
+// pool, or nil
+func (pool *ContextPool) Err() error {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+	return pool.oom
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// Adjust the capacity from the statistics of a returned context
+func (pool *ContextPool) autoscale(stats ProcessStats) {
+	scale := pool.scale
+	if stats.RTF > 0 {
+		if pool.rtf == 0 {
+			pool.rtf = stats.RTF
+		} else {
+			pool.rtf += scale.Smoothing * (stats.RTF - pool.rtf)
+		}
+	}
+	switch {
+	case scale.Memory != nil && scale.Memory() > scale.MaxMemory:
+		pool.capacity = max(pool.capacity-1, scale.Min)
+	case pool.rtf > scale.TargetRTF:
+		pool.capacity = max(pool.capacity-1, scale.Min)
+	case pool.waiting > 0 && pool.rtf > 0:
+		pool.capacity = min(pool.capacity+1, pool.maxCapacity())
+	}
+}
+
+// Return a context to the idle contexts, or close it if the pool is closed
+// or its compute buffers could not be freed or allocated, shrinking the pool
+// when they do not fit into memory. Must be called with the lock held.
+func (pool *ContextPool) idleOrClose(context Context, err error) {
+	if err == nil && !pool.closed {
+		pool.idle = append(pool.idle, context)
+		return
+	}
+	closeContext(context)
+	pool.size--
+	if isOutOfMemory(err) && pool.size > 0 {
+		pool.capacity, pool.oom, pool.limit = pool.size, err, pool.size
+	}
+}
+
+// Return the largest capacity allowed by auto-scaling, which is below the
+// capacity at which the backend ran out of memory
+func (pool *ContextPool) maxCapacity() int {
+	if pool.limit > 0 {
+		return max(min(pool.scale.Max, pool.limit), 1)
+	}
+	return pool.scale.Max
+}
+
+// Return a new stateful context of a model, or an error if the model does
+// not implement StatefulModel
+func newStatefulContext(model Model) (Context, error) {
+	if model, ok := model.(StatefulModel); ok {
+		return model.NewStatefulContext()
+	}
+	return nil, ErrInternalAppError
+}
+
+// Close a context which implements io.Closer
+func closeContext(context Context) error {
+	if closer, ok := context.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// Free or allocate again the compute buffers of a context which implements
+// ComputeReleaser
+func releaseCompute(context Context) error {
+	if releaser, ok := context.(ComputeReleaser); ok {
+		return releaser.ReleaseComputeBuffers()
+	}
+	return nil
+}
+
+func reacquire(context Context) error {
+	if releaser, ok := context.(ComputeReleaser); ok {
+		return releaser.Reacquire()
+	}
+	return nil
+}
+
+// Return the statistics of the last call to Process of a context which
+// implements StatsReporter
+func contextStats(context Context) ProcessStats {
+	if reporter, ok := context.(StatsReporter); ok {
+		return reporter.Stats()
+	}
+	return ProcessStats{}
+}
+
+func isOutOfMemory(err error) bool {
+	return errors.Is(err, ErrOutOfMemory)
+}
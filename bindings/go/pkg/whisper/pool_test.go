@@ -0,0 +1,289 @@
+package whisper_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ggerganov/whisper.cpp/bindings/go/pkg/whisper"
+	assert "github.com/stretchr/testify/assert"
+)
+
+// limitedModel allocates up to limit stateful contexts before running out
+// of memory
+type limitedModel struct {
+	whisper.Model
+	sync.Mutex
+	limit, n int
+}
+
+type closerContext struct {
+	whisper.Context
+	model     *limitedModel
+	rtf       float64
+	released  bool
+	reacquire func() error
+}
+
+func (model *limitedModel) NewStatefulContext() (whisper.Context, error) {
+	model.Lock()
+	defer model.Unlock()
+	if model.n >= model.limit {
+		return nil, &whisper.OutOfMemoryError{Backend: "GPU", States: model.n}
+	}
+	model.n++
+	return &closerContext{model: model}, nil
+}
+
+func (context *closerContext) Stats() whisper.ProcessStats {
+	return whisper.ProcessStats{RTF: context.rtf}
+}
+
+func (context *closerContext) Close() error {
+	context.model.Lock()
+	defer context.model.Unlock()
+	context.model.n--
+	return nil
+}
+
+func (context *closerContext) ReleaseComputeBuffers() error {
+	context.released = true
+	return nil
+}
+
+func (context *closerContext) Reacquire() error {
+	if context.reacquire != nil {
+		if err := context.reacquire(); err != nil {
+			return err
+		}
+	}
+	context.released = false
+	return nil
+}
+
+func TestContextPoolShrink(t *testing.T) {
+	assert := assert.New(t)
+
+	model := &limitedModel{limit: 2}
+	pool := whisper.NewContextPool(model, 4)
+	assert.Equal(4, pool.Capacity())
+
+	a, err := pool.Get()
+	assert.NoError(err)
+	b, err := pool.Get()
+	assert.NoError(err)
+
+	// The third context does not fit, so the pool shrinks and waits
+	got := make(chan whisper.Context)
+	go func() {
+		c, err := pool.Get()
+		assert.NoError(err)
+		got <- c
+	}()
+	assert.Eventually(func() bool { return pool.Capacity() == 2 }, time.Second, time.Millisecond)
+	assert.ErrorIs(pool.Err(), whisper.ErrOutOfMemory)
+	pool.Put(a)
+	assert.Equal(a, <-got)
+
+	pool.Put(a)
+	pool.Put(b)
+	assert.Equal(2, pool.Size())
+	assert.NoError(pool.Close())
+	assert.Equal(0, model.n)
+}
+
+func TestContextPoolNoMemory(t *testing.T) {
+	assert := assert.New(t)
+
+	pool := whisper.NewContextPool(&limitedModel{limit: 0}, 2)
+	_, err := pool.Get()
+	var oom *whisper.OutOfMemoryError
+	assert.ErrorAs(err, &oom)
+	assert.Equal("GPU", oom.Backend)
+}
+
+func TestStatefulContext(t *testing.T) {
+	assert := assert.New(t)
+
+	model, err := fullModel(whisper.New(ModelPath))
+	assert.NoError(err)
+	defer model.Close()
+
+	a, err := fullContext(model.NewStatefulContext())
+	assert.NoError(err)
+	defer a.Close()
+	b, err := fullContext(model.NewStatefulContext())
+	assert.NoError(err)
+	defer b.Close()
+
+	// Stateful contexts process concurrently
+	data := make([]float32, whisper.SampleRate)
+	var wg sync.WaitGroup
+	for _, context := range []whisper.Context{a, b} {
+		wg.Add(1)
+		go func(context whisper.Context) {
+			defer wg.Done()
+			assert.NoError(context.Process(data, nil, nil, nil))
+		}(context)
+	}
+	wg.Wait()
+	assert.Zero(model.GateStats().Rejected)
+}
+
+func TestStatefulContextClosed(t *testing.T) {
+	assert := assert.New(t)
+
+	model, err := fullModel(whisper.New(ModelPath))
+	assert.NoError(err)
+	defer model.Close()
+
+	context, err := fullContext(model.NewStatefulContext())
+	assert.NoError(err)
+	assert.NoError(context.Close())
+	assert.NoError(context.Close())
+
+	// A closed context does not fall back to the shared state of the model
+	data := make([]float32, whisper.SampleRate)
+	assert.ErrorIs(context.Process(data, nil, nil, nil), whisper.ErrInternalAppError)
+	_, err = context.NextSegment()
+	assert.ErrorIs(err, whisper.ErrInternalAppError)
+	_, err = context.DetectLanguageTop(1)
+	assert.ErrorIs(err, whisper.ErrInternalAppError)
+	assert.ErrorIs(context.Reacquire(), whisper.ErrInternalAppError)
+	assert.Zero(context.Timings())
+
+	// The shared state of the model is still usable
+	shared, err := fullContext(model.NewContext())
+	assert.NoError(err)
+	assert.NoError(shared.Process(data, nil, nil, nil))
+}
+
+func TestContextPoolAutoScale(t *testing.T) {
+	assert := assert.New(t)
+
+	model := &limitedModel{limit: 8}
+	pool := whisper.NewContextPool(model, 1)
+	pool.SetAutoScale(&whisper.AutoScale{Min: 1, Max: 3, TargetRTF: 0.5, Smoothing: 1})
+
+	// Fast processing with a waiting request grows the pool
+	a, err := pool.Get()
+	assert.NoError(err)
+	got := make(chan whisper.Context)
+	go func() {
+		c, err := pool.Get()
+		assert.NoError(err)
+		got <- c
+	}()
+	assert.Eventually(func() bool { return pool.Stats().Waiting == 1 }, time.Second, time.Millisecond)
+	a.(*closerContext).rtf = 0.1
+	pool.Put(a)
+	b := <-got
+	assert.Equal(2, pool.Capacity())
+
+	// Slow processing shrinks the pool
+	b.(*closerContext).rtf = 2
+	pool.Put(b)
+	assert.Equal(1, pool.Capacity())
+	assert.Equal(1, pool.Size())
+	assert.NoError(pool.Close())
+}
+
+func TestContextPoolReleaseIdle(t *testing.T) {
+	assert := assert.New(t)
+
+	pool := whisper.NewContextPool(&limitedModel{limit: 2}, 2)
+	pool.SetReleaseIdle(true)
+	a, err := pool.Get()
+	assert.NoError(err)
+
+	// Idle contexts hold no compute buffers
+	pool.Put(a)
+	assert.True(a.(*closerContext).released)
+	b, err := pool.Get()
+	assert.NoError(err)
+	assert.Same(a, b)
+	assert.False(b.(*closerContext).released)
+}
+
+func TestContextPoolReacquireOutOfMemory(t *testing.T) {
+	assert := assert.New(t)
+
+	model := &limitedModel{limit: 2}
+	pool := whisper.NewContextPool(model, 2)
+	pool.SetReleaseIdle(true)
+	a, err := pool.Get()
+	assert.NoError(err)
+	b, err := pool.Get()
+	assert.NoError(err)
+	pool.Put(a)
+	pool.Put(b)
+
+	// The compute buffers are allocated without holding the pool, and a
+	// context whose buffers do not fit is closed, shrinking the pool to the
+	// contexts which do
+	b.(*closerContext).reacquire = func() error {
+		assert.Equal(1, pool.Stats().Idle)
+		return &whisper.OutOfMemoryError{Backend: "GPU", States: 2}
+	}
+	c, err := pool.Get()
+	assert.NoError(err)
+	assert.Same(a, c)
+	assert.Equal(1, pool.Capacity())
+	assert.Equal(1, pool.Size())
+	assert.ErrorIs(pool.Err(), whisper.ErrOutOfMemory)
+	assert.Equal(1, model.n)
+}
+
+func TestContextPoolReleaseIdleOutOfMemory(t *testing.T) {
+	assert := assert.New(t)
+
+	model := &limitedModel{limit: 2}
+	pool := whisper.NewContextPool(model, 2)
+	pool.SetReleaseIdle(true)
+	a, err := pool.Get()
+	assert.NoError(err)
+	b, err := pool.Get()
+	assert.NoError(err)
+	pool.Put(a)
+	pool.Put(b)
+
+	// Allocating the buffers of idle contexts again closes those which do
+	// not fit, and shrinks the pool as Get does
+	b.(*closerContext).reacquire = func() error {
+		return &whisper.OutOfMemoryError{Backend: "GPU", States: 2}
+	}
+	pool.SetReleaseIdle(false)
+	assert.False(a.(*closerContext).released)
+	assert.Equal(1, pool.Stats().Idle)
+	assert.Equal(1, pool.Capacity())
+	assert.Equal(1, pool.Size())
+	assert.ErrorIs(pool.Err(), whisper.ErrOutOfMemory)
+	assert.Equal(1, model.n)
+}
+
+func TestReleaseComputeBuffers(t *testing.T) {
+	assert := assert.New(t)
+
+	model, err := fullModel(whisper.New(ModelPath))
+	assert.NoError(err)
+	defer model.Close()
+
+	data := make([]float32, whisper.SampleRate)
+	for _, stateful := range []bool{false, true} {
+		var context testContext
+		if stateful {
+			context, err = fullContext(model.NewStatefulContext())
+		} else {
+			context, err = fullContext(model.NewContext())
+		}
+		assert.NoError(err)
+
+		// Buffers are allocated on the next call to Process, or by Reacquire
+		assert.NoError(context.ReleaseComputeBuffers())
+		assert.NoError(context.Process(data, nil, nil, nil))
+		assert.NoError(context.ReleaseComputeBuffers())
+		assert.NoError(context.Reacquire())
+		assert.NoError(context.Process(data, nil, nil, nil))
+		assert.NoError(context.Close())
+	}
+}
@@ -0,0 +1,293 @@
+package whisper
+
+import (
+	"bufio"
+	"cmp"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	// Bindings
+	whisper "github.com/ggerganov/whisper.cpp/bindings/go"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// Quantization describes the types of the weights of a loaded model. Most
+// weights of a quantized model have the quantized type, but the biases and
+// normalization weights are kept as floating point.
+type Quantization struct {
+	FileType FileType     // Type of most of the weights
+	Types    []WeightType // Weights of each type, the largest first
+}
+
+// WeightType is the number and size of the weight tensors of one type
+type WeightType struct {
+	Type    string // Name of the ggml type, such as "q5_0" or "f32"
+	Tensors int    // Number of tensors
+	Bytes   uint64 // Total size of the tensors in bytes
+}
+
+// The header of a tensor in a model file, which is followed by its shape,
+// its name and its data
+type tensorHeader struct {
+	Dims, Length int32
+	Type         int32
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+// Tensors which are not quantized, as in the quantize tool
+var quantizeSkip = []string{
+	"encoder.conv1.bias",
+	"encoder.conv2.bias",
+	"encoder.positional_embedding",
+	"decoder.positional_embedding",
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+// Return the file type followed by the breakdown of the weights by type,
+// such as "q5_0 (q5_0: 124 tensors 29.8 MiB, f32: 164 tensors 0.5 MiB)"
+func (q Quantization) String() string {
+	types := make([]string, 0, len(q.Types))
+	for _, t := range q.Types {
+		types = append(types, fmt.Sprintf("%s: %d tensors %.1f MiB", t.Type, t.Tensors, float64(t.Bytes)/(1<<20)))
+	}
+	return fmt.Sprintf("%v (%s)", q.FileType, strings.Join(types, ", "))
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Return the type of the weights of the model, and the number and size of
+// the weight tensors of each type
+func (model *model) Quantization() Quantization {
+	var q Quantization
+	ctx := model.rlock()
+	if ctx == nil {
+		return q
+	}
+	defer model.runlock()
+	q.FileType = FileType(ctx.Whisper_model_ftype())
+	for _, tensor := range ctx.Whisper_model_tensors() {
+		i := slices.IndexFunc(q.Types, func(t WeightType) bool {
+			return t.Type == tensor.Type
+		})
+		if i < 0 {
+			i = len(q.Types)
+			q.Types = append(q.Types, WeightType{Type: tensor.Type})
+		}
+		q.Types[i].Tensors++
+		q.Types[i].Bytes += tensor.Bytes
+	}
+	slices.SortStableFunc(q.Types, func(a, b WeightType) int {
+		return cmp.Or(cmp.Compare(b.Bytes, a.Bytes), cmp.Compare(a.Type, b.Type))
+	})
+	return q
+}
+
+// Return the file type with a name such as "q5_0", or with its number as in
+// the quantize tool
+func ParseFileType(name string) (FileType, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for t, str := range fileTypeNames {
+		if str == name {
+			return t, nil
+		}
+	}
+	if n, err := strconv.Atoi(name); err == nil {
+		if _, exists := fileTypeNames[FileType(n)]; exists {
+			return FileType(n), nil
+		}
+	}
+	return 0, fmt.Errorf("%w: %q", ErrInvalidFileType, name)
+}
+
+// QuantizeModel converts the model file at inPath to a model file at outPath
+// whose weights are quantized to the file type, in the same way as the
+// quantize tool, so that a downloaded model can be quantized where it is
+// deployed. The model is written to a temporary file next to outPath, which
+// is renamed once it is complete. An error wrapping ErrInvalidFileType is
+// returned if the file type is not a quantization, or the weights of the
+// model cannot be quantized to it.
+func QuantizeModel(inPath, outPath string, ftype FileType) error {
+	if _, exists := fileTypeNames[ftype]; !exists || !ftype.IsQuantized() {
+		return fmt.Errorf("%w: %v", ErrInvalidFileType, ftype)
+	}
+	in, err := os.Open(inPath)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.CreateTemp(filepath.Dir(outPath), "."+filepath.Base(outPath)+".*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if out != nil {
+			out.Close()
+			os.Remove(out.Name())
+		}
+	}()
+
+	w := bufio.NewWriterSize(out, 1<<20)
+	if err := quantizeModel(bufio.NewReaderSize(in, 1<<20), w, ftype); err != nil {
+		return fmt.Errorf("%s: %w", inPath, err)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(out.Name(), outPath); err != nil {
+		return err
+	}
+	out = nil
+	return nil
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// Copy the model from r to w, quantizing the two dimensional weights
+func quantizeModel(r io.Reader, w io.Writer, ftype FileType) error {
+	truncated := func(err error) error {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return fmt.Errorf("%w: truncated model", ErrUnableToLoadModel)
+		}
+		return err
+	}
+	read := func(v any) error {
+		return truncated(binary.Read(r, binary.LittleEndian, v))
+	}
+	write := func(v ...any) error {
+		for _, v := range v {
+			if err := binary.Write(w, binary.LittleEndian, v); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	copyN := func(n int64) error {
+		_, err := io.CopyN(w, r, n)
+		return truncated(err)
+	}
+
+	// Header, with the file type and quantization version of the output
+	var hdr modelFileHeader
+	if err := read(&hdr); err != nil {
+		return err
+	}
+	if hdr.Magic != ggmlFileMagic {
+		return fmt.Errorf("%w: bad magic", ErrUnableToLoadModel)
+	}
+	hdr.Ftype = whisper.QuantizationVersion*whisper.QuantizationVersionFactor + int32(ftype)
+	if err := write(hdr); err != nil {
+		return err
+	}
+
+	// Mel filters and vocabulary are copied unchanged
+	var filters [2]int32
+	if err := read(&filters); err != nil {
+		return err
+	} else if err := write(filters); err != nil {
+		return err
+	} else if err := copyN(4 * int64(filters[0]) * int64(filters[1])); err != nil {
+		return err
+	}
+	var vocab int32
+	if err := read(&vocab); err != nil {
+		return err
+	} else if err := write(vocab); err != nil {
+		return err
+	}
+	for i := int32(0); i < vocab; i++ {
+		var n uint32
+		if err := read(&n); err != nil {
+			return err
+		} else if err := write(n); err != nil {
+			return err
+		} else if err := copyN(int64(n)); err != nil {
+			return err
+		}
+	}
+
+	// Tensors until the end of the file
+	qtype := whisper.Ggml_ftype_to_ggml_type(int(ftype))
+	for {
+		var tensor tensorHeader
+		if err := binary.Read(r, binary.LittleEndian, &tensor); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return truncated(err)
+		}
+		if tensor.Dims < 1 || tensor.Dims > 4 || tensor.Length < 0 {
+			return fmt.Errorf("%w: bad tensor header", ErrUnableToLoadModel)
+		}
+		ne := make([]int32, tensor.Dims)
+		if err := read(ne); err != nil {
+			return err
+		}
+		name := make([]byte, tensor.Length)
+		if err := read(name); err != nil {
+			return err
+		}
+		n := 1
+		for _, v := range ne {
+			n *= int(v)
+		}
+
+		// Tensors which are not quantized are copied unchanged
+		ttype := whisper.TensorType(tensor.Type)
+		if tensor.Dims != 2 || slices.Contains(quantizeSkip, string(name)) {
+			bpe := 2
+			if ttype == whisper.TENSOR_TYPE_F32 {
+				bpe = 4
+			}
+			if err := write(tensor, ne, name); err != nil {
+				return err
+			} else if err := copyN(int64(n * bpe)); err != nil {
+				return err
+			}
+			continue
+		}
+
+		// Read the weights as single precision floats, and quantize them
+		data := make([]float32, n)
+		switch ttype {
+		case whisper.TENSOR_TYPE_F32:
+			if err := read(data); err != nil {
+				return err
+			}
+		case whisper.TENSOR_TYPE_F16:
+			half := make([]uint16, n)
+			if err := read(half); err != nil {
+				return err
+			}
+			whisper.Ggml_fp16_to_fp32_row(half, data)
+		default:
+			return fmt.Errorf("%w: %s has type %v, which cannot be quantized", ErrInvalidFileType, name, ttype)
+		}
+		if int(ne[0])%qtype.BlockSize() != 0 {
+			return fmt.Errorf("%w: %s has rows of %d, which is not a multiple of %d for %v", ErrInvalidFileType, name, ne[0], qtype.BlockSize(), ftype)
+		}
+		quantized, err := whisper.Ggml_quantize_chunk(qtype, data, int(ne[0]))
+		if err != nil {
+			return err
+		}
+		tensor.Type = int32(qtype)
+		if err := write(tensor, ne, name, quantized); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package whisper
+
+import (
+	"sync"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// SafetyCheck decides whether a segment is withheld from normal consumers,
+// returning true and the reason if it is, for example when a moderation
+// classifier flags the text
+type SafetyCheck func(Segment) (bool, string)
+
+// WithheldSegment is a segment which was held back for review
+type WithheldSegment struct {
+	Segment
+	Reason string
+}
+
+// Quarantine applies a safety check to segments as they are delivered, for
+// moderated live captions. Segments which pass are delivered as usual, and
+// withheld segments are routed to the review callback instead. Filter
+// wraps the segment callback of Process, and Wrap wraps a Transcriber so
+// that NextSegment skips withheld segments.
+type Quarantine struct {
+	mu     sync.Mutex
+	check  SafetyCheck
+	review func(WithheldSegment)
+	stats  QuarantineStats
+}
+
+// QuarantineStats reports the segments passed through a Quarantine
+type QuarantineStats struct {
+	Delivered int // Segments delivered to consumers
+	Withheld  int // Segments routed to review
+}
+
+// A transcriber whose segments pass through a quarantine, with the
+// decisions for the segments already checked by the segment callback
+type quarantinedTranscriber struct {
+	Transcriber
+	quarantine *Quarantine
+	checked    map[int]bool
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// LIFECYCLE
+
+// Return a quarantine which withholds the segments failing the check, and
+// calls review with each of them. The review callback can be nil, for
+// example to drop withheld segments, and is called from the goroutine which
+// delivers the segment, so it should forward the segment to a review
+// channel rather than block.
+func NewQuarantine(check SafetyCheck, review func(WithheldSegment)) *Quarantine {
+	return &Quarantine{check: check, review: review}
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Return a segment callback which calls fn with the segments which pass the
+// check, to pass to Process
+func (q *Quarantine) Filter(fn SegmentCallback) SegmentCallback {
+	return func(segment Segment) {
+		if q.Pass(segment) && fn != nil {
+			fn(segment)
+		}
+	}
+}
+
+// Return a transcriber whose segments pass through the quarantine, both
+// those delivered to the segment callback of Process and those returned by
+// NextSegment
+func (q *Quarantine) Wrap(t Transcriber) Transcriber {
+	return &quarantinedTranscriber{Transcriber: t, quarantine: q}
+}
+
+// Check a segment, routing it to review if it is withheld, and return true
+// if it can be delivered
+func (q *Quarantine) Pass(segment Segment) bool {
+	withhold, reason := false, ""
+	if q.check != nil {
+		withhold, reason = q.check(segment)
+	}
+	q.mu.Lock()
+	if withhold {
+		q.stats.Withheld++
+	} else {
+		q.stats.Delivered++
+	}
+	q.mu.Unlock()
+	if withhold && q.review != nil {
+		q.review(WithheldSegment{Segment: segment, Reason: reason})
+	}
+	return !withhold
+}
+
+// Return the number of segments delivered and withheld
+func (q *Quarantine) Stats() QuarantineStats {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.stats
+}
+
+func (t *quarantinedTranscriber) Process(data []float32, encoderBeginCallback EncoderBeginCallback, segmentCallback SegmentCallback, progressCallback ProgressCallback) error {
+	t.checked = make(map[int]bool)
+	if segmentCallback != nil {
+		fn := segmentCallback
+		segmentCallback = func(segment Segment) {
+			pass := t.quarantine.Pass(segment)
+			t.checked[segment.Num] = pass
+			if pass {
+				fn(segment)
+			}
+		}
+	}
+	return t.Transcriber.Process(data, encoderBeginCallback, segmentCallback, progressCallback)
+}
+
+// Return the next segment which passes the check. Segments which were
+// already checked by the segment callback are not routed to review again.
+func (t *quarantinedTranscriber) NextSegment() (Segment, error) {
+	for {
+		segment, err := t.Transcriber.NextSegment()
+		if err != nil {
+			return segment, err
+		}
+		pass, checked := t.checked[segment.Num]
+		if !checked {
+			pass = t.quarantine.Pass(segment)
+		}
+		if pass {
+			return segment, nil
+		}
+	}
+}
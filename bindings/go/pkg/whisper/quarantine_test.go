@@ -0,0 +1,89 @@
+package whisper_test
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/ggerganov/whisper.cpp/bindings/go/pkg/whisper"
+	assert "github.com/stretchr/testify/assert"
+)
+
+// A transcriber which delivers fixed segments to the callback and through
+// NextSegment
+type segmentsTranscriber struct {
+	segments []whisper.Segment
+	next     int
+}
+
+func (f *segmentsTranscriber) Process(_ []float32, _ whisper.EncoderBeginCallback, fn whisper.SegmentCallback, _ whisper.ProgressCallback) error {
+	f.next = 0
+	if fn != nil {
+		for _, segment := range f.segments {
+			fn(segment)
+		}
+	}
+	return nil
+}
+
+func (f *segmentsTranscriber) NextSegment() (whisper.Segment, error) {
+	if f.next >= len(f.segments) {
+		return whisper.Segment{}, io.EOF
+	}
+	f.next++
+	return f.segments[f.next-1], nil
+}
+
+func TestQuarantine(t *testing.T) {
+	assert := assert.New(t)
+
+	var review []whisper.WithheldSegment
+	quarantine := whisper.NewQuarantine(func(segment whisper.Segment) (bool, string) {
+		if strings.Contains(segment.Text, "darn") {
+			return true, "profanity"
+		}
+		return false, ""
+	}, func(segment whisper.WithheldSegment) {
+		review = append(review, segment)
+	})
+	transcriber := quarantine.Wrap(&segmentsTranscriber{segments: []whisper.Segment{
+		{Num: 0, Text: " Hello"},
+		{Num: 1, Text: " darn it"},
+		{Num: 2, Text: " goodbye"},
+	}})
+
+	// Withheld segments are not delivered to the callback
+	var live []string
+	assert.NoError(transcriber.Process(nil, nil, func(segment whisper.Segment) {
+		live = append(live, segment.Text)
+	}, nil))
+	assert.Equal([]string{" Hello", " goodbye"}, live)
+	if assert.Len(review, 1) {
+		assert.Equal(" darn it", review[0].Text)
+		assert.Equal("profanity", review[0].Reason)
+	}
+
+	// Nor returned by NextSegment, without being reviewed twice
+	var text []string
+	for {
+		segment, err := transcriber.NextSegment()
+		if err != nil {
+			assert.Equal(io.EOF, err)
+			break
+		}
+		text = append(text, segment.Text)
+	}
+	assert.Equal([]string{" Hello", " goodbye"}, text)
+	assert.Len(review, 1)
+	assert.Equal(whisper.QuarantineStats{Delivered: 2, Withheld: 1}, quarantine.Stats())
+
+	// Without a callback, segments are checked by NextSegment
+	assert.NoError(transcriber.Process(nil, nil, nil, nil))
+	for {
+		if _, err := transcriber.NextSegment(); err != nil {
+			break
+		}
+	}
+	assert.Len(review, 2)
+	assert.Equal(whisper.QuarantineStats{Delivered: 4, Withheld: 2}, quarantine.Stats())
+}
@@ -0,0 +1,174 @@
+package whisper
+
+import (
+	gocontext "context"
+	"errors"
+	"io"
+	"strings"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// Recognizer transcribes audio with a speech recognition engine. Whisper
+// implements it with NewRecognizer and NewPoolRecognizer, and other engines,
+// such as cloud APIs, can implement it so that applications can swap or
+// combine engines behind one interface.
+type Recognizer interface {
+	// Transcribe 16kHz mono audio data, returning the error of the context
+	// if it is cancelled
+	Recognize(ctx gocontext.Context, data []float32) (Recognition, error)
+}
+
+// Recognition is the transcription of audio by a Recognizer
+type Recognition struct {
+	// Name of the engine which transcribed the audio, such as "whisper"
+	Engine string
+
+	// Text of the audio, and its segments when the engine reports them
+	Text     string
+	Segments []Segment
+
+	// Spoken language, when the engine reports it
+	Language string
+
+	// Confidence that the text is correct between zero and one, or negative
+	// when the engine does not report it. Whisper reports the geometric mean
+	// of the probabilities of the text tokens.
+	Confidence float64
+}
+
+// FallbackRecognizer transcribes with each of its recognizers in order,
+// until one is confident enough in its text
+type FallbackRecognizer struct {
+	// Confidence at which the text of a recognizer is accepted. Recognitions
+	// with an unknown confidence are always accepted.
+	Threshold float64
+
+	Recognizers []Recognizer
+}
+
+// Recognizes with one transcriber at a time, or with a context of a pool
+type transcriberRecognizer struct {
+	transcriber Transcriber
+	pool        *ContextPool
+}
+
+// Make sure the recognizers adhere to the interface
+var _ Recognizer = (*FallbackRecognizer)(nil)
+var _ Recognizer = (*transcriberRecognizer)(nil)
+
+///////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+// Name of the engine of whisper recognitions
+const whisperEngine = "whisper"
+
+///////////////////////////////////////////////////////////////////////////////
+// LIFECYCLE
+
+// Return a recognizer which transcribes with a context or another
+// transcriber, which must not be used by more than one goroutine at a time.
+// The language of a context is that it detected, or that which is set.
+func NewRecognizer(transcriber Transcriber) Recognizer {
+	return &transcriberRecognizer{transcriber: transcriber}
+}
+
+// Return a recognizer which transcribes with the contexts of a pool, so that
+// it can be used by several goroutines at a time
+func NewPoolRecognizer(pool *ContextPool) Recognizer {
+	return &transcriberRecognizer{pool: pool}
+}
+
+// Return a recognizer which falls back to the next recognizer when the
+// confidence of a recognition is below the threshold
+func NewFallbackRecognizer(threshold float64, recognizers ...Recognizer) *FallbackRecognizer {
+	return &FallbackRecognizer{Threshold: threshold, Recognizers: recognizers}
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+func (r *transcriberRecognizer) Recognize(ctx gocontext.Context, data []float32) (Recognition, error) {
+	transcriber := r.transcriber
+	if r.pool != nil {
+		context, err := r.pool.Get()
+		if err != nil {
+			return Recognition{Engine: whisperEngine, Confidence: -1}, err
+		}
+		defer r.pool.Put(context)
+		transcriber = context
+	}
+	return recognize(ctx, transcriber, data)
+}
+
+// Recognize with each recognizer in order, and return the first recognition
+// whose confidence reaches the threshold. When none does, the most confident
+// recognition is returned. Recognizers which fail are skipped, and their
+// errors are returned when all of them fail.
+func (r *FallbackRecognizer) Recognize(ctx gocontext.Context, data []float32) (Recognition, error) {
+	best := Recognition{Confidence: -1}
+	var errs []error
+	found := false
+	for _, recognizer := range r.Recognizers {
+		recognition, err := recognizer.Recognize(ctx, data)
+		if ctx.Err() != nil {
+			return recognition, ctx.Err()
+		} else if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if recognition.Confidence < 0 || recognition.Confidence >= r.Threshold {
+			return recognition, nil
+		}
+		if !found || recognition.Confidence > best.Confidence {
+			best, found = recognition, true
+		}
+	}
+	if !found {
+		if len(errs) == 0 {
+			return best, ErrInternalAppError
+		}
+		return best, errors.Join(errs...)
+	}
+	return best, nil
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// Transcribe the audio with the transcriber, aborting when the context is
+// cancelled
+func recognize(ctx gocontext.Context, transcriber Transcriber, data []float32) (Recognition, error) {
+	recognition := Recognition{Engine: whisperEngine, Confidence: -1}
+	err := transcriber.Process(data, func() bool {
+		return ctx.Err() == nil
+	}, nil, nil)
+	if ctx.Err() != nil {
+		return recognition, ctx.Err()
+	} else if err != nil {
+		return recognition, err
+	}
+
+	var text []string
+	for {
+		segment, err := transcriber.NextSegment()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return recognition, err
+		}
+		if t := strings.TrimSpace(segment.Text); t != "" {
+			text = append(text, t)
+		}
+		recognition.Segments = append(recognition.Segments, segment)
+	}
+	recognition.Text = strings.Join(text, " ")
+	recognition.Confidence = confidence(recognition.Segments...)
+	if context, ok := transcriber.(Context); ok {
+		if recognition.Language = context.DetectedLanguage(); recognition.Language == "" {
+			recognition.Language = context.Language()
+		}
+	}
+	return recognition, nil
+}
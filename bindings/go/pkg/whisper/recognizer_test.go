@@ -0,0 +1,126 @@
+package whisper_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/ggerganov/whisper.cpp/bindings/go/pkg/whisper"
+	assert "github.com/stretchr/testify/assert"
+)
+
+// A recognizer which returns a fixed recognition
+type fixedRecognizer struct {
+	recognition whisper.Recognition
+	err         error
+	calls       int
+}
+
+func (r *fixedRecognizer) Recognize(_ context.Context, _ []float32) (whisper.Recognition, error) {
+	r.calls++
+	return r.recognition, r.err
+}
+
+// A transcriber which returns the same segment for each call
+type tokenTranscriber struct {
+	segments []whisper.Segment
+	next     []whisper.Segment
+}
+
+func (f *tokenTranscriber) Process(_ []float32, begin whisper.EncoderBeginCallback, _ whisper.SegmentCallback, _ whisper.ProgressCallback) error {
+	if begin != nil && !begin() {
+		return whisper.ErrProcessingFailed
+	}
+	f.next = f.segments
+	return nil
+}
+
+func (f *tokenTranscriber) NextSegment() (whisper.Segment, error) {
+	if len(f.next) == 0 {
+		return whisper.Segment{}, io.EOF
+	}
+	segment := f.next[0]
+	f.next = f.next[1:]
+	return segment, nil
+}
+
+func TestRecognizer(t *testing.T) {
+	assert := assert.New(t)
+
+	// Confidence is the geometric mean of the probabilities of the text
+	// tokens, without the special tokens
+	transcriber := &tokenTranscriber{segments: []whisper.Segment{
+		{Text: " hello", Tokens: []whisper.Token{{Text: "[_BEG_]", P: 0.01}, {Text: " hello", P: 0.5}}},
+		{Text: " world", Tokens: []whisper.Token{{Text: " world", P: 0.5}, {Text: "<|endoftext|>", P: 0.01}}},
+	}}
+	recognition, err := whisper.NewRecognizer(transcriber).Recognize(context.Background(), nil)
+	assert.NoError(err)
+	assert.Equal("whisper", recognition.Engine)
+	assert.Equal("hello world", recognition.Text)
+	assert.Len(recognition.Segments, 2)
+	assert.InDelta(0.5, recognition.Confidence, 1e-9)
+
+	// Processing is aborted when the context is cancelled
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = whisper.NewRecognizer(transcriber).Recognize(ctx, nil)
+	assert.ErrorIs(err, context.Canceled)
+}
+
+func TestFallbackRecognizer(t *testing.T) {
+	assert := assert.New(t)
+
+	// The first recognition which is confident enough is returned
+	low := &fixedRecognizer{recognition: whisper.Recognition{Engine: "low", Confidence: 0.4}}
+	high := &fixedRecognizer{recognition: whisper.Recognition{Engine: "high", Confidence: 0.9}}
+	unused := &fixedRecognizer{recognition: whisper.Recognition{Engine: "unused", Confidence: 1}}
+	recognition, err := whisper.NewFallbackRecognizer(0.8, low, high, unused).Recognize(context.Background(), nil)
+	assert.NoError(err)
+	assert.Equal("high", recognition.Engine)
+	assert.Equal(0, unused.calls)
+
+	// Otherwise the most confident, skipping recognizers which fail
+	failed := &fixedRecognizer{err: errors.New("unavailable")}
+	lower := &fixedRecognizer{recognition: whisper.Recognition{Engine: "lower", Confidence: 0.2}}
+	recognition, err = whisper.NewFallbackRecognizer(0.8, failed, low, lower).Recognize(context.Background(), nil)
+	assert.NoError(err)
+	assert.Equal("low", recognition.Engine)
+
+	// Unknown confidence is accepted
+	unknown := &fixedRecognizer{recognition: whisper.Recognition{Engine: "unknown", Confidence: -1}}
+	recognition, err = whisper.NewFallbackRecognizer(0.8, unknown, high).Recognize(context.Background(), nil)
+	assert.NoError(err)
+	assert.Equal("unknown", recognition.Engine)
+
+	// The errors are returned when all recognizers fail
+	_, err = whisper.NewFallbackRecognizer(0.8, failed, failed).Recognize(context.Background(), nil)
+	assert.ErrorContains(err, "unavailable")
+}
+
+func TestPoolRecognizer(t *testing.T) {
+	assert := assert.New(t)
+	if _, err := os.Stat(ModelPath); os.IsNotExist(err) {
+		t.Skip("Skipping test, model not found:", ModelPath)
+	}
+	if _, err := os.Stat(SamplePath); os.IsNotExist(err) {
+		t.Skip("Skipping test, sample not found:", SamplePath)
+	}
+	model, err := whisper.New(ModelPath)
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+	defer model.Close()
+	pool := whisper.NewContextPool(model, 1)
+	defer pool.Close()
+
+	recognition, err := whisper.NewPoolRecognizer(pool).Recognize(context.Background(), loadSamples(t, SamplePath))
+	assert.NoError(err)
+	assert.Equal("whisper", recognition.Engine)
+	assert.Equal("en", recognition.Language)
+	if len(recognition.Segments) > 0 {
+		assert.GreaterOrEqual(recognition.Confidence, 0.0)
+		assert.LessOrEqual(recognition.Confidence, 1.0)
+	}
+}
@@ -0,0 +1,167 @@
+package whisper
+
+import (
+	"slices"
+	"strings"
+	"time"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// RepetitionOptions configure the detection of decoder repetition loops,
+// where the decoder repeats a word or phrase, often until the end of the
+// window. The repeats of a loop are removed from the segments, so that only
+// the first copy of the phrase remains.
+type RepetitionOptions struct {
+	// Longest sequence of tokens which is checked for repeats (default 8)
+	NGram int
+
+	// Number of copies of a sequence in a row which make a loop (default 4)
+	Repeats int
+}
+
+// The tokens of the segments which were removed as repeats, and the end of
+// the last loop which was found
+type repetitions struct {
+	dropped map[int][]bool
+	loops   int
+	tokens  int
+	end     time.Duration
+}
+
+// A text token of a segment
+type repeatToken struct {
+	segment, token, id int
+	end                time.Duration
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Return copies of the segments with the repeats of each repetition loop
+// removed from their text and tokens, and the number of loops which were
+// found. Loops are found across segments, and segments which only contain
+// repeats are removed, so that Num is no longer sequential.
+func CollapseRepetitions(segments []Segment, opts RepetitionOptions) ([]Segment, int) {
+	r := findRepetitions(segments, 0, opts)
+	result := make([]Segment, 0, len(segments))
+	for i, segment := range segments {
+		if segment, ok := r.collapse(segment, i); ok {
+			result = append(result, segment)
+		}
+	}
+	return result, r.loops
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// Find the loops in the text tokens of the segments, which are numbered from
+// first. A loop is the shortest sequence of up to NGram tokens which repeats
+// at least Repeats times in a row, and its repeats extend over any partial
+// copy which follows.
+func findRepetitions(segments []Segment, first int, opts RepetitionOptions) *repetitions {
+	ngram, repeats := opts.NGram, opts.Repeats
+	if ngram <= 0 {
+		ngram = 8
+	}
+	if repeats <= 1 {
+		repeats = 4
+	}
+
+	// Flatten the text tokens of the segments
+	var seq []repeatToken
+	for i, segment := range segments {
+		for k, token := range segment.Tokens {
+			if !isSpecialText(token.Text) {
+				seq = append(seq, repeatToken{segment: i, token: k, id: token.Id, end: token.End})
+			}
+		}
+	}
+	same := func(i, j, n int) bool {
+		for k := 0; k < n; k++ {
+			if seq[i+k].id != seq[j+k].id {
+				return false
+			}
+		}
+		return true
+	}
+
+	// Mark the repeats of each loop as dropped
+	r := &repetitions{dropped: make(map[int][]bool)}
+	for i := 0; i < len(seq); {
+		found := false
+		for n := 1; n <= ngram && i+n*repeats <= len(seq); n++ {
+			count := 1
+			for i+(count+1)*n <= len(seq) && same(i, i+count*n, n) {
+				count++
+			}
+			if count < repeats {
+				continue
+			}
+			end := i + count*n
+			for end < len(seq) && seq[end].id == seq[end-n].id {
+				end++
+			}
+			for _, t := range seq[i+n : end] {
+				num := first + t.segment
+				if r.dropped[num] == nil {
+					r.dropped[num] = make([]bool, len(segments[t.segment].Tokens))
+				}
+				r.dropped[num][t.token] = true
+			}
+			r.loops++
+			r.tokens += end - i - n
+			r.end = max(r.end, seq[end-1].end)
+			i, found = end, true
+			break
+		}
+		if !found {
+			i++
+		}
+	}
+	return r
+}
+
+// Return the segment numbered n with the repeats removed, or false if only
+// repeats remain
+func (r *repetitions) collapse(segment Segment, n int) (Segment, bool) {
+	dropped, exists := r.dropped[n]
+	if !exists {
+		return segment, true
+	}
+	offsets := tokenOffsets(segment)
+	var text strings.Builder
+	tokens := make([]Token, 0, len(segment.Tokens))
+	last := -1
+	for k, token := range segment.Tokens {
+		if dropped[k] {
+			continue
+		}
+		tokens = append(tokens, token)
+		if isSpecialText(token.Text) {
+			continue
+		}
+		if offsets != nil {
+			text.WriteString(segment.Text[max(offsets[k], 0) : offsets[k]+len(token.Text)])
+		} else {
+			text.WriteString(token.Text)
+		}
+		last = k
+	}
+	if last < 0 {
+		return segment, false
+	}
+
+	// The segment ends with its last token when the repeats were at the end
+	if slices.Contains(dropped[last+1:], true) {
+		if t := segment.Tokens[last].End; t > segment.Start && t < segment.End {
+			segment.End = t
+		}
+	}
+	segment.Text = strings.TrimSpace(text.String())
+	segment.Tokens = tokens
+	segment.Events = SegmentEvents(segment)
+	return segment, true
+}
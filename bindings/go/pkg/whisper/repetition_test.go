@@ -0,0 +1,99 @@
+package whisper_test
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/ggerganov/whisper.cpp/bindings/go/pkg/whisper"
+	"github.com/go-audio/wav"
+	assert "github.com/stretchr/testify/assert"
+)
+
+func TestCollapseRepetitions(t *testing.T) {
+	assert := assert.New(t)
+
+	token := func(id int, text string, end time.Duration) whisper.Token {
+		return whisper.Token{Id: id, Text: text, Start: end - 500*time.Millisecond, End: end}
+	}
+	segments := []whisper.Segment{
+		{Num: 0, Start: 0, End: 10 * time.Second, Text: "Thank you. Thank you. Thank you. Thank you. Thank", Tokens: []whisper.Token{
+			{Id: 50363, Text: "[_BEG_]"},
+			token(1, " Thank", time.Second), token(2, " you", 2*time.Second), token(3, ".", 3*time.Second),
+			token(1, " Thank", 4*time.Second), token(2, " you", 5*time.Second), token(3, ".", 6*time.Second),
+			token(1, " Thank", 7*time.Second), token(2, " you", 8*time.Second), token(3, ".", 9*time.Second),
+			token(1, " Thank", 9500*time.Millisecond), token(2, " you", 9700*time.Millisecond), token(3, ".", 9800*time.Millisecond),
+			token(1, " Thank", 10*time.Second),
+		}},
+		{Num: 1, Start: 10 * time.Second, End: 11 * time.Second, Text: "you.", Tokens: []whisper.Token{
+			token(2, " you", 10500*time.Millisecond), token(3, ".", 11*time.Second),
+		}},
+		{Num: 2, Start: 11 * time.Second, End: 12 * time.Second, Text: "Goodbye.", Tokens: []whisper.Token{
+			token(4, " Goodbye", 11500*time.Millisecond), token(3, ".", 12*time.Second),
+		}},
+	}
+
+	// The repeats are removed across segments, and the segment which only
+	// contained repeats is removed
+	result, loops := whisper.CollapseRepetitions(segments, whisper.RepetitionOptions{})
+	assert.Equal(1, loops)
+	if assert.Len(result, 2) {
+		assert.Equal(0, result[0].Num)
+		assert.Equal("Thank you.", result[0].Text)
+		assert.Len(result[0].Tokens, 4)
+		assert.Equal(3*time.Second, result[0].End)
+		assert.Equal(2, result[1].Num)
+		assert.Equal("Goodbye.", result[1].Text)
+	}
+
+	// Fewer copies than the threshold are not a loop
+	result, loops = whisper.CollapseRepetitions(segments, whisper.RepetitionOptions{Repeats: 6})
+	assert.Zero(loops)
+	assert.Equal(segments, result)
+}
+
+func TestProcessRepetitionDetector(t *testing.T) {
+	assert := assert.New(t)
+
+	if _, err := os.Stat(ModelPath); os.IsNotExist(err) {
+		t.Skip("Skipping test, model not found:", ModelPath)
+	}
+
+	fh, err := os.Open(SamplePath)
+	assert.NoError(err)
+	defer fh.Close()
+
+	// Decode the WAV file - load the full buffer
+	dec := wav.NewDecoder(fh)
+	buf, err := dec.FullPCMBuffer()
+	assert.NoError(err)
+	data := buf.AsFloat32Buffer().Data
+
+	model, err := fullModel(whisper.New(ModelPath))
+	assert.NoError(err)
+	assert.NotNil(model)
+	defer model.Close()
+
+	context, err := fullContext(model.NewContext())
+	assert.NoError(err)
+	context.SetRepetitionDetector(&whisper.RepetitionOptions{NGram: 4, Repeats: 2})
+	var segments []whisper.Segment
+	assert.NoError(context.Process(data, nil, func(segment whisper.Segment) {
+		segments = append(segments, segment)
+	}, nil))
+
+	// Every loop removes at least one token and skips past itself before
+	// the next window, and segments which only contained repeats are not
+	// delivered
+	stats := context.Stats()
+	assert.GreaterOrEqual(stats.RepeatedTokens, stats.Repetitions)
+	assert.LessOrEqual(stats.Reseeks, stats.Repetitions)
+	for _, segment := range segments {
+		assert.NotEmpty(segment.Tokens)
+	}
+
+	// Disabling the detector resets the counters
+	context.SetRepetitionDetector(nil)
+	assert.NoError(context.Process(data, nil, nil, nil))
+	assert.Zero(context.Stats().Repetitions)
+}
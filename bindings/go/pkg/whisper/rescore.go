@@ -0,0 +1,129 @@
+package whisper
+
+import (
+	"io"
+	"strings"
+	"time"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// Rescorer transcribes the low confidence segments of a transcription again
+// with a larger model, and keeps the text of the larger model where it is
+// materially more confident. Only the audio of those segments is processed
+// twice, so most of the cost is that of the smaller model.
+type Rescorer struct {
+	transcriber Transcriber
+	opts        RescoreOptions
+}
+
+// RescoreOptions are the options of a Rescorer. Zero values select the
+// defaults.
+type RescoreOptions struct {
+	// Segments whose average log probability of text tokens is below the
+	// threshold are rescored (default -1, the log probability threshold of
+	// whisper's temperature fallback)
+	Threshold float64
+
+	// Amount by which the average log probability of the larger model must
+	// exceed that of the segment to replace it (default 0.2)
+	Margin float64
+
+	// Audio on each side of a segment which is transcribed with it (default
+	// 100ms)
+	Pad time.Duration
+}
+
+// RescoreStats reports the segments checked by a Rescorer
+type RescoreStats struct {
+	Segments  int           // Number of segments
+	Rescored  int           // Number of segments transcribed again
+	Replaced  int           // Number of segments replaced
+	Processed time.Duration // Audio transcribed again
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// LIFECYCLE
+
+// Return a rescorer which transcribes again with a context of a larger
+// model, such as one from a ContextPool
+func NewRescorer(transcriber Transcriber, opts RescoreOptions) *Rescorer {
+	if opts.Threshold == 0 {
+		opts.Threshold = -1
+	}
+	if opts.Margin <= 0 {
+		opts.Margin = 0.2
+	}
+	if opts.Pad <= 0 {
+		opts.Pad = 100 * time.Millisecond
+	}
+	return &Rescorer{transcriber: transcriber, opts: opts}
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Rescore the segments of the audio data, whose timestamps are relative to
+// the start of the data, and return the segments with those which are
+// replaced. A replaced segment keeps its number and time span, and has the
+// text and tokens of the larger model. Segments without text tokens are not
+// rescored.
+func (r *Rescorer) Rescore(data []float32, segments []Segment) ([]Segment, RescoreStats, error) {
+	stats := RescoreStats{Segments: len(segments)}
+	result := make([]Segment, len(segments))
+	copy(result, segments)
+	for i, segment := range segments {
+		logprob, ok := segment.AvgLogprob()
+		if !ok || logprob >= r.opts.Threshold {
+			continue
+		}
+		stats.Rescored++
+		clip := ExtractClip(data, segment, r.opts.Pad)
+		stats.Processed += samplesToDuration(len(clip))
+		replacement, err := r.transcribe(clip, max(segment.Start-r.opts.Pad, 0))
+		if err != nil {
+			return result, stats, err
+		}
+		if rescored, ok := replacement.AvgLogprob(); ok && rescored >= logprob+r.opts.Margin {
+			replacement.Num, replacement.Seq, replacement.Window = segment.Num, segment.Seq, segment.Window
+			replacement.Start, replacement.End = segment.Start, segment.End
+			result[i] = replacement
+			stats.Replaced++
+		}
+	}
+	return result, stats, nil
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// Transcribe a clip which starts at offset, returning its segments as one
+// segment on the timeline of the audio
+func (r *Rescorer) transcribe(clip []float32, offset time.Duration) (Segment, error) {
+	var result Segment
+	if err := r.transcriber.Process(clip, nil, nil, nil); err != nil {
+		return result, err
+	}
+	var text []string
+	for {
+		segment, err := r.transcriber.NextSegment()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return result, err
+		}
+		segment.shift(offset)
+		if t := strings.TrimSpace(segment.Text); t != "" {
+			text = append(text, t)
+		}
+		if result.ProcessLanguage == "" {
+			result.ProcessLanguage = segment.ProcessLanguage
+		}
+		result.Tokens = append(result.Tokens, segment.Tokens...)
+		result.Events = append(result.Events, segment.Events...)
+	}
+	result.Text = strings.Join(text, " ")
+	result.Script = DetectScript(result.Text)
+	return result, nil
+}
@@ -0,0 +1,48 @@
+package whisper_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ggerganov/whisper.cpp/bindings/go/pkg/whisper"
+	assert "github.com/stretchr/testify/assert"
+)
+
+func TestRescorer(t *testing.T) {
+	assert := assert.New(t)
+
+	segment := func(num int, start time.Duration, text string, p float32) whisper.Segment {
+		return whisper.Segment{Num: num, Start: start, End: start + time.Second, Text: text, Tokens: []whisper.Token{
+			{Text: "[_BEG_]", P: 0.01},
+			{Text: text, P: p, Start: start, End: start + time.Second},
+		}}
+	}
+	larger := &tokenTranscriber{segments: []whisper.Segment{
+		{Text: "rescored", Tokens: []whisper.Token{{Text: " rescored", P: 0.3, Start: 100 * time.Millisecond, End: time.Second}}},
+	}}
+	rescorer := whisper.NewRescorer(larger, whisper.RescoreOptions{})
+
+	// Confident segments are kept, and so are those for which the larger
+	// model is not materially more confident
+	segments := []whisper.Segment{
+		segment(0, 0, "confident", 0.9),
+		segment(1, time.Second, "unsure", 0.1),
+		segment(2, 2*time.Second, "close", 0.25),
+	}
+	result, stats, err := rescorer.Rescore(make([]float32, 3*whisper.SampleRate), segments)
+	assert.NoError(err)
+	assert.Equal(whisper.RescoreStats{Segments: 3, Rescored: 2, Replaced: 1, Processed: 2300 * time.Millisecond}, stats)
+	if assert.Len(result, 3) {
+		assert.Equal(segments[0], result[0])
+		assert.Equal(segments[2], result[2])
+
+		// The replaced segment keeps its number and time span, and its
+		// tokens are moved onto the timeline of the audio
+		assert.Equal(1, result[1].Num)
+		assert.Equal("rescored", result[1].Text)
+		assert.Equal(time.Second, result[1].Start)
+		assert.Equal(2*time.Second, result[1].End)
+		assert.Equal(time.Second, result[1].Tokens[0].Start)
+	}
+	assert.Equal("unsure", segments[1].Text)
+}
@@ -0,0 +1,32 @@
+package whisper
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// Residency reports how much of a file is held in the page cache. Pages of
+// the page cache are shared by every process on the host which reads or
+// maps the file, so a model file which is fully resident is loaded by
+// further processes without reading from disk.
+//
+// The native library copies the weights out of the file into the buffers
+// of the backend, so each process still holds its own copy of the weights,
+// and weights are not shared between processes in any mode.
+// Loading with SetUseMmap avoids a second copy of the file in the memory of
+// each process while it loads, and for GPU backends the weights live in
+// device memory. Residency is how to check that processes which load the
+// same model share the file rather than reading it again.
+type Residency struct {
+	Size     int64 // Size of the file in bytes
+	Resident int64 // Bytes of the file held in the page cache
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Return the fraction of the file held in the page cache
+func (r Residency) Fraction() float64 {
+	if r.Size == 0 {
+		return 0
+	}
+	return float64(r.Resident) / float64(r.Size)
+}
@@ -0,0 +1,49 @@
+//go:build linux || darwin || freebsd
+
+package whisper
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Return how much of a file is held in the page cache, by mapping it and
+// asking the kernel which of its pages are resident
+func FileResidency(path string) (Residency, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Residency{}, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return Residency{}, err
+	} else if info.Size() == 0 {
+		return Residency{}, nil
+	}
+	buf, err := syscall.Mmap(int(f.Fd()), 0, int(info.Size()), syscall.PROT_READ, syscall.MAP_SHARED)
+	if err != nil {
+		return Residency{}, err
+	}
+	defer syscall.Munmap(buf)
+
+	// One byte per page, with the lowest bit set when the page is resident
+	page := int64(os.Getpagesize())
+	vec := make([]byte, (info.Size()+page-1)/page)
+	if _, _, errno := syscall.Syscall(syscall.SYS_MINCORE, uintptr(unsafe.Pointer(&buf[0])), uintptr(len(buf)), uintptr(unsafe.Pointer(&vec[0]))); errno != 0 {
+		return Residency{}, errno
+	}
+	result := Residency{Size: info.Size()}
+	for i, v := range vec {
+		if v&1 != 0 {
+			result.Resident += min(page, info.Size()-int64(i)*page)
+		}
+	}
+
+	// Return success
+	return result, nil
+}
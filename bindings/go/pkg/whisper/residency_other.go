@@ -0,0 +1,16 @@
+//go:build !linux && !darwin && !freebsd
+
+package whisper
+
+import (
+	"errors"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Return how much of a file is held in the page cache. This is not
+// supported on this platform.
+func FileResidency(path string) (Residency, error) {
+	return Residency{}, errors.ErrUnsupported
+}
@@ -0,0 +1,54 @@
+package whisper
+
+import (
+	// Bindings
+	whisper "github.com/ggerganov/whisper.cpp/bindings/go"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// results are the outputs of the last call to whisper_full, which are read
+// from the default state of the model, or from the state of a stateful
+// context
+type results interface {
+	Whisper_full_lang_id() int
+	Whisper_full_n_segments() int
+	Whisper_full_get_seek() int64
+	Whisper_full_set_seek(seek int64)
+	Whisper_full_get_segment_t0(segment int) int64
+	Whisper_full_get_segment_t1(segment int) int64
+	Whisper_full_get_segment_text(segment int) string
+	Whisper_full_append_segment_text(dst []byte, segment int) []byte
+	Whisper_full_n_tokens(segment int) int
+	Whisper_full_get_token_text(segment int, token int) string
+	Whisper_full_append_token_text(dst []byte, segment int, token int) []byte
+	Whisper_full_get_token_id(segment int, token int) whisper.Token
+	Whisper_full_get_token_data(segment int, token int) whisper.TokenData
+	Whisper_full_get_token_p(segment int, token int) float32
+	Whisper_full_n_vad_segments() int
+	Whisper_full_get_vad_segment_t0(segment int) int64
+	Whisper_full_get_vad_segment_t1(segment int) int64
+}
+
+// stateResults reads results from a state, and the vocabulary from the
+// context the state was created from
+type stateResults struct {
+	*whisper.State
+	ctx *whisper.Context
+}
+
+// Make sure both kinds of results adhere to the interface
+var _ results = (*whisper.Context)(nil)
+var _ results = stateResults{}
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+func (r stateResults) Whisper_full_get_token_text(segment int, token int) string {
+	return r.State.Whisper_full_get_token_text(r.ctx, segment, token)
+}
+
+func (r stateResults) Whisper_full_append_token_text(dst []byte, segment int, token int) []byte {
+	return r.State.Whisper_full_append_token_text(r.ctx, dst, segment, token)
+}
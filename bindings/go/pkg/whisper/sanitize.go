@@ -0,0 +1,95 @@
+package whisper
+
+import (
+	"time"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// The times of the segments repaired by the timestamp sanitizer, the end of
+// the last segment, and the number of segments which were repaired
+type timestampRepairs struct {
+	times   map[int][2]time.Duration
+	end     time.Duration
+	repairs int
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Return copies of the segments with monotonic, non-overlapping times, and
+// the number of segments which were repaired. A segment which starts before
+// the end of the segment before it starts with its first text token instead,
+// or at the end of the segment before when the token timestamps do not help,
+// and a segment which ends before it starts ends with its last text token, or
+// when it starts. Segments are only moved later, so that a segment which has
+// already been passed on is never changed.
+func SanitizeTimestamps(segments []Segment) ([]Segment, int) {
+	r := &timestampRepairs{times: make(map[int][2]time.Duration)}
+	result := make([]Segment, 0, len(segments))
+	for i, segment := range segments {
+		r.add(segment, i)
+		result = append(result, r.apply(segment, i))
+	}
+	return result, r.repairs
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// Repair the times of segment n, which must follow the segments added before
+func (r *timestampRepairs) add(segment Segment, n int) {
+	start, end := repairTimes(segment, r.end)
+	if start != segment.Start || end != segment.End {
+		r.times[n] = [2]time.Duration{start, end}
+		r.repairs++
+	}
+	r.end = end
+}
+
+// Return segment n with its times repaired
+func (r *timestampRepairs) apply(segment Segment, n int) Segment {
+	times, exists := r.times[n]
+	if !exists {
+		return segment
+	}
+	segment.Start, segment.End = times[0], times[1]
+	segment.Events = SegmentEvents(segment)
+	return segment
+}
+
+// Return the times of a segment which follows a segment ending at end
+func repairTimes(segment Segment, end time.Duration) (time.Duration, time.Duration) {
+	start, stop := segment.Start, segment.End
+	first, last := textTokenSpan(segment)
+	if start < end {
+		start = end
+		if first >= end && first < stop {
+			start = first
+		}
+	}
+	if stop < start {
+		stop = start
+		if last > start {
+			stop = last
+		}
+	}
+	return start, stop
+}
+
+// Return the start of the first text token and the end of the last text
+// token with timestamps, or -1 when there are none
+func textTokenSpan(segment Segment) (time.Duration, time.Duration) {
+	first, last := time.Duration(-1), time.Duration(-1)
+	for _, token := range segment.Tokens {
+		if isSpecialText(token.Text) || token.Start < 0 || token.End < token.Start {
+			continue
+		}
+		if first < 0 {
+			first = token.Start
+		}
+		last = token.End
+	}
+	return first, last
+}
@@ -0,0 +1,107 @@
+package whisper_test
+
+import (
+	"io"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/ggerganov/whisper.cpp/bindings/go/pkg/whisper"
+	assert "github.com/stretchr/testify/assert"
+)
+
+func TestSanitizeTimestamps(t *testing.T) {
+	assert := assert.New(t)
+
+	token := func(text string, start, end time.Duration) whisper.Token {
+		return whisper.Token{Text: text, Start: start, End: end}
+	}
+	segments := []whisper.Segment{
+		{Num: 0, Start: 0, End: 3 * time.Second, Text: "One two.", Tokens: []whisper.Token{
+			token("[_BEG_]", 0, 0), token(" One", 500*time.Millisecond, time.Second), token(" two.", 2*time.Second, 3*time.Second),
+		}},
+		// Overlaps the segment before, but its first token starts after it
+		{Num: 1, Start: 2 * time.Second, End: 5 * time.Second, Text: "Three.", Tokens: []whisper.Token{
+			token("[_BEG_]", 2*time.Second, 2*time.Second), token(" Three.", 3500*time.Millisecond, 5*time.Second),
+		}},
+		// Overlaps, and has no token timestamps
+		{Num: 2, Start: 4 * time.Second, End: 6 * time.Second, Text: "Four.", Tokens: []whisper.Token{
+			token(" Four.", -10*time.Millisecond, -10*time.Millisecond),
+		}},
+		// Ends before it starts
+		{Num: 3, Start: 7 * time.Second, End: 6500 * time.Millisecond, Text: "Five.", Tokens: []whisper.Token{
+			token(" Five.", 7*time.Second, 7500*time.Millisecond),
+		}},
+		{Num: 4, Start: 8 * time.Second, End: 9 * time.Second, Text: "Six."},
+	}
+
+	result, repairs := whisper.SanitizeTimestamps(segments)
+	assert.Equal(3, repairs)
+	if assert.Len(result, len(segments)) {
+		assert.Equal(segments[0], result[0])
+		assert.Equal(3500*time.Millisecond, result[1].Start)
+		assert.Equal(5*time.Second, result[1].End)
+		assert.Equal(5*time.Second, result[2].Start)
+		assert.Equal(6*time.Second, result[2].End)
+		assert.Equal(7*time.Second, result[3].Start)
+		assert.Equal(7500*time.Millisecond, result[3].End)
+		assert.Equal(segments[4], result[4])
+	}
+	for i := 1; i < len(result); i++ {
+		assert.GreaterOrEqual(result[i].Start, result[i-1].End)
+		assert.GreaterOrEqual(result[i].End, result[i].Start)
+	}
+
+	// The input is not modified, and monotonic segments are not repaired
+	assert.Equal(2*time.Second, segments[1].Start)
+	result, repairs = whisper.SanitizeTimestamps(result)
+	assert.Zero(repairs)
+	assert.Len(result, len(segments))
+}
+
+func TestProcessTimestampSanitizer(t *testing.T) {
+	assert := assert.New(t)
+
+	if _, err := os.Stat(ModelPath); os.IsNotExist(err) {
+		t.Skip("Skipping test, model not found:", ModelPath)
+	}
+	data := loadSamples(t, SamplePath)
+
+	model, err := fullModel(whisper.New(ModelPath))
+	assert.NoError(err)
+	defer model.Close()
+
+	context, err := fullContext(model.NewContext())
+	assert.NoError(err)
+	context.SetTokenTimestamps(true)
+	context.SetTimestampSanitizer(true)
+	var segments []whisper.Segment
+	assert.NoError(context.Process(data, nil, func(segment whisper.Segment) {
+		segments = append(segments, segment)
+	}, nil))
+
+	// The segments passed to the callback and returned by NextSegment are
+	// the same, and are monotonic
+	for i := 0; ; i++ {
+		segment, err := context.NextSegment()
+		if err == io.EOF {
+			assert.Equal(len(segments), i)
+			break
+		}
+		assert.NoError(err)
+		if assert.Less(i, len(segments)) {
+			assert.Equal(segments[i].Start, segment.Start)
+			assert.Equal(segments[i].End, segment.End)
+		}
+		if i > 0 {
+			assert.GreaterOrEqual(segment.Start, segments[i-1].End)
+		}
+		assert.GreaterOrEqual(segment.End, segment.Start)
+	}
+	assert.LessOrEqual(context.Stats().TimestampRepairs, len(segments))
+
+	// Disabling the sanitizer resets the counter
+	context.SetTimestampSanitizer(false)
+	assert.NoError(context.Process(data, nil, nil, nil))
+	assert.Zero(context.Stats().TimestampRepairs)
+}
@@ -0,0 +1,212 @@
+package whisper
+
+import (
+	"sync"
+	"time"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// Priority is the scheduling class of a context attached to a Scheduler
+type Priority int
+
+const (
+	PriorityBatch Priority = iota // Offline jobs, which can be delayed
+	PriorityLive                  // Live streams, which need a bounded latency
+)
+
+// Scheduler interleaves the encoder windows of stateful contexts which share
+// a device. Before each window is encoded, a context waits for its turn. Live
+// contexts go first, but after LiveShare live windows in a row a waiting
+// batch window runs, so that neither class is starved. A live stream then
+// waits at most for the window of one batch job, however long that job is.
+type Scheduler struct {
+	mu        sync.Mutex
+	liveShare int
+	held      *ticket
+	waiting   [2][]*ticket
+	streak    int
+	stats     [2]ClassStats
+}
+
+// SchedulerStats reports the windows and waiting time of each class
+type SchedulerStats struct {
+	Live  ClassStats
+	Batch ClassStats
+}
+
+// ClassStats reports the windows and waiting time of one class
+type ClassStats struct {
+	Windows uint64        // Number of windows which have been run
+	Wait    time.Duration // Total time spent waiting for a turn
+	MaxWait time.Duration // Longest time spent waiting for a turn
+}
+
+// A turn requested by a call to Process
+type ticket struct {
+	priority Priority
+	ready    chan struct{}
+	since    time.Time
+}
+
+const defaultLiveShare = 4
+
+///////////////////////////////////////////////////////////////////////////////
+// LIFECYCLE
+
+// Return a new scheduler which runs up to liveShare live windows for each
+// batch window while both are waiting. If liveShare is zero or less, a
+// default of 4 is used.
+func NewScheduler(liveShare int) *Scheduler {
+	if liveShare <= 0 {
+		liveShare = defaultLiveShare
+	}
+	return &Scheduler{liveShare: liveShare}
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (p Priority) String() string {
+	switch p {
+	case PriorityLive:
+		return "live"
+	case PriorityBatch:
+		return "batch"
+	default:
+		return "unknown"
+	}
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Attach a context to the scheduler with the given priority. The context
+// waits for its turn before each window from the next call to Process.
+func (s *Scheduler) Attach(ctx Context, priority Priority) error {
+	context, ok := ctx.(*context)
+	if !ok || (priority != PriorityLive && priority != PriorityBatch) {
+		return ErrInternalAppError
+	}
+	context.paramsMu.Lock()
+	defer context.paramsMu.Unlock()
+	context.scheduler = s
+	context.priority = priority
+	return nil
+}
+
+// Detach a context from the scheduler
+func (s *Scheduler) Detach(ctx Context) {
+	context, ok := ctx.(*context)
+	if !ok {
+		return
+	}
+	context.paramsMu.Lock()
+	defer context.paramsMu.Unlock()
+	if context.scheduler == s {
+		context.scheduler = nil
+	}
+}
+
+// Return statistics for each class
+func (s *Scheduler) Stats() SchedulerStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return SchedulerStats{
+		Live:  s.stats[PriorityLive],
+		Batch: s.stats[PriorityBatch],
+	}
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// Return an encoder begin callback which waits for a turn before calling fn,
+// and a function which gives up the turn once processing is done. While it
+// waits, the context is not counted as processing by the yielder, so that
+// YieldGPU does not wait for a window which cannot start.
+func (s *Scheduler) wrap(priority Priority, y *yielder, fn EncoderBeginCallback) (EncoderBeginCallback, func()) {
+	t := &ticket{priority: priority}
+	return func() bool {
+			if fn != nil && !fn() {
+				return false
+			}
+			s.acquire(t, y)
+			return true
+		}, func() {
+			s.release(t)
+		}
+}
+
+// Wait for a turn. When the ticket already holds the turn, it is given up to
+// any waiting window first.
+func (s *Scheduler) acquire(t *ticket, y *yielder) {
+	s.mu.Lock()
+	if s.held == t {
+		if len(s.waiting[PriorityLive])+len(s.waiting[PriorityBatch]) == 0 {
+			s.stats[t.priority].Windows++
+			s.mu.Unlock()
+			return
+		}
+		s.held = nil
+	}
+	t.ready = make(chan struct{})
+	t.since = time.Now()
+	s.waiting[t.priority] = append(s.waiting[t.priority], t)
+	if s.held == nil {
+		s.dispatch()
+	}
+	ready := t.ready
+	s.mu.Unlock()
+	if y == nil {
+		<-ready
+		return
+	}
+	y.exit()
+	<-ready
+	y.enter()
+}
+
+// Give up the turn, if the ticket holds it
+func (s *Scheduler) release(t *ticket) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.held == t {
+		s.held = nil
+		s.dispatch()
+	}
+}
+
+// Hand the turn to the next waiting window. Must be called with the lock
+// held and the turn free.
+func (s *Scheduler) dispatch() {
+	live, batch := len(s.waiting[PriorityLive]), len(s.waiting[PriorityBatch])
+	var priority Priority
+	switch {
+	case live > 0 && batch == 0:
+		priority, s.streak = PriorityLive, 0
+	case live > 0 && s.streak < s.liveShare:
+		priority = PriorityLive
+		s.streak++
+	case batch > 0:
+		priority, s.streak = PriorityBatch, 0
+	default:
+		return
+	}
+
+	// Pop the first ticket of the class
+	t := s.waiting[priority][0]
+	s.waiting[priority] = s.waiting[priority][1:]
+	s.held = t
+
+	// Update statistics
+	wait := time.Since(t.since)
+	stats := &s.stats[priority]
+	stats.Windows++
+	stats.Wait += wait
+	stats.MaxWait = max(stats.MaxWait, wait)
+
+	// Wake the waiting window
+	close(t.ready)
+}
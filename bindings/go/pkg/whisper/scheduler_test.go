@@ -0,0 +1,108 @@
+package whisper_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ggerganov/whisper.cpp/bindings/go/pkg/whisper"
+	assert "github.com/stretchr/testify/assert"
+)
+
+func TestScheduler(t *testing.T) {
+	assert := assert.New(t)
+
+	model, err := fullModel(whisper.New(ModelPath))
+	assert.NoError(err)
+	defer model.Close()
+
+	// Only contexts of this package can be attached
+	scheduler := whisper.NewScheduler(0)
+	assert.ErrorIs(scheduler.Attach(&closerContext{}, whisper.PriorityLive), whisper.ErrInternalAppError)
+
+	live, err := fullContext(model.NewStatefulContext())
+	assert.NoError(err)
+	defer live.Close()
+	batch, err := fullContext(model.NewStatefulContext())
+	assert.NoError(err)
+	defer batch.Close()
+	assert.NoError(scheduler.Attach(live, whisper.PriorityLive))
+	assert.NoError(scheduler.Attach(batch, whisper.PriorityBatch))
+
+	// Process on both contexts at the same time
+	var wg sync.WaitGroup
+	for _, context := range []whisper.Context{live, batch} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(context.Process(make([]float32, whisper.SampleRate), nil, nil, nil))
+		}()
+	}
+	wg.Wait()
+
+	stats := scheduler.Stats()
+	assert.NotZero(stats.Live.Windows)
+	assert.NotZero(stats.Batch.Windows)
+
+	// Detached contexts no longer wait for a turn
+	scheduler.Detach(batch)
+	assert.NoError(batch.Process(make([]float32, whisper.SampleRate), nil, nil, nil))
+	assert.Equal(stats.Batch.Windows, scheduler.Stats().Batch.Windows)
+}
+
+func TestSchedulerYieldGPU(t *testing.T) {
+	assert := assert.New(t)
+
+	model, err := fullModel(whisper.New(ModelPath))
+	assert.NoError(err)
+	defer model.Close()
+
+	scheduler := whisper.NewScheduler(0)
+	a, err := fullContext(model.NewStatefulContext())
+	assert.NoError(err)
+	defer a.Close()
+	b, err := fullContext(model.NewStatefulContext())
+	assert.NoError(err)
+	defer b.Close()
+	assert.NoError(scheduler.Attach(a, whisper.PriorityLive))
+	assert.NoError(scheduler.Attach(b, whisper.PriorityLive))
+	for _, context := range []whisper.Context{a, b} {
+		context.SetMaxTokensPerSegment(1)
+		context.SetTemperatureFallback(0)
+	}
+
+	// At the second window of a, which holds the turn, b waits for a turn
+	// and the device is yielded. a stops after its third window.
+	yielded := make(chan error, 1)
+	var wg sync.WaitGroup
+	windows := 0
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		assert.NoError(a.Process(make([]float32, 60*whisper.SampleRate), func() bool {
+			if windows++; windows == 2 {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					assert.NoError(b.Process(make([]float32, whisper.SampleRate), nil, nil, nil))
+				}()
+				time.Sleep(100 * time.Millisecond)
+				go func() {
+					yielded <- model.YieldGPU()
+				}()
+				time.Sleep(100 * time.Millisecond)
+			}
+			return windows < 3
+		}, nil, nil))
+	}()
+
+	// YieldGPU does not wait for the context which is waiting for a turn
+	select {
+	case err := <-yielded:
+		assert.NoError(err)
+	case <-time.After(30 * time.Second):
+		t.Fatal("YieldGPU did not return")
+	}
+	model.Resume()
+	wg.Wait()
+}
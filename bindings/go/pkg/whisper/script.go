@@ -0,0 +1,98 @@
+package whisper
+
+import (
+	"unicode"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+// Scripts which DetectScript reports, with their ISO 15924 codes
+var scripts = []struct {
+	code  string
+	table *unicode.RangeTable
+}{
+	{"Latn", unicode.Latin},
+	{"Cyrl", unicode.Cyrillic},
+	{"Grek", unicode.Greek},
+	{"Armn", unicode.Armenian},
+	{"Geor", unicode.Georgian},
+	{"Arab", unicode.Arabic},
+	{"Hebr", unicode.Hebrew},
+	{"Hani", unicode.Han},
+	{"Hira", unicode.Hiragana},
+	{"Kana", unicode.Katakana},
+	{"Hang", unicode.Hangul},
+	{"Deva", unicode.Devanagari},
+	{"Beng", unicode.Bengali},
+	{"Guru", unicode.Gurmukhi},
+	{"Gujr", unicode.Gujarati},
+	{"Orya", unicode.Oriya},
+	{"Taml", unicode.Tamil},
+	{"Telu", unicode.Telugu},
+	{"Knda", unicode.Kannada},
+	{"Mlym", unicode.Malayalam},
+	{"Sinh", unicode.Sinhala},
+	{"Thai", unicode.Thai},
+	{"Laoo", unicode.Lao},
+	{"Khmr", unicode.Khmer},
+	{"Mymr", unicode.Myanmar},
+	{"Tibt", unicode.Tibetan},
+	{"Mong", unicode.Mongolian},
+	{"Ethi", unicode.Ethiopic},
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// DetectScript returns the ISO 15924 code of the script most letters of the
+// text are written in, such as "Latn" or "Cyrl", or an empty string if it
+// has no letters of a known script. Japanese text with kana is "Jpan", and
+// Korean text with hangul is "Kore". Whisper writes Chinese in either
+// simplified or traditional characters, which are both "Hani".
+func DetectScript(text string) string {
+	counts := make([]int, len(scripts))
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		for i, script := range scripts {
+			if unicode.Is(script.table, r) {
+				counts[i]++
+				break
+			}
+		}
+	}
+	best := -1
+	for i, n := range counts {
+		if n > 0 && (best < 0 || n > counts[best]) {
+			best = i
+		}
+	}
+	if best < 0 {
+		return ""
+	}
+	switch code := scripts[best].code; code {
+	case "Hani", "Hira", "Kana":
+		if counts[scriptIndex("Hira")]+counts[scriptIndex("Kana")] > 0 {
+			return "Jpan"
+		}
+		return code
+	case "Hang":
+		return "Kore"
+	default:
+		return code
+	}
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+func scriptIndex(code string) int {
+	for i, script := range scripts {
+		if script.code == code {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,181 @@
+package whisper
+
+import (
+	"fmt"
+	"runtime"
+
+	// Bindings
+	whisper "github.com/ggerganov/whisper.cpp/bindings/go"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// OutOfMemoryError is returned when a decoding state cannot be allocated,
+// usually because the KV caches and compute buffers do not fit into the
+// memory of the backend. It matches ErrOutOfMemory with errors.Is
+type OutOfMemoryError struct {
+	Backend string // Backend requested when loading the model, "CPU" or "GPU"
+	Device  int    // Device index for the GPU backend
+	States  int    // Number of states of the model allocated at the time
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// LIFECYCLE
+
+// Return a new context with its own decoding state, which can process audio
+// at the same time as other contexts of the model. The state is freed when
+// the context or the model is closed. An *OutOfMemoryError is returned if
+// the state cannot be allocated.
+func (model *model) NewStatefulContext() (Context, error) {
+	ctx := model.rlock()
+	if ctx == nil {
+		return nil, ErrInternalAppError
+	}
+	defer model.runlock()
+
+	// Allocate the state
+	model.statesMu.Lock()
+	defer model.statesMu.Unlock()
+	state, err := ctx.Whisper_init_state()
+	if err != nil {
+		return nil, model.outOfMemory(len(model.states))
+	}
+	if model.states == nil {
+		model.states = make(map[*whisper.State]struct{})
+	}
+	model.states[state] = struct{}{}
+
+	// Create the context
+	context := model.newContext(ctx)
+	context.stateful, context.state = true, state
+	context.gate = new(gate)
+
+	track(&tracking.contexts, context)
+
+	// Return success
+	return context, nil
+}
+
+// Free the compute buffers of the decoding state. For a context which shares
+// the state of the model, the buffers of the model are freed.
+func (context *context) ReleaseComputeBuffers() error {
+	ctx := context.rlock()
+	if ctx == nil {
+		return ErrInternalAppError
+	}
+	defer context.model.runlock()
+	if err := context.acquire(context.label); err != nil {
+		return err
+	}
+	defer context.gate.release()
+
+	if context.gate == &context.model.gate {
+		ctx.Whisper_release_compute()
+	} else if context.stateful {
+		context.state.Whisper_release_compute_with_state()
+	} else {
+		return ErrInternalAppError
+	}
+
+	// Return success
+	return nil
+}
+
+// Allocate the compute buffers of the decoding state again, returning an
+// *OutOfMemoryError if they do not fit into memory
+func (context *context) Reacquire() error {
+	ctx := context.rlock()
+	if ctx == nil {
+		return ErrInternalAppError
+	}
+	defer context.model.runlock()
+	if err := context.acquire(context.label); err != nil {
+		return err
+	}
+	defer context.gate.release()
+
+	var err error
+	if context.gate == &context.model.gate {
+		err = ctx.Whisper_reacquire_compute()
+	} else if context.stateful {
+		err = ctx.Whisper_reacquire_compute_with_state(context.state)
+	} else {
+		return ErrInternalAppError
+	}
+	if err != nil {
+		context.model.statesMu.Lock()
+		defer context.model.statesMu.Unlock()
+		return context.model.outOfMemory(len(context.model.states))
+	}
+
+	// Return success
+	return nil
+}
+
+// Release the decoding state of a stateful context, and the parameters of
+// any context
+func (context *context) Close() error {
+	if !context.stateful {
+		context.paramsMu.Lock()
+		defer context.paramsMu.Unlock()
+		if !context.closed.Swap(true) {
+			context.params.Free()
+			runtime.SetFinalizer(context, nil)
+		}
+		return nil
+	}
+	if err := context.gate.acquire("close"); err != nil {
+		return err
+	}
+	defer context.gate.release()
+	if context.closed.Swap(true) {
+		return nil
+	}
+
+	// The state may already have been freed with the model
+	context.model.statesMu.Lock()
+	defer context.model.statesMu.Unlock()
+	if _, exists := context.model.states[context.state]; exists {
+		delete(context.model.states, context.state)
+		context.state.Whisper_free_state()
+	}
+
+	// Release resources, including the logits filter which would otherwise
+	// keep its callback referenced
+	context.paramsMu.Lock()
+	context.params.Free()
+	context.paramsMu.Unlock()
+	context.state = nil
+	runtime.SetFinalizer(context, nil)
+	untrack(&tracking.contexts, context)
+
+	// Return success
+	return nil
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+func (err *OutOfMemoryError) Error() string {
+	backend := err.Backend
+	if backend == "GPU" {
+		backend = fmt.Sprintf("GPU %d", err.Device)
+	}
+	return fmt.Sprintf("%v: on %s with %d states allocated", ErrOutOfMemory, backend, err.States)
+}
+
+func (err *OutOfMemoryError) Unwrap() error {
+	return ErrOutOfMemory
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+func (model *model) outOfMemory(states int) error {
+	err := &OutOfMemoryError{Backend: "CPU", States: states}
+	if model.params.UseGPU() {
+		err.Backend, err.Device = "GPU", model.params.GPUDevice()
+	}
+	return err
+}
@@ -0,0 +1,217 @@
+package whisper
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"slices"
+	"sync"
+	"time"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// StreamID identifies an input stream of a StreamManager
+type StreamID uint32
+
+// StreamManager transcribes several live input streams at once, such as the
+// participants of a meeting. Each stream is split into utterances by its own
+// Endpointer, and each utterance is transcribed with a context which is taken
+// from the pool for just that utterance, so that more streams can be served
+// than there are contexts. Streams can be written from different goroutines.
+// Segments are tagged with their stream, and their timestamps are on a
+// timeline shared by all streams, or on the timeline of the clock of the
+// stream.
+type StreamManager struct {
+	mu      sync.Mutex
+	pool    *ContextPool
+	opts    EndpointOptions
+	streams map[StreamID]*stream
+	next    StreamID
+}
+
+// StreamSegment is a segment of one stream of a StreamManager
+type StreamSegment struct {
+	Stream StreamID
+	Segment
+}
+
+type stream struct {
+	sync.Mutex
+	id         StreamID
+	clock      Clock // Maps the audio of the stream onto the shared timeline
+	written    int   // Samples written to the stream
+	endpointer *Endpointer
+}
+
+// Transcribes with a context taken from the pool for each call to Process,
+// and keeps the segments once the context is returned
+type pooledTranscriber struct {
+	pool     *ContextPool
+	segments []Segment
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// LIFECYCLE
+
+// Return a stream manager which transcribes with the contexts of the pool,
+// and splits each stream into utterances with the options
+func NewStreamManager(pool *ContextPool, opts EndpointOptions) *StreamManager {
+	return &StreamManager{pool: pool, opts: opts, streams: make(map[StreamID]*stream)}
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Open a stream whose first sample is at start on the shared timeline, such
+// as the time a participant joined the meeting, and return its identifier.
+// Identifiers are not reused.
+func (m *StreamManager) Open(start time.Duration) StreamID {
+	return m.OpenWithClock(OffsetClock(start))
+}
+
+// Open a stream whose timestamps are mapped by the clock, such as to the
+// presentation timestamps of a video or to the wall clock time the audio was
+// captured, and return its identifier. When the clock has a Mark method,
+// such as a WallClock, it is marked with the arrival of each write.
+func (m *StreamManager) OpenWithClock(clock Clock) StreamID {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.next++
+	m.streams[m.next] = &stream{
+		id:         m.next,
+		clock:      clock,
+		endpointer: NewEndpointer(&pooledTranscriber{pool: m.pool}, m.opts),
+	}
+	return m.next
+}
+
+// Return the identifiers of the open streams, in the order they were opened
+func (m *StreamManager) Streams() []StreamID {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	result := make([]StreamID, 0, len(m.streams))
+	for id := range m.streams {
+		result = append(result, id)
+	}
+	slices.Sort(result)
+	return result
+}
+
+// Write mono audio data of a stream, and return the segments of the
+// utterances it ended
+func (m *StreamManager) Write(id StreamID, data []float32) ([]StreamSegment, error) {
+	s, err := m.stream(id)
+	if err != nil {
+		return nil, err
+	}
+	s.Lock()
+	defer s.Unlock()
+	s.written += len(data)
+	if clock, ok := s.clock.(marker); ok {
+		clock.Mark(samplesToDuration(s.written), time.Now())
+	}
+	utterances, err := s.endpointer.Write(data)
+	return s.segments(utterances), err
+}
+
+// Close a stream, and return the segments of its last utterance
+func (m *StreamManager) Close(id StreamID) ([]StreamSegment, error) {
+	s, err := m.stream(id)
+	if err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	delete(m.streams, id)
+	m.mu.Unlock()
+	s.Lock()
+	defer s.Unlock()
+	utterances, err := s.endpointer.Flush()
+	return s.segments(utterances), err
+}
+
+// End the current utterance of every open stream, and return their segments
+// mixed in order of time. The streams remain open.
+func (m *StreamManager) Flush() ([]StreamSegment, error) {
+	var result [][]StreamSegment
+	for _, id := range m.Streams() {
+		s, err := m.stream(id)
+		if err != nil {
+			continue
+		}
+		s.Lock()
+		utterances, err := s.endpointer.Flush()
+		s.Unlock()
+		result = append(result, s.segments(utterances))
+		if err != nil {
+			return MixSegments(result...), err
+		}
+	}
+	return MixSegments(result...), nil
+}
+
+// Mix the segments of several streams into one list in order of their start
+// time. Segments which start at the same time are ordered by stream.
+func MixSegments(segments ...[]StreamSegment) []StreamSegment {
+	result := slices.Concat(segments...)
+	slices.SortStableFunc(result, func(a, b StreamSegment) int {
+		return cmp.Or(cmp.Compare(a.Start, b.Start), cmp.Compare(a.Stream, b.Stream))
+	})
+	return result
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+func (m *StreamManager) stream(id StreamID) (*stream, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if s, exists := m.streams[id]; exists {
+		return s, nil
+	}
+	return nil, fmt.Errorf("%w: stream %d", ErrUnknownStream, id)
+}
+
+// Tag the segments of utterances with the stream, and map them onto the
+// shared timeline with the clock of the stream
+func (s *stream) segments(utterances []Utterance) []StreamSegment {
+	var result []StreamSegment
+	for _, utterance := range utterances {
+		for _, segment := range utterance.Segments {
+			segment.retime(s.clock)
+			result = append(result, StreamSegment{Stream: s.id, Segment: segment})
+		}
+	}
+	return result
+}
+
+func (t *pooledTranscriber) Process(data []float32, encoderBeginCallback EncoderBeginCallback, segmentCallback SegmentCallback, progressCallback ProgressCallback) error {
+	t.segments = t.segments[:0]
+	context, err := t.pool.Get()
+	if err != nil {
+		return err
+	}
+	defer t.pool.Put(context)
+	if err := context.Process(data, encoderBeginCallback, segmentCallback, progressCallback); err != nil {
+		return err
+	}
+	for {
+		segment, err := context.NextSegment()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		t.segments = append(t.segments, segment)
+	}
+}
+
+func (t *pooledTranscriber) NextSegment() (Segment, error) {
+	if len(t.segments) == 0 {
+		return Segment{}, io.EOF
+	}
+	segment := t.segments[0]
+	t.segments = t.segments[1:]
+	return segment, nil
+}
@@ -0,0 +1,165 @@
+package whisper_test
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ggerganov/whisper.cpp/bindings/go/pkg/whisper"
+	assert "github.com/stretchr/testify/assert"
+)
+
+// streamModel hands out contexts which transcribe like an eotTranscriber,
+// and counts the contexts created
+type streamModel struct {
+	whisper.Model
+	sync.Mutex
+	n int
+}
+
+type streamContext struct {
+	whisper.Context
+	eotTranscriber
+}
+
+func (model *streamModel) NewStatefulContext() (whisper.Context, error) {
+	model.Lock()
+	defer model.Unlock()
+	model.n++
+	return &streamContext{}, nil
+}
+
+func (model *streamModel) Close() error {
+	return nil
+}
+
+func (context *streamContext) Process(data []float32, cb1 whisper.EncoderBeginCallback, cb2 whisper.SegmentCallback, cb3 whisper.ProgressCallback) error {
+	return context.eotTranscriber.Process(data, cb1, cb2, cb3)
+}
+
+func (context *streamContext) NextSegment() (whisper.Segment, error) {
+	return context.eotTranscriber.NextSegment()
+}
+
+func (context *streamContext) Stats() whisper.ProcessStats {
+	return whisper.ProcessStats{}
+}
+
+func (context *streamContext) Close() error {
+	return nil
+}
+
+func TestStreamManager(t *testing.T) {
+	assert := assert.New(t)
+
+	model := &streamModel{}
+	pool := whisper.NewContextPool(model, 1)
+	defer pool.Close()
+	manager := whisper.NewStreamManager(pool, whisper.EndpointOptions{})
+
+	// The second participant joins the meeting a second later
+	a := manager.Open(0)
+	b := manager.Open(time.Second)
+	assert.Equal([]whisper.StreamID{a, b}, manager.Streams())
+
+	// Both speak at once, and finish at the same time
+	var wg sync.WaitGroup
+	var segments [2][]whisper.StreamSegment
+	for i, id := range []whisper.StreamID{a, b} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for _, speech := range []bool{true, false} {
+				for range 10 {
+					result, err := manager.Write(id, endpointAudio(100*time.Millisecond, speech))
+					assert.NoError(err)
+					segments[i] = append(segments[i], result...)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if assert.Len(segments[0], 1) && assert.Len(segments[1], 1) {
+		assert.Equal(a, segments[0][0].Stream)
+		assert.Equal(b, segments[1][0].Stream)
+		assert.Equal(time.Second, segments[1][0].Start-segments[0][0].Start)
+
+		// Segments are mixed in order of time
+		mixed := whisper.MixSegments(segments[1], segments[0])
+		assert.Equal([]whisper.StreamID{a, b}, []whisper.StreamID{mixed[0].Stream, mixed[1].Stream})
+	}
+
+	// The streams share the single context of the pool
+	assert.Equal(1, model.n)
+
+	// Closed streams are unknown
+	_, err := manager.Close(a)
+	assert.NoError(err)
+	_, err = manager.Write(a, endpointAudio(100*time.Millisecond, true))
+	assert.True(errors.Is(err, whisper.ErrUnknownStream))
+	assert.Equal([]whisper.StreamID{b}, manager.Streams())
+
+	// Flushing ends the utterance of every stream
+	manager.Write(b, endpointAudio(time.Second, true))
+	segments[1], err = manager.Flush()
+	assert.NoError(err)
+	if assert.Len(segments[1], 1) {
+		assert.Equal(b, segments[1][0].Stream)
+	}
+}
+
+func TestStreamClock(t *testing.T) {
+	assert := assert.New(t)
+
+	model := &streamModel{}
+	pool := whisper.NewContextPool(model, 1)
+	defer pool.Close()
+	manager := whisper.NewStreamManager(pool, whisper.EndpointOptions{})
+
+	// Timestamps are mapped to the presentation timestamps of a video whose
+	// audio starts at 10s and plays slightly fast
+	pts := whisper.ClockFunc(func(audio time.Duration) time.Duration {
+		return 10*time.Second + audio*1001/1000
+	})
+	video := manager.OpenWithClock(pts)
+	offset := manager.Open(10 * time.Second)
+	var segments [2][]whisper.StreamSegment
+	for i, id := range []whisper.StreamID{video, offset} {
+		for _, speech := range []bool{true, false} {
+			for range 10 {
+				result, err := manager.Write(id, endpointAudio(100*time.Millisecond, speech))
+				assert.NoError(err)
+				segments[i] = append(segments[i], result...)
+			}
+		}
+	}
+	if assert.Len(segments[0], 1) && assert.Len(segments[1], 1) {
+		want := segments[1][0].Segment
+		assert.Equal(want.Start+(want.Start-10*time.Second)/1000, segments[0][0].Start)
+		assert.Equal(want.End+(want.End-10*time.Second)/1000, segments[0][0].End)
+	}
+}
+
+func TestWallClock(t *testing.T) {
+	assert := assert.New(t)
+
+	epoch := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	clock := whisper.NewWallClock(epoch)
+	assert.Equal(time.Second, clock.Timestamp(time.Second))
+
+	// Audio arrives in writes of a second, the second of which is late, and
+	// the capture time is counted back from the next write
+	clock.Mark(time.Second, epoch.Add(time.Second))
+	clock.Mark(2*time.Second, epoch.Add(2500*time.Millisecond))
+	assert.Equal(500*time.Millisecond, clock.Timestamp(500*time.Millisecond))
+	assert.Equal(2*time.Second, clock.Timestamp(1500*time.Millisecond))
+	assert.Equal(2500*time.Millisecond, clock.Timestamp(2*time.Second))
+
+	// Audio after the last write is counted forward from it
+	assert.Equal(3500*time.Millisecond, clock.Timestamp(3*time.Second))
+	assert.Equal(epoch.Add(3500*time.Millisecond), clock.Time(clock.Timestamp(3*time.Second)))
+
+	// Offset clocks move the audio
+	assert.Equal(3*time.Second, whisper.OffsetClock(2*time.Second).Timestamp(time.Second))
+}
@@ -0,0 +1,107 @@
+package whisper
+
+import (
+	"math"
+	"slices"
+	"strings"
+
+	// Bindings
+	whisper "github.com/ggerganov/whisper.cpp/bindings/go"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+// The stock list of non-speech tokens, which matches the tokens suppressed
+// by the native library with SetSuppressNonSpeechTokens
+var nonSpeechTokens = []string{
+	"\"", "#", "(", ")", "*", "+", "/", ":", ";", "<", "=", ">", "@", "[", "\\", "]", "^",
+	"_", "`", "{", "|", "}", "~", "「", "」", "『", "』", "<<", ">>", "<<<", ">>>", "--",
+	"---", "-(", "-[", "('", "(\"", "((", "))", "(((", ")))", "[[", "]]", "{{", "}}", "♪♪",
+	"♪♪♪", "♩", "♪", "♫", "♬", "♭", "♮", "♯",
+
+	// Hyphens and single quotes are allowed between words, but not at the
+	// beginning of a word
+	" -", " '",
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Return the texts of the tokens suppressed by SetSuppressNonSpeechTokens.
+// A text is matched both as it is and with a leading space, unless it
+// starts with a space, in which case it is matched exactly.
+func NonSpeechTokens() []string {
+	return slices.Clone(nonSpeechTokens)
+}
+
+// Suppress the tokens with the given texts. Texts which are not in the
+// vocabulary of the model are ignored.
+func (context *context) SetSuppressTokens(texts []string) error {
+	ctx := context.rlock()
+	if ctx == nil {
+		return ErrInternalAppError
+	}
+	defer context.model.runlock()
+
+	// Look up the tokens
+	var suppress map[whisper.Token]float32
+	if texts != nil {
+		vocab := context.model.meta.Load().tokens(ctx)
+		suppress = make(map[whisper.Token]float32, len(texts)*2)
+		for _, text := range texts {
+			variants := []string{text, " " + text}
+			if strings.HasPrefix(text, " ") {
+				variants = variants[:1]
+			}
+			for _, variant := range variants {
+				if id, exists := vocab[variant]; exists {
+					suppress[id] = float32(math.Inf(-1))
+				}
+			}
+		}
+	}
+
+	// Apply the bias
+	context.paramsMu.Lock()
+	defer context.paramsMu.Unlock()
+	context.suppress = suppress
+	if texts != nil {
+		context.params.SetSuppressNonSpeechTokens(false)
+	}
+	context.applyBias()
+	return nil
+}
+
+// Return the ids of the tokens suppressed with SetSuppressTokens, in order
+func (context *context) SuppressedTokens() []int {
+	context.paramsMu.Lock()
+	defer context.paramsMu.Unlock()
+	if context.suppress == nil {
+		return nil
+	}
+	result := make([]int, 0, len(context.suppress))
+	for id := range context.suppress {
+		result = append(result, int(id))
+	}
+	slices.Sort(result)
+	return result
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// Return the token ids of the vocabulary by text, reading them from the
+// model on first use
+func (meta *modelMeta) tokens(ctx *whisper.Context) map[string]whisper.Token {
+	meta.textOnce.Do(func() {
+		meta.text = make(map[string]whisper.Token, meta.vocab)
+		for id := 0; id < meta.vocab; id++ {
+			text := ctx.Whisper_token_to_str(whisper.Token(id))
+			if _, exists := meta.text[text]; !exists {
+				meta.text[text] = whisper.Token(id)
+			}
+		}
+	})
+	return meta.text
+}
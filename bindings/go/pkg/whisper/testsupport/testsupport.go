@@ -0,0 +1,139 @@
+// Package testsupport synthesizes deterministic mono audio for tests, such
+// as tones, chirps, noise and silence, and concatenates them with known
+// boundaries. Tests of voice activity detection, streaming and segment
+// merging can use it instead of models or WAV fixtures.
+//
+// The package does not use the native library, so it can be imported by
+// tests which do not load a model.
+package testsupport
+
+import (
+	"math"
+	"math/rand/v2"
+	"time"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// Part is a named piece of audio which is concatenated with Concat
+type Part struct {
+	Label string
+	Data  []float32
+}
+
+// Boundary is the position of a part in concatenated audio
+type Boundary struct {
+	Label      string
+	Start, End time.Duration
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+// SampleRate is the sample rate of the synthesized audio, which is the
+// sample rate expected by whisper
+const SampleRate = 16000
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Return the number of samples in a duration, rounded down
+func Samples(d time.Duration) int {
+	return int(d * SampleRate / time.Second)
+}
+
+// Return the duration of a number of samples
+func Duration(n int) time.Duration {
+	return time.Duration(n) * time.Second / SampleRate
+}
+
+// Return silence of the given length
+func Silence(d time.Duration) []float32 {
+	return make([]float32, Samples(d))
+}
+
+// Return a sine wave with the frequency in Hz and the peak amplitude. A tone
+// of a whole number of Hz ends every second at the phase it started with.
+func Tone(freq float64, amplitude float32, d time.Duration) []float32 {
+	data := make([]float32, Samples(d))
+	for i := range data {
+		data[i] = float32(float64(amplitude) * math.Sin(2*math.Pi*freq*float64(i)/SampleRate))
+	}
+	return data
+}
+
+// Return a sine wave whose frequency sweeps linearly from one frequency to
+// another in Hz over its length
+func Chirp(from, to float64, amplitude float32, d time.Duration) []float32 {
+	data := make([]float32, Samples(d))
+	rate := (to - from) / d.Seconds()
+	for i := range data {
+		t := float64(i) / SampleRate
+		data[i] = float32(float64(amplitude) * math.Sin(2*math.Pi*(from*t+rate*t*t/2)))
+	}
+	return data
+}
+
+// Return white noise which is uniform up to the peak amplitude. The same
+// seed always returns the same noise.
+func Noise(amplitude float32, d time.Duration, seed uint64) []float32 {
+	rng := rand.New(rand.NewPCG(seed, seed))
+	data := make([]float32, Samples(d))
+	for i := range data {
+		data[i] = amplitude * float32(2*rng.Float64()-1)
+	}
+	return data
+}
+
+// Return a tone which is silent in every other period, such as bursts of
+// 30ms which are heard as noise rather than music
+func Bursts(freq float64, amplitude float32, period, d time.Duration) []float32 {
+	data := Tone(freq, amplitude, d)
+	n := max(Samples(period), 1)
+	for i := range data {
+		if (i/n)%2 == 1 {
+			data[i] = 0
+		}
+	}
+	return data
+}
+
+// Return a signal with the envelope of speech, which is a voice at the pitch
+// in Hz with its first harmonics, modulated at four syllables per second.
+// It is detected as speech by energy based detectors, but is not words.
+func Speech(pitch float64, amplitude float32, d time.Duration) []float32 {
+	data := make([]float32, Samples(d))
+	for i := range data {
+		t := float64(i) / SampleRate
+		voice := math.Sin(2*math.Pi*pitch*t) + math.Sin(4*math.Pi*pitch*t)/2 + math.Sin(6*math.Pi*pitch*t)/3
+		envelope := (1 - math.Cos(2*math.Pi*4*t)) / 2
+		data[i] = float32(float64(amplitude) * envelope * voice / (1 + 1.0/2 + 1.0/3))
+	}
+	return data
+}
+
+// Return the parts one after the other, and the boundaries of each part
+func Concat(parts ...Part) ([]float32, []Boundary) {
+	var n int
+	for _, part := range parts {
+		n += len(part.Data)
+	}
+	data := make([]float32, 0, n)
+	boundaries := make([]Boundary, 0, len(parts))
+	for _, part := range parts {
+		start := Duration(len(data))
+		data = append(data, part.Data...)
+		boundaries = append(boundaries, Boundary{Label: part.Label, Start: start, End: Duration(len(data))})
+	}
+	return data, boundaries
+}
+
+// Return a copy of the audio repeated n times
+func Repeat(data []float32, n int) []float32 {
+	result := make([]float32, 0, len(data)*max(n, 0))
+	for i := 0; i < n; i++ {
+		result = append(result, data...)
+	}
+	return result
+}
@@ -0,0 +1,89 @@
+package testsupport_test
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/ggerganov/whisper.cpp/bindings/go/pkg/whisper/testsupport"
+	assert "github.com/stretchr/testify/assert"
+)
+
+// Return the peak absolute value of the samples
+func peak(data []float32) float32 {
+	var result float32
+	for _, v := range data {
+		result = max(result, float32(math.Abs(float64(v))))
+	}
+	return result
+}
+
+// Return the number of times the samples cross zero upwards
+func crossings(data []float32) int {
+	var n int
+	for i := 1; i < len(data); i++ {
+		if data[i-1] < 0 && data[i] >= 0 {
+			n++
+		}
+	}
+	return n
+}
+
+func TestGenerators(t *testing.T) {
+	assert := assert.New(t)
+
+	// Generators have the requested length and amplitude
+	assert.Len(testsupport.Silence(time.Second), testsupport.SampleRate)
+	assert.Zero(peak(testsupport.Silence(time.Second)))
+	tone := testsupport.Tone(440, 0.5, time.Second)
+	assert.Len(tone, testsupport.SampleRate)
+	assert.InDelta(0.5, peak(tone), 1e-3)
+	assert.InDelta(440, crossings(tone), 1)
+
+	// A chirp sweeps through the frequencies in between
+	chirp := testsupport.Chirp(100, 900, 0.5, time.Second)
+	assert.InDelta(500, crossings(chirp), 2)
+	assert.Less(crossings(chirp[:testsupport.SampleRate/2]), crossings(chirp[testsupport.SampleRate/2:]))
+
+	// Noise is deterministic for a seed
+	assert.Equal(testsupport.Noise(0.1, time.Second, 1), testsupport.Noise(0.1, time.Second, 1))
+	assert.NotEqual(testsupport.Noise(0.1, time.Second, 1), testsupport.Noise(0.1, time.Second, 2))
+	assert.LessOrEqual(peak(testsupport.Noise(0.1, time.Second, 1)), float32(0.1))
+
+	// Bursts are silent in every other period
+	bursts := testsupport.Bursts(440, 0.5, 30*time.Millisecond, time.Second)
+	period := testsupport.Samples(30 * time.Millisecond)
+	assert.Positive(peak(bursts[:period]))
+	assert.Zero(peak(bursts[period : 2*period]))
+
+	// Speech is silent between syllables
+	speech := testsupport.Speech(150, 0.5, time.Second)
+	assert.LessOrEqual(peak(speech), float32(0.5))
+	assert.Less(peak(speech[:testsupport.Samples(10*time.Millisecond)]), float32(0.01))
+	assert.Greater(peak(speech[:testsupport.Samples(250*time.Millisecond)]), float32(0.25))
+}
+
+func TestConcat(t *testing.T) {
+	assert := assert.New(t)
+
+	data, boundaries := testsupport.Concat(
+		testsupport.Part{Label: "silence", Data: testsupport.Silence(time.Second)},
+		testsupport.Part{Label: "tone", Data: testsupport.Tone(440, 0.5, 500*time.Millisecond)},
+		testsupport.Part{Data: testsupport.Silence(250 * time.Millisecond)},
+	)
+	assert.Len(data, testsupport.Samples(1750*time.Millisecond))
+	assert.Equal([]testsupport.Boundary{
+		{Label: "silence", Start: 0, End: time.Second},
+		{Label: "tone", Start: time.Second, End: 1500 * time.Millisecond},
+		{Start: 1500 * time.Millisecond, End: 1750 * time.Millisecond},
+	}, boundaries)
+	assert.Zero(peak(data[:testsupport.SampleRate]))
+	assert.Positive(peak(data[testsupport.SampleRate:]))
+
+	// Repeats are copies of the audio
+	tone := testsupport.Tone(440, 0.5, 100*time.Millisecond)
+	repeated := testsupport.Repeat(tone, 3)
+	assert.Len(repeated, 3*len(tone))
+	assert.Equal(tone, repeated[2*len(tone):])
+	assert.Empty(testsupport.Repeat(tone, 0))
+}
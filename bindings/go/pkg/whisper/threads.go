@@ -0,0 +1,37 @@
+package whisper
+
+import (
+	"runtime"
+	"sync"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+// Number of threads which drive a GPU backend. The compute runs on the
+// device, so more threads only contend for the CPU.
+const gpuThreads = 4
+
+// Number of physical cores, which is read once
+var physicalCores = sync.OnceValue(func() int {
+	if n := numPhysicalCores(); n > 0 {
+		return min(n, runtime.NumCPU())
+	}
+	return runtime.NumCPU()
+})
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Return the recommended number of threads for processing. On the CPU
+// backend this is the number of physical cores, not counting hyperthreads
+// which share the execution units of a core. With a GPU backend the CPU only
+// drives the device, so fewer threads are used. Either way the count does
+// not exceed GOMAXPROCS.
+func RecommendedThreads(useGPU bool) int {
+	n := physicalCores()
+	if useGPU {
+		n = min(n, gpuThreads)
+	}
+	return max(min(n, runtime.GOMAXPROCS(0)), 1)
+}
@@ -0,0 +1,32 @@
+package whisper
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// Return the number of physical cores from the CPU topology in sysfs, as
+// the number of distinct cores across packages, or zero if it is unknown
+func numPhysicalCores() int {
+	cpus, err := filepath.Glob("/sys/devices/system/cpu/cpu[0-9]*/topology")
+	if err != nil {
+		return 0
+	}
+	cores := make(map[string]struct{})
+	for _, topology := range cpus {
+		pkg, err := os.ReadFile(filepath.Join(topology, "physical_package_id"))
+		if err != nil {
+			continue
+		}
+		core, err := os.ReadFile(filepath.Join(topology, "core_id"))
+		if err != nil {
+			continue
+		}
+		cores[strings.TrimSpace(string(pkg))+":"+strings.TrimSpace(string(core))] = struct{}{}
+	}
+	return len(cores)
+}
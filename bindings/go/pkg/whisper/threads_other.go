@@ -0,0 +1,12 @@
+//go:build !linux
+
+package whisper
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// The number of physical cores is not read on this platform, so all
+// logical processors are used
+func numPhysicalCores() int {
+	return 0
+}
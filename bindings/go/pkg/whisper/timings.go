@@ -0,0 +1,134 @@
+package whisper
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	// Bindings
+	whisper "github.com/ggerganov/whisper.cpp/bindings/go"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// Timings are the timings of the native library for the calls to Process
+// since the timings of the context were reset, which are the same timings
+// as those printed by PrintTimings
+type Timings struct {
+	Load time.Duration // Time taken to load the model
+	Mel  time.Duration // Time taken to compute the log mel spectrograms
+
+	// Time taken by each step of the model, and the number of runs
+	Sample, Encode, Decode, Batchd, Prompt TimingRuns
+
+	// Temperature fallbacks due to the average log probability of the
+	// tokens, and due to their entropy
+	FallbacksP, FallbacksH int
+
+	// Wall clock time since the model was loaded or the timings were
+	// reset, and the duration of the audio processed in that time
+	Total, Audio time.Duration
+
+	// Real-time factor, the ratio of Total to Audio
+	RTF float64
+}
+
+// TimingRuns is the time taken by a step of the model, and the number of
+// times it was run
+type TimingRuns struct {
+	Time time.Duration
+	Runs int
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+// Return the timings in the format of whisper_print_timings, so that logs
+// of the Go bindings and the native library can be compared
+func (t Timings) String() string {
+	var str strings.Builder
+	line := func(format string, args ...any) {
+		fmt.Fprintf(&str, "whisper_print_timings: "+format+"\n", args...)
+	}
+	ms := func(d time.Duration) float32 {
+		return 1e-3 * float32(d.Microseconds())
+	}
+	runs := func(name string, r TimingRuns) {
+		n := max(1, r.Runs)
+		line("%s time = %8.2f ms / %5d runs ( %8.2f ms per run)", name, ms(r.Time), n, ms(r.Time)/float32(n))
+	}
+	line("    load time = %8.2f ms", float32(t.Load.Microseconds())/1000)
+	line("    fallbacks = %3d p / %3d h", t.FallbacksP, t.FallbacksH)
+	line("     mel time = %8.2f ms", float32(t.Mel.Microseconds())/1000)
+	runs("  sample", t.Sample)
+	runs("  encode", t.Encode)
+	runs("  decode", t.Decode)
+	runs("  batchd", t.Batchd)
+	runs("  prompt", t.Prompt)
+	line("   total time = %8.2f ms", float32(t.Total.Microseconds())/1000)
+	return str.String()
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Return the time taken per run, or zero if the step was not run
+func (r TimingRuns) PerRun() time.Duration {
+	if r.Runs == 0 {
+		return 0
+	}
+	return r.Time / time.Duration(r.Runs)
+}
+
+// Return the timings of the context since they were last reset
+func (context *context) Timings() Timings {
+	ctx := context.rlock()
+	if ctx == nil {
+		return Timings{}
+	}
+	defer context.model.runlock()
+
+	// Close frees the state while it holds the states of the model
+	context.model.statesMu.Lock()
+	defer context.model.statesMu.Unlock()
+	if context.closed.Load() {
+		return Timings{}
+	}
+	t := newTimings(ctx.Whisper_get_state_timings(context.state))
+	t.Audio = time.Duration(context.audio.Load())
+	if t.Audio > 0 {
+		t.RTF = t.Total.Seconds() / t.Audio.Seconds()
+	}
+	return t
+}
+
+// Write the timings of the context to w in the format of PrintTimings, so
+// that they can be sent to a Go logger instead of the standard output of the
+// native library
+func (context *context) WriteTimings(w io.Writer) error {
+	_, err := io.WriteString(w, "\n"+context.Timings().String())
+	return err
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+func newTimings(t whisper.Timings) Timings {
+	us := func(v int64) time.Duration {
+		return time.Duration(v) * time.Microsecond
+	}
+	return Timings{
+		Load:       us(t.LoadUs),
+		Mel:        us(t.MelUs),
+		Sample:     TimingRuns{Time: us(t.SampleUs), Runs: t.NSample},
+		Encode:     TimingRuns{Time: us(t.EncodeUs), Runs: t.NEncode},
+		Decode:     TimingRuns{Time: us(t.DecodeUs), Runs: t.NDecode},
+		Batchd:     TimingRuns{Time: us(t.BatchdUs), Runs: t.NBatchd},
+		Prompt:     TimingRuns{Time: us(t.PromptUs), Runs: t.NPrompt},
+		FallbacksP: t.NFailP,
+		FallbacksH: t.NFailH,
+		Total:      us(t.TotalUs),
+	}
+}
@@ -0,0 +1,142 @@
+package whisper
+
+import (
+	"cmp"
+	"fmt"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// LiveObject is a model or stateful context which has not been closed,
+// reported by LiveObjects while debug tracking is on
+type LiveObject struct {
+	// "model" or "context"
+	Kind string
+
+	// Path of the model, or label of the context
+	Name string
+
+	// Time the object was created, and the stack of the goroutine which
+	// created it
+	Created time.Time
+	Stack   string
+}
+
+// The creation of a tracked object
+type creation struct {
+	seq     uint64
+	created time.Time
+	stack   string
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+// Live models and stateful contexts, which are recorded while debug tracking
+// is on
+var tracking struct {
+	sync.Mutex
+	enabled  bool
+	seq      uint64
+	models   map[*model]creation
+	contexts map[*context]creation
+}
+
+// Frames of the stack which are recorded for each object
+const trackingFrames = 32
+
+///////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (o LiveObject) String() string {
+	return fmt.Sprintf("%s %q created at %s\n%s", o.Kind, o.Name, o.Created.Format(time.RFC3339Nano), o.Stack)
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Turn debug tracking of models and stateful contexts on or off. While it is
+// on, each model and stateful context is recorded with the stack which
+// created it until it is closed, and LiveObjects returns those which are not
+// closed. Recorded objects are kept alive, so they are not freed by the
+// garbage collector but reported. Turning tracking off forgets them.
+func SetDebugTracking(v bool) {
+	tracking.Lock()
+	defer tracking.Unlock()
+	tracking.enabled = v
+	if !v {
+		tracking.models, tracking.contexts = nil, nil
+	}
+}
+
+// Return the models and stateful contexts created while debug tracking was
+// on which have not been closed, in order of creation. Tests and services
+// can check that it is empty once they have closed their resources.
+func LiveObjects() []LiveObject {
+	tracking.Lock()
+	defer tracking.Unlock()
+	type entry struct {
+		LiveObject
+		seq uint64
+	}
+	entries := make([]entry, 0, len(tracking.models)+len(tracking.contexts))
+	for model, c := range tracking.models {
+		entries = append(entries, entry{LiveObject{Kind: "model", Name: model.path, Created: c.created, Stack: c.stack}, c.seq})
+	}
+	for context, c := range tracking.contexts {
+		entries = append(entries, entry{LiveObject{Kind: "context", Name: context.Label(), Created: c.created, Stack: c.stack}, c.seq})
+	}
+	slices.SortFunc(entries, func(a, b entry) int {
+		return cmp.Compare(a.seq, b.seq)
+	})
+	result := make([]LiveObject, len(entries))
+	for i, e := range entries {
+		result[i] = e.LiveObject
+	}
+	return result
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// Record a model or stateful context when debug tracking is on
+func track[T comparable](objects *map[T]creation, object T) {
+	tracking.Lock()
+	defer tracking.Unlock()
+	if !tracking.enabled {
+		return
+	}
+	if *objects == nil {
+		*objects = make(map[T]creation)
+	}
+	tracking.seq++
+	(*objects)[object] = creation{seq: tracking.seq, created: time.Now(), stack: callers(2)}
+}
+
+// Forget a model or stateful context which is closed
+func untrack[T comparable](objects *map[T]creation, object T) {
+	tracking.Lock()
+	defer tracking.Unlock()
+	delete(*objects, object)
+}
+
+// Return the stack of the goroutine, starting skip frames above callers
+func callers(skip int) string {
+	pcs := make([]uintptr, trackingFrames)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(skip+1, pcs)])
+	var b strings.Builder
+	for {
+		frame, more := frames.Next()
+		fmt.Fprintf(&b, "%s\n\t%s:%d\n", frame.Function, frame.File, frame.Line)
+		if !more {
+			break
+		}
+	}
+	return b.String()
+}
@@ -1,6 +1,144 @@
 package whisper_test
 
+import (
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ggerganov/whisper.cpp/bindings/go/pkg/whisper"
+	"github.com/go-audio/audio"
+	"github.com/go-audio/wav"
+)
+
 const (
-	ModelPath  = "../../models/ggml-small.en.bin"
-	SamplePath = "../../samples/jfk.wav"
+	ModelPath    = "../../models/ggml-small.en.bin"
+	SamplePath   = "../../samples/jfk.wav"
+	VADModelPath = "../../../../models/for-tests-silero-v6.2.0-ggml.bin"
 )
+
+// Directory for the artifacts written by benchmarks, such as the audio they
+// processed. Artifacts are not written when empty, and "temp" writes them to
+// the temporary directory. The default is taken from WHISPER_BENCH_ARTIFACTS.
+var artifacts = flag.String("whisper.artifacts", os.Getenv("WHISPER_BENCH_ARTIFACTS"), "directory for benchmark artifacts")
+
+// The optional interfaces which the models of the package implement
+type testModel interface {
+	whisper.Model
+	whisper.StatefulModel
+	whisper.ModelDescriber
+	whisper.Warmer
+	whisper.Yielder
+	whisper.AdapterModel
+	whisper.MemoryReporter
+	whisper.RawAccessor
+}
+
+// The optional interfaces which the contexts of the package implement
+type testContext interface {
+	whisper.Context
+	whisper.Configurable
+	whisper.Labeled
+	whisper.LanguageDetector
+	whisper.DecodingConfigurable
+	whisper.TokenSuppressor
+	whisper.LogitsBiaser
+	whisper.ProcessHooks
+	whisper.TextAppender
+	whisper.StatsReporter
+	whisper.TimingsReporter
+	whisper.ComputeReleaser
+	whisper.MemoryReporter
+	whisper.RawAccessor
+	io.Closer
+}
+
+// Return a model with its optional interfaces, for the result of one of
+// the functions which load a model
+func fullModel(model whisper.Model, err error) (testModel, error) {
+	if err != nil {
+		return nil, err
+	}
+	return model.(testModel), nil
+}
+
+// Return a context with its optional interfaces, for the result of
+// NewContext or NewStatefulContext
+func fullContext(context whisper.Context, err error) (testContext, error) {
+	if err != nil {
+		return nil, err
+	}
+	return context.(testContext), nil
+}
+
+// Return the mono samples of the wav file, or skip the test if the file
+// does not exist
+func loadSamples(tb testing.TB, path string) []float32 {
+	tb.Helper()
+	fh, err := os.Open(path)
+	if err != nil {
+		tb.Skip("Skipping, sample not found:", path)
+	}
+	defer fh.Close()
+	buf, err := wav.NewDecoder(fh).FullPCMBuffer()
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return buf.AsFloat32Buffer().Data
+}
+
+// Return the samples of the wav file repeated n times with a gap of silence
+// between each copy, for testing long audio
+func concatSamples(tb testing.TB, path string, n int, gap int) []float32 {
+	tb.Helper()
+	data := loadSamples(tb, path)
+	result := make([]float32, 0, n*(len(data)+gap))
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			result = append(result, make([]float32, gap)...)
+		}
+		result = append(result, data...)
+	}
+	return result
+}
+
+// Write the samples as a 16-bit mono wav file named name in the artifacts
+// directory, and return its path, or an empty string if artifacts are not
+// written
+func writeArtifact(tb testing.TB, name string, data []float32) string {
+	tb.Helper()
+	dir := *artifacts
+	switch dir {
+	case "":
+		return ""
+	case "temp":
+		dir = os.TempDir()
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		tb.Fatal(err)
+	}
+	path := filepath.Join(dir, name)
+	fh, err := os.Create(path)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	defer fh.Close()
+	buf := &audio.IntBuffer{
+		Format:         &audio.Format{NumChannels: 1, SampleRate: whisper.SampleRate},
+		Data:           make([]int, len(data)),
+		SourceBitDepth: 16,
+	}
+	for i, v := range data {
+		buf.Data[i] = int(max(-1, min(1, v)) * 32767)
+	}
+	enc := wav.NewEncoder(fh, whisper.SampleRate, 16, 1, 1)
+	if err := enc.Write(buf); err != nil {
+		tb.Fatal(err)
+	}
+	if err := enc.Close(); err != nil {
+		tb.Fatal(err)
+	}
+	tb.Logf("Wrote %s", path)
+	return path
+}
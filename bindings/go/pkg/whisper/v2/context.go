@@ -0,0 +1,99 @@
+package whisper
+
+import (
+	"context"
+	"io"
+	"iter"
+
+	// Package imports
+	v1 "github.com/ggerganov/whisper.cpp/bindings/go/pkg/whisper"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// Context transcribes audio with its own decoding state. A context processes
+// one stream of audio at a time.
+type Context struct {
+	ctx v1Context
+}
+
+// The interfaces of the first version of the API which its contexts
+// implement, and which a Context uses
+type v1Context interface {
+	v1.Context
+	v1.StatsReporter
+	io.Closer
+}
+
+// Segment is the text of a span of audio
+type Segment = v1.Segment
+
+// Token is a text or special token of a segment
+type Token = v1.Token
+
+// Word is a word of a segment, with the confidence in its text and timing
+type Word = v1.Word
+
+// Stats are the performance statistics of a transcription
+type Stats = v1.ProcessStats
+
+///////////////////////////////////////////////////////////////////////////////
+// LIFECYCLE
+
+// Close the context and free its decoding state
+func (c *Context) Close() error {
+	return c.ctx.Close()
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Change the configuration of the context with the options in order, which
+// takes effect on the next transcription
+func (c *Context) Set(opts ...Option) error {
+	return v1.ApplyOptions(c.ctx, opts...)
+}
+
+// Transcribe mono samples at SampleRate, and return the segments of the
+// text once processing is done. Processing starts when iteration starts,
+// and is cancelled with ctx between windows of audio, in which case the
+// error of ctx is returned. Iteration stops after an error.
+func (c *Context) Transcribe(ctx context.Context, samples []float32) iter.Seq2[Segment, error] {
+	return func(yield func(Segment, error) bool) {
+		if err := ctx.Err(); err != nil {
+			yield(Segment{}, err)
+			return
+		}
+		proceed := func() bool {
+			return ctx.Err() == nil
+		}
+		if err := c.ctx.Process(samples, proceed, nil, nil); err != nil {
+			yield(Segment{}, err)
+			return
+		}
+		if c.ctx.Stats().Aborted {
+			yield(Segment{}, ctx.Err())
+			return
+		}
+		for {
+			segment, err := c.ctx.NextSegment()
+			if err == io.EOF {
+				return
+			}
+			if !yield(segment, err) || err != nil {
+				return
+			}
+		}
+	}
+}
+
+// Return the performance statistics of the last transcription
+func (c *Context) Stats() Stats {
+	return c.ctx.Stats()
+}
+
+// Return the underlying context of the first version of the API
+func (c *Context) Unwrap() v1.Context {
+	return c.ctx
+}
@@ -0,0 +1,29 @@
+/*
+Package whisper is the second version of the higher-level speech-to-text
+whisper.cpp API for go. It gathers the API into a Model, which is loaded with
+Open, and a Context with its own decoding state, which is configured with
+options and transcribes audio as an iterator of segments:
+
+	model, err := whisper.Open("models/ggml-base.en.bin")
+	if err != nil {
+		return err
+	}
+	defer model.Close()
+
+	context, err := model.NewContext(whisper.WithLanguage("en"))
+	if err != nil {
+		return err
+	}
+	defer context.Close()
+
+	for segment, err := range context.Transcribe(ctx, samples) {
+		if err != nil {
+			return err
+		}
+		fmt.Println(segment.Start, segment.Text)
+	}
+
+The package is a layer over the first version, which stays stable. Unwrap
+returns the underlying model or context for settings which have no option.
+*/
+package whisper
@@ -0,0 +1,157 @@
+package whisper
+
+import (
+	"io"
+
+	// Package imports
+	v1 "github.com/ggerganov/whisper.cpp/bindings/go/pkg/whisper"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// Model is a whisper model, which is safe to use from several goroutines
+type Model struct {
+	model v1.Model
+}
+
+// ModelOption configures how a model is loaded by Open
+type ModelOption func(*v1.ModelContextParams)
+
+// AlignmentHead identifies an attention head used for DTW timestamps
+type AlignmentHead = v1.AlignmentHead
+
+// DeviceInfo describes a compute device which a model can be loaded onto
+type DeviceInfo = v1.DeviceInfo
+
+///////////////////////////////////////////////////////////////////////////////
+// LIFECYCLE
+
+// Open loads the model at path, configured with the options in order
+func Open(path string, opts ...ModelOption) (*Model, error) {
+	params := v1.NewModelContextParams()
+	for _, opt := range opts {
+		opt(params)
+	}
+	model, err := v1.NewWithParams(path, params)
+	if err != nil {
+		return nil, err
+	}
+	return &Model{model: model}, nil
+}
+
+// OpenReader loads a model from a stream, such as the body of a download,
+// configured with the options in order
+func OpenReader(r io.Reader, opts ...ModelOption) (*Model, error) {
+	params := v1.NewModelContextParams()
+	for _, opt := range opts {
+		opt(params)
+	}
+	model, err := v1.NewFromReader(r, params)
+	if err != nil {
+		return nil, err
+	}
+	return &Model{model: model}, nil
+}
+
+// Close the model. Contexts of the model must be closed first.
+func (m *Model) Close() error {
+	return m.model.Close()
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// OPTIONS
+
+// Map the model file into memory rather than reading it
+func WithMmap() ModelOption {
+	return func(params *v1.ModelContextParams) {
+		params.SetUseMmap(true)
+	}
+}
+
+// Use flash attention, when supported by the backend, or disable it
+func WithFlashAttention(v bool) ModelOption {
+	return func(params *v1.ModelContextParams) {
+		params.SetUseFlashAttention(v)
+	}
+}
+
+// Use the GPU with the given index, the GPU field of one of the devices
+// returned by Devices
+func WithGPUDevice(n int) ModelOption {
+	return func(params *v1.ModelContextParams) {
+		params.SetUseGPU(true)
+		params.SetGPUDevice(n)
+	}
+}
+
+// Load the model on the CPU when it cannot be loaded on the GPU. The
+// fallback is reported by the Warnings of the underlying model.
+func WithCPUFallback() ModelOption {
+	return func(params *v1.ModelContextParams) {
+		params.SetCPUFallback(true)
+	}
+}
+
+// Use the Core ML encoder model at path, or the default one if empty, and
+// keep compiled models in cacheDir if it is not empty
+func WithCoreML(path, cacheDir string) ModelOption {
+	return func(params *v1.ModelContextParams) {
+		params.SetCoreMLPath(path)
+		params.SetCoreMLCacheDir(cacheDir)
+	}
+}
+
+// Call fn after each tensor of the model is loaded, for example to show a
+// loading bar
+func WithLoadProgress(fn func(v1.LoadProgress)) ModelOption {
+	return func(p *v1.ModelContextParams) {
+		p.SetLoadProgressCallback(fn)
+	}
+}
+
+// Use custom alignment heads for DTW token-level timestamps
+func WithDTWAheads(heads []AlignmentHead) ModelOption {
+	return func(params *v1.ModelContextParams) {
+		params.SetDTWAheads(heads)
+	}
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Return the compute devices available to the library
+func Devices() []DeviceInfo {
+	return v1.Devices()
+}
+
+// Return a new context with its own decoding state, configured with the
+// options in order. Contexts can transcribe at the same time, and must be
+// closed to free the state.
+func (m *Model) NewContext(opts ...Option) (*Context, error) {
+	context, err := m.model.(v1.StatefulModel).NewStatefulContext()
+	if err != nil {
+		return nil, err
+	}
+	ctx := context.(v1Context)
+	if err := v1.ApplyOptions(ctx, opts...); err != nil {
+		ctx.Close()
+		return nil, err
+	}
+	return &Context{ctx: ctx}, nil
+}
+
+// Return true if the model is multilingual
+func (m *Model) IsMultilingual() bool {
+	return m.model.IsMultilingual()
+}
+
+// Return the languages supported by the model
+func (m *Model) Languages() []string {
+	return m.model.Languages()
+}
+
+// Return the underlying model of the first version of the API
+func (m *Model) Unwrap() v1.Model {
+	return m.model
+}
@@ -0,0 +1,75 @@
+package whisper
+
+import (
+	"time"
+
+	// Package imports
+	v1 "github.com/ggerganov/whisper.cpp/bindings/go/pkg/whisper"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// Option configures a context. The options of the first version of the API
+// can also be used.
+type Option = v1.ContextOption
+
+///////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+// SampleRate is the sample rate of the audio data
+const SampleRate = v1.SampleRate
+
+///////////////////////////////////////////////////////////////////////////////
+// OPTIONS
+
+// Set the language, or "auto" to detect the language
+func WithLanguage(lang string) Option {
+	return v1.WithLanguage(lang)
+}
+
+// Translate the speech into English
+func WithTranslate() Option {
+	return v1.WithTranslate()
+}
+
+// Set the number of threads
+func WithThreads(n uint) Option {
+	return v1.WithThreads(n)
+}
+
+// Use beam search with the given beam size, instead of greedy sampling
+func WithBeamSize(n int) Option {
+	return v1.WithBeamSize(n)
+}
+
+// Enable voice activity detection with the VAD model at path
+func WithVAD(path string) Option {
+	return v1.WithVAD(path)
+}
+
+// Set the initial prompt
+func WithInitialPrompt(prompt string) Option {
+	return v1.WithInitialPrompt(prompt)
+}
+
+// Enable token timestamps
+func WithTokenTimestamps() Option {
+	return v1.WithTokenTimestamps()
+}
+
+// Set the sampling temperature
+func WithTemperature(t float32) Option {
+	return v1.WithTemperature(t)
+}
+
+// Process only part of the audio, starting at offset for duration. A zero
+// duration processes until the end of the audio.
+func WithWindow(offset, duration time.Duration) Option {
+	return v1.WithWindow(offset, duration)
+}
+
+// Set the label which identifies the context in errors
+func WithLabel(label string) Option {
+	return v1.WithLabel(label)
+}
@@ -0,0 +1,96 @@
+package whisper_test
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	whisper "github.com/ggerganov/whisper.cpp/bindings/go/pkg/whisper/v2"
+	"github.com/go-audio/wav"
+	assert "github.com/stretchr/testify/assert"
+)
+
+const (
+	ModelPath  = "../../../models/ggml-small.en.bin"
+	SamplePath = "../../../samples/jfk.wav"
+)
+
+func loadSamples(t *testing.T) []float32 {
+	fh, err := os.Open(SamplePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fh.Close()
+	buf, err := wav.NewDecoder(fh).FullPCMBuffer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buf.AsFloat32Buffer().Data
+}
+
+func TestOpen(t *testing.T) {
+	assert := assert.New(t)
+
+	_, err := whisper.Open("invalid-model-path.bin")
+	assert.Error(err)
+
+	model, err := whisper.Open(ModelPath, whisper.WithMmap())
+	assert.NoError(err)
+	defer model.Close()
+	assert.False(model.IsMultilingual())
+	assert.NotNil(model.Unwrap())
+
+	// Options which fail are returned from NewContext
+	_, err = model.NewContext(whisper.WithLanguage("de"))
+	assert.Error(err)
+	_, err = model.NewContext(whisper.WithBeamSize(0))
+	assert.Error(err)
+}
+
+func TestTranscribe(t *testing.T) {
+	assert := assert.New(t)
+	samples := loadSamples(t)
+
+	model, err := whisper.Open(ModelPath)
+	assert.NoError(err)
+	defer model.Close()
+	ctx, err := model.NewContext(whisper.WithThreads(2), whisper.WithLabel("v2"))
+	assert.NoError(err)
+	defer ctx.Close()
+
+	n := 0
+	for segment, err := range ctx.Transcribe(context.Background(), samples) {
+		assert.NoError(err)
+		assert.GreaterOrEqual(segment.End, segment.Start)
+		n++
+	}
+	assert.Equal(ctx.Stats().Segments, n)
+	assert.False(ctx.Stats().Aborted)
+
+	// Iteration can stop early
+	for range ctx.Transcribe(context.Background(), samples) {
+		break
+	}
+	assert.NoError(ctx.Set(whisper.WithTemperature(0.2)))
+}
+
+func TestTranscribeCancel(t *testing.T) {
+	assert := assert.New(t)
+	samples := loadSamples(t)
+
+	model, err := whisper.Open(ModelPath)
+	assert.NoError(err)
+	defer model.Close()
+	ctx, err := model.NewContext()
+	assert.NoError(err)
+	defer ctx.Close()
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	n := 0
+	for _, err := range ctx.Transcribe(cancelled, samples) {
+		assert.ErrorIs(err, context.Canceled)
+		n++
+	}
+	assert.Equal(1, n)
+}
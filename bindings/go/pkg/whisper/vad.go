@@ -0,0 +1,265 @@
+package whisper
+
+import (
+	"io"
+	"math"
+	"time"
+
+	// Bindings
+	whisper "github.com/ggerganov/whisper.cpp/bindings/go"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// SpeechRegion is a span of the input audio which contains speech
+type SpeechRegion struct {
+	Start, End time.Duration
+}
+
+// VAD detects the regions of mono audio data which contain speech
+type VAD interface {
+	DetectSpeech([]float32) ([]SpeechRegion, error)
+}
+
+// VADModel is a VAD backed by a model, which needs to be closed after use
+type VADModel interface {
+	io.Closer
+	VAD
+}
+
+// EnergyVAD is a simple VAD which marks audio frames with an RMS energy
+// above a threshold as speech. It does not require a model.
+type EnergyVAD struct {
+	// RMS energy threshold above which a frame is speech (default 0.02)
+	Threshold float32
+
+	// Analysis frame length (default 30ms)
+	Frame time.Duration
+
+	// Silence shorter than this does not split a region (default 300ms)
+	MinSilence time.Duration
+
+	// Regions shorter than this are discarded (default 250ms)
+	MinSpeech time.Duration
+
+	// Padding added before and after each region (default 100ms)
+	Pad time.Duration
+}
+
+// RegionResult contains the segments transcribed from a single speech
+// region, with timestamps relative to the start of the original audio
+type RegionResult struct {
+	SpeechRegion
+	Segments []Segment
+}
+
+// SpeechStats reports how much of the audio was transcribed and skipped
+type SpeechStats struct {
+	Regions int           // Number of speech regions transcribed
+	Audio   time.Duration // Duration of the input audio
+	Speech  time.Duration // Duration of audio passed to the model
+	Skipped time.Duration // Duration of audio skipped as non-speech
+}
+
+type sileroVAD struct {
+	ctx    *whisper.VadContext
+	params whisper.VadParams
+}
+
+// Make sure sileroVAD adheres to the interface
+var _ VADModel = (*sileroVAD)(nil)
+
+///////////////////////////////////////////////////////////////////////////////
+// LIFECYCLE
+
+// NewSileroVAD loads a Silero VAD model with default detection parameters
+func NewSileroVAD(path string, threads uint) (VADModel, error) {
+	vad := new(sileroVAD)
+	if ctx := whisper.Whisper_vad_init(path, int(threads)); ctx == nil {
+		return nil, ErrUnableToLoadModel
+	} else {
+		vad.ctx = ctx
+		vad.params = whisper.Whisper_vad_default_params()
+	}
+
+	// Return success
+	return vad, nil
+}
+
+func (vad *sileroVAD) Close() error {
+	if vad.ctx != nil {
+		vad.ctx.Whisper_vad_free()
+	}
+
+	// Release resources
+	vad.ctx = nil
+
+	// Return success
+	return nil
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Return the speech regions detected by the Silero VAD model
+func (vad *sileroVAD) DetectSpeech(data []float32) ([]SpeechRegion, error) {
+	if vad.ctx == nil {
+		return nil, ErrInternalAppError
+	}
+	if len(data) == 0 {
+		return nil, nil
+	}
+	segments, err := vad.ctx.Whisper_vad_segments_from_samples(vad.params, data)
+	if err != nil {
+		return nil, err
+	}
+	defer segments.Whisper_vad_free_segments()
+
+	result := make([]SpeechRegion, segments.Whisper_vad_segments_n_segments())
+	for i := range result {
+		result[i] = SpeechRegion{
+			Start: time.Duration(segments.Whisper_vad_segments_get_segment_t0(i)*10) * time.Millisecond,
+			End:   time.Duration(segments.Whisper_vad_segments_get_segment_t1(i)*10) * time.Millisecond,
+		}
+	}
+	return result, nil
+}
+
+// Return the speech regions where the frame energy exceeds the threshold
+func (vad EnergyVAD) DetectSpeech(data []float32) ([]SpeechRegion, error) {
+	threshold := vad.Threshold
+	if threshold <= 0 {
+		threshold = 0.02
+	}
+	frame := durationToSamples(vad.Frame)
+	if frame <= 0 {
+		frame = durationToSamples(30 * time.Millisecond)
+	}
+	minSilence, minSpeech, pad := vad.MinSilence, vad.MinSpeech, vad.Pad
+	if minSilence == 0 {
+		minSilence = 300 * time.Millisecond
+	}
+	if minSpeech == 0 {
+		minSpeech = 250 * time.Millisecond
+	}
+	if pad == 0 {
+		pad = 100 * time.Millisecond
+	}
+
+	// Mark frames above the threshold and merge regions separated by short silences
+	var result []SpeechRegion
+	for i := 0; i < len(data); i += frame {
+		j := min(i+frame, len(data))
+		if rms(data[i:j]) < threshold {
+			continue
+		}
+		region := SpeechRegion{Start: samplesToDuration(i), End: samplesToDuration(j)}
+		if n := len(result); n > 0 && region.Start-result[n-1].End < minSilence {
+			result[n-1].End = region.End
+		} else {
+			result = append(result, region)
+		}
+	}
+
+	// Drop short regions and apply padding
+	total := samplesToDuration(len(data))
+	regions := result[:0]
+	for _, region := range result {
+		if region.End-region.Start < minSpeech {
+			continue
+		}
+		region.Start = max(region.Start-pad, 0)
+		region.End = min(region.End+pad, total)
+		if n := len(regions); n > 0 && region.Start <= regions[n-1].End {
+			regions[n-1].End = region.End
+		} else {
+			regions = append(regions, region)
+		}
+	}
+	return regions, nil
+}
+
+// ProcessSpeechRegions runs the VAD over the audio data and then transcribes
+// only the detected speech regions with the context. Segment and token
+// timestamps are mapped back onto the timeline of the original audio, and
+// segments are numbered sequentially across regions. If defined, each segment
+// is also passed to the callback function as soon as its region completes.
+func ProcessSpeechRegions(context Transcriber, vad VAD, data []float32, callNewSegment SegmentCallback) ([]RegionResult, SpeechStats, error) {
+	stats := SpeechStats{Audio: samplesToDuration(len(data))}
+	regions, err := vad.DetectSpeech(data)
+	if err != nil {
+		return nil, stats, err
+	}
+
+	var num int
+	result := make([]RegionResult, 0, len(regions))
+	for _, region := range regions {
+		i, j := durationToSamples(region.Start), min(durationToSamples(region.End), len(data))
+		if i >= j {
+			continue
+		}
+		if err := context.Process(data[i:j], nil, nil, nil); err != nil {
+			return result, stats, err
+		}
+
+		// Collect segments and shift them onto the original timeline
+		item := RegionResult{SpeechRegion: region}
+		for {
+			segment, err := context.NextSegment()
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				return result, stats, err
+			}
+			segment.Num = num
+			segment.shift(region.Start)
+			if callNewSegment != nil {
+				callNewSegment(segment)
+			}
+			item.Segments = append(item.Segments, segment)
+			num++
+		}
+
+		result = append(result, item)
+		stats.Regions++
+		stats.Speech += samplesToDuration(j - i)
+	}
+	stats.Skipped = stats.Audio - stats.Speech
+
+	// Return success
+	return result, stats, nil
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// Move the segment, its tokens and its events later by the offset
+func (segment *Segment) shift(offset time.Duration) {
+	segment.Start += offset
+	segment.End += offset
+	for k := range segment.Tokens {
+		segment.Tokens[k].Start += offset
+		segment.Tokens[k].End += offset
+	}
+	for k := range segment.Events {
+		segment.Events[k].Start += offset
+		segment.Events[k].End += offset
+	}
+}
+
+func durationToSamples(d time.Duration) int {
+	return int(d * SampleRate / time.Second)
+}
+
+func samplesToDuration(n int) time.Duration {
+	return time.Duration(n) * time.Second / SampleRate
+}
+
+func rms(data []float32) float32 {
+	var sum float64
+	for _, v := range data {
+		sum += float64(v) * float64(v)
+	}
+	return float32(math.Sqrt(sum / float64(len(data))))
+}
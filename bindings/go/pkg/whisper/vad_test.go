@@ -0,0 +1,114 @@
+package whisper_test
+
+import (
+	"io"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/ggerganov/whisper.cpp/bindings/go/pkg/whisper"
+	"github.com/ggerganov/whisper.cpp/bindings/go/pkg/whisper/testsupport"
+	"github.com/go-audio/wav"
+	assert "github.com/stretchr/testify/assert"
+)
+
+func TestEnergyVAD(t *testing.T) {
+	assert := assert.New(t)
+
+	// One second of silence, one second of tone, one second of silence
+	assert.Equal(whisper.SampleRate, testsupport.SampleRate)
+	data, boundaries := testsupport.Concat(
+		testsupport.Part{Data: testsupport.Silence(time.Second)},
+		testsupport.Part{Label: "tone", Data: testsupport.Tone(440, 0.5, time.Second)},
+		testsupport.Part{Data: testsupport.Silence(time.Second)},
+	)
+
+	// The region is padded by 100ms on both sides
+	regions, err := whisper.EnergyVAD{}.DetectSpeech(data)
+	assert.NoError(err)
+	assert.Len(regions, 1)
+	assert.InDelta(boundaries[1].Start-100*time.Millisecond, regions[0].Start, float64(30*time.Millisecond))
+	assert.InDelta(boundaries[1].End+100*time.Millisecond, regions[0].End, float64(30*time.Millisecond))
+
+	// Silence alone has no speech
+	regions, err = whisper.EnergyVAD{}.DetectSpeech(make([]float32, whisper.SampleRate))
+	assert.NoError(err)
+	assert.Empty(regions)
+}
+
+func TestSileroVAD(t *testing.T) {
+	assert := assert.New(t)
+	if _, err := os.Stat(VADModelPath); os.IsNotExist(err) {
+		t.Skip("Skipping test, model not found:", VADModelPath)
+	}
+
+	fh, err := os.Open(SamplePath)
+	assert.NoError(err)
+	defer fh.Close()
+
+	// Decode the WAV file - load the full buffer
+	dec := wav.NewDecoder(fh)
+	buf, err := dec.FullPCMBuffer()
+	assert.NoError(err)
+	data := buf.AsFloat32Buffer().Data
+
+	vad, err := whisper.NewSileroVAD(VADModelPath, 1)
+	assert.NoError(err)
+	defer vad.Close()
+
+	regions, err := vad.DetectSpeech(data)
+	assert.NoError(err)
+	assert.NotEmpty(regions)
+	for i, region := range regions {
+		assert.Less(region.Start, region.End)
+		if i > 0 {
+			assert.LessOrEqual(regions[i-1].End, region.Start)
+		}
+	}
+}
+
+// A transcriber which returns one segment spanning the audio of each call
+type fakeTranscriber struct {
+	samples []int
+	next    []whisper.Segment
+}
+
+func (f *fakeTranscriber) Process(data []float32, _ whisper.EncoderBeginCallback, _ whisper.SegmentCallback, _ whisper.ProgressCallback) error {
+	f.samples = append(f.samples, len(data))
+	f.next = []whisper.Segment{{End: time.Duration(len(data)) * time.Second / whisper.SampleRate, Text: "speech"}}
+	return nil
+}
+
+func (f *fakeTranscriber) NextSegment() (whisper.Segment, error) {
+	if len(f.next) == 0 {
+		return whisper.Segment{}, io.EOF
+	}
+	segment := f.next[0]
+	f.next = f.next[1:]
+	return segment, nil
+}
+
+func TestProcessSpeechRegionsTranscriber(t *testing.T) {
+	assert := assert.New(t)
+
+	// Two seconds of tone separated by two seconds of silence
+	tone := testsupport.Tone(440, 0.5, time.Second)
+	data, _ := testsupport.Concat(
+		testsupport.Part{Data: tone},
+		testsupport.Part{Data: testsupport.Silence(2 * time.Second)},
+		testsupport.Part{Data: tone},
+		testsupport.Part{Data: testsupport.Silence(time.Second)},
+	)
+
+	transcriber := new(fakeTranscriber)
+	result, stats, err := whisper.ProcessSpeechRegions(transcriber, whisper.EnergyVAD{}, data, nil)
+	assert.NoError(err)
+	assert.Len(transcriber.samples, 2)
+	assert.Equal(2, stats.Regions)
+	if assert.Len(result, 2) {
+		// Segments are numbered across regions and placed on the original timeline
+		assert.Equal(1, result[1].Segments[0].Num)
+		assert.Equal(result[1].Start, result[1].Segments[0].Start)
+		assert.Equal(result[1].End, result[1].Segments[0].End)
+	}
+}
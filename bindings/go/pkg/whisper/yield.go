@@ -0,0 +1,146 @@
+package whisper
+
+import (
+	"sync"
+
+	// Bindings
+	whisper "github.com/ggerganov/whisper.cpp/bindings/go"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// Pauses processing at window boundaries while the model has yielded the
+// device to the host application
+type yielder struct {
+	sync.Mutex
+	cond     sync.Cond
+	yielded  bool
+	released bool
+	running  int
+
+	// The native context, which is locked for reading from the time the
+	// compute buffers are released until Resume
+	ctx *whisper.Context
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// YieldGPU lets a host application, such as a game or an app coming to the
+// foreground, reclaim the device. Contexts which are processing finish their
+// current window and pause, then the compute buffers of all decoding states
+// are freed, keeping the weights, KV caches and results. YieldGPU returns
+// once the buffers are freed. Close blocks until Resume is called.
+func (model *model) YieldGPU() error {
+	ctx := model.rlock()
+	if ctx == nil {
+		return ErrInternalAppError
+	}
+
+	y := &model.yield
+	y.Lock()
+	defer y.Unlock()
+	y.init()
+	if y.yielded {
+		// Wait for another call to release the buffers
+		for y.yielded && !y.released {
+			y.cond.Wait()
+		}
+		model.runlock()
+		return nil
+	}
+
+	// Wait for processing contexts to reach the end of their window
+	y.yielded = true
+	for y.running > 0 {
+		y.cond.Wait()
+	}
+
+	// Free the compute buffers
+	ctx.Whisper_release_compute()
+	model.statesMu.Lock()
+	for state := range model.states {
+		state.Whisper_release_compute_with_state()
+	}
+	model.statesMu.Unlock()
+	y.ctx = ctx
+	y.released = true
+	y.cond.Broadcast()
+
+	// Return success
+	return nil
+}
+
+// Resume processing after YieldGPU. The compute buffers of each decoding
+// state are allocated again when it next encodes or decodes, so paused
+// contexts pay for the allocation as they continue.
+func (model *model) Resume() {
+	y := &model.yield
+	y.Lock()
+	defer y.Unlock()
+	if !y.released {
+		return
+	}
+	y.yielded = false
+	y.released = false
+	y.ctx = nil
+	y.cond.Broadcast()
+	model.runlock()
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+func (y *yielder) init() {
+	if y.cond.L == nil {
+		y.cond.L = &y.Mutex
+	}
+}
+
+// Wait while the device is yielded, then mark a context as processing.
+// exit must be called once processing is done.
+func (y *yielder) enter() {
+	y.Lock()
+	defer y.Unlock()
+	y.init()
+	for y.yielded {
+		y.cond.Wait()
+	}
+	y.running++
+}
+
+func (y *yielder) exit() {
+	y.Lock()
+	defer y.Unlock()
+	y.running--
+	y.cond.Broadcast()
+}
+
+// Pause a processing context at a window boundary while the device is
+// yielded
+func (y *yielder) pause() {
+	y.Lock()
+	defer y.Unlock()
+	if !y.yielded {
+		return
+	}
+	y.running--
+	y.cond.Broadcast()
+	for y.yielded {
+		y.cond.Wait()
+	}
+	y.running++
+}
+
+// Return an encoder begin callback which calls fn, then pauses while the
+// device is yielded
+func (y *yielder) wrap(fn EncoderBeginCallback) EncoderBeginCallback {
+	return func() bool {
+		if fn != nil && !fn() {
+			return false
+		}
+		y.pause()
+		return true
+	}
+}
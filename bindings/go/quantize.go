@@ -0,0 +1,102 @@
+package whisper
+
+import (
+	"fmt"
+	"sync"
+	"unsafe"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// CGO
+
+/*
+#include <ggml.h>
+#include <stdlib.h>
+
+// Initialize the tables of ggml, which are needed to convert half precision
+// floats before any context has been created
+static void whisper_ggml_init_tables(void) {
+    struct ggml_init_params params = { 0, NULL, false };
+    ggml_free(ggml_init(params));
+}
+*/
+import "C"
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// TensorType is the ggml type of the elements of a tensor
+type TensorType C.enum_ggml_type
+
+///////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+const (
+	TENSOR_TYPE_F32 TensorType = C.GGML_TYPE_F32
+	TENSOR_TYPE_F16 TensorType = C.GGML_TYPE_F16
+)
+
+const (
+	QuantizationVersion       = C.GGML_QNT_VERSION        // Version of the quantization format
+	QuantizationVersionFactor = C.GGML_QNT_VERSION_FACTOR // Multiplier of the version in the ftype of a model file
+)
+
+var ggmlInit sync.Once
+
+///////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (t TensorType) String() string {
+	if name := C.ggml_type_name(C.enum_ggml_type(t)); name != nil {
+		return C.GoString(name)
+	}
+	return fmt.Sprintf("type(%d)", int(t))
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Return the tensor type of most of the weights of a model with the ftype
+func Ggml_ftype_to_ggml_type(ftype int) TensorType {
+	return TensorType(C.ggml_ftype_to_ggml_type(C.enum_ggml_ftype(ftype)))
+}
+
+// Return true if the type is quantized
+func (t TensorType) IsQuantized() bool {
+	return bool(C.ggml_is_quantized(C.enum_ggml_type(t)))
+}
+
+// Return the number of elements in a block of the type, which the length of
+// the rows of a tensor must be a multiple of
+func (t TensorType) BlockSize() int {
+	return int(C.ggml_blck_size(C.enum_ggml_type(t)))
+}
+
+// Return the size in bytes of a row of n elements of the type
+func (t TensorType) RowSize(n int) int {
+	return int(C.ggml_row_size(C.enum_ggml_type(t), C.int64_t(n)))
+}
+
+// Convert half precision floats to single precision
+func Ggml_fp16_to_fp32_row(src []uint16, dst []float32) {
+	ggmlInit.Do(func() { C.whisper_ggml_init_tables() })
+	if n := min(len(src), len(dst)); n > 0 {
+		C.ggml_fp16_to_fp32_row((*C.ggml_fp16_t)(unsafe.Pointer(&src[0])), (*C.float)(unsafe.Pointer(&dst[0])), C.int64_t(n))
+	}
+}
+
+// Quantize rows of n elements to the type, and return the quantized rows.
+// The number of elements must be a multiple of the block size of the type.
+func Ggml_quantize_chunk(t TensorType, src []float32, n int) ([]byte, error) {
+	if !t.IsQuantized() || n <= 0 || n%t.BlockSize() != 0 || len(src)%n != 0 {
+		return nil, fmt.Errorf("%w: cannot quantize rows of %d elements to %v", ErrInvalidParams, n, t)
+	}
+	ggmlInit.Do(func() { C.whisper_ggml_init_tables() })
+	rows := len(src) / n
+	dst := make([]byte, rows*t.RowSize(n))
+	if rows == 0 {
+		return dst, nil
+	}
+	size := C.ggml_quantize_chunk(C.enum_ggml_type(t), (*C.float)(unsafe.Pointer(&src[0])), unsafe.Pointer(&dst[0]), 0, C.int64_t(rows), C.int64_t(n), nil)
+	return dst[:size], nil
+}
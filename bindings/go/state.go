@@ -0,0 +1,261 @@
+package whisper
+
+import (
+	"errors"
+	"unsafe"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// CGO
+
+/*
+#include <whisper.h>
+#include <stdlib.h>
+*/
+import "C"
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// State is a decoding state, which holds the KV caches, compute buffers and
+// results of a call to Whisper_full_with_state. Several states can share the
+// weights of a single context and be used concurrently.
+type State C.struct_whisper_state
+
+///////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+var (
+	ErrOutOfMemory = errors.New("whisper_init_state failed: out of memory")
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Allocate a new decoding state for the context. The native library fails
+// to allocate a state when the KV caches or compute buffers do not fit into
+// the memory of the backend, in which case ErrOutOfMemory is returned.
+func (ctx *Context) Whisper_init_state() (*State, error) {
+	if state := C.whisper_init_state((*C.struct_whisper_context)(ctx)); state == nil {
+		return nil, ErrOutOfMemory
+	} else {
+		return (*State)(state), nil
+	}
+}
+
+// Frees all memory allocated by the state
+func (state *State) Whisper_free_state() {
+	C.whisper_free_state((*C.struct_whisper_state)(state))
+}
+
+// Return true if the encoder of the state runs with Core ML
+func (state *State) Whisper_uses_coreml() bool {
+	return bool(C.whisper_uses_coreml_with_state((*C.struct_whisper_state)(state)))
+}
+
+// Return the memory of the state on each of its backends
+func (state *State) Whisper_memory_usage() []BackendMemory {
+	return memoryUsage(func(memory *C.struct_whisper_backend_memory, n C.int) C.int {
+		return C.whisper_memory_usage_with_state((*C.struct_whisper_state)(state), memory, n)
+	})
+}
+
+// Return the sizes of the KV caches and compute buffers of the state
+func (state *State) Whisper_get_state_sizes() StateSizes {
+	return stateSizes(nil, (*C.struct_whisper_state)(state))
+}
+
+// Free the compute buffers of the state, keeping its KV caches and results.
+// They are allocated again by Whisper_reacquire_compute_with_state, or on
+// the next use of the state.
+func (state *State) Whisper_release_compute_with_state() {
+	C.whisper_release_compute_with_state((*C.struct_whisper_state)(state))
+}
+
+// Allocate the compute buffers of the state again, returning
+// ErrOutOfMemory if they do not fit into the memory of the backend
+func (ctx *Context) Whisper_reacquire_compute_with_state(state *State) error {
+	if C.whisper_reacquire_compute_with_state((*C.struct_whisper_context)(ctx), (*C.struct_whisper_state)(state)) != 0 {
+		return ErrOutOfMemory
+	}
+	return nil
+}
+
+// Run the entire model using the given state instead of the default state
+// of the context, so that several states can process concurrently.
+func (ctx *Context) Whisper_full_with_state(
+	state *State,
+	params Params,
+	samples []float32,
+	encoderBeginCallback func() bool,
+	newSegmentCallback func(int),
+	progressCallback func(int),
+) error {
+	// Callbacks are identified by the state, rather than the context
+	params.new_segment_callback_user_data = unsafe.Pointer(state)
+	params.encoder_begin_callback_user_data = unsafe.Pointer(state)
+	params.progress_callback_user_data = unsafe.Pointer(state)
+	registerEncoderBeginCallback(unsafe.Pointer(state), encoderBeginCallback)
+	registerNewSegmentCallback(unsafe.Pointer(state), newSegmentCallback)
+	registerProgressCallback(unsafe.Pointer(state), progressCallback)
+	defer registerEncoderBeginCallback(unsafe.Pointer(state), nil)
+	defer registerNewSegmentCallback(unsafe.Pointer(state), nil)
+	defer registerProgressCallback(unsafe.Pointer(state), nil)
+	if code := C.whisper_full_with_state((*C.struct_whisper_context)(ctx), (*C.struct_whisper_state)(state), (C.struct_whisper_full_params)(params), (*C.float)(&samples[0]), C.int(len(samples))); code == 0 {
+		return nil
+	} else {
+		return &FullError{Code: int(code)}
+	}
+}
+
+// Convert PCM audio to a log mel spectrogram in the state, for use with
+// Whisper_lang_auto_detect_with_state
+func (ctx *Context) Whisper_pcm_to_mel_with_state(state *State, data []float32, threads int) error {
+	if C.whisper_pcm_to_mel_with_state((*C.struct_whisper_context)(ctx), (*C.struct_whisper_state)(state), (*C.float)(&data[0]), C.int(len(data)), C.int(threads)) == 0 {
+		return nil
+	} else {
+		return ErrConversionFailed
+	}
+}
+
+// Use the mel data of the state at offset_ms to auto-detect the spoken
+// language, and return the probabilities of all languages.
+func (ctx *Context) Whisper_lang_auto_detect_with_state(state *State, offset_ms, n_threads int) ([]float32, error) {
+	probs := make([]float32, Whisper_lang_max_id()+1)
+	if n := int(C.whisper_lang_auto_detect_with_state((*C.struct_whisper_context)(ctx), (*C.struct_whisper_state)(state), C.int(offset_ms), C.int(n_threads), (*C.float)(&probs[0]))); n < 0 {
+		return nil, ErrAutoDetectFailed
+	} else {
+		return probs, nil
+	}
+}
+
+// Return the id of the language detected by the last call to
+// Whisper_full_with_state
+func (state *State) Whisper_full_lang_id() int {
+	return int(C.whisper_full_lang_id_from_state((*C.struct_whisper_state)(state)))
+}
+
+// Number of generated text segments
+func (state *State) Whisper_full_n_segments() int {
+	return int(C.whisper_full_n_segments_from_state((*C.struct_whisper_state)(state)))
+}
+
+// Start of the audio window being decoded, in units of 10 ms
+func (state *State) Whisper_full_get_seek() int64 {
+	return int64(C.whisper_full_get_seek_from_state((*C.struct_whisper_state)(state)))
+}
+
+// Skip ahead to the offset, in units of 10 ms, instead of encoding the
+// current window
+func (state *State) Whisper_full_set_seek(seek int64) {
+	C.whisper_full_set_seek_with_state((*C.struct_whisper_state)(state), C.int64_t(seek))
+}
+
+// Get the start time of the specified segment
+func (state *State) Whisper_full_get_segment_t0(segment int) int64 {
+	return int64(C.whisper_full_get_segment_t0_from_state((*C.struct_whisper_state)(state), C.int(segment)))
+}
+
+// Get the end time of the specified segment
+func (state *State) Whisper_full_get_segment_t1(segment int) int64 {
+	return int64(C.whisper_full_get_segment_t1_from_state((*C.struct_whisper_state)(state), C.int(segment)))
+}
+
+// Get the text of the specified segment
+func (state *State) Whisper_full_get_segment_text(segment int) string {
+	return C.GoString(C.whisper_full_get_segment_text_from_state((*C.struct_whisper_state)(state), C.int(segment)))
+}
+
+// Append the text of the specified segment to dst and return the extended
+// buffer
+func (state *State) Whisper_full_append_segment_text(dst []byte, segment int) []byte {
+	return appendCString(dst, C.whisper_full_get_segment_text_from_state((*C.struct_whisper_state)(state), C.int(segment)))
+}
+
+// Get number of tokens in the specified segment
+func (state *State) Whisper_full_n_tokens(segment int) int {
+	return int(C.whisper_full_n_tokens_from_state((*C.struct_whisper_state)(state), C.int(segment)))
+}
+
+// Get the token text of the specified token in the specified segment. The
+// vocabulary is read from the context.
+func (state *State) Whisper_full_get_token_text(ctx *Context, segment int, token int) string {
+	return C.GoString(C.whisper_full_get_token_text_from_state((*C.struct_whisper_context)(ctx), (*C.struct_whisper_state)(state), C.int(segment), C.int(token)))
+}
+
+// Append the token text of the specified token in the specified segment to
+// dst and return the extended buffer
+func (state *State) Whisper_full_append_token_text(ctx *Context, dst []byte, segment int, token int) []byte {
+	return appendCString(dst, C.whisper_full_get_token_text_from_state((*C.struct_whisper_context)(ctx), (*C.struct_whisper_state)(state), C.int(segment), C.int(token)))
+}
+
+// Get the token of the specified token index in the specified segment
+func (state *State) Whisper_full_get_token_id(segment int, token int) Token {
+	return Token(C.whisper_full_get_token_id_from_state((*C.struct_whisper_state)(state), C.int(segment), C.int(token)))
+}
+
+// Get token data for the specified token in the specified segment
+func (state *State) Whisper_full_get_token_data(segment int, token int) TokenData {
+	return TokenData(C.whisper_full_get_token_data_from_state((*C.struct_whisper_state)(state), C.int(segment), C.int(token)))
+}
+
+// Get the probability of the specified token in the specified segment
+func (state *State) Whisper_full_get_token_p(segment int, token int) float32 {
+	return float32(C.whisper_full_get_token_p_from_state((*C.struct_whisper_state)(state), C.int(segment), C.int(token)))
+}
+
+// Number of speech segments detected by VAD in the last call to
+// Whisper_full_with_state
+func (state *State) Whisper_full_n_vad_segments() int {
+	return int(C.whisper_full_n_vad_segments_from_state((*C.struct_whisper_state)(state)))
+}
+
+// Get the start time of the specified VAD segment, in centiseconds
+func (state *State) Whisper_full_get_vad_segment_t0(segment int) int64 {
+	return int64(C.whisper_full_get_vad_segment_t0_from_state((*C.struct_whisper_state)(state), C.int(segment)))
+}
+
+// Get the end time of the specified VAD segment, in centiseconds
+func (state *State) Whisper_full_get_vad_segment_t1(segment int) int64 {
+	return int64(C.whisper_full_get_vad_segment_t1_from_state((*C.struct_whisper_state)(state), C.int(segment)))
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// Return the memory usage filled in by fn, which returns the number of
+// backends and writes at most n of them
+func memoryUsage(fn func(*C.struct_whisper_backend_memory, C.int) C.int) []BackendMemory {
+	var memory [8]C.struct_whisper_backend_memory
+	buf := memory[:]
+	n := int(fn(&buf[0], C.int(len(buf))))
+	if n > len(buf) {
+		buf = make([]C.struct_whisper_backend_memory, n)
+		n = min(int(fn(&buf[0], C.int(len(buf)))), len(buf))
+	}
+	result := make([]BackendMemory, 0, n)
+	for _, m := range buf[:n] {
+		result = append(result, BackendMemory{
+			Name:    C.GoString(m.name),
+			KVCache: uint64(m.kv_cache),
+			Compute: uint64(m.compute),
+		})
+	}
+	return result
+}
+
+// Return the sizes of the buffers of the state, or of the default state of
+// the context if state is nil
+func stateSizes(ctx *C.struct_whisper_context, state *C.struct_whisper_state) StateSizes {
+	var sizes C.struct_whisper_state_sizes
+	C.whisper_get_state_sizes(ctx, state, &sizes)
+	return StateSizes{
+		KVSelf:  uint64(sizes.kv_self),
+		KVCross: uint64(sizes.kv_cross),
+		KVPad:   uint64(sizes.kv_pad),
+		Conv:    uint64(sizes.compute_conv),
+		Encode:  uint64(sizes.compute_encode),
+		Cross:   uint64(sizes.compute_cross),
+		Decode:  uint64(sizes.compute_decode),
+	}
+}
@@ -2,6 +2,8 @@ package whisper
 
 import (
 	"errors"
+	"fmt"
+	"sync"
 	"unsafe"
 )
 
@@ -15,6 +17,7 @@ import (
 #cgo darwin LDFLAGS: -framework Accelerate -framework Metal -framework Foundation -framework CoreGraphics
 #include <whisper.h>
 #include <stdlib.h>
+#include <string.h>
 
 extern void callNewSegment(void* user_data, int new);
 extern void callProgress(void* user_data, int progress);
@@ -71,8 +74,74 @@ type (
 	TokenData        C.struct_whisper_token_data
 	SamplingStrategy C.enum_whisper_sampling_strategy
 	Params           C.struct_whisper_full_params
+	VadContext       C.struct_whisper_vad_context
+	VadParams        C.struct_whisper_vad_params
+	VadSegments      C.struct_whisper_vad_segments
 )
 
+// FullError is returned when Whisper_full fails, with the code returned by
+// the native library. It matches ErrConversionFailed with errors.Is
+type FullError struct {
+	Code int
+}
+
+// Timings are the totals of the timings of a decoding state since they were
+// reset, in microseconds, with the number of runs of each step and the
+// number of temperature fallbacks
+type Timings struct {
+	LoadUs, TotalUs, MelUs                           int64
+	SampleUs, EncodeUs, DecodeUs, BatchdUs, PromptUs int64
+	NSample, NEncode, NDecode, NBatchd, NPrompt      int
+	NFailP, NFailH                                   int
+}
+
+// Tensor is a weight tensor of a model, with the name of its ggml type
+// such as "f16" or "q5_0", and its size in bytes
+type Tensor struct {
+	Name  string
+	Type  string
+	Bytes uint64
+}
+
+// Buffer is a backend buffer of the model weights, with the name of its
+// buffer type such as "CPU" or "CUDA0", and its size in bytes
+type Buffer struct {
+	Name  string
+	Bytes uint64
+}
+
+// StateSizes are the sizes of the buffers of a state in bytes, as printed
+// when the state is allocated. The compute buffers are zero while they are
+// released.
+type StateSizes struct {
+	KVSelf, KVCross, KVPad      uint64
+	Conv, Encode, Cross, Decode uint64
+}
+
+// BackendMemory is the memory of the KV caches and compute buffers of a
+// state on one of its backends, in bytes. Compute is zero while the compute
+// buffers are released.
+type BackendMemory struct {
+	Name             string
+	KVCache, Compute uint64
+}
+
+// A registered logits filter
+type logitsFilterRef struct {
+	fn   LogitsFilterCallback
+	refs int
+}
+
+// LogitsFilterCallback is called before each token is sampled, with the
+// tokens of the current sequence and the logits of the vocabulary, which it
+// can modify in place
+type LogitsFilterCallback func(tokens []TokenData, logits []float32)
+
+// LoadProgressCallback is called after each tensor of a model is loaded,
+// with the bytes and tensors loaded so far and in total. If it returns
+// false, loading is cancelled and the model fails to load.
+type LoadProgressCallback func(bytes, totalBytes uint64, tensors, totalTensors int) bool
+
 ///////////////////////////////////////////////////////////////////////////////
 // GLOBALS
 
@@ -94,8 +163,19 @@ var (
 	ErrAutoDetectFailed = errors.New("whisper_lang_auto_detect failed")
 	ErrConversionFailed = errors.New("whisper_convert failed")
 	ErrInvalidLanguage  = errors.New("invalid language")
+	ErrVadFailed        = errors.New("whisper_vad_segments_from_samples failed")
+	ErrInvalidParams    = errors.New("invalid parameters")
+	ErrCoreMLDisabled   = errors.New("whisper_ctx_set_coreml_encoder failed: Core ML is not enabled in the build")
+
+	// Translation needs a multilingual model, and English-only models emit
+	// garbage when it is requested. The error wraps ErrInvalidParams.
+	ErrTranslateUnsupported = fmt.Errorf("%w: translate requires a multilingual model", ErrInvalidParams)
 )
 
+// The reports of the benchmarks are returned in a static buffer, so one
+// benchmark runs at a time
+var benchMu sync.Mutex
+
 ///////////////////////////////////////////////////////////////////////////////
 // PUBLIC METHODS
 
@@ -116,6 +196,59 @@ func (ctx *Context) Whisper_free() {
 	C.whisper_free((*C.struct_whisper_context)(ctx))
 }
 
+// Free the compute buffers of the default state, keeping the weights, KV
+// caches and results. They are allocated again by Whisper_reacquire_compute,
+// or on the next use of the context.
+func (ctx *Context) Whisper_release_compute() {
+	C.whisper_release_compute((*C.struct_whisper_context)(ctx))
+}
+
+// Allocate the compute buffers of the default state again, returning
+// ErrOutOfMemory if they do not fit into the memory of the backend
+func (ctx *Context) Whisper_reacquire_compute() error {
+	if C.whisper_reacquire_compute((*C.struct_whisper_context)(ctx)) != 0 {
+		return ErrOutOfMemory
+	}
+	return nil
+}
+
+// Replace the default state with a new one, which recreates its backends, for
+// example after Whisper_full fails because a backend is in an error state.
+// ErrOutOfMemory is returned, and the old state kept, if the new state cannot
+// be allocated.
+func (ctx *Context) Whisper_reset_state() error {
+	if C.whisper_reset_state((*C.struct_whisper_context)(ctx)) != 0 {
+		return ErrOutOfMemory
+	}
+	return nil
+}
+
+// Set the Core ML encoder model used by the states allocated afterwards,
+// which is either compiled (.mlmodelc), or compiled when it is loaded
+// (.mlmodel or .mlpackage). Compiled models are kept in cacheDir, if it is
+// not empty. An empty path uses the model next to the ggml model file.
+// ErrCoreMLDisabled is returned when the library is built without Core ML.
+func (ctx *Context) Whisper_ctx_set_coreml_encoder(path, cacheDir string) error {
+	var cPath, cCacheDir *C.char
+	if path != "" {
+		cPath = C.CString(path)
+		defer C.free(unsafe.Pointer(cPath))
+	}
+	if cacheDir != "" {
+		cCacheDir = C.CString(cacheDir)
+		defer C.free(unsafe.Pointer(cCacheDir))
+	}
+	if C.whisper_ctx_set_coreml_encoder((*C.struct_whisper_context)(ctx), cPath, cCacheDir) != 0 {
+		return ErrCoreMLDisabled
+	}
+	return nil
+}
+
+// Return true if the encoder of the default state runs with Core ML
+func (ctx *Context) Whisper_uses_coreml() bool {
+	return bool(C.whisper_uses_coreml((*C.struct_whisper_context)(ctx)))
+}
+
 // Convert RAW PCM audio to log mel spectrogram.
 // The resulting spectrogram is stored inside the provided whisper context.
 func (ctx *Context) Whisper_pcm_to_mel(data []float32, threads int) error {
@@ -192,6 +325,12 @@ func Whisper_lang_str(id int) string {
 	return C.GoString(C.whisper_lang_str(C.int(id)))
 }
 
+// Return the full string of the specified language id (e.g. 2 -> "german"),
+// returns empty string if not found
+func Whisper_lang_str_full(id int) string {
+	return C.GoString(C.whisper_lang_str_full(C.int(id)))
+}
+
 // Use mel data at offset_ms to try and auto-detect the spoken language
 // Make sure to call whisper_pcm_to_mel() or whisper_set_mel() first.
 // Returns the probabilities of all languages.
@@ -225,6 +364,52 @@ func (ctx *Context) Whisper_is_multilingual() int {
 	return int(C.whisper_is_multilingual((*C.struct_whisper_context)(ctx)))
 }
 
+// Return the type of most of the weights of the model, as a ggml_ftype
+func (ctx *Context) Whisper_model_ftype() int {
+	return int(C.whisper_model_ftype((*C.struct_whisper_context)(ctx)))
+}
+
+// Return the weight tensors of the model, in order of their name
+func (ctx *Context) Whisper_model_tensors() []Tensor {
+	n := int(C.whisper_model_n_tensors((*C.struct_whisper_context)(ctx)))
+	result := make([]Tensor, 0, n)
+	for i := 0; i < n; i++ {
+		result = append(result, Tensor{
+			Name:  C.GoString(C.whisper_model_tensor_name((*C.struct_whisper_context)(ctx), C.int(i))),
+			Type:  C.GoString(C.ggml_type_name(C.whisper_model_tensor_type((*C.struct_whisper_context)(ctx), C.int(i)))),
+			Bytes: uint64(C.whisper_model_tensor_nbytes((*C.struct_whisper_context)(ctx), C.int(i))),
+		})
+	}
+	return result
+}
+
+// Return the backend buffers of the model weights
+func (ctx *Context) Whisper_model_buffers() []Buffer {
+	n := int(C.whisper_model_n_buffers((*C.struct_whisper_context)(ctx)))
+	result := make([]Buffer, 0, n)
+	for i := 0; i < n; i++ {
+		result = append(result, Buffer{
+			Name:  C.GoString(C.whisper_model_buffer_name((*C.struct_whisper_context)(ctx), C.int(i))),
+			Bytes: uint64(C.whisper_model_buffer_size((*C.struct_whisper_context)(ctx), C.int(i))),
+		})
+	}
+	return result
+}
+
+// Return the memory of the default state on each of its backends, which is
+// empty when the context has no default state
+func (ctx *Context) Whisper_memory_usage() []BackendMemory {
+	return memoryUsage(func(memory *C.struct_whisper_backend_memory, n C.int) C.int {
+		return C.whisper_memory_usage((*C.struct_whisper_context)(ctx), memory, n)
+	})
+}
+
+// Return the sizes of the buffers of the default state, which are zero when
+// the context has no default state
+func (ctx *Context) Whisper_get_state_sizes() StateSizes {
+	return stateSizes((*C.struct_whisper_context)(ctx), nil)
+}
+
 // The probabilities for the next token
 //func (ctx *Whisper_context) Whisper_get_probs() []float32 {
 //	return (*[1 << 30]float32)(unsafe.Pointer(C.whisper_get_probs((*C.struct_whisper_context)(ctx))))[:ctx.Whisper_n_vocab()]
@@ -280,6 +465,34 @@ func (ctx *Context) Whisper_token_transcribe() Token {
 	return Token(C.whisper_token_transcribe((*C.struct_whisper_context)(ctx)))
 }
 
+// Special tokens
+func (ctx *Context) Whisper_token_nosp() Token {
+	return Token(C.whisper_token_nosp((*C.struct_whisper_context)(ctx)))
+}
+
+// Return the start of transcription sequence used to prompt the decoder:
+// <|startoftranscript|>, then the language and task tokens for multilingual
+// models, then <|notimestamps|> if timestamps are disabled. This is the same
+// sequence that whisper_full() builds, for use with Whisper_decode().
+func (ctx *Context) Whisper_sot_sequence(lang_id int, translate, no_timestamps bool) ([]Token, error) {
+	tokens := []Token{ctx.Whisper_token_sot()}
+	if ctx.Whisper_is_multilingual() != 0 {
+		if lang_id < 0 || lang_id > Whisper_lang_max_id() {
+			return nil, ErrInvalidLanguage
+		}
+		tokens = append(tokens, ctx.Whisper_token_lang(lang_id))
+		if translate {
+			tokens = append(tokens, ctx.Whisper_token_translate())
+		} else {
+			tokens = append(tokens, ctx.Whisper_token_transcribe())
+		}
+	}
+	if no_timestamps {
+		tokens = append(tokens, ctx.Whisper_token_not())
+	}
+	return tokens, nil
+}
+
 // Performance information
 func (ctx *Context) Whisper_print_timings() {
 	C.whisper_print_timings((*C.struct_whisper_context)(ctx))
@@ -290,11 +503,56 @@ func (ctx *Context) Whisper_reset_timings() {
 	C.whisper_reset_timings((*C.struct_whisper_context)(ctx))
 }
 
+// Return the timings of the state, or of the default state if state is nil,
+// as printed by Whisper_print_timings
+func (ctx *Context) Whisper_get_state_timings(state *State) Timings {
+	var t C.struct_whisper_state_timings
+	C.whisper_get_state_timings((*C.struct_whisper_context)(ctx), (*C.struct_whisper_state)(state), &t)
+	return Timings{
+		LoadUs:   int64(t.t_load_us),
+		TotalUs:  int64(t.t_total_us),
+		MelUs:    int64(t.t_mel_us),
+		SampleUs: int64(t.t_sample_us),
+		EncodeUs: int64(t.t_encode_us),
+		DecodeUs: int64(t.t_decode_us),
+		BatchdUs: int64(t.t_batchd_us),
+		PromptUs: int64(t.t_prompt_us),
+		NSample:  int(t.n_sample),
+		NEncode:  int(t.n_encode),
+		NDecode:  int(t.n_decode),
+		NBatchd:  int(t.n_batchd),
+		NPrompt:  int(t.n_prompt),
+		NFailP:   int(t.n_fail_p),
+		NFailH:   int(t.n_fail_h),
+	}
+}
+
+// Reset the timings of the state, or of the default state if state is nil
+func (ctx *Context) Whisper_reset_timings_with_state(state *State) {
+	C.whisper_reset_timings_with_state((*C.struct_whisper_context)(ctx), (*C.struct_whisper_state)(state))
+}
+
 // Print system information
 func Whisper_print_system_info() string {
 	return C.GoString(C.whisper_print_system_info())
 }
 
+// Benchmark memcpy of a 1GB array with 1 to n_threads threads, and return
+// the report of the bandwidth of each
+func Whisper_bench_memcpy_str(n_threads int) string {
+	benchMu.Lock()
+	defer benchMu.Unlock()
+	return C.GoString(C.whisper_bench_memcpy_str(C.int(n_threads)))
+}
+
+// Benchmark matrix multiplication of square matrices for each weight type
+// with n_threads threads, and return the report of the GFLOPS of each
+func Whisper_bench_ggml_mul_mat_str(n_threads int) string {
+	benchMu.Lock()
+	defer benchMu.Unlock()
+	return C.GoString(C.whisper_bench_ggml_mul_mat_str(C.int(n_threads)))
+}
+
 // Return default parameters for a strategy
 func (ctx *Context) Whisper_full_default_params(strategy SamplingStrategy) Params {
 	// Get default parameters
@@ -310,16 +568,16 @@ func (ctx *Context) Whisper_full(
 	newSegmentCallback func(int),
 	progressCallback func(int),
 ) error {
-	registerEncoderBeginCallback(ctx, encoderBeginCallback)
-	registerNewSegmentCallback(ctx, newSegmentCallback)
-	registerProgressCallback(ctx, progressCallback)
-	defer registerEncoderBeginCallback(ctx, nil)
-	defer registerNewSegmentCallback(ctx, nil)
-	defer registerProgressCallback(ctx, nil)
-	if C.whisper_full((*C.struct_whisper_context)(ctx), (C.struct_whisper_full_params)(params), (*C.float)(&samples[0]), C.int(len(samples))) == 0 {
+	registerEncoderBeginCallback(unsafe.Pointer(ctx), encoderBeginCallback)
+	registerNewSegmentCallback(unsafe.Pointer(ctx), newSegmentCallback)
+	registerProgressCallback(unsafe.Pointer(ctx), progressCallback)
+	defer registerEncoderBeginCallback(unsafe.Pointer(ctx), nil)
+	defer registerNewSegmentCallback(unsafe.Pointer(ctx), nil)
+	defer registerProgressCallback(unsafe.Pointer(ctx), nil)
+	if code := C.whisper_full((*C.struct_whisper_context)(ctx), (C.struct_whisper_full_params)(params), (*C.float)(&samples[0]), C.int(len(samples))); code == 0 {
 		return nil
 	} else {
-		return ErrConversionFailed
+		return &FullError{Code: int(code)}
 	}
 }
 
@@ -327,15 +585,15 @@ func (ctx *Context) Whisper_full(
 // It seems this approach can offer some speedup in some cases.
 // However, the transcription accuracy can be worse at the beginning and end of each chunk.
 func (ctx *Context) Whisper_full_parallel(params Params, samples []float32, processors int, encoderBeginCallback func() bool, newSegmentCallback func(int)) error {
-	registerEncoderBeginCallback(ctx, encoderBeginCallback)
-	registerNewSegmentCallback(ctx, newSegmentCallback)
-	defer registerEncoderBeginCallback(ctx, nil)
-	defer registerNewSegmentCallback(ctx, nil)
+	registerEncoderBeginCallback(unsafe.Pointer(ctx), encoderBeginCallback)
+	registerNewSegmentCallback(unsafe.Pointer(ctx), newSegmentCallback)
+	defer registerEncoderBeginCallback(unsafe.Pointer(ctx), nil)
+	defer registerNewSegmentCallback(unsafe.Pointer(ctx), nil)
 
-	if C.whisper_full_parallel((*C.struct_whisper_context)(ctx), (C.struct_whisper_full_params)(params), (*C.float)(&samples[0]), C.int(len(samples)), C.int(processors)) == 0 {
+	if code := C.whisper_full_parallel((*C.struct_whisper_context)(ctx), (C.struct_whisper_full_params)(params), (*C.float)(&samples[0]), C.int(len(samples)), C.int(processors)); code == 0 {
 		return nil
 	} else {
-		return ErrConversionFailed
+		return &FullError{Code: int(code)}
 	}
 }
 
@@ -351,6 +609,18 @@ func (ctx *Context) Whisper_full_lang_id() int {
 	return int(C.whisper_full_lang_id((*C.struct_whisper_context)(ctx)))
 }
 
+// Start of the audio window being decoded, in units of 10 ms. Call this from
+// the new segment callback to get the window which produced the segments.
+func (ctx *Context) Whisper_full_get_seek() int64 {
+	return int64(C.whisper_full_get_seek((*C.struct_whisper_context)(ctx)))
+}
+
+// Skip ahead to the offset, in units of 10 ms, instead of encoding the
+// current window. Call this from the encoder begin callback.
+func (ctx *Context) Whisper_full_set_seek(seek int64) {
+	C.whisper_full_set_seek((*C.struct_whisper_context)(ctx), C.int64_t(seek))
+}
+
 // Number of generated text segments.
 // A segment can be a few words, a sentence, or even a paragraph.
 func (ctx *Context) Whisper_full_n_segments() int {
@@ -372,6 +642,12 @@ func (ctx *Context) Whisper_full_get_segment_text(segment int) string {
 	return C.GoString(C.whisper_full_get_segment_text((*C.struct_whisper_context)(ctx), C.int(segment)))
 }
 
+// Append the text of the specified segment to dst and return the extended
+// buffer. Unlike Whisper_full_get_segment_text, no string is allocated.
+func (ctx *Context) Whisper_full_append_segment_text(dst []byte, segment int) []byte {
+	return appendCString(dst, C.whisper_full_get_segment_text((*C.struct_whisper_context)(ctx), C.int(segment)))
+}
+
 // Get number of tokens in the specified segment.
 func (ctx *Context) Whisper_full_n_tokens(segment int) int {
 	return int(C.whisper_full_n_tokens((*C.struct_whisper_context)(ctx), C.int(segment)))
@@ -382,6 +658,12 @@ func (ctx *Context) Whisper_full_get_token_text(segment int, token int) string {
 	return C.GoString(C.whisper_full_get_token_text((*C.struct_whisper_context)(ctx), C.int(segment), C.int(token)))
 }
 
+// Append the token text of the specified token index in the specified segment
+// to dst and return the extended buffer.
+func (ctx *Context) Whisper_full_append_token_text(dst []byte, segment int, token int) []byte {
+	return appendCString(dst, C.whisper_full_get_token_text((*C.struct_whisper_context)(ctx), C.int(segment), C.int(token)))
+}
+
 // Get the token of the specified token index in the specified segment.
 func (ctx *Context) Whisper_full_get_token_id(segment int, token int) Token {
 	return Token(C.whisper_full_get_token_id((*C.struct_whisper_context)(ctx), C.int(segment), C.int(token)))
@@ -398,56 +680,235 @@ func (ctx *Context) Whisper_full_get_token_p(segment int, token int) float32 {
 	return float32(C.whisper_full_get_token_p((*C.struct_whisper_context)(ctx), C.int(segment), C.int(token)))
 }
 
+// Return default parameters for voice activity detection
+func Whisper_vad_default_params() VadParams {
+	return VadParams(C.whisper_vad_default_params())
+}
+
+// Allocates all memory needed for the VAD model and loads the model from the given file.
+// Returns NULL on failure.
+func Whisper_vad_init(path string, threads int) *VadContext {
+	cPath := C.CString(path)
+	defer C.free(unsafe.Pointer(cPath))
+	params := C.whisper_vad_default_context_params()
+	params.n_threads = C.int(threads)
+	if vctx := C.whisper_vad_init_from_file_with_params(cPath, params); vctx != nil {
+		return (*VadContext)(vctx)
+	} else {
+		return nil
+	}
+}
+
+// Frees all memory allocated by the VAD model.
+func (vctx *VadContext) Whisper_vad_free() {
+	C.whisper_vad_free((*C.struct_whisper_vad_context)(vctx))
+}
+
+// Run the VAD model on the samples and return the detected speech segments.
+// The segments must be freed with Whisper_vad_free_segments().
+func (vctx *VadContext) Whisper_vad_segments_from_samples(params VadParams, samples []float32) (*VadSegments, error) {
+	if segments := C.whisper_vad_segments_from_samples((*C.struct_whisper_vad_context)(vctx), (C.struct_whisper_vad_params)(params), (*C.float)(&samples[0]), C.int(len(samples))); segments != nil {
+		return (*VadSegments)(segments), nil
+	} else {
+		return nil, ErrVadFailed
+	}
+}
+
+// Number of detected speech segments.
+func (segments *VadSegments) Whisper_vad_segments_n_segments() int {
+	return int(C.whisper_vad_segments_n_segments((*C.struct_whisper_vad_segments)(segments)))
+}
+
+// Get the start time of the specified speech segment, in centiseconds.
+func (segments *VadSegments) Whisper_vad_segments_get_segment_t0(segment int) float32 {
+	return float32(C.whisper_vad_segments_get_segment_t0((*C.struct_whisper_vad_segments)(segments), C.int(segment)))
+}
+
+// Get the end time of the specified speech segment, in centiseconds.
+func (segments *VadSegments) Whisper_vad_segments_get_segment_t1(segment int) float32 {
+	return float32(C.whisper_vad_segments_get_segment_t1((*C.struct_whisper_vad_segments)(segments), C.int(segment)))
+}
+
+// Frees all memory allocated for the speech segments.
+func (segments *VadSegments) Whisper_vad_free_segments() {
+	C.whisper_vad_free_segments((*C.struct_whisper_vad_segments)(segments))
+}
+
+// Number of speech segments detected by VAD during the last call to
+// Whisper_full(), when VAD is enabled.
+func (ctx *Context) Whisper_full_n_vad_segments() int {
+	return int(C.whisper_full_n_vad_segments((*C.struct_whisper_context)(ctx)))
+}
+
+// Get the start time of the specified VAD speech segment, in centiseconds.
+func (ctx *Context) Whisper_full_get_vad_segment_t0(segment int) int64 {
+	return int64(C.whisper_full_get_vad_segment_t0((*C.struct_whisper_context)(ctx), C.int(segment)))
+}
+
+// Get the end time of the specified VAD speech segment, in centiseconds.
+func (ctx *Context) Whisper_full_get_vad_segment_t1(segment int) int64 {
+	return int64(C.whisper_full_get_vad_segment_t1((*C.struct_whisper_context)(ctx), C.int(segment)))
+}
+
+func (err *FullError) Error() string {
+	return fmt.Sprintf("%v: code %d", ErrConversionFailed, err.Code)
+}
+
+func (err *FullError) Unwrap() error {
+	return ErrConversionFailed
+}
+
+// Return true when the encoder or decoder could not be computed, which
+// happens when the backend fails rather than because of the parameters or
+// the input. The state can be used again once it is recreated.
+func (err *FullError) Backend() bool {
+	switch err.Code {
+	case -6, -8, -9:
+		return true
+	default:
+		return false
+	}
+}
+
+// Return true when the compute buffers, which were released by
+// Whisper_release_compute, could not be allocated again. The state is not
+// in an error state, and can be used once memory is freed.
+func (err *FullError) OutOfMemory() bool {
+	return err.Code == -10
+}
+
 ///////////////////////////////////////////////////////////////////////////////
 // CALLBACKS
 
+// Callbacks are registered by the user data passed to the native library,
+// which is the context, or the state for Whisper_full_with_state
 var (
+	cbMu           sync.RWMutex
 	cbNewSegment   = make(map[unsafe.Pointer]func(int))
 	cbProgress     = make(map[unsafe.Pointer]func(int))
 	cbEncoderBegin = make(map[unsafe.Pointer]func() bool)
+
+	// Logits filters are registered by their C memory, with their callback
+	// and the number of parameters which refer to them, which are the
+	// parameters the filter was set on and their copies made with Clone
+	cbLogitsFilter = make(map[unsafe.Pointer]*logitsFilterRef)
+
+	// Load progress callbacks are registered by memory allocated for them,
+	// which the context parameters and their copies refer to
+	cbLoadProgress = make(map[unsafe.Pointer]LoadProgressCallback)
 )
 
-func registerNewSegmentCallback(ctx *Context, fn func(int)) {
+func registerNewSegmentCallback(key unsafe.Pointer, fn func(int)) {
+	cbMu.Lock()
+	defer cbMu.Unlock()
+	if fn == nil {
+		delete(cbNewSegment, key)
+	} else {
+		cbNewSegment[key] = fn
+	}
+}
+
+func registerProgressCallback(key unsafe.Pointer, fn func(int)) {
+	cbMu.Lock()
+	defer cbMu.Unlock()
 	if fn == nil {
-		delete(cbNewSegment, unsafe.Pointer(ctx))
+		delete(cbProgress, key)
 	} else {
-		cbNewSegment[unsafe.Pointer(ctx)] = fn
+		cbProgress[key] = fn
 	}
 }
 
-func registerProgressCallback(ctx *Context, fn func(int)) {
+func registerEncoderBeginCallback(key unsafe.Pointer, fn func() bool) {
+	cbMu.Lock()
+	defer cbMu.Unlock()
 	if fn == nil {
-		delete(cbProgress, unsafe.Pointer(ctx))
+		delete(cbEncoderBegin, key)
 	} else {
-		cbProgress[unsafe.Pointer(ctx)] = fn
+		cbEncoderBegin[key] = fn
+	}
+}
+
+func registerLogitsFilter(key unsafe.Pointer, fn LogitsFilterCallback) {
+	cbMu.Lock()
+	defer cbMu.Unlock()
+	cbLogitsFilter[key] = &logitsFilterRef{fn: fn, refs: 1}
+}
+
+func retainLogitsFilter(key unsafe.Pointer) {
+	cbMu.Lock()
+	defer cbMu.Unlock()
+	if ref, exists := cbLogitsFilter[key]; exists {
+		ref.refs++
+	}
+}
+
+// Release a reference to the filter, and return true when it was the last,
+// in which case the filter is unregistered and its memory can be freed
+func releaseLogitsFilter(key unsafe.Pointer) bool {
+	cbMu.Lock()
+	defer cbMu.Unlock()
+	ref, exists := cbLogitsFilter[key]
+	if !exists {
+		return false
+	}
+	if ref.refs--; ref.refs > 0 {
+		return false
+	}
+	delete(cbLogitsFilter, key)
+	return true
+}
+
+func lookupLogitsFilterCallback(key unsafe.Pointer) LogitsFilterCallback {
+	cbMu.RLock()
+	defer cbMu.RUnlock()
+	if ref, exists := cbLogitsFilter[key]; exists {
+		return ref.fn
 	}
+	return nil
 }
 
-func registerEncoderBeginCallback(ctx *Context, fn func() bool) {
+func registerLoadProgressCallback(key unsafe.Pointer, fn LoadProgressCallback) {
+	cbMu.Lock()
+	defer cbMu.Unlock()
 	if fn == nil {
-		delete(cbEncoderBegin, unsafe.Pointer(ctx))
+		delete(cbLoadProgress, key)
 	} else {
-		cbEncoderBegin[unsafe.Pointer(ctx)] = fn
+		cbLoadProgress[key] = fn
 	}
 }
 
+func lookupLoadProgressCallback(key unsafe.Pointer) LoadProgressCallback {
+	cbMu.RLock()
+	defer cbMu.RUnlock()
+	return cbLoadProgress[key]
+}
+
 //export callNewSegment
 func callNewSegment(user_data unsafe.Pointer, new C.int) {
-	if fn, ok := cbNewSegment[user_data]; ok {
+	cbMu.RLock()
+	fn, ok := cbNewSegment[user_data]
+	cbMu.RUnlock()
+	if ok {
 		fn(int(new))
 	}
 }
 
 //export callProgress
 func callProgress(user_data unsafe.Pointer, progress C.int) {
-	if fn, ok := cbProgress[user_data]; ok {
+	cbMu.RLock()
+	fn, ok := cbProgress[user_data]
+	cbMu.RUnlock()
+	if ok {
 		fn(int(progress))
 	}
 }
 
 //export callEncoderBegin
 func callEncoderBegin(user_data unsafe.Pointer) C.bool {
-	if fn, ok := cbEncoderBegin[user_data]; ok {
+	cbMu.RLock()
+	fn, ok := cbEncoderBegin[user_data]
+	cbMu.RUnlock()
+	if ok {
 		if fn() {
 			return C.bool(true)
 		} else {
@@ -457,6 +918,28 @@ func callEncoderBegin(user_data unsafe.Pointer) C.bool {
 	return true
 }
 
+//export callLogitsFilter
+func callLogitsFilter(user_data unsafe.Pointer, tokens *C.whisper_token_data, n_tokens C.int, logits *C.float, n_vocab C.int) {
+	if fn := lookupLogitsFilterCallback(user_data); fn != nil {
+		fn(unsafe.Slice((*TokenData)(unsafe.Pointer(tokens)), int(n_tokens)), unsafe.Slice((*float32)(unsafe.Pointer(logits)), int(n_vocab)))
+	}
+}
+
+//export callLoadProgress
+func callLoadProgress(user_data unsafe.Pointer, n_bytes, n_bytes_total C.size_t, n_tensors, n_tensors_total C.int) C.bool {
+	if fn := lookupLoadProgressCallback(user_data); fn != nil {
+		return C.bool(fn(uint64(n_bytes), uint64(n_bytes_total), int(n_tensors), int(n_tensors_total)))
+	}
+	return true
+}
+
+func appendCString(dst []byte, str *C.char) []byte {
+	if str == nil {
+		return dst
+	}
+	return append(dst, unsafe.Slice((*byte)(unsafe.Pointer(str)), C.strlen(str))...)
+}
+
 func (t TokenData) T0() int64 {
 	return int64(t.t0)
 }
@@ -468,3 +951,19 @@ func (t TokenData) T1() int64 {
 func (t TokenData) Id() Token {
 	return Token(t.id)
 }
+
+// Timestamp of the token from DTW, in units of 10 ms, or -1 if token-level
+// timestamps with DTW are not enabled
+func (t TokenData) TDTW() int64 {
+	return int64(t.t_dtw)
+}
+
+// Probability of the timestamp token at the position of the token
+func (t TokenData) Pt() float32 {
+	return float32(t.pt)
+}
+
+// Sum of the probabilities of all timestamp tokens at the position
+func (t TokenData) PtSum() float32 {
+	return float32(t.ptsum)
+}
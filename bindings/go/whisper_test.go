@@ -1,6 +1,7 @@
 package whisper_test
 
 import (
+	"math"
 	"os"
 	"runtime"
 	"testing"
@@ -111,3 +112,270 @@ func Test_Whisper_003(t *testing.T) {
 		t.Logf("%s: %f", whisper.Whisper_lang_str(i), p)
 	}
 }
+
+func Test_Whisper_004(t *testing.T) {
+	assert := assert.New(t)
+	if _, err := os.Stat(ModelPath); os.IsNotExist(err) {
+		t.Skip("Skipping test, model not found:", ModelPath)
+	}
+
+	ctx := whisper.Whisper_init(ModelPath)
+	assert.NotNil(ctx)
+	defer ctx.Whisper_free()
+
+	// English-only models have no language or task tokens
+	tokens, err := ctx.Whisper_sot_sequence(0, false, false)
+	assert.NoError(err)
+	assert.Equal([]whisper.Token{ctx.Whisper_token_sot()}, tokens)
+
+	tokens, err = ctx.Whisper_sot_sequence(0, false, true)
+	assert.NoError(err)
+	assert.Equal([]whisper.Token{ctx.Whisper_token_sot(), ctx.Whisper_token_not()}, tokens)
+}
+
+func Test_Whisper_Params_Thold(t *testing.T) {
+	assert := assert.New(t)
+	if _, err := os.Stat(ModelPath); os.IsNotExist(err) {
+		t.Skip("Skipping test, model not found:", ModelPath)
+	}
+
+	ctx := whisper.Whisper_init(ModelPath)
+	assert.NotNil(ctx)
+	defer ctx.Whisper_free()
+
+	params := ctx.Whisper_full_default_params(whisper.SAMPLING_GREEDY)
+	params.SetLogprobThold(-0.5)
+	params.SetNoSpeechThold(0.8)
+	assert.Equal(float32(-0.5), params.LogprobThold())
+	assert.Equal(float32(0.8), params.NoSpeechThold())
+}
+
+func Test_Whisper_Params_PromptTokens(t *testing.T) {
+	assert := assert.New(t)
+	if _, err := os.Stat(ModelPath); os.IsNotExist(err) {
+		t.Skip("Skipping test, model not found:", ModelPath)
+	}
+
+	ctx := whisper.Whisper_init(ModelPath)
+	assert.NotNil(ctx)
+	defer ctx.Whisper_free()
+
+	params := ctx.Whisper_full_default_params(whisper.SAMPLING_GREEDY)
+	assert.Nil(params.InitialPromptTokens())
+
+	tokens := make([]whisper.Token, 16)
+	n, err := ctx.Whisper_tokenize("Hello, world", tokens)
+	assert.NoError(err)
+	params.SetInitialPromptTokens(tokens[:n])
+	assert.Equal(tokens[:n], params.InitialPromptTokens())
+
+	// Replacing the tokens does not change those of a clone
+	clone := params.Clone()
+	params.SetInitialPromptTokens(tokens[:1])
+	assert.Equal(tokens[:1], params.InitialPromptTokens())
+	assert.Equal(tokens[:n], clone.InitialPromptTokens())
+	clone.Free()
+
+	params.SetInitialPromptTokens(nil)
+	assert.Nil(params.InitialPromptTokens())
+}
+
+func Test_Whisper_Params_LogitBias(t *testing.T) {
+	assert := assert.New(t)
+	if _, err := os.Stat(ModelPath); os.IsNotExist(err) {
+		t.Skip("Skipping test, model not found:", ModelPath)
+	}
+
+	ctx := whisper.Whisper_init(ModelPath)
+	assert.NotNil(ctx)
+	defer ctx.Whisper_free()
+
+	params := ctx.Whisper_full_default_params(whisper.SAMPLING_GREEDY)
+	assert.Nil(params.LogitBias())
+
+	bias := map[whisper.Token]float32{10: 2.5, 20: float32(math.Inf(-1))}
+	params.SetLogitBias(bias)
+	assert.Equal(bias, params.LogitBias())
+	clone := params.Clone()
+	assert.Equal(bias, clone.LogitBias())
+	assert.Contains(params.String(), "logit_bias=")
+
+	// The bias is kept when a callback is set
+	var calls int
+	params.SetLogitsFilterCallback(func(tokens []whisper.TokenData, logits []float32) { calls++ })
+	assert.Equal(bias, params.LogitBias())
+	assert.NotNil(params.LogitsFilterCallback())
+	assert.Contains(params.String(), "logits_filter_callback")
+
+	params.SetLogitBias(nil)
+	assert.Nil(params.LogitBias())
+	assert.NotNil(params.LogitsFilterCallback())
+	params.SetLogitsFilterCallback(nil)
+	assert.Nil(params.LogitsFilterCallback())
+}
+
+func Test_Whisper_Params_LogitsFilterFree(t *testing.T) {
+	assert := assert.New(t)
+	if _, err := os.Stat(ModelPath); os.IsNotExist(err) {
+		t.Skip("Skipping test, model not found:", ModelPath)
+	}
+
+	ctx := whisper.Whisper_init(ModelPath)
+	assert.NotNil(ctx)
+	defer ctx.Whisper_free()
+
+	// The callback is referenced by the parameters and their clones, and is
+	// released once it is replaced in both
+	params := ctx.Whisper_full_default_params(whisper.SAMPLING_GREEDY)
+	collected := make(chan struct{})
+	func() {
+		calls := new(int)
+		runtime.SetFinalizer(calls, func(*int) { close(collected) })
+		params.SetLogitsFilterCallback(func(tokens []whisper.TokenData, logits []float32) { *calls++ })
+	}()
+	params.SetLogitBias(map[whisper.Token]float32{10: 1})
+	clone := params.Clone()
+	params.SetLogitsFilterCallback(nil)
+	assert.NotNil(clone.LogitsFilterCallback())
+	assert.Equal(map[whisper.Token]float32{10: 1}, clone.LogitBias())
+	clone.Free()
+	assert.Nil(clone.LogitsFilterCallback())
+	for i := 0; i < 10; i++ {
+		runtime.GC()
+		select {
+		case <-collected:
+			return
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+	t.Error("the logits filter callback was not released")
+}
+
+func Test_Whisper_Params_Clone(t *testing.T) {
+	assert := assert.New(t)
+	if _, err := os.Stat(ModelPath); os.IsNotExist(err) {
+		t.Skip("Skipping test, model not found:", ModelPath)
+	}
+
+	ctx := whisper.Whisper_init(ModelPath)
+	assert.NotNil(ctx)
+	defer ctx.Whisper_free()
+
+	params := ctx.Whisper_full_default_params(whisper.SAMPLING_GREEDY)
+	params.SetInitialPrompt("first")
+	params.SetInitialPromptTokens([]whisper.Token{1, 2, 3})
+	params.SetTemperatures([]float32{0, 0.2, 0.4, 0.8})
+	params.SetBeamSize(2)
+
+	clone := params.Clone()
+	assert.Equal(params.String(), clone.String())
+	assert.Equal(params.InitialPromptTokens(), clone.InitialPromptTokens())
+	assert.Equal([]float32{0, 0.2, 0.4, 0.8}, clone.Temperatures())
+
+	clone.SetInitialPrompt("second")
+	clone.SetInitialPromptTokens(nil)
+	clone.SetBeamSize(5)
+	assert.Contains(params.String(), "initial_prompt=first")
+	assert.Contains(params.String(), "beam_size=2")
+	assert.Equal([]whisper.Token{1, 2, 3}, params.InitialPromptTokens())
+	assert.Contains(clone.String(), "initial_prompt=second")
+
+	// Replacing the expression of the parameters frees only their own copy
+	params.SetSuppressRegex("^[0-9]+$")
+	clone.Free()
+	clone = params.Clone()
+	params.SetSuppressRegex("^[a-z]+$")
+	assert.Contains(clone.String(), "suppress_regex=^[0-9]+$")
+	assert.Contains(params.String(), "suppress_regex=^[a-z]+$")
+	params.SetSuppressRegex("")
+	assert.NotContains(params.String(), "suppress_regex")
+
+	// Replacing the schedule of the parameters frees only their own copy
+	params.SetTemperatures([]float32{0, 0.5})
+	assert.Equal([]float32{0, 0.2, 0.4, 0.8}, clone.Temperatures())
+	clone.SetTemperatures([]float32{0.1})
+	assert.Equal([]float32{0, 0.5}, params.Temperatures())
+	clone.Free()
+	params.Free()
+	assert.Nil(params.Temperatures())
+}
+
+func Test_Whisper_ResetState(t *testing.T) {
+	assert := assert.New(t)
+	if _, err := os.Stat(ModelPath); os.IsNotExist(err) {
+		t.Skip("Skipping test, model not found:", ModelPath)
+	}
+
+	ctx := whisper.Whisper_init(ModelPath)
+	assert.NotNil(ctx)
+	defer ctx.Whisper_free()
+
+	// The context can be used with its new state
+	assert.NoError(ctx.Whisper_reset_state())
+	assert.NoError(ctx.Whisper_reacquire_compute())
+
+	// Failures of the encoder and decoder are reported as backend errors
+	var err error = &whisper.FullError{Code: -6}
+	assert.ErrorIs(err, whisper.ErrConversionFailed)
+	assert.True(err.(*whisper.FullError).Backend())
+	assert.False((&whisper.FullError{Code: -5}).Backend())
+
+	// Failing to allocate the released compute buffers is not
+	assert.True((&whisper.FullError{Code: -10}).OutOfMemory())
+	assert.False((&whisper.FullError{Code: -10}).Backend())
+	assert.False((&whisper.FullError{Code: -6}).OutOfMemory())
+}
+
+func Test_Whisper_get_state_sizes(t *testing.T) {
+	assert := assert.New(t)
+	if _, err := os.Stat(ModelPath); os.IsNotExist(err) {
+		t.Skip("Skipping test, model not found:", ModelPath)
+	}
+
+	ctx := whisper.Whisper_init(ModelPath)
+	assert.NotNil(ctx)
+	defer ctx.Whisper_free()
+	state, err := ctx.Whisper_init_state()
+	assert.NoError(err)
+	defer state.Whisper_free_state()
+
+	// A new state is the same size as the default state
+	sizes := state.Whisper_get_state_sizes()
+	assert.Equal(ctx.Whisper_get_state_sizes(), sizes)
+	assert.Positive(sizes.KVSelf)
+	assert.Positive(sizes.KVCross)
+	assert.Positive(sizes.Decode)
+
+	// The compute buffers are zero once released, and the KV caches remain
+	state.Whisper_release_compute_with_state()
+	released := state.Whisper_get_state_sizes()
+	assert.Equal(sizes.KVSelf, released.KVSelf)
+	assert.Zero(released.Conv + released.Encode + released.Cross + released.Decode)
+}
+
+func Test_Ggml_quantize_chunk(t *testing.T) {
+	assert := assert.New(t)
+
+	// Rows of 64 elements are quantized to two blocks of q8_0 each
+	qtype := whisper.Ggml_ftype_to_ggml_type(7)
+	assert.Equal("q8_0", qtype.String())
+	assert.True(qtype.IsQuantized())
+	assert.False(whisper.TENSOR_TYPE_F16.IsQuantized())
+	src := make([]float32, 2*64)
+	for i := range src {
+		src[i] = float32(math.Sin(float64(i)))
+	}
+	dst, err := whisper.Ggml_quantize_chunk(qtype, src, 64)
+	assert.NoError(err)
+	assert.Len(dst, 2*qtype.RowSize(64))
+
+	// Rows which are not a multiple of the block size are rejected
+	_, err = whisper.Ggml_quantize_chunk(qtype, src, 48)
+	assert.ErrorIs(err, whisper.ErrInvalidParams)
+
+	// Half precision floats are converted
+	half := []uint16{0x3c00, 0xc000}
+	f32 := make([]float32, 2)
+	whisper.Ggml_fp16_to_fp32_row(half, f32)
+	assert.Equal([]float32{1, -2}, f32)
+}
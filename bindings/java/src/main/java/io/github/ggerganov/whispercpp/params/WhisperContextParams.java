@@ -40,6 +40,12 @@ public class WhisperContextParams extends Structure {
     /** DTW memory size (internal use) */
     public NativeLong dtw_mem_size;
 
+    /** Callback for the progress of loading the model (not used by the Java binding) */
+    public Pointer load_progress_callback;
+
+    /** User data for the load progress callback */
+    public Pointer load_progress_callback_user_data;
+
     /** Use GPU for inference */
     public void useGpu(boolean enable) {
         use_gpu = enable ? CBool.TRUE : CBool.FALSE;
@@ -70,7 +76,9 @@ public class WhisperContextParams extends Structure {
             "dtw_aheads_preset",
             "dtw_n_top",
             "dtw_aheads",
-            "dtw_mem_size"
+            "dtw_mem_size",
+            "load_progress_callback",
+            "load_progress_callback_user_data"
         );
     }
 
@@ -113,6 +113,11 @@ extern "C" {
         const whisper_ahead * heads;
     } whisper_aheads;
 
+    // Progress callback for loading the model
+    // Called after each tensor is loaded, with the bytes and tensors loaded so far and in total
+    // If it returns false, loading is cancelled and fails
+    typedef bool (*whisper_load_progress_callback)(size_t n_bytes, size_t n_bytes_total, int n_tensors, int n_tensors_total, void * user_data);
+
     struct whisper_context_params {
         bool  use_gpu;
         bool  flash_attn;
@@ -126,6 +131,10 @@ extern "C" {
         struct whisper_aheads dtw_aheads;
 
         size_t dtw_mem_size; // TODO: remove
+
+        // called while the model is loaded
+        whisper_load_progress_callback load_progress_callback;
+        void * load_progress_callback_user_data;
     };
 
     typedef struct whisper_token_data {
@@ -264,12 +273,48 @@ extern "C" {
                     const char * device,
                     const char * cache_dir);
 
+    // Given a context, set the Core ML encoder model used by the states allocated afterwards.
+    // Use this with whisper_init_from_file_with_params_no_state(), and then allocate the default state
+    // with whisper_reset_state(), or other states with whisper_init_state().
+    // model_path: Optional path to a compiled Core ML model (.mlmodelc), or to a model (.mlmodel or
+    //                      .mlpackage) which is compiled when it is loaded. If set to nullptr, the path
+    //                      is generated from the ggml model path. For example, if 'path_model' was
+    //                      "/path/to/ggml-base.en.bin", then the Core ML model path will be assumed to be
+    //                      "/path/to/ggml-base.en-encoder.mlmodelc".
+    // cache_dir: Optional directory where compiled models are kept, so that a model is only
+    //                     compiled again when it changes. Set to nullptr if not used.
+    // Returns 0 on success. If Core ML is not enabled in build, this simply returns 1.
+    WHISPER_API int whisper_ctx_set_coreml_encoder(
+        struct whisper_context * ctx,
+                    const char * model_path,
+                    const char * cache_dir);
+
+    // Return true if the encoder of the default state, or of the given state, runs with Core ML
+    WHISPER_API bool whisper_uses_coreml           (struct whisper_context * ctx);
+    WHISPER_API bool whisper_uses_coreml_with_state(struct whisper_state * state);
+
     // Frees all allocated memory
     WHISPER_API void whisper_free      (struct whisper_context * ctx);
     WHISPER_API void whisper_free_state(struct whisper_state * state);
     WHISPER_API void whisper_free_params(struct whisper_full_params * params);
     WHISPER_API void whisper_free_context_params(struct whisper_context_params * params);
 
+    // Free the compute buffers of a state, keeping the model weights, the KV caches and the results
+    // of the last call to whisper_full(). This gives the memory back to the device between requests.
+    // The buffers are allocated again by whisper_reacquire_compute(), or otherwise on the next encode
+    // or decode. whisper_reacquire_compute() returns 0 on success, or -1 if they cannot be allocated.
+    // whisper_full() returns -10 if they cannot be allocated on the next encode or decode.
+    WHISPER_API void whisper_release_compute(struct whisper_context * ctx);
+    WHISPER_API void whisper_release_compute_with_state(struct whisper_state * state);
+    WHISPER_API int  whisper_reacquire_compute(struct whisper_context * ctx);
+    WHISPER_API int  whisper_reacquire_compute_with_state(struct whisper_context * ctx, struct whisper_state * state);
+
+    // Replace the default state of the context with a newly allocated one, which recreates its backends.
+    // Use this to recover after whisper_full() fails because a backend is in an error state, for example
+    // when a GPU command buffer fails. The results of the previous state are lost.
+    // Returns 0 on success, or -1 if the new state cannot be allocated, in which case the old state is kept.
+    WHISPER_API int whisper_reset_state(struct whisper_context * ctx);
+
     // Convert RAW PCM audio to log mel spectrogram.
     // The resulting spectrogram is stored inside the default state of the provided whisper context.
     // Returns 0 on success
@@ -408,6 +453,31 @@ extern "C" {
     WHISPER_API int whisper_model_ftype        (struct whisper_context * ctx);
     WHISPER_API int whisper_model_type         (struct whisper_context * ctx);
 
+    // Weight tensors of the model, in order of their name
+    // Returns NULL, GGML_TYPE_COUNT or 0 if i is out of range
+    WHISPER_API int            whisper_model_n_tensors    (struct whisper_context * ctx);
+    WHISPER_API const char *   whisper_model_tensor_name  (struct whisper_context * ctx, int i);
+    WHISPER_API enum ggml_type whisper_model_tensor_type  (struct whisper_context * ctx, int i);
+    WHISPER_API size_t         whisper_model_tensor_nbytes(struct whisper_context * ctx, int i);
+
+    // Backend buffers of the model weights, with the name of their buffer type, such as "CPU" or "CUDA0"
+    // Returns NULL or 0 if i is out of range
+    WHISPER_API int          whisper_model_n_buffers  (struct whisper_context * ctx);
+    WHISPER_API const char * whisper_model_buffer_name(struct whisper_context * ctx, int i);
+    WHISPER_API size_t       whisper_model_buffer_size(struct whisper_context * ctx, int i);
+
+    // Memory of the KV caches and compute buffers of a state on one of its backends, in bytes
+    struct whisper_backend_memory {
+        const char * name;     // name of the buffer type of the backend, such as "CPU" or "CUDA0"
+        size_t       kv_cache; // self-attention, cross-attention and padding caches
+        size_t       compute;  // compute buffers, which are 0 while they are released
+    };
+
+    // Memory of the default state, or of the given state, on each of its backends
+    // Returns the number of backends, of which at most n are written to memory
+    WHISPER_API int whisper_memory_usage           (struct whisper_context * ctx,   struct whisper_backend_memory * memory, int n);
+    WHISPER_API int whisper_memory_usage_with_state(struct whisper_state   * state, struct whisper_backend_memory * memory, int n);
+
     // Token logits obtained from the last call to whisper_decode()
     // The logits for the last token are stored in the last row
     // Rows: n_tokens
@@ -446,6 +516,49 @@ extern "C" {
     WHISPER_API void whisper_print_timings(struct whisper_context * ctx);
     WHISPER_API void whisper_reset_timings(struct whisper_context * ctx);
 
+    // Totals of the timings of a state since the timings were reset, as printed by whisper_print_timings
+    struct whisper_state_timings {
+        int64_t t_load_us;  // time to load the model
+        int64_t t_total_us; // wall clock time since the context was created or the timings were reset
+        int64_t t_mel_us;
+        int64_t t_sample_us;
+        int64_t t_encode_us;
+        int64_t t_decode_us;
+        int64_t t_batchd_us;
+        int64_t t_prompt_us;
+
+        int32_t n_sample;
+        int32_t n_encode;
+        int32_t n_decode;
+        int32_t n_batchd;
+        int32_t n_prompt;
+
+        int32_t n_fail_p; // fallbacks due to the average log probability
+        int32_t n_fail_h; // fallbacks due to the entropy
+    };
+
+    // Get the timings of the state, or of the default state if state is NULL, which are zero when there is no state
+    WHISPER_API void whisper_get_state_timings(struct whisper_context * ctx, struct whisper_state * state, struct whisper_state_timings * timings);
+
+    // Sizes of the buffers of a state in bytes, as printed by whisper_init_state
+    struct whisper_state_sizes {
+        size_t kv_self;  // self-attention cache, which grows with the number of decoders
+        size_t kv_cross; // cross-attention cache
+        size_t kv_pad;   // padding cache for flash attention
+
+        // compute buffers, which are 0 while they are released
+        size_t compute_conv;
+        size_t compute_encode;
+        size_t compute_cross;
+        size_t compute_decode;
+    };
+
+    // Get the sizes of the buffers of the state, or of the default state if state is NULL, which are zero when there is no state
+    WHISPER_API void whisper_get_state_sizes(struct whisper_context * ctx, struct whisper_state * state, struct whisper_state_sizes * sizes);
+
+    // Reset the timings of the state, or of the default state if state is NULL
+    WHISPER_API void whisper_reset_timings_with_state(struct whisper_context * ctx, struct whisper_state * state);
+
     // Print system information
     WHISPER_API const char * whisper_print_system_info(void);
 
@@ -588,6 +701,14 @@ extern "C" {
         const char * vad_model_path;              // Path to VAD model
 
         whisper_vad_params vad_params;
+
+        // explicit temperature fallback schedule, used instead of temperature and temperature_inc when set
+        const float * temperatures;
+        int           n_temperatures;
+
+        // max segment duration in ms, split on the nearest word boundary when used with split_on_word
+        // (requires token_timestamps, 0 = no limit)
+        int max_duration_ms;
     };
 
     // NOTE: this function allocates memory, and it is the responsibility of the caller to free the pointer - see whisper_free_context_params & whisper_free_params()
@@ -636,6 +757,16 @@ extern "C" {
     // Language id associated with the provided state
     WHISPER_API int whisper_full_lang_id_from_state(struct whisper_state * state);
 
+    // Start of the audio window being decoded, in units of 10 ms from the start of the samples
+    // This can be called from the new segment callback to get the window which produced the new segments
+    WHISPER_API int64_t whisper_full_get_seek           (struct whisper_context * ctx);
+    WHISPER_API int64_t whisper_full_get_seek_from_state(struct whisper_state * state);
+
+    // Skip ahead to the given offset, in units of 10 ms from the start of the samples, instead of encoding the current window
+    // This can be called from the encoder begin callback, for example to jump over music. Offsets before the current window are ignored
+    WHISPER_API void whisper_full_set_seek           (struct whisper_context * ctx, int64_t seek);
+    WHISPER_API void whisper_full_set_seek_with_state(struct whisper_state * state, int64_t seek);
+
     // Get the start and end time of the specified segment
     WHISPER_API int64_t whisper_full_get_segment_t0           (struct whisper_context * ctx, int i_segment);
     WHISPER_API int64_t whisper_full_get_segment_t0_from_state(struct whisper_state * state, int i_segment);
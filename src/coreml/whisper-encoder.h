@@ -12,6 +12,10 @@ extern "C" {
 struct whisper_coreml_context;
 
 struct whisper_coreml_context * whisper_coreml_init(const char * path_model);
+
+// Load a compiled model (.mlmodelc), or compile a model (.mlmodel or .mlpackage) first. When
+// path_cache is not NULL, the compiled model is kept there and reused until the model changes.
+struct whisper_coreml_context * whisper_coreml_init_with_cache(const char * path_model, const char * path_cache);
 void whisper_coreml_free(struct whisper_coreml_context * ctx);
 
 void whisper_coreml_encode(
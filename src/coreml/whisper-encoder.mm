@@ -41,6 +41,53 @@ struct whisper_coreml_context * whisper_coreml_init(const char * path_model) {
     return ctx;
 }
 
+// compile the model, or return the compiled model in the cache if it is not older than the model
+static NSURL * whisper_coreml_compile(NSURL * url_model, const char * path_cache) {
+    NSFileManager * fm = [NSFileManager defaultManager];
+
+    NSURL * url_cache  = nil;
+    NSURL * url_cached = nil;
+    if (path_cache != NULL) {
+        url_cache  = [NSURL fileURLWithPath:[[NSString alloc] initWithUTF8String:path_cache] isDirectory:YES];
+        url_cached = [url_cache URLByAppendingPathComponent:[[[url_model lastPathComponent] stringByDeletingPathExtension] stringByAppendingPathExtension:@"mlmodelc"]];
+
+        if ([fm fileExistsAtPath:url_cached.path]) {
+            NSDate * t_model  = [[fm attributesOfItemAtPath:url_model.path  error:nil] fileModificationDate];
+            NSDate * t_cached = [[fm attributesOfItemAtPath:url_cached.path error:nil] fileModificationDate];
+            if (t_model != nil && t_cached != nil && [t_cached compare:t_model] != NSOrderedAscending) {
+                return url_cached;
+            }
+            [fm removeItemAtURL:url_cached error:nil];
+        }
+    }
+
+    NSURL * url_compiled = [MLModel compileModelAtURL:url_model error:nil];
+    if (url_compiled == nil || url_cached == nil) {
+        return url_compiled;
+    }
+
+    // keep the compiled model, which is otherwise in a temporary directory
+    [fm createDirectoryAtURL:url_cache withIntermediateDirectories:YES attributes:nil error:nil];
+    if (![fm moveItemAtURL:url_compiled toURL:url_cached error:nil]) {
+        return url_compiled;
+    }
+
+    return url_cached;
+}
+
+struct whisper_coreml_context * whisper_coreml_init_with_cache(const char * path_model, const char * path_cache) {
+    NSURL * url_model = [NSURL fileURLWithPath:[[NSString alloc] initWithUTF8String:path_model]];
+
+    if (![[url_model pathExtension] isEqualToString:@"mlmodelc"]) {
+        url_model = whisper_coreml_compile(url_model, path_cache);
+        if (url_model == nil) {
+            return NULL;
+        }
+    }
+
+    return whisper_coreml_init([url_model.path UTF8String]);
+}
+
 void whisper_coreml_free(struct whisper_coreml_context * ctx) {
     CFRelease(ctx->data);
     delete ctx;
@@ -903,6 +903,11 @@ struct whisper_state {
     whisper_openvino_context * ctx_openvino = nullptr;
 #endif
 
+    // start of the audio window being decoded, and the offset to skip to
+    // requested by the encoder begin callback
+    int64_t seek      = 0;
+    int64_t seek_skip = -1;
+
     // [EXPERIMENTAL] token-level timestamps data
     int64_t t_beg  = 0;
     int64_t t_last = 0;
@@ -949,6 +954,9 @@ struct whisper_context {
     whisper_state * state = nullptr;
 
     std::string path_model; // populated by whisper_init_from_file_with_params()
+
+    std::string path_coreml;       // set by whisper_ctx_set_coreml_encoder(), or derived from path_model
+    std::string path_coreml_cache; // directory of compiled Core ML models, if set
 };
 
 struct whisper_global {
@@ -1866,6 +1874,14 @@ static bool whisper_model_load(struct whisper_model_loader * loader, whisper_con
 
         std::vector<char> read_buf;
 
+        // the size of all the tensors, for reporting progress
+        size_t total_size_all = 0;
+        if (wctx.params.load_progress_callback) {
+            for (const auto & kv : model.tensors) {
+                total_size_all += ggml_nbytes(kv.second);
+            }
+        }
+
         while (true) {
             int32_t n_dims;
             int32_t length;
@@ -1934,6 +1950,13 @@ static bool whisper_model_load(struct whisper_model_loader * loader, whisper_con
 
             total_size += ggml_nbytes(tensor);
             model.n_loaded++;
+
+            if (wctx.params.load_progress_callback) {
+                if (!wctx.params.load_progress_callback(total_size, total_size_all, model.n_loaded, (int) model.tensors.size(), wctx.params.load_progress_callback_user_data)) {
+                    WHISPER_LOG_ERROR("%s: load_progress_callback returned false - aborting\n", __func__);
+                    return false;
+                }
+            }
         }
 
         WHISPER_LOG_INFO("%s: model size    = %7.2f MB\n", __func__, total_size/1e6);
@@ -2364,6 +2387,12 @@ static bool whisper_encode_internal(
                    void * abort_callback_data) {
     const int64_t t_start_us = ggml_time_us();
 
+    // allocate the compute buffers again if they have been released
+    if (whisper_reacquire_compute_with_state(&wctx, &wstate) != 0) {
+        WHISPER_LOG_ERROR("%s: failed to allocate the compute buffers\n", __func__);
+        return false;
+    }
+
     // conv
     {
         auto & sched = wstate.sched_conv.sched;
@@ -2855,6 +2884,12 @@ static bool whisper_decode_internal(
                    void * abort_callback_data) {
     const int64_t t_start_us = ggml_time_us();
 
+    // allocate the compute buffers again if they have been released
+    if (whisper_reacquire_compute_with_state(&wctx, &wstate) != 0) {
+        WHISPER_LOG_ERROR("%s: failed to allocate the compute buffers\n", __func__);
+        return false;
+    }
+
     const auto & model   = wctx.model;
     const auto & hparams = model.hparams;
 
@@ -3371,6 +3406,85 @@ static std::string whisper_openvino_get_path_cache(std::string path_bin) {
 }
 #endif
 
+// allocate the compute buffers of the state
+static bool whisper_state_alloc_compute(struct whisper_context * ctx, struct whisper_state * state) {
+    // conv allocator
+    {
+        bool ok = whisper_sched_graph_init(state->sched_conv, state->backends,
+                [&]() {
+                    return whisper_build_graph_conv(*ctx, *state);
+                });
+
+        if (!ok) {
+            WHISPER_LOG_ERROR("%s: failed to init conv allocator\n", __func__);
+            return false;
+        }
+
+        WHISPER_LOG_INFO("%s: compute buffer (conv)   = %7.2f MB\n", __func__, whisper_sched_size(state->sched_conv) / 1e6);
+    }
+
+    // encoder allocator
+    if (!whisper_encode_external(*state)) {
+        bool ok = whisper_sched_graph_init(state->sched_encode, state->backends,
+                [&]() {
+                    return whisper_build_graph_encoder(*ctx, *state);
+                });
+
+        if (!ok) {
+            WHISPER_LOG_ERROR("%s: failed to init encoder allocator\n", __func__);
+            return false;
+        }
+
+        WHISPER_LOG_INFO("%s: compute buffer (encode) = %7.2f MB\n", __func__, whisper_sched_size(state->sched_encode) / 1e6);
+    }
+
+    // cross allocator
+    {
+        bool ok = whisper_sched_graph_init(state->sched_cross, state->backends,
+                [&]() {
+                    return whisper_build_graph_cross(*ctx, *state);
+                });
+
+        if (!ok) {
+            WHISPER_LOG_ERROR("%s: failed to init cross allocator\n", __func__);
+            return false;
+        }
+
+        WHISPER_LOG_INFO("%s: compute buffer (cross)  = %7.2f MB\n", __func__, whisper_sched_size(state->sched_cross) / 1e6);
+    }
+
+    // decoder allocator
+    {
+        const auto & hparams = ctx->model.hparams;
+
+        // TODO: make sure this is the worst-case scenario
+        const int n_tokens = hparams.n_text_ctx;
+        const int n_past   = 0;
+
+        // reserve with a scratch batch, since the compute buffers can be allocated again by
+        // whisper_decode_internal() after the batch of the state has been prepared for decoding
+        whisper_batch batch = whisper_batch_init(n_tokens, 1);
+
+        bool ok = whisper_sched_graph_init(state->sched_decode, state->backends,
+                [&]() {
+                    whisper_batch_prep_legacy(batch, nullptr, n_tokens, n_past, 0);
+
+                    return whisper_build_graph_decoder(*ctx, *state, batch, ctx->params.dtw_token_timestamps, true);
+                });
+
+        whisper_batch_free(batch);
+
+        if (!ok) {
+            WHISPER_LOG_ERROR("%s: failed to init decoder allocator\n", __func__);
+            return false;
+        }
+
+        WHISPER_LOG_INFO("%s: compute buffer (decode) = %7.2f MB\n", __func__, whisper_sched_size(state->sched_decode) / 1e6);
+    }
+
+    return true;
+}
+
 struct whisper_state * whisper_init_state(whisper_context * ctx) {
     whisper_state * state = new whisper_state;
 
@@ -3438,12 +3552,12 @@ struct whisper_state * whisper_init_state(whisper_context * ctx) {
     }
 
 #ifdef WHISPER_USE_COREML
-    const auto path_coreml = whisper_get_coreml_path_encoder(ctx->path_model);
+    const auto path_coreml = ctx->path_coreml.empty() ? whisper_get_coreml_path_encoder(ctx->path_model) : ctx->path_coreml;
 
     WHISPER_LOG_INFO("%s: loading Core ML model from '%s'\n", __func__, path_coreml.c_str());
     WHISPER_LOG_INFO("%s: first run on a device may take a while ...\n", __func__);
 
-    state->ctx_coreml = whisper_coreml_init(path_coreml.c_str());
+    state->ctx_coreml = whisper_coreml_init_with_cache(path_coreml.c_str(), ctx->path_coreml_cache.empty() ? nullptr : ctx->path_coreml_cache.c_str());
     if (!state->ctx_coreml) {
         WHISPER_LOG_ERROR("%s: failed to load Core ML model from '%s'\n", __func__, path_coreml.c_str());
 #ifndef WHISPER_COREML_ALLOW_FALLBACK
@@ -3469,79 +3583,44 @@ struct whisper_state * whisper_init_state(whisper_context * ctx) {
 
     state->decoders[0].rng = std::mt19937(0);
 
-    // conv allocator
-    {
-        bool ok = whisper_sched_graph_init(state->sched_conv, state->backends,
-                [&]() {
-                    return whisper_build_graph_conv(*ctx, *state);
-                });
-
-        if (!ok) {
-            WHISPER_LOG_ERROR("%s: failed to init conv allocator\n", __func__);
-            whisper_free_state(state);
-            return nullptr;
-        }
-
-        WHISPER_LOG_INFO("%s: compute buffer (conv)   = %7.2f MB\n", __func__, whisper_sched_size(state->sched_conv) / 1e6);
-    }
-
-    // encoder allocator
-    if (!whisper_encode_external(*state)) {
-        bool ok = whisper_sched_graph_init(state->sched_encode, state->backends,
-                [&]() {
-                    return whisper_build_graph_encoder(*ctx, *state);
-                });
-
-        if (!ok) {
-            WHISPER_LOG_ERROR("%s: failed to init encoder allocator\n", __func__);
-            whisper_free_state(state);
-            return nullptr;
-        }
-
-        WHISPER_LOG_INFO("%s: compute buffer (encode) = %7.2f MB\n", __func__, whisper_sched_size(state->sched_encode) / 1e6);
-    }
-
-    // cross allocator
-    {
-        bool ok = whisper_sched_graph_init(state->sched_cross, state->backends,
-                [&]() {
-                    return whisper_build_graph_cross(*ctx, *state);
-                });
-
-        if (!ok) {
-            WHISPER_LOG_ERROR("%s: failed to init cross allocator\n", __func__);
-            whisper_free_state(state);
-            return nullptr;
-        }
-
-        WHISPER_LOG_INFO("%s: compute buffer (cross)  = %7.2f MB\n", __func__, whisper_sched_size(state->sched_cross) / 1e6);
+    if (!whisper_state_alloc_compute(ctx, state)) {
+        whisper_free_state(state);
+        return nullptr;
     }
 
-    // decoder allocator
-    {
-        bool ok = whisper_sched_graph_init(state->sched_decode, state->backends,
-                [&]() {
-                    const auto & hparams = ctx->model.hparams;
+    return state;
+}
 
-                    // TODO: make sure this is the worst-case scenario
-                    const int n_tokens = hparams.n_text_ctx;
-                    const int n_past   = 0;
+int whisper_ctx_set_coreml_encoder(
+        struct whisper_context * ctx,
+                    const char * model_path,
+                    const char * cache_dir) {
+#ifndef WHISPER_USE_COREML
+    (void)(ctx);
+    (void)(model_path);
+    (void)(cache_dir);
 
-                    whisper_batch_prep_legacy(state->batch, nullptr, n_tokens, n_past, 0);
+    return 1;
+#else
+    ctx->path_coreml       = model_path ? model_path : "";
+    ctx->path_coreml_cache = cache_dir  ? cache_dir  : "";
 
-                    return whisper_build_graph_decoder(*ctx, *state, state->batch, ctx->params.dtw_token_timestamps, true);
-                });
+    return 0;
+#endif
+}
 
-        if (!ok) {
-            WHISPER_LOG_ERROR("%s: failed to init decoder allocator\n", __func__);
-            whisper_free_state(state);
-            return nullptr;
-        }
+bool whisper_uses_coreml(struct whisper_context * ctx) {
+    return whisper_uses_coreml_with_state(ctx->state);
+}
 
-        WHISPER_LOG_INFO("%s: compute buffer (decode) = %7.2f MB\n", __func__, whisper_sched_size(state->sched_decode) / 1e6);
-    }
+bool whisper_uses_coreml_with_state(struct whisper_state * state) {
+#ifdef WHISPER_USE_COREML
+    return state != nullptr && state->ctx_coreml != nullptr;
+#else
+    (void)(state);
 
-    return state;
+    return false;
+#endif
 }
 
 int whisper_ctx_init_openvino_encoder_with_state(
@@ -3617,6 +3696,9 @@ struct whisper_context_params whisper_context_default_params() {
             /*.heads            =*/ NULL,
         },
         /*.dtw_mem_size         =*/ 1024*1024*128,
+
+        /*.load_progress_callback           =*/ nullptr,
+        /*.load_progress_callback_user_data =*/ nullptr,
     };
     return result;
 }
@@ -3858,6 +3940,62 @@ void whisper_free_state(struct whisper_state * state) {
     }
 }
 
+void whisper_release_compute(struct whisper_context * ctx) {
+    whisper_release_compute_with_state(ctx->state);
+}
+
+void whisper_release_compute_with_state(struct whisper_state * state) {
+    if (state == nullptr) {
+        return;
+    }
+
+    for (auto * sched : { &state->sched_conv, &state->sched_encode, &state->sched_cross, &state->sched_decode }) {
+        ggml_backend_sched_free(sched->sched);
+        sched->sched = nullptr;
+    }
+}
+
+// the compute buffers are released when they cannot be allocated again, so that whisper_full() can
+// tell a failure to allocate them from a failure of the backend
+static bool whisper_compute_allocated(const whisper_state & state) {
+    return state.sched_conv.sched != nullptr;
+}
+
+int whisper_reacquire_compute(struct whisper_context * ctx) {
+    return whisper_reacquire_compute_with_state(ctx, ctx->state);
+}
+
+int whisper_reacquire_compute_with_state(struct whisper_context * ctx, struct whisper_state * state) {
+    if (state == nullptr) {
+        return -1;
+    }
+
+    // the buffers are still allocated
+    if (state->sched_conv.sched != nullptr) {
+        return 0;
+    }
+
+    if (!whisper_state_alloc_compute(ctx, state)) {
+        whisper_release_compute_with_state(state);
+        return -1;
+    }
+
+    return 0;
+}
+
+int whisper_reset_state(struct whisper_context * ctx) {
+    // allocate the new state first, so that the context keeps a state on failure
+    whisper_state * state = whisper_init_state(ctx);
+    if (state == nullptr) {
+        return -1;
+    }
+
+    whisper_free_state(ctx->state);
+    ctx->state = state;
+
+    return 0;
+}
+
 void whisper_free(struct whisper_context * ctx) {
     if (ctx) {
         for (ggml_context * context : ctx->model.ctxs) {
@@ -4163,6 +4301,85 @@ int whisper_model_type(struct whisper_context * ctx) {
     return ctx->model.type;
 }
 
+static const std::pair<const std::string, ggml_tensor *> * whisper_model_tensor(struct whisper_context * ctx, int i) {
+    if (i < 0 || i >= (int) ctx->model.tensors.size()) {
+        return nullptr;
+    }
+    return &*std::next(ctx->model.tensors.begin(), i);
+}
+
+int whisper_model_n_tensors(struct whisper_context * ctx) {
+    return ctx->model.tensors.size();
+}
+
+const char * whisper_model_tensor_name(struct whisper_context * ctx, int i) {
+    const auto * t = whisper_model_tensor(ctx, i);
+    return t ? t->first.c_str() : nullptr;
+}
+
+enum ggml_type whisper_model_tensor_type(struct whisper_context * ctx, int i) {
+    const auto * t = whisper_model_tensor(ctx, i);
+    return t ? t->second->type : GGML_TYPE_COUNT;
+}
+
+size_t whisper_model_tensor_nbytes(struct whisper_context * ctx, int i) {
+    const auto * t = whisper_model_tensor(ctx, i);
+    return t ? ggml_nbytes(t->second) : 0;
+}
+
+int whisper_model_n_buffers(struct whisper_context * ctx) {
+    return ctx->model.buffers.size();
+}
+
+const char * whisper_model_buffer_name(struct whisper_context * ctx, int i) {
+    if (i < 0 || i >= (int) ctx->model.buffers.size()) {
+        return nullptr;
+    }
+    return ggml_backend_buffer_name(ctx->model.buffers[i]);
+}
+
+size_t whisper_model_buffer_size(struct whisper_context * ctx, int i) {
+    if (i < 0 || i >= (int) ctx->model.buffers.size()) {
+        return 0;
+    }
+    return ggml_backend_buffer_get_size(ctx->model.buffers[i]);
+}
+
+int whisper_memory_usage(struct whisper_context * ctx, struct whisper_backend_memory * memory, int n) {
+    return whisper_memory_usage_with_state(ctx->state, memory, n);
+}
+
+int whisper_memory_usage_with_state(struct whisper_state * state, struct whisper_backend_memory * memory, int n) {
+    if (state == nullptr) {
+        return 0;
+    }
+
+    const int n_backends = state->backends.size();
+    for (int i = 0; i < n_backends && i < n; ++i) {
+        ggml_backend_t backend = state->backends[i];
+        ggml_backend_buffer_type_t buft = ggml_backend_get_default_buffer_type(backend);
+
+        memory[i].name     = ggml_backend_buft_name(buft);
+        memory[i].kv_cache = 0;
+        memory[i].compute  = 0;
+
+        for (const auto * kv : { &state->kv_self, &state->kv_cross, &state->kv_pad }) {
+            if (kv->buffer && ggml_backend_buffer_get_type(kv->buffer) == buft) {
+                memory[i].kv_cache += ggml_backend_buffer_get_size(kv->buffer);
+            }
+        }
+
+        // the schedulers are freed when the compute buffers are released
+        for (const auto * sched : { &state->sched_conv, &state->sched_encode, &state->sched_cross, &state->sched_decode }) {
+            if (sched->sched) {
+                memory[i].compute += ggml_backend_sched_get_buffer_size(sched->sched, backend);
+            }
+        }
+    }
+
+    return n_backends;
+}
+
 const char *whisper_model_type_readable(struct whisper_context * ctx) {
     switch (ctx->model.type) {
     case e_model::MODEL_TINY:
@@ -4294,19 +4511,84 @@ void whisper_print_timings(struct whisper_context * ctx) {
 }
 
 void whisper_reset_timings(struct whisper_context * ctx) {
+    whisper_reset_timings_with_state(ctx, nullptr);
+}
+
+void whisper_get_state_timings(struct whisper_context * ctx, struct whisper_state * state, struct whisper_state_timings * timings) {
+    if (state == nullptr) {
+        state = ctx->state;
+    }
+
+    *timings = {};
+    timings->t_load_us  = ctx->t_load_us;
+    timings->t_total_us = ggml_time_us() - ctx->t_start_us;
+
+    if (state != nullptr) {
+        timings->t_mel_us    = state->t_mel_us;
+        timings->t_sample_us = state->t_sample_us;
+        timings->t_encode_us = state->t_encode_us;
+        timings->t_decode_us = state->t_decode_us;
+        timings->t_batchd_us = state->t_batchd_us;
+        timings->t_prompt_us = state->t_prompt_us;
+
+        timings->n_sample = state->n_sample;
+        timings->n_encode = state->n_encode;
+        timings->n_decode = state->n_decode;
+        timings->n_batchd = state->n_batchd;
+        timings->n_prompt = state->n_prompt;
+
+        timings->n_fail_p = state->n_fail_p;
+        timings->n_fail_h = state->n_fail_h;
+    }
+}
+
+void whisper_get_state_sizes(struct whisper_context * ctx, struct whisper_state * state, struct whisper_state_sizes * sizes) {
+    if (state == nullptr && ctx != nullptr) {
+        state = ctx->state;
+    }
+
+    *sizes = {};
+    if (state == nullptr) {
+        return;
+    }
+
+    const auto kv_size = [](const whisper_kv_cache & kv) -> size_t {
+        return kv.k && kv.v ? ggml_nbytes(kv.k) + ggml_nbytes(kv.v) : 0;
+    };
+
+    // the schedulers are freed when the compute buffers are released
+    const auto sched_size = [](whisper_sched & sched) -> size_t {
+        return sched.sched ? whisper_sched_size(sched) : 0;
+    };
+
+    sizes->kv_self  = kv_size(state->kv_self);
+    sizes->kv_cross = kv_size(state->kv_cross);
+    sizes->kv_pad   = kv_size(state->kv_pad);
+
+    sizes->compute_conv   = sched_size(state->sched_conv);
+    sizes->compute_encode = sched_size(state->sched_encode);
+    sizes->compute_cross  = sched_size(state->sched_cross);
+    sizes->compute_decode = sched_size(state->sched_decode);
+}
+
+void whisper_reset_timings_with_state(struct whisper_context * ctx, struct whisper_state * state) {
+    if (state == nullptr) {
+        state = ctx->state;
+    }
+
     ctx->t_start_us = ggml_time_us();
-    if (ctx->state != nullptr) {
-        ctx->state->t_mel_us = 0;
-        ctx->state->t_sample_us = 0;
-        ctx->state->t_encode_us = 0;
-        ctx->state->t_decode_us = 0;
-        ctx->state->t_batchd_us = 0;
-        ctx->state->t_prompt_us = 0;
-        ctx->state->n_sample = 0;
-        ctx->state->n_encode = 0;
-        ctx->state->n_decode = 0;
-        ctx->state->n_batchd = 0;
-        ctx->state->n_prompt = 0;
+    if (state != nullptr) {
+        state->t_mel_us = 0;
+        state->t_sample_us = 0;
+        state->t_encode_us = 0;
+        state->t_decode_us = 0;
+        state->t_batchd_us = 0;
+        state->t_prompt_us = 0;
+        state->n_sample = 0;
+        state->n_encode = 0;
+        state->n_decode = 0;
+        state->n_batchd = 0;
+        state->n_prompt = 0;
     }
 }
 
@@ -6012,6 +6294,11 @@ struct whisper_full_params whisper_full_default_params(enum whisper_sampling_str
         /*.vad_model_path              =*/ nullptr,
 
         /* vad_params =*/ whisper_vad_default_params(),
+
+        /*.temperatures   =*/ nullptr,
+        /*.n_temperatures =*/ 0,
+
+        /*.max_duration_ms =*/ 0,
     };
 
     switch (strategy) {
@@ -6128,6 +6415,69 @@ static int whisper_wrap_segment(struct whisper_context & ctx, struct whisper_sta
     return res;
 }
 
+// split the segments from index i0 so that none is longer than max_t, before the last token within the
+// limit which starts a word, or before the last token within the limit if no word starts within it
+static int whisper_cap_segments(struct whisper_context & ctx, struct whisper_state & state, int i0, int64_t max_t, bool split_on_word) {
+    auto & result_all = state.result_all;
+
+    for (int s = i0; s < (int) result_all.size(); s++) {
+        const auto & segment = result_all[s];
+        if (segment.t1 - segment.t0 <= max_t) {
+            continue;
+        }
+
+        int split_word = -1;
+        int split_any  = -1;
+        for (int i = 1; i < (int) segment.tokens.size(); i++) {
+            const auto & token = segment.tokens[i];
+            if (token.id >= whisper_token_eot(&ctx)) {
+                continue;
+            }
+            if (token.t0 - segment.t0 > max_t) {
+                break;
+            }
+            if (token.t0 <= segment.t0) {
+                continue;
+            }
+            split_any = i;
+            if (should_split_on_word(whisper_token_to_str(&ctx, token.id), split_on_word)) {
+                split_word = i;
+            }
+        }
+
+        const int split = split_word >= 0 ? split_word : split_any;
+        if (split < 0) {
+            continue;
+        }
+
+        whisper_segment tail = segment;
+        tail.t0 = segment.tokens[split].t0;
+        tail.tokens.assign(segment.tokens.begin() + split, segment.tokens.end());
+        tail.text.clear();
+        for (const auto & token : tail.tokens) {
+            if (token.id < whisper_token_eot(&ctx)) {
+                tail.text += whisper_token_to_str(&ctx, token.id);
+            }
+        }
+
+        auto & head = result_all[s];
+        head.t1 = tail.t0;
+        head.tokens.resize(split);
+        head.speaker_turn_next = false;
+        head.text.clear();
+        for (const auto & token : head.tokens) {
+            if (token.id < whisper_token_eot(&ctx)) {
+                head.text += whisper_token_to_str(&ctx, token.id);
+            }
+        }
+
+        // the tail is checked on the next iteration
+        result_all.insert(result_all.begin() + s + 1, std::move(tail));
+    }
+
+    return result_all.size() - i0;
+}
+
 static const std::vector<std::string> non_speech_tokens = {
     "\"", "#", "(", ")", "*", "+", "/", ":", ";", "<", "=", ">", "@", "[", "\\", "]", "^",
     "_", "`", "{", "|", "}", "~", "「", "」", "『", "』", "<<", ">>", "<<<", ">>>", "--",
@@ -6872,7 +7222,9 @@ int whisper_full_with_state(
     // a set of temperatures to use
     // [ t0, t0 + delta, t0 + 2*delta, ..., < 1.0f + 1e-6f ]
     std::vector<float> temperatures;
-    if (params.temperature_inc > 0.0f) {
+    if (params.temperatures && params.n_temperatures > 0) {
+        temperatures.assign(params.temperatures, params.temperatures + params.n_temperatures);
+    } else if (params.temperature_inc > 0.0f) {
         for (float t = params.temperature; t < 1.0f + 1e-6f; t += params.temperature_inc) {
             temperatures.push_back(t);
         }
@@ -7030,6 +7382,9 @@ int whisper_full_with_state(
             break;
         }
 
+        state->seek      = seek;
+        state->seek_skip = -1;
+
         if (params.encoder_begin_callback) {
             if (params.encoder_begin_callback(ctx, state, params.encoder_begin_callback_user_data) == false) {
                 WHISPER_LOG_ERROR("%s: encoder_begin_callback returned false - aborting\n", __func__);
@@ -7037,10 +7392,20 @@ int whisper_full_with_state(
             }
         }
 
+        // skip ahead when requested by the encoder begin callback, dropping the text context which
+        // belongs to the audio before the jump
+        if (state->seek_skip > seek) {
+            const int seek_next = (int) std::min<int64_t>(state->seek_skip, seek_end);
+            WHISPER_LOG_DEBUG("%s: skipping from %d to %d\n", __func__, seek, seek_next);
+            seek = seek_next;
+            prompt_past1.clear();
+            continue;
+        }
+
         // encode audio features starting at offset seek
         if (!whisper_encode_internal(*ctx, *state, seek, params.n_threads, params.abort_callback, params.abort_callback_user_data)) {
             WHISPER_LOG_ERROR("%s: failed to encode\n", __func__);
-            return -6;
+            return whisper_compute_allocated(*state) ? -6 : -10;
         }
 
         // if there is a very short audio segment left to process, we remove any past prompt since it tends
@@ -7166,7 +7531,7 @@ int whisper_full_with_state(
 
                 if (!whisper_decode_internal(*ctx, *state, state->batch, params.n_threads, false, params.abort_callback, params.abort_callback_user_data)) {
                     WHISPER_LOG_ERROR("%s: failed to decode\n", __func__);
-                    return -8;
+                    return whisper_compute_allocated(*state) ? -8 : -10;
                 }
 
                 // Calculate no_speech probability after first decode.
@@ -7478,7 +7843,7 @@ int whisper_full_with_state(
 
                     if (!whisper_decode_internal(*ctx, *state, state->batch, params.n_threads, false, params.abort_callback, params.abort_callback_user_data)) {
                         WHISPER_LOG_ERROR("%s: failed to decode\n", __func__);
-                        return -9;
+                        return whisper_compute_allocated(*state) ? -9 : -10;
                     }
 
                     const int64_t t_start_sample_us = ggml_time_us();
@@ -7674,6 +8039,10 @@ int whisper_full_with_state(
                                 if (params.max_len > 0) {
                                     n_new = whisper_wrap_segment(*ctx, *state, params.max_len, params.split_on_word);
                                 }
+
+                                if (params.max_duration_ms > 0) {
+                                    n_new = whisper_cap_segments(*ctx, *state, result_all.size() - n_new, params.max_duration_ms/10, params.split_on_word);
+                                }
                             }
                             if (params.new_segment_callback && !ctx->params.dtw_token_timestamps) {
                                 params.new_segment_callback(ctx, state, n_new, params.new_segment_callback_user_data);
@@ -7722,6 +8091,10 @@ int whisper_full_with_state(
                         if (params.max_len > 0) {
                             n_new = whisper_wrap_segment(*ctx, *state, params.max_len, params.split_on_word);
                         }
+
+                        if (params.max_duration_ms > 0) {
+                            n_new = whisper_cap_segments(*ctx, *state, result_all.size() - n_new, params.max_duration_ms/10, params.split_on_word);
+                        }
                     }
                     if (params.new_segment_callback && !ctx->params.dtw_token_timestamps) {
                         params.new_segment_callback(ctx, state, n_new, params.new_segment_callback_user_data);
@@ -7979,6 +8352,70 @@ static int64_t map_processed_to_original_time(int64_t processed_time, const std:
     return lower->original_time + (offset * original_diff) / processed_diff;
 }
 
+// The inverse of map_processed_to_original_time, for offsets in the original audio
+static int64_t map_original_to_processed_time(int64_t original_time, const std::vector<vad_time_mapping> & mapping_table) {
+    if (mapping_table.empty()) {
+        return original_time;
+    }
+
+    if (original_time <= mapping_table.front().original_time) {
+        return mapping_table.front().processed_time;
+    }
+
+    if (original_time >= mapping_table.back().original_time) {
+        return mapping_table.back().processed_time;
+    }
+
+    auto upper = std::lower_bound(mapping_table.begin(), mapping_table.end(), original_time,
+        [](const vad_time_mapping & entry, int64_t time) {
+            return entry.original_time < time;
+        }
+    );
+
+    if (upper->original_time == original_time) {
+        return upper->processed_time;
+    }
+
+    auto lower = upper - 1;
+
+    int64_t original_diff = upper->original_time - lower->original_time;
+    int64_t processed_diff = upper->processed_time - lower->processed_time;
+    int64_t offset = original_time - lower->original_time;
+
+    if (original_diff == 0) {
+        return lower->processed_time;
+    }
+
+    return lower->processed_time + (offset * processed_diff) / original_diff;
+}
+
+// Function to get the start of the audio window being decoded
+int64_t whisper_full_get_seek_from_state(struct whisper_state * state) {
+    if (!state->has_vad_segments || state->vad_mapping_table.empty()) {
+        return state->seek;
+    }
+
+    return map_processed_to_original_time(state->seek, state->vad_mapping_table);
+}
+
+int64_t whisper_full_get_seek(struct whisper_context * ctx) {
+    return whisper_full_get_seek_from_state(ctx->state);
+}
+
+// Function to skip ahead from the encoder begin callback, mapping the offset onto the speech
+// segments when VAD is used
+void whisper_full_set_seek_with_state(struct whisper_state * state, int64_t seek) {
+    if (state->has_vad_segments && !state->vad_mapping_table.empty()) {
+        seek = map_original_to_processed_time(seek, state->vad_mapping_table);
+    }
+
+    state->seek_skip = seek;
+}
+
+void whisper_full_set_seek(struct whisper_context * ctx, int64_t seek) {
+    whisper_full_set_seek_with_state(ctx->state, seek);
+}
+
 // Function to get the starting timestamp of a segment
 int64_t whisper_full_get_segment_t0_from_state(struct whisper_state * state, int i_segment) {
     // If VAD wasn't used, return the original timestamp
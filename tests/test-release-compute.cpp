@@ -0,0 +1,68 @@
+#include "whisper.h"
+#include "common-whisper.h"
+
+#include <cmath>
+#include <cstdio>
+#include <string>
+#include <vector>
+
+#ifdef NDEBUG
+#undef NDEBUG
+#endif
+#include <cassert>
+
+// decode the tokens after the audio has been encoded, and return the logits of the last token
+static std::vector<float> decode(struct whisper_context * ctx, const std::vector<whisper_token> & tokens) {
+    assert(whisper_decode(ctx, tokens.data(), tokens.size(), 0, 1) == 0);
+
+    const int     n_vocab = whisper_n_vocab(ctx);
+    const float * logits  = whisper_get_logits(ctx);
+
+    return std::vector<float>(logits + (tokens.size() - 1)*n_vocab, logits + tokens.size()*n_vocab);
+}
+
+static void assert_same_logits(const std::vector<float> & a, const std::vector<float> & b) {
+    assert(a.size() == b.size());
+    for (size_t i = 0; i < a.size(); ++i) {
+        assert(std::fabs(a[i] - b[i]) <= 1e-4f*std::fmax(1.0f, std::fabs(a[i])));
+    }
+}
+
+int main() {
+    std::string whisper_model_path = WHISPER_MODEL_PATH;
+    std::string sample_path        = SAMPLE_PATH;
+
+    // Load the sample audio file
+    std::vector<float> pcmf32;
+    std::vector<std::vector<float>> pcmf32s;
+    assert(read_audio_data(sample_path.c_str(), pcmf32, pcmf32s, false));
+
+    struct whisper_context_params cparams = whisper_context_default_params();
+    cparams.use_gpu = false;
+    struct whisper_context * ctx = whisper_init_from_file_with_params(whisper_model_path.c_str(), cparams);
+    assert(ctx != nullptr);
+
+    assert(whisper_pcm_to_mel(ctx, pcmf32.data(), pcmf32.size(), 1) == 0);
+    assert(whisper_encode(ctx, 0, 1) == 0);
+
+    const std::vector<whisper_token> tokens = {
+        whisper_token_sot(ctx),
+        whisper_token_not(ctx),
+        whisper_token_beg(ctx),
+    };
+    const std::vector<float> expected = decode(ctx, tokens);
+
+    // The first decode after the compute buffers are released allocates them again, and
+    // decodes the tokens of the caller rather than the batch which the buffers are reserved with
+    whisper_release_compute(ctx);
+    assert_same_logits(expected, decode(ctx, tokens));
+
+    // The buffers can also be allocated again explicitly
+    whisper_release_compute(ctx);
+    assert(whisper_reacquire_compute(ctx) == 0);
+    assert_same_logits(expected, decode(ctx, tokens));
+
+    whisper_free(ctx);
+
+    return 0;
+}